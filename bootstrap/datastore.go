@@ -0,0 +1,276 @@
+// +build !js
+
+package bootstrap
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	leveldbStore "github.com/ipfs/go-ds-leveldb"
+	sqlds "github.com/opaolini/go-ds-sql"
+
+	_ "github.com/lib/pq"           // postgres driver
+	_ "github.com/mattn/go-sqlite3" // sqlite driver
+)
+
+const (
+	// PostgresEngine is the SQL database engine (and driver name) for Postgres.
+	PostgresEngine = "postgres"
+	// SQLiteEngine is the SQL database engine (and driver name) for SQLite.
+	SQLiteEngine = "sqlite3"
+
+	dhtTableName       = "dhtkv"
+	peerStoreTableName = "peerStore"
+)
+
+// DatastoreProvider provides the datastores used to persist the DHT and the
+// peerstore of a bootstrap node.
+type DatastoreProvider interface {
+	// DHTDatastore returns the datastore used by the DHT.
+	DHTDatastore() datastore.Batching
+	// PeerstoreDatastore returns the datastore used by the peerstore.
+	PeerstoreDatastore() datastore.Batching
+	// Close releases any resources held by the provider. It is called by the
+	// Node after the DHT has shut down.
+	Close() error
+}
+
+// LevelDBDatastoreProvider is a DatastoreProvider which stores data in LevelDB.
+type LevelDBDatastoreProvider struct {
+	dhtStore       *leveldbStore.Datastore
+	peerstoreStore *leveldbStore.Datastore
+}
+
+var _ DatastoreProvider = &LevelDBDatastoreProvider{}
+
+// NewLevelDBDatastoreProvider creates and returns a new
+// LevelDBDatastoreProvider. The DHT and peerstore data are stored in the "dht"
+// and "peerstore" subdirectories of dir.
+func NewLevelDBDatastoreProvider(dir string) (*LevelDBDatastoreProvider, error) {
+	dhtStore, err := leveldbStore.NewDatastore(filepath.Join(dir, "dht"), nil)
+	if err != nil {
+		return nil, err
+	}
+	peerstoreStore, err := leveldbStore.NewDatastore(filepath.Join(dir, "peerstore"), nil)
+	if err != nil {
+		_ = dhtStore.Close()
+		return nil, err
+	}
+	return &LevelDBDatastoreProvider{
+		dhtStore:       dhtStore,
+		peerstoreStore: peerstoreStore,
+	}, nil
+}
+
+// DHTDatastore implements DatastoreProvider.
+func (p *LevelDBDatastoreProvider) DHTDatastore() datastore.Batching {
+	return p.dhtStore
+}
+
+// PeerstoreDatastore implements DatastoreProvider.
+func (p *LevelDBDatastoreProvider) PeerstoreDatastore() datastore.Batching {
+	return p.peerstoreStore
+}
+
+// Close implements DatastoreProvider.
+func (p *LevelDBDatastoreProvider) Close() error {
+	dhtErr := p.dhtStore.Close()
+	if err := p.peerstoreStore.Close(); err != nil {
+		return err
+	}
+	return dhtErr
+}
+
+// InMemoryDatastoreProvider is a DatastoreProvider which keeps all data in
+// memory. It is mostly useful for tests.
+type InMemoryDatastoreProvider struct {
+	dhtStore       datastore.Batching
+	peerstoreStore datastore.Batching
+}
+
+var _ DatastoreProvider = &InMemoryDatastoreProvider{}
+
+// NewInMemoryDatastoreProvider creates and returns a new
+// InMemoryDatastoreProvider.
+func NewInMemoryDatastoreProvider() *InMemoryDatastoreProvider {
+	return &InMemoryDatastoreProvider{
+		dhtStore:       dssync.MutexWrap(datastore.NewMapDatastore()),
+		peerstoreStore: dssync.MutexWrap(datastore.NewMapDatastore()),
+	}
+}
+
+// DHTDatastore implements DatastoreProvider.
+func (p *InMemoryDatastoreProvider) DHTDatastore() datastore.Batching {
+	return p.dhtStore
+}
+
+// PeerstoreDatastore implements DatastoreProvider.
+func (p *InMemoryDatastoreProvider) PeerstoreDatastore() datastore.Batching {
+	return p.peerstoreStore
+}
+
+// Close implements DatastoreProvider.
+func (p *InMemoryDatastoreProvider) Close() error {
+	return nil
+}
+
+// SQLDatastoreProvider is a DatastoreProvider which stores data in a SQL
+// database (either Postgres or SQLite).
+type SQLDatastoreProvider struct {
+	db             *sql.DB
+	dhtStore       *sqlds.Datastore
+	peerstoreStore *sqlds.Datastore
+}
+
+var _ DatastoreProvider = &SQLDatastoreProvider{}
+
+// NewSQLDatastoreProvider creates and returns a new SQLDatastoreProvider which
+// uses the given database. engine must be either PostgresEngine or
+// SQLiteEngine. The tables used by the DHT and the peerstore are created if
+// they don't already exist. The database is closed when the provider is
+// closed.
+func NewSQLDatastoreProvider(db *sql.DB, engine string) (*SQLDatastoreProvider, error) {
+	if err := prepareSQLDatabase(db, engine); err != nil {
+		return nil, err
+	}
+	dhtQueries, err := newSQLQueries(engine, dhtTableName)
+	if err != nil {
+		return nil, err
+	}
+	peerStoreQueries, err := newSQLQueries(engine, peerStoreTableName)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLDatastoreProvider{
+		db:             db,
+		dhtStore:       sqlds.NewDatastore(db, dhtQueries),
+		peerstoreStore: sqlds.NewDatastore(db, peerStoreQueries),
+	}, nil
+}
+
+// DHTDatastore implements DatastoreProvider.
+func (p *SQLDatastoreProvider) DHTDatastore() datastore.Batching {
+	return p.dhtStore
+}
+
+// PeerstoreDatastore implements DatastoreProvider.
+func (p *SQLDatastoreProvider) PeerstoreDatastore() datastore.Batching {
+	return p.peerstoreStore
+}
+
+// Close implements DatastoreProvider.
+func (p *SQLDatastoreProvider) Close() error {
+	return p.db.Close()
+}
+
+// OpenSQLiteDatabase opens the SQLite database at the given path, creating it
+// if it doesn't exist. The database is configured so that it can be used by
+// a SQLDatastoreProvider.
+func OpenSQLiteDatabase(path string) (*sql.DB, error) {
+	// Both the DHT and the peerstore write to the database while iterating
+	// over query results, which requires more than one connection. WAL mode
+	// lets readers and a writer use the database at the same time and the
+	// busy timeout makes concurrent writers wait for each other instead of
+	// failing.
+	connstr := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000", path)
+	return sql.Open(SQLiteEngine, connstr)
+}
+
+func prepareSQLDatabase(db *sql.DB, engine string) error {
+	dataType, err := getSQLDataType(engine)
+	if err != nil {
+		return err
+	}
+	createTableString := "CREATE TABLE IF NOT EXISTS %s (key TEXT NOT NULL UNIQUE, data %s NOT NULL)"
+	createDHTTable := fmt.Sprintf(createTableString, dhtTableName, dataType)
+	createPeerStoreTable := fmt.Sprintf(createTableString, peerStoreTableName, dataType)
+
+	_, err = db.Exec(createDHTTable)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(createPeerStoreTable)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getSQLDataType returns the column type used to store binary data with the
+// given engine.
+func getSQLDataType(engine string) (string, error) {
+	switch engine {
+	case PostgresEngine:
+		return "BYTEA", nil
+	case SQLiteEngine:
+		return "BLOB", nil
+	default:
+		return "", fmt.Errorf("unsupported SQL database engine: %s", engine)
+	}
+}
+
+// newSQLQueries returns the queries used by the sqldb datastore for the given
+// engine and table.
+func newSQLQueries(engine string, table string) (sqlds.Queries, error) {
+	switch engine {
+	case PostgresEngine:
+		return sqlds.NewQueriesForTable(table), nil
+	case SQLiteEngine:
+		return sqliteQueries{table: table}, nil
+	default:
+		return nil, fmt.Errorf("unsupported SQL database engine: %s", engine)
+	}
+}
+
+// sqliteQueries implements sqlds.Queries for SQLite.
+type sqliteQueries struct {
+	table string
+}
+
+var _ sqlds.Queries = sqliteQueries{}
+
+func (q sqliteQueries) Delete() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE key = ?", q.table)
+}
+
+func (q sqliteQueries) Exists() string {
+	return fmt.Sprintf("SELECT exists(SELECT 1 FROM %s WHERE key = ?)", q.table)
+}
+
+func (q sqliteQueries) Get() string {
+	return fmt.Sprintf("SELECT data FROM %s WHERE key = ?", q.table)
+}
+
+func (q sqliteQueries) Put() string {
+	return fmt.Sprintf("INSERT INTO %s (key, data) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET data = excluded.data", q.table)
+}
+
+func (q sqliteQueries) Query() string {
+	return fmt.Sprintf("SELECT key, data FROM %s", q.table)
+}
+
+func (q sqliteQueries) Prefix() string {
+	// LIKE is case-insensitive in SQLite, but keys are case-sensitive, so we
+	// compare the beginning of the key instead. The datastore formats this
+	// string with the prefix as its only argument.
+	return " WHERE substr(key, 1, length('%[1]s')) = '%[1]s' ORDER BY key"
+}
+
+func (q sqliteQueries) Limit() string {
+	return " LIMIT %d"
+}
+
+func (q sqliteQueries) Offset() string {
+	// Note that SQLite doesn't support OFFSET without LIMIT. Neither the DHT
+	// nor the peerstore use offsets.
+	return " OFFSET %d"
+}
+
+func (q sqliteQueries) GetSize() string {
+	return fmt.Sprintf("SELECT length(data) FROM %s WHERE key = ?", q.table)
+}
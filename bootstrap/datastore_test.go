@@ -0,0 +1,163 @@
+// +build !js
+
+package bootstrap
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSQLiteDatastoreProvider returns a SQLDatastoreProvider which uses a
+// new SQLite database in a temporary directory.
+func newTestSQLiteDatastoreProvider(t *testing.T) *SQLDatastoreProvider {
+	dataDir, err := ioutil.TempDir("", "bootstrap-sqlite")
+	require.NoError(t, err)
+	db, err := OpenSQLiteDatabase(filepath.Join(dataDir, "datastore.sqlite"))
+	require.NoError(t, err)
+	provider, err := NewSQLDatastoreProvider(db, SQLiteEngine)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestSQLiteDatastore(t *testing.T) {
+	t.Parallel()
+	store := newTestSQLiteDatastoreProvider(t).DHTDatastore()
+	key := ds.NewKey("/providers/CIQA")
+
+	_, err := store.Get(key)
+	assert.Equal(t, ds.ErrNotFound, err)
+	has, err := store.Has(key)
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	require.NoError(t, store.Put(key, []byte("foo")))
+	value, err := store.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), value)
+	has, err = store.Has(key)
+	require.NoError(t, err)
+	assert.True(t, has)
+
+	// Putting the same key again should replace the value.
+	require.NoError(t, store.Put(key, []byte("barbaz")))
+	value, err = store.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("barbaz"), value)
+	size, err := store.GetSize(key)
+	require.NoError(t, err)
+	assert.Equal(t, 6, size)
+
+	require.NoError(t, store.Delete(key))
+	_, err = store.Get(key)
+	assert.Equal(t, ds.ErrNotFound, err)
+	_, err = store.GetSize(key)
+	assert.Equal(t, ds.ErrNotFound, err)
+}
+
+func TestSQLiteDatastoreQueryPrefix(t *testing.T) {
+	t.Parallel()
+	store := newTestSQLiteDatastoreProvider(t).PeerstoreDatastore()
+	for _, key := range []string{
+		"/peers/addrs/CIQA",
+		"/peers/addrs/CIQB",
+		"/peers/ADDRS/CIQC",
+		"/peers/keys/CIQA",
+		"/peers/addrsx/CIQD",
+	} {
+		require.NoError(t, store.Put(ds.NewKey(key), []byte(key)))
+	}
+
+	results, err := store.Query(dsq.Query{Prefix: "/peers/addrs"})
+	require.NoError(t, err)
+	entries, err := results.Rest()
+	require.NoError(t, err)
+	actualKeys := []string{}
+	for _, entry := range entries {
+		assert.Equal(t, []byte(entry.Key), entry.Value)
+		actualKeys = append(actualKeys, entry.Key)
+	}
+	sort.Strings(actualKeys)
+	// Prefixes are case-sensitive and only match whole path segments.
+	assert.Equal(t, []string{"/peers/addrs/CIQA", "/peers/addrs/CIQB"}, actualKeys)
+
+	results, err = store.Query(dsq.Query{Prefix: "/peers", Limit: 2})
+	require.NoError(t, err)
+	entries, err = results.Rest()
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestPrepareSQLDatabaseIsIdempotent(t *testing.T) {
+	t.Parallel()
+	provider := newTestSQLiteDatastoreProvider(t)
+	require.NoError(t, provider.DHTDatastore().Put(ds.NewKey("/foo"), []byte("bar")))
+	require.NoError(t, prepareSQLDatabase(provider.db, SQLiteEngine))
+	// Preparing the database again must not remove any data.
+	value, err := provider.DHTDatastore().Get(ds.NewKey("/foo"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bar"), value)
+}
+
+func TestUnsupportedSQLEngine(t *testing.T) {
+	t.Parallel()
+	db, err := OpenSQLiteDatabase(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = NewSQLDatastoreProvider(db, "mysql")
+	assert.Error(t, err)
+	_, err = newSQLQueries("mysql", dhtTableName)
+	assert.Error(t, err)
+}
+
+func TestLevelDBDatastoreProvider(t *testing.T) {
+	t.Parallel()
+	dataDir, err := ioutil.TempDir("", "bootstrap-leveldb")
+	require.NoError(t, err)
+	provider, err := NewLevelDBDatastoreProvider(dataDir)
+	require.NoError(t, err)
+	key := ds.NewKey("/foo")
+	require.NoError(t, provider.DHTDatastore().Put(key, []byte("bar")))
+	// The DHT and the peerstore must not share data.
+	has, err := provider.PeerstoreDatastore().Has(key)
+	require.NoError(t, err)
+	assert.False(t, has)
+	assert.DirExists(t, filepath.Join(dataDir, "dht"))
+	assert.DirExists(t, filepath.Join(dataDir, "peerstore"))
+	require.NoError(t, provider.Close())
+
+	// The data should be persisted after re-opening the datastores.
+	provider, err = NewLevelDBDatastoreProvider(dataDir)
+	require.NoError(t, err)
+	defer provider.Close()
+	value, err := provider.DHTDatastore().Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bar"), value)
+}
+
+func TestSQLiteDatastoreWriteWhileIterating(t *testing.T) {
+	t.Parallel()
+	store := newTestSQLiteDatastoreProvider(t).PeerstoreDatastore()
+	for _, key := range []string{"/peers/gc/1", "/peers/gc/2", "/peers/gc/3"} {
+		require.NoError(t, store.Put(ds.NewKey(key), []byte{}))
+	}
+
+	// The peerstore deletes entries while iterating over query results, which
+	// must not deadlock or fail.
+	results, err := store.Query(dsq.Query{Prefix: "/peers/gc"})
+	require.NoError(t, err)
+	defer results.Close()
+	for result := range results.Next() {
+		require.NoError(t, result.Error)
+		require.NoError(t, store.Delete(ds.NewKey(result.Key)))
+	}
+	has, err := store.Has(ds.NewKey("/peers/gc/3"))
+	require.NoError(t, err)
+	assert.False(t, has)
+}
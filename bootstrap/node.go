@@ -0,0 +1,328 @@
+// +build !js
+
+// Package bootstrap implements bootstrap nodes. Bootstrap nodes will not share
+// or receive any orders and their sole responsibility is to facilitate peer
+// discovery and/or serve as a relay for peer connections. The mesh-bootstrap
+// executable is a thin wrapper around this package, which can also be used to
+// embed bootstrap nodes in other programs and tests.
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/0xProject/0x-mesh/p2p/banner"
+	"github.com/ipfs/go-datastore"
+	libp2p "github.com/libp2p/go-libp2p"
+	autonat "github.com/libp2p/go-libp2p-autonat-svc"
+	circuit "github.com/libp2p/go-libp2p-circuit"
+	connmgr "github.com/libp2p/go-libp2p-connmgr"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/metrics"
+	p2pnet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/routing"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	dhtopts "github.com/libp2p/go-libp2p-kad-dht/opts"
+	"github.com/libp2p/go-libp2p-peerstore/pstoreds"
+	filter "github.com/libp2p/go-maddr-filter"
+	ma "github.com/multiformats/go-multiaddr"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// peerGraceDuration is the amount of time a newly opened connection is given
+	// before it becomes subject to pruning.
+	peerGraceDuration = 10 * time.Second
+	// defaultPeerCountLow is the default value for PeerCountLow.
+	defaultPeerCountLow = 100
+	// defaultPeerCountHigh is the default value for PeerCountHigh.
+	defaultPeerCountHigh = 110
+	// defaultMaxBytesPerSecond is the default value for MaxBytesPerSecond.
+	defaultMaxBytesPerSecond = 100 * 1024 * 1024 // 100 MiB.
+	// bootstrapPeerTag is the tag used to protect other bootstrap peers from
+	// being disconnected by the Connection Manager.
+	bootstrapPeerTag = "bootstrap-peer"
+)
+
+// Config contains configuration options for a Node.
+type Config struct {
+	// PrivateKey is the private key which will be used for signing messages
+	// and generating a peer ID. Required.
+	PrivateKey p2pcrypto.PrivKey
+	// DatastoreProvider provides the datastores used by the DHT and the
+	// peerstore. It is closed after ctx is canceled and the DHT has shut down.
+	// Required.
+	DatastoreProvider DatastoreProvider
+	// BindAddrs are the libp2p multiaddresses which the node will bind to.
+	// Required.
+	BindAddrs []ma.Multiaddr
+	// AdvertiseAddrs are the libp2p multiaddresses which the node will
+	// advertise to peers. If empty, the addresses the node is listening on
+	// are advertised.
+	AdvertiseAddrs []ma.Multiaddr
+	// BootstrapList is a list of multiaddresses of other bootstrap nodes to
+	// connect to. Bootstrap peers are protected from being disconnected and
+	// their IP addresses are protected from being banned. If empty, the node
+	// doesn't connect to any peers on its own.
+	BootstrapList []string
+	// EnableRelayHost is whether or not the node should serve as a relay host.
+	EnableRelayHost bool
+	// PeerCountLow is the target number of peers to connect to at any given
+	// time. Defaults to 100.
+	PeerCountLow int
+	// PeerCountHigh is the maximum number of peers to be connected to. If the
+	// number of connections exceeds this number, we will prune connections until
+	// we reach PeerCountLow. Defaults to 110.
+	PeerCountHigh int
+	// MaxBytesPerSecond is the maximum number of bytes per second that a peer is
+	// allowed to send before failing the bandwidth check. Defaults to 100 MiB.
+	MaxBytesPerSecond float64
+}
+
+// Node is a bootstrap node.
+type Node struct {
+	host             host.Host
+	dht              *dht.IpfsDHT
+	connManager      *connmgr.BasicConnMgr
+	bandwidthCounter *metrics.BandwidthCounter
+	banner           *banner.Banner
+}
+
+// New creates and starts a new bootstrap node. It connects to the peers in
+// config.BootstrapList before returning. The node shuts down when ctx is
+// canceled.
+func New(ctx context.Context, config Config) (*Node, error) {
+	if config.PrivateKey == nil {
+		return nil, errors.New("config.PrivateKey is required")
+	}
+	if config.DatastoreProvider == nil {
+		return nil, errors.New("config.DatastoreProvider is required")
+	}
+	if len(config.BindAddrs) == 0 {
+		return nil, errors.New("config.BindAddrs is required")
+	}
+	if config.PeerCountLow == 0 {
+		config.PeerCountLow = defaultPeerCountLow
+	}
+	if config.PeerCountHigh == 0 {
+		config.PeerCountHigh = defaultPeerCountHigh
+		if config.PeerCountHigh < config.PeerCountLow {
+			config.PeerCountHigh = config.PeerCountLow + (defaultPeerCountHigh - defaultPeerCountLow)
+		}
+	}
+	if config.PeerCountLow < 0 || config.PeerCountHigh < config.PeerCountLow {
+		return nil, errors.New("config.PeerCountHigh must be greater than or equal to config.PeerCountLow")
+	}
+	if config.MaxBytesPerSecond == 0 {
+		config.MaxBytesPerSecond = defaultMaxBytesPerSecond
+	}
+	bootstrapAddrInfos, err := p2p.BootstrapListToAddrInfos(config.BootstrapList)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &Node{
+		connManager:      connmgr.NewConnManager(config.PeerCountLow, config.PeerCountHigh, peerGraceDuration),
+		bandwidthCounter: metrics.NewBandwidthCounter(),
+	}
+
+	peerStore, err := pstoreds.NewPeerstore(ctx, config.DatastoreProvider.PeerstoreDatastore(), pstoreds.DefaultOpts())
+	if err != nil {
+		_ = config.DatastoreProvider.Close()
+		return nil, err
+	}
+
+	// Initialize filters.
+	filters := filter.NewFilters()
+
+	// Set up the transport and the host. libp2p calls node.newDHT while
+	// creating the host, which sets node.dht.
+	opts := []libp2p.Option{
+		libp2p.ListenAddrs(config.BindAddrs...),
+		libp2p.Identity(config.PrivateKey),
+		libp2p.ConnectionManager(node.connManager),
+		libp2p.EnableAutoRelay(),
+		libp2p.Routing(node.newDHT(ctx, config.DatastoreProvider)),
+		libp2p.BandwidthReporter(node.bandwidthCounter),
+		libp2p.Peerstore(peerStore),
+		p2p.Filters(filters),
+	}
+	if len(config.AdvertiseAddrs) > 0 {
+		opts = append(opts, libp2p.AddrsFactory(newAddrsFactory(config.AdvertiseAddrs)))
+	}
+	if config.EnableRelayHost {
+		opts = append(opts, libp2p.EnableRelay(circuit.OptHop))
+	} else {
+		opts = append(opts, libp2p.EnableRelay())
+	}
+	basicHost, err := libp2p.New(ctx, opts...)
+	if err != nil {
+		if node.dht == nil {
+			// Otherwise the DatastoreProvider is closed when the DHT shuts
+			// down.
+			_ = config.DatastoreProvider.Close()
+		}
+		return nil, err
+	}
+	node.host = basicHost
+
+	// Close the host whenever the context is canceled.
+	go func() {
+		<-ctx.Done()
+		_ = basicHost.Close()
+	}()
+
+	// Set up the notifee.
+	basicHost.Network().Notify(&notifee{})
+
+	// Enable AutoNAT service.
+	if _, err := autonat.NewAutoNATService(ctx, basicHost); err != nil {
+		return nil, err
+	}
+
+	// Initialize the DHT and then connect to the other bootstrap nodes.
+	if err := node.dht.Bootstrap(ctx); err != nil {
+		return nil, err
+	}
+	if len(config.BootstrapList) > 0 {
+		if err := p2p.ConnectToBootstrapList(ctx, basicHost, config.BootstrapList); err != nil {
+			return nil, err
+		}
+	}
+
+	// Configure banner.
+	node.banner = banner.New(ctx, banner.Config{
+		Host:                   basicHost,
+		Filters:                filters,
+		BandwidthCounter:       node.bandwidthCounter,
+		MaxBytesPerSecond:      config.MaxBytesPerSecond,
+		LogBandwidthUsageStats: true,
+	})
+
+	// Protect each other bootstrap peer via the connection manager so that we
+	// maintain an active connection to them. Also prevent other bootstrap nodes
+	// from being banned.
+	for _, addrInfo := range bootstrapAddrInfos {
+		node.connManager.Protect(addrInfo.ID, bootstrapPeerTag)
+		for _, addr := range addrInfo.Addrs {
+			_ = node.banner.ProtectIP(addr)
+		}
+	}
+
+	return node, nil
+}
+
+// newDHT returns a function which can be passed to libp2p.Routing. The DHT it
+// creates is stored in n.dht. (Assigning it to a variable which is local to a
+// helper function instead would leave the caller with a nil DHT, which panics
+// when bootstrapped.) The DatastoreProvider is closed once the DHT has shut
+// down because the DHT flushes its datastore when shutting down.
+func (n *Node) newDHT(ctx context.Context, provider DatastoreProvider) func(host.Host) (routing.PeerRouting, error) {
+	return func(h host.Host) (routing.PeerRouting, error) {
+		kadDHT, err := NewDHTWithDatastore(ctx, provider.DHTDatastore(), h)
+		if err != nil {
+			log.WithField("error", err).Error("could not create DHT")
+			return nil, err
+		}
+		n.dht = kadDHT
+		go func() {
+			<-kadDHT.Process().Closed()
+			_ = provider.Close()
+		}()
+		return kadDHT, nil
+	}
+}
+
+// NewDHTWithDatastore returns a new Kademlia DHT instance configured with store
+// as the persistant storage interface.
+func NewDHTWithDatastore(ctx context.Context, store datastore.Batching, host host.Host) (*dht.IpfsDHT, error) {
+	return dht.New(ctx, host, dhtopts.Datastore(store), dhtopts.Protocols(p2p.DHTProtocolID))
+}
+
+// ID returns the peer ID of the node.
+func (n *Node) ID() peer.ID {
+	return n.host.ID()
+}
+
+// Multiaddrs returns the multiaddresses of the node, including its peer ID.
+// They can be used in the BootstrapList of other nodes.
+func (n *Node) Multiaddrs() []ma.Multiaddr {
+	p2pAddrs, err := peer.AddrInfoToP2pAddrs(&peer.AddrInfo{
+		ID:    n.host.ID(),
+		Addrs: n.host.Addrs(),
+	})
+	if err != nil {
+		// This can only happen if the peer ID is invalid.
+		log.WithField("error", err).Error("could not get multiaddresses for bootstrap node")
+		return nil
+	}
+	return p2pAddrs
+}
+
+// Host returns the libp2p host of the node.
+func (n *Node) Host() host.Host {
+	return n.host
+}
+
+// DHT returns the Kademlia DHT of the node.
+func (n *Node) DHT() *dht.IpfsDHT {
+	return n.dht
+}
+
+// ConnManager returns the Connection Manager of the node.
+func (n *Node) ConnManager() *connmgr.BasicConnMgr {
+	return n.connManager
+}
+
+// BandwidthCounter returns the bandwidth counter of the node.
+func (n *Node) BandwidthCounter() *metrics.BandwidthCounter {
+	return n.bandwidthCounter
+}
+
+// Banner returns the banner which is used to ban and protect IP addresses.
+func (n *Node) Banner() *banner.Banner {
+	return n.banner
+}
+
+func newAddrsFactory(advertiseAddrs []ma.Multiaddr) func([]ma.Multiaddr) []ma.Multiaddr {
+	return func([]ma.Multiaddr) []ma.Multiaddr {
+		return advertiseAddrs
+	}
+}
+
+// notifee receives notifications for network-related events.
+type notifee struct{}
+
+var _ p2pnet.Notifiee = &notifee{}
+
+// Listen is called when network starts listening on an addr
+func (n *notifee) Listen(p2pnet.Network, ma.Multiaddr) {}
+
+// ListenClose is called when network stops listening on an addr
+func (n *notifee) ListenClose(p2pnet.Network, ma.Multiaddr) {}
+
+// Connected is called when a connection opened
+func (n *notifee) Connected(network p2pnet.Network, conn p2pnet.Conn) {
+	log.WithFields(map[string]interface{}{
+		"remotePeerID":       conn.RemotePeer(),
+		"remoteMultiaddress": conn.RemoteMultiaddr(),
+	}).Info("connected to peer")
+}
+
+// Disconnected is called when a connection closed
+func (n *notifee) Disconnected(network p2pnet.Network, conn p2pnet.Conn) {
+	log.WithFields(map[string]interface{}{
+		"remotePeerID":       conn.RemotePeer(),
+		"remoteMultiaddress": conn.RemoteMultiaddr(),
+	}).Info("disconnected from peer")
+}
+
+// OpenedStream is called when a stream opened
+func (n *notifee) OpenedStream(network p2pnet.Network, stream p2pnet.Stream) {}
+
+// ClosedStream is called when a stream closed
+func (n *notifee) ClosedStream(network p2pnet.Network, stream p2pnet.Stream) {}
@@ -0,0 +1,157 @@
+// +build !js
+
+package bootstrap
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/p2p/banner"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 10 * time.Second
+
+// newTestConfig returns a Config for a node which listens on the loopback
+// interface and keeps its data in memory.
+func newTestConfig(t *testing.T, bootstrapList []string) Config {
+	privKey, _, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	bindAddr, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/0")
+	require.NoError(t, err)
+	return Config{
+		PrivateKey:        privKey,
+		DatastoreProvider: NewInMemoryDatastoreProvider(),
+		BindAddrs:         []ma.Multiaddr{bindAddr},
+		BootstrapList:     bootstrapList,
+		EnableRelayHost:   true,
+	}
+}
+
+func multiaddrStrings(maddrs []ma.Multiaddr) []string {
+	result := make([]string, len(maddrs))
+	for i, maddr := range maddrs {
+		result[i] = maddr.String()
+	}
+	return result
+}
+
+func TestNewNodesFindEachOther(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Start a few nodes in-process which all use the first node as their only
+	// bootstrap peer.
+	node0, err := New(ctx, newTestConfig(t, nil))
+	require.NoError(t, err)
+	require.NotNil(t, node0.DHT())
+	bootstrapList := multiaddrStrings(node0.Multiaddrs())
+	node1, err := New(ctx, newTestConfig(t, bootstrapList))
+	require.NoError(t, err)
+	node2, err := New(ctx, newTestConfig(t, bootstrapList))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, node0.DHT().RoutingTable().Size())
+	for _, node := range []*Node{node1, node2} {
+		assert.Equal(t, banner.ErrProtectedIP, node.Banner().BanIP(node0.Multiaddrs()[0]), "IP address of bootstrap peer should be protected")
+	}
+
+	// node1 and node2 are not connected to each other, but they should be able
+	// to find each other via the DHT of node0.
+	findCtx, findCancel := context.WithTimeout(ctx, testTimeout)
+	defer findCancel()
+	addrInfo, err := node1.DHT().FindPeer(findCtx, node2.ID())
+	require.NoError(t, err)
+	assert.Equal(t, node2.ID(), addrInfo.ID)
+	assert.NotEmpty(t, addrInfo.Addrs)
+}
+
+func TestNewWithAdvertiseAddrs(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := newTestConfig(t, nil)
+	advertiseAddr, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/60558")
+	require.NoError(t, err)
+	config.AdvertiseAddrs = []ma.Multiaddr{advertiseAddr}
+	node, err := New(ctx, config)
+	require.NoError(t, err)
+	assert.Equal(t, []ma.Multiaddr{advertiseAddr}, node.Host().Addrs())
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testCases := []struct {
+		name          string
+		modify        func(*Config)
+		expectedError string
+	}{
+		{"no private key", func(c *Config) { c.PrivateKey = nil }, "PrivateKey"},
+		{"no datastore provider", func(c *Config) { c.DatastoreProvider = nil }, "DatastoreProvider"},
+		{"no bind addrs", func(c *Config) { c.BindAddrs = nil }, "BindAddrs"},
+		{"peer count high less than low", func(c *Config) { c.PeerCountLow, c.PeerCountHigh = 10, 5 }, "PeerCountHigh"},
+		{"invalid bootstrap list", func(c *Config) { c.BootstrapList = []string{"foo"} }, "multiaddr"},
+	}
+	for _, testCase := range testCases {
+		config := newTestConfig(t, nil)
+		testCase.modify(&config)
+		_, err := New(ctx, config)
+		require.Error(t, err, testCase.name)
+		assert.True(t, strings.Contains(err.Error(), testCase.expectedError), "%s: unexpected error: %s", testCase.name, err)
+	}
+}
+
+// closeRecordingProvider is a DatastoreProvider which records when it is
+// closed.
+type closeRecordingProvider struct {
+	*InMemoryDatastoreProvider
+	closed chan struct{}
+}
+
+func (p *closeRecordingProvider) Close() error {
+	close(p.closed)
+	return nil
+}
+
+func TestDatastoreProviderIsClosedOnShutdown(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &closeRecordingProvider{
+		InMemoryDatastoreProvider: NewInMemoryDatastoreProvider(),
+		closed:                    make(chan struct{}),
+	}
+	config := newTestConfig(t, nil)
+	config.DatastoreProvider = provider
+	node, err := New(ctx, config)
+	require.NoError(t, err)
+
+	select {
+	case <-provider.closed:
+		t.Fatal("provider was closed before the node shut down")
+	default:
+	}
+	cancel()
+	select {
+	case <-provider.closed:
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for provider to be closed")
+	}
+	select {
+	case <-node.DHT().Process().Closed():
+	default:
+		t.Fatal("provider was closed before the DHT shut down")
+	}
+}
@@ -90,6 +90,12 @@ func convertConfig(jsConfig js.Value) (core.Config, error) {
 		EthereumRPCMaxRequestsPer24HrUTC: 100000,
 		EthereumRPCMaxRequestsPerSecond:  30,
 		MaxOrdersInStorage:               100000,
+		StorageEvictionPolicy:            "expirationTime",
+		OrderSharingStrategy:             "roundRobin",
+		EnableSetReconciliation:          false,
+		PeerBanThreshold:                 0,
+		PeerCountLow:                     10,
+		PeerCountHigh:                    12,
 	}
 
 	// Required config options
@@ -132,6 +138,51 @@ func convertConfig(jsConfig js.Value) (core.Config, error) {
 	if maxOrdersInStorage := jsConfig.Get("maxOrdersInStorage"); !isNullOrUndefined(maxOrdersInStorage) {
 		config.MaxOrdersInStorage = maxOrdersInStorage.Int()
 	}
+	if maxOrdersPerMaker := jsConfig.Get("maxOrdersPerMaker"); !isNullOrUndefined(maxOrdersPerMaker) {
+		config.MaxOrdersPerMaker = maxOrdersPerMaker.Int()
+	}
+	if maxOrdersPerFeeRecipient := jsConfig.Get("maxOrdersPerFeeRecipient"); !isNullOrUndefined(maxOrdersPerFeeRecipient) {
+		config.MaxOrdersPerFeeRecipient = maxOrdersPerFeeRecipient.Int()
+	}
+	if storageEvictionPolicy := jsConfig.Get("storageEvictionPolicy"); !isNullOrUndefined(storageEvictionPolicy) {
+		config.StorageEvictionPolicy = storageEvictionPolicy.String()
+	}
+	if orderSharingStrategy := jsConfig.Get("orderSharingStrategy"); !isNullOrUndefined(orderSharingStrategy) {
+		config.OrderSharingStrategy = orderSharingStrategy.String()
+	}
+	if enableSetReconciliation := jsConfig.Get("enableSetReconciliation"); !isNullOrUndefined(enableSetReconciliation) {
+		config.EnableSetReconciliation = enableSetReconciliation.Bool()
+	}
+	if peerBanThreshold := jsConfig.Get("peerBanThreshold"); !isNullOrUndefined(peerBanThreshold) {
+		config.PeerBanThreshold = peerBanThreshold.Int()
+	}
+	if privateNetworkKey := jsConfig.Get("privateNetworkKey"); !isNullOrUndefined(privateNetworkKey) {
+		config.PrivateNetworkKey = privateNetworkKey.String()
+	}
+	if privateNetworkNamespace := jsConfig.Get("privateNetworkNamespace"); !isNullOrUndefined(privateNetworkNamespace) {
+		config.PrivateNetworkNamespace = privateNetworkNamespace.String()
+	}
+	if peerAllowlist := jsConfig.Get("peerAllowlist"); !isNullOrUndefined(peerAllowlist) {
+		config.PeerAllowlist = peerAllowlist.String()
+	}
+	if enableAdaptivePubSubRateLimits := jsConfig.Get("enableAdaptivePubSubRateLimits"); !isNullOrUndefined(enableAdaptivePubSubRateLimits) {
+		config.EnableAdaptivePubSubRateLimits = enableAdaptivePubSubRateLimits.Bool()
+	}
+	if peerCountLow := jsConfig.Get("peerCountLow"); !isNullOrUndefined(peerCountLow) {
+		config.PeerCountLow = peerCountLow.Int()
+	}
+	if peerCountHigh := jsConfig.Get("peerCountHigh"); !isNullOrUndefined(peerCountHigh) {
+		config.PeerCountHigh = peerCountHigh.Int()
+	}
+	if maxPeersPerSubnet := jsConfig.Get("maxPeersPerSubnet"); !isNullOrUndefined(maxPeersPerSubnet) {
+		config.MaxPeersPerSubnet = maxPeersPerSubnet.Int()
+	}
+	if minOutboundPeers := jsConfig.Get("minOutboundPeers"); !isNullOrUndefined(minOutboundPeers) {
+		config.MinOutboundPeers = minOutboundPeers.Int()
+	}
+	if privateKeyPassphrase := jsConfig.Get("privateKeyPassphrase"); !isNullOrUndefined(privateKeyPassphrase) {
+		config.PrivateKeyPassphrase = privateKeyPassphrase.String()
+	}
 
 	return config, nil
 }
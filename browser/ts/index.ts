@@ -118,6 +118,74 @@ export interface Config {
     // maximum expiration time for incoming orders and remove any orders with an
     // expiration time too far in the future. Defaults to 100,000.
     maxOrdersInStorage?: number;
+    // The maximum number of orders from a single maker address that Mesh will
+    // keep in storage. Incoming orders from a maker that has reached this limit
+    // will be rejected. Pinned orders are never rejected because of this limit.
+    // Defaults to 0, which means there is no limit.
+    maxOrdersPerMaker?: number;
+    // The maximum number of orders with a single fee recipient address that
+    // Mesh will keep in storage. Incoming orders with a fee recipient that has
+    // reached this limit will be rejected. Pinned orders are never rejected
+    // because of this limit. Defaults to 0, which means there is no limit.
+    maxOrdersPerFeeRecipient?: number;
+    // Determines which orders are removed when the number of orders in storage
+    // reaches maxOrdersInStorage. "expirationTime" removes the orders with the
+    // highest expiration time first. "fairShareByMaker" removes orders from the
+    // makers with the most orders first. Defaults to "expirationTime".
+    storageEvictionPolicy?: 'expirationTime' | 'fairShareByMaker';
+    // Determines which orders are shared with peers. "priority" shares recently
+    // added orders, pinned orders, and orders which have not been shared yet
+    // first while still eventually sharing every order. "roundRobin" shares all
+    // orders in turn. Defaults to "roundRobin".
+    orderSharingStrategy?: 'priority' | 'roundRobin';
+    // Determines whether Mesh periodically compares its orders with those of
+    // its peers and requests only the orders it is missing. When enabled, the
+    // "priority" orderSharingStrategy stops re-sharing old orders, which greatly
+    // reduces upload bandwidth. Peers running older versions of Mesh don't
+    // support set reconciliation. Defaults to false.
+    enableSetReconciliation?: boolean;
+    // The score below which a peer that keeps sending invalid messages is
+    // banned. Each protocol-level fault (e.g. a message which can't be decoded
+    // or fails schema validation) lowers the score by 5. Orders rejected
+    // because of their on-chain state don't count. A value of 0 disables
+    // automatic banning. Defaults to 0.
+    peerBanThreshold?: number;
+    // An optional hex-encoded 32 byte pre-shared key. If set, Mesh only
+    // connects to peers which use the same key. Because the default bootstrap
+    // nodes don't know the key, bootstrapList is required unless
+    // useBootstrapList is false.
+    privateNetworkKey?: string;
+    // An optional name for a private network. If set, Mesh shares orders on a
+    // pubsub topic and uses a rendezvous string which include the namespace, so
+    // that it never gossips with nodes in the public network.
+    privateNetworkNamespace?: string;
+    // An optional list of peer IDs. If set, Mesh only stays connected to and
+    // accepts orders from these peers and the peers in bootstrapList.
+    peerAllowlist?: string[];
+    // Determines whether the per-peer limits for receiving orders depend on
+    // the score of each peer. If true, peers which have sent us orders that we
+    // stored are allowed to send bigger bursts of messages and peers which have
+    // sent us invalid messages are limited to a lower rate. Defaults to false.
+    enableAdaptivePubSubRateLimits?: boolean;
+    // The target number of peers to connect to at any given time. Defaults to
+    // 10.
+    peerCountLow?: number;
+    // The maximum number of peers to be connected to. If the number of
+    // connections exceeds this number, Mesh will prune connections until it
+    // reaches peerCountLow. Defaults to 12.
+    peerCountHigh?: number;
+    // The maximum number of peers with IP addresses in the same /24 (IPv4) or
+    // /48 (IPv6) subnet that Mesh will be connected to. A value of 0 means
+    // there is no limit. Defaults to 0.
+    maxPeersPerSubnet?: number;
+    // The minimum number of peers that Mesh dialed itself to stay connected
+    // to. Must not be greater than peerCountLow. Defaults to 0.
+    minOutboundPeers?: number;
+    // An optional passphrase used to encrypt the libp2p private key which is
+    // stored in localStorage. If set, an existing plaintext private key is
+    // encrypted the next time Mesh starts up. Encrypted private keys can't be
+    // loaded without the passphrase.
+    privateKeyPassphrase?: string;
 }
 
 export interface ContractAddresses {
@@ -170,6 +238,21 @@ interface WrapperConfig {
     ethereumRPCMaxRequestsPerSecond?: number;
     customContractAddresses?: string; // json-encoded instead of Object.
     maxOrdersInStorage?: number;
+    maxOrdersPerMaker?: number;
+    maxOrdersPerFeeRecipient?: number;
+    storageEvictionPolicy?: string;
+    orderSharingStrategy?: string;
+    enableSetReconciliation?: boolean;
+    peerBanThreshold?: number;
+    privateNetworkKey?: string;
+    privateNetworkNamespace?: string;
+    peerAllowlist?: string; // comma-separated string instead of an array
+    enableAdaptivePubSubRateLimits?: boolean;
+    peerCountLow?: number;
+    peerCountHigh?: number;
+    maxPeersPerSubnet?: number;
+    minOutboundPeers?: number;
+    privateKeyPassphrase?: string;
 }
 
 // The type for signed orders exposed by MeshWrapper. Unlike other types, the
@@ -664,10 +747,12 @@ function configToWrapperConfig(config: Config): WrapperConfig {
     const bootstrapList = config.bootstrapList == null ? undefined : config.bootstrapList.join(',');
     const customContractAddresses =
         config.customContractAddresses == null ? undefined : JSON.stringify(config.customContractAddresses);
+    const peerAllowlist = config.peerAllowlist == null ? undefined : config.peerAllowlist.join(',');
     return {
         ...config,
         bootstrapList,
         customContractAddresses,
+        peerAllowlist,
     };
 }
 
@@ -0,0 +1,417 @@
+// +build !js
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/bootstrap"
+	"github.com/0xProject/0x-mesh/p2p/banner"
+	circuit "github.com/libp2p/go-libp2p-circuit"
+	connmgr "github.com/libp2p/go-libp2p-connmgr"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/metrics"
+	p2pnet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	ma "github.com/multiformats/go-multiaddr"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// adminProtectTag is the tag used to protect peers via the admin API so that
+	// they are not disconnected by the Connection Manager.
+	adminProtectTag = "admin-protected"
+	// maxAdminRequestSize is the maximum size of the body of an admin API
+	// request.
+	maxAdminRequestSize = 1 << 16
+)
+
+// adminServer serves a small JSON HTTP API for operators of bootstrap nodes.
+// It reports the state of the node (connected peers, DHT routing table size,
+// relayed connections, bandwidth usage and bans) and can be used to ban, unban
+// and protect peers.
+//
+// Endpoints:
+//
+//	GET  /status   summary of the node
+//	GET  /peers    information about every connected peer
+//	GET  /bans     all banned IP addresses
+//	POST /ban      ban a peer or IP address
+//	POST /unban    unban a peer or IP address
+//	POST /protect  protect a peer or IP address from being banned
+//
+// The POST endpoints accept a JSON body of the form {"peerID": "..."} or
+// {"multiaddr": "..."}.
+type adminServer struct {
+	host             host.Host
+	dht              *dht.IpfsDHT
+	connManager      *connmgr.BasicConnMgr
+	banner           *banner.Banner
+	bandwidthCounter *metrics.BandwidthCounter
+	token            string
+	startTime        time.Time
+	bannedPeersMut   sync.Mutex
+	// bannedPeers maps each peer banned via the admin API to the addresses
+	// that were banned along with it, so that they can be unbanned later.
+	bannedPeers map[peer.ID][]ma.Multiaddr
+}
+
+// adminStatus is the response for GET /status.
+type adminStatus struct {
+	PeerID           string    `json:"peerID"`
+	Multiaddrs       []string  `json:"multiaddrs"`
+	StartTime        time.Time `json:"startTime"`
+	NumPeers         int       `json:"numPeers"`
+	NumInbound       int       `json:"numInbound"`
+	NumOutbound      int       `json:"numOutbound"`
+	RoutingTableSize int       `json:"routingTableSize"`
+	// RelayedConns is the number of connections currently being relayed
+	// through this node for other peers.
+	RelayedConns int     `json:"relayedConns"`
+	NumBans      int     `json:"numBans"`
+	BytesIn      int64   `json:"bytesIn"`
+	BytesOut     int64   `json:"bytesOut"`
+	RateIn       float64 `json:"rateIn"`
+	RateOut      float64 `json:"rateOut"`
+}
+
+// adminPeerInfo is the information about a single peer returned by GET
+// /peers. It uses the same field names as rpc.PeerInfo.
+type adminPeerInfo struct {
+	ID         string         `json:"id"`
+	Multiaddrs []string       `json:"multiaddrs"`
+	Direction  string         `json:"direction"`
+	Latency    time.Duration  `json:"latency"`
+	Scores     map[string]int `json:"scores"`
+	// RelayedConns is the number of connections this node is currently relaying
+	// on behalf of the peer.
+	RelayedConns int     `json:"relayedConns"`
+	BytesIn      int64   `json:"bytesIn"`
+	BytesOut     int64   `json:"bytesOut"`
+	RateIn       float64 `json:"rateIn"`
+	RateOut      float64 `json:"rateOut"`
+}
+
+// adminActionRequest is the body of a POST /ban, /unban or /protect request.
+// Exactly one of PeerID and Multiaddr must be set.
+type adminActionRequest struct {
+	PeerID    string `json:"peerID"`
+	Multiaddr string `json:"multiaddr"`
+}
+
+type adminErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type adminSuccessResponse struct {
+	Success bool `json:"success"`
+}
+
+// newAdminServer creates and returns a new adminServer for the given node. If
+// token is not empty, every request must include an
+// "Authorization: Bearer <token>" header.
+func newAdminServer(node *bootstrap.Node, token string) *adminServer {
+	return &adminServer{
+		host:             node.Host(),
+		dht:              node.DHT(),
+		connManager:      node.ConnManager(),
+		banner:           node.Banner(),
+		bandwidthCounter: node.BandwidthCounter(),
+		token:            token,
+		startTime:        time.Now(),
+		bannedPeers:      map[peer.ID][]ma.Multiaddr{},
+	}
+}
+
+// Listen serves the admin API on the given address. It blocks until there is
+// an error or the given context is canceled.
+func (s *adminServer) Listen(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.serve(ctx, listener)
+}
+
+func (s *adminServer) serve(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{Handler: s.handler()}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// handler returns the http.Handler for the admin API.
+func (s *adminServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleGet(s.status))
+	mux.HandleFunc("/peers", s.handleGet(s.peers))
+	mux.HandleFunc("/bans", s.handleGet(func() interface{} { return s.banner.Bans() }))
+	mux.HandleFunc("/ban", s.handleAction(s.ban))
+	mux.HandleFunc("/unban", s.handleAction(s.unban))
+	mux.HandleFunc("/protect", s.handleAction(s.protect))
+	return s.authenticate(mux)
+}
+
+func (s *adminServer) authenticate(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+	expected := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeAdminError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *adminServer) handleGet(get func() interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, get())
+	}
+}
+
+func (s *adminServer) handleAction(action func(adminActionRequest) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeAdminError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		var req adminActionRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminRequestSize)).Decode(&req); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		if (req.PeerID == "") == (req.Multiaddr == "") {
+			writeAdminError(w, http.StatusBadRequest, errors.New("exactly one of peerID or multiaddr is required"))
+			return
+		}
+		if err := action(req); err != nil {
+			status := http.StatusBadRequest
+			if err == banner.ErrProtectedIP {
+				status = http.StatusConflict
+			}
+			writeAdminError(w, status, err)
+			return
+		}
+		log.WithFields(map[string]interface{}{
+			"path":      r.URL.Path,
+			"peerID":    req.PeerID,
+			"multiaddr": req.Multiaddr,
+		}).Info("performed admin action")
+		writeAdminJSON(w, http.StatusOK, adminSuccessResponse{Success: true})
+	}
+}
+
+func (s *adminServer) status() interface{} {
+	status := adminStatus{
+		PeerID:           s.host.ID().Pretty(),
+		Multiaddrs:       multiaddrsToStrings(s.host.Addrs()),
+		StartTime:        s.startTime,
+		RoutingTableSize: s.dht.RoutingTable().Size(),
+		NumBans:          len(s.banner.Bans()),
+	}
+	peers := s.host.Network().Peers()
+	status.NumPeers = len(peers)
+	for _, id := range peers {
+		conns := s.host.Network().ConnsToPeer(id)
+		if len(conns) == 0 {
+			continue
+		}
+		switch conns[0].Stat().Direction {
+		case p2pnet.DirInbound:
+			status.NumInbound++
+		case p2pnet.DirOutbound:
+			status.NumOutbound++
+		}
+		status.RelayedConns += countRelayedConns(conns)
+	}
+	totals := s.bandwidthCounter.GetBandwidthTotals()
+	status.BytesIn = totals.TotalIn
+	status.BytesOut = totals.TotalOut
+	status.RateIn = totals.RateIn
+	status.RateOut = totals.RateOut
+	return status
+}
+
+func (s *adminServer) peers() interface{} {
+	peers := s.host.Network().Peers()
+	peerInfos := make([]adminPeerInfo, 0, len(peers))
+	for _, id := range peers {
+		conns := s.host.Network().ConnsToPeer(id)
+		if len(conns) == 0 {
+			// The peer disconnected after we got the list of peers.
+			continue
+		}
+		bandwidth := s.banner.GetBandwidthForPeer(id)
+		peerInfo := adminPeerInfo{
+			ID:           id.Pretty(),
+			Multiaddrs:   make([]string, len(conns)),
+			Direction:    directionToString(conns[0].Stat().Direction),
+			Latency:      s.host.Peerstore().LatencyEWMA(id),
+			Scores:       map[string]int{},
+			RelayedConns: countRelayedConns(conns),
+			BytesIn:      bandwidth.TotalIn,
+			BytesOut:     bandwidth.TotalOut,
+			RateIn:       bandwidth.RateIn,
+			RateOut:      bandwidth.RateOut,
+		}
+		for i, conn := range conns {
+			peerInfo.Multiaddrs[i] = conn.RemoteMultiaddr().String()
+		}
+		if tagInfo := s.connManager.GetTagInfo(id); tagInfo != nil {
+			for tag, val := range tagInfo.Tags {
+				peerInfo.Scores[tag] = val
+			}
+		}
+		peerInfos = append(peerInfos, peerInfo)
+	}
+	return peerInfos
+}
+
+// ban bans the given IP address or the IP addresses of all connections to the
+// given peer. Banned peers are disconnected.
+func (s *adminServer) ban(req adminActionRequest) error {
+	if req.Multiaddr != "" {
+		maddr, err := ma.NewMultiaddr(req.Multiaddr)
+		if err != nil {
+			return err
+		}
+		return s.banner.BanIP(maddr)
+	}
+	id, err := peer.IDB58Decode(req.PeerID)
+	if err != nil {
+		return err
+	}
+	conns := s.host.Network().ConnsToPeer(id)
+	if len(conns) == 0 {
+		return errors.New("not connected to peer")
+	}
+	bannedAddrs := []ma.Multiaddr{}
+	for _, conn := range conns {
+		if err := s.banner.BanIP(conn.RemoteMultiaddr()); err != nil {
+			return err
+		}
+		bannedAddrs = append(bannedAddrs, conn.RemoteMultiaddr())
+	}
+	s.bannedPeersMut.Lock()
+	s.bannedPeers[id] = append(s.bannedPeers[id], bannedAddrs...)
+	s.bannedPeersMut.Unlock()
+	return s.host.Network().ClosePeer(id)
+}
+
+// unban removes the ban for the given IP address or for the IP addresses
+// which were banned along with the given peer.
+func (s *adminServer) unban(req adminActionRequest) error {
+	if req.Multiaddr != "" {
+		maddr, err := ma.NewMultiaddr(req.Multiaddr)
+		if err != nil {
+			return err
+		}
+		return s.banner.UnbanIP(maddr)
+	}
+	id, err := peer.IDB58Decode(req.PeerID)
+	if err != nil {
+		return err
+	}
+	s.bannedPeersMut.Lock()
+	bannedAddrs, found := s.bannedPeers[id]
+	delete(s.bannedPeers, id)
+	s.bannedPeersMut.Unlock()
+	if !found {
+		return errors.New("peer was not banned via the admin API")
+	}
+	for _, addr := range bannedAddrs {
+		if err := s.banner.UnbanIP(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protect protects the given IP address from being banned. For a peer, the IP
+// addresses of all connections to it are protected and the peer is protected
+// from being disconnected by the Connection Manager.
+func (s *adminServer) protect(req adminActionRequest) error {
+	if req.Multiaddr != "" {
+		maddr, err := ma.NewMultiaddr(req.Multiaddr)
+		if err != nil {
+			return err
+		}
+		return s.banner.ProtectIP(maddr)
+	}
+	id, err := peer.IDB58Decode(req.PeerID)
+	if err != nil {
+		return err
+	}
+	s.connManager.Protect(id, adminProtectTag)
+	for _, conn := range s.host.Network().ConnsToPeer(id) {
+		if err := s.banner.ProtectIP(conn.RemoteMultiaddr()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countRelayedConns returns the number of connections being relayed on
+// behalf of a peer, i.e. the number of inbound relay hop streams.
+func countRelayedConns(conns []p2pnet.Conn) int {
+	count := 0
+	for _, conn := range conns {
+		for _, stream := range conn.GetStreams() {
+			if stream.Protocol() == circuit.ProtoID && stream.Stat().Direction == p2pnet.DirInbound {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func directionToString(direction p2pnet.Direction) string {
+	switch direction {
+	case p2pnet.DirInbound:
+		return "inbound"
+	case p2pnet.DirOutbound:
+		return "outbound"
+	default:
+		return "unknown"
+	}
+}
+
+func multiaddrsToStrings(maddrs []ma.Multiaddr) []string {
+	result := make([]string, len(maddrs))
+	for i, maddr := range maddrs {
+		result[i] = maddr.String()
+	}
+	return result
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithField("error", err).Error("could not write admin API response")
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	writeAdminJSON(w, status, adminErrorResponse{Error: err.Error()})
+}
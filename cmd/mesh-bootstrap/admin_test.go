@@ -0,0 +1,236 @@
+// +build !js
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/bootstrap"
+	"github.com/0xProject/0x-mesh/p2p/banner"
+	libp2p "github.com/libp2p/go-libp2p"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAdminServer creates an adminServer for a new bootstrap node which
+// listens on the loopback interface and returns it along with a test HTTP
+// server which serves the admin API.
+func newTestAdminServer(ctx context.Context, t *testing.T, token string) (*adminServer, *httptest.Server) {
+	privKey, _, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	bindAddr, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/0")
+	require.NoError(t, err)
+	node, err := bootstrap.New(ctx, bootstrap.Config{
+		PrivateKey:        privKey,
+		DatastoreProvider: bootstrap.NewInMemoryDatastoreProvider(),
+		BindAddrs:         []ma.Multiaddr{bindAddr},
+		PeerCountLow:      10,
+		PeerCountHigh:     20,
+	})
+	require.NoError(t, err)
+	admin := newAdminServer(node, token)
+	server := httptest.NewServer(admin.handler())
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	return admin, server
+}
+
+// newTestPeer creates a new host and connects it to the host of the given
+// adminServer.
+func newTestPeer(ctx context.Context, t *testing.T, admin *adminServer) host.Host {
+	peerHost, err := libp2p.New(ctx, libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	require.NoError(t, peerHost.Connect(ctx, peer.AddrInfo{
+		ID:    admin.host.ID(),
+		Addrs: admin.host.Addrs(),
+	}))
+	return peerHost
+}
+
+func getAdminJSON(t *testing.T, server *httptest.Server, path string, result interface{}) {
+	resp, err := http.Get(server.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+}
+
+func postAdminAction(t *testing.T, server *httptest.Server, path string, req adminActionRequest) (int, string) {
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+	resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, ""
+	}
+	var errResp adminErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	return resp.StatusCode, errResp.Error
+}
+
+func TestAdminStatusAndPeers(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	admin, server := newTestAdminServer(ctx, t, "")
+	peerHost := newTestPeer(ctx, t, admin)
+
+	var status adminStatus
+	getAdminJSON(t, server, "/status", &status)
+	assert.Equal(t, admin.host.ID().Pretty(), status.PeerID)
+	assert.Equal(t, 1, status.NumPeers)
+	assert.Equal(t, 1, status.NumInbound)
+	assert.Equal(t, 0, status.NumOutbound)
+	assert.Equal(t, 0, status.RelayedConns)
+	assert.Equal(t, 0, status.NumBans)
+
+	var peers []adminPeerInfo
+	getAdminJSON(t, server, "/peers", &peers)
+	require.Len(t, peers, 1)
+	assert.Equal(t, peerHost.ID().Pretty(), peers[0].ID)
+	assert.Equal(t, "inbound", peers[0].Direction)
+	require.Len(t, peers[0].Multiaddrs, 1)
+	assert.NotNil(t, peers[0].Scores)
+}
+
+func TestAdminBanAndUnbanPeer(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	admin, server := newTestAdminServer(ctx, t, "")
+	peerHost := newTestPeer(ctx, t, admin)
+
+	status, errMsg := postAdminAction(t, server, "/ban", adminActionRequest{PeerID: peerHost.ID().Pretty()})
+	require.Equal(t, http.StatusOK, status, errMsg)
+	assert.Len(t, admin.host.Network().ConnsToPeer(peerHost.ID()), 0, "banned peer should be disconnected")
+
+	var bans []banner.Ban
+	getAdminJSON(t, server, "/bans", &bans)
+	require.Len(t, bans, 1)
+	assert.Equal(t, "127.0.0.1", bans[0].IP.String())
+	assert.True(t, bans[0].Expiration.IsZero())
+
+	// The banned peer should not be able to reconnect.
+	dialCtx, dialCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer dialCancel()
+	peerHost.Peerstore().ClearAddrs(admin.host.ID())
+	err := peerHost.Connect(dialCtx, peer.AddrInfo{ID: admin.host.ID(), Addrs: admin.host.Addrs()})
+	assert.Error(t, err)
+
+	status, errMsg = postAdminAction(t, server, "/unban", adminActionRequest{PeerID: peerHost.ID().Pretty()})
+	require.Equal(t, http.StatusOK, status, errMsg)
+	getAdminJSON(t, server, "/bans", &bans)
+	assert.Len(t, bans, 0)
+
+	// Unbanning the peer again should fail because it is no longer banned.
+	status, _ = postAdminAction(t, server, "/unban", adminActionRequest{PeerID: peerHost.ID().Pretty()})
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestAdminBanAndUnbanIP(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, server := newTestAdminServer(ctx, t, "")
+
+	status, errMsg := postAdminAction(t, server, "/ban", adminActionRequest{Multiaddr: "/ip4/1.2.3.4/tcp/60558"})
+	require.Equal(t, http.StatusOK, status, errMsg)
+	var bans []banner.Ban
+	getAdminJSON(t, server, "/bans", &bans)
+	require.Len(t, bans, 1)
+	assert.Equal(t, "1.2.3.4", bans[0].IP.String())
+
+	status, errMsg = postAdminAction(t, server, "/unban", adminActionRequest{Multiaddr: "/ip4/1.2.3.4/tcp/60558"})
+	require.Equal(t, http.StatusOK, status, errMsg)
+	getAdminJSON(t, server, "/bans", &bans)
+	assert.Len(t, bans, 0)
+}
+
+func TestAdminProtect(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	admin, server := newTestAdminServer(ctx, t, "")
+	peerHost := newTestPeer(ctx, t, admin)
+
+	status, errMsg := postAdminAction(t, server, "/protect", adminActionRequest{PeerID: peerHost.ID().Pretty()})
+	require.Equal(t, http.StatusOK, status, errMsg)
+
+	// The peer and its IP address should no longer be bannable.
+	status, errMsg = postAdminAction(t, server, "/ban", adminActionRequest{PeerID: peerHost.ID().Pretty()})
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, banner.ErrProtectedIP.Error(), errMsg)
+	assert.NotEmpty(t, admin.host.Network().ConnsToPeer(peerHost.ID()), "protected peer should not be disconnected")
+	maddr, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/1234")
+	require.NoError(t, err)
+	assert.Equal(t, banner.ErrProtectedIP, admin.banner.BanIP(maddr))
+}
+
+func TestAdminInvalidRequests(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, server := newTestAdminServer(ctx, t, "")
+
+	testCases := []struct {
+		name string
+		path string
+		req  adminActionRequest
+	}{
+		{"no peer ID or multiaddr", "/ban", adminActionRequest{}},
+		{"both peer ID and multiaddr", "/ban", adminActionRequest{PeerID: "foo", Multiaddr: "/ip4/1.2.3.4/tcp/60558"}},
+		{"invalid peer ID", "/ban", adminActionRequest{PeerID: "foo"}},
+		{"invalid multiaddr", "/protect", adminActionRequest{Multiaddr: "foo"}},
+	}
+	for _, testCase := range testCases {
+		status, errMsg := postAdminAction(t, server, testCase.path, testCase.req)
+		assert.Equal(t, http.StatusBadRequest, status, testCase.name)
+		assert.NotEmpty(t, errMsg, testCase.name)
+	}
+
+	resp, err := http.Get(server.URL + "/ban")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, server := newTestAdminServer(ctx, t, "secret")
+
+	for _, authorization := range []string{"", "Bearer wrong", "secret"} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/status", nil)
+		require.NoError(t, err)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "authorization: %q", authorization)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/status", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
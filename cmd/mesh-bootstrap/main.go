@@ -12,36 +12,19 @@ import (
 	"strings"
 	"time"
 
+	"github.com/0xProject/0x-mesh/bootstrap"
+	"github.com/0xProject/0x-mesh/keys"
 	"github.com/0xProject/0x-mesh/loghooks"
 	"github.com/0xProject/0x-mesh/p2p"
 	"github.com/0xProject/0x-mesh/p2p/banner"
-	"github.com/ipfs/go-datastore"
-	leveldbStore "github.com/ipfs/go-ds-leveldb"
-	libp2p "github.com/libp2p/go-libp2p"
-	autonat "github.com/libp2p/go-libp2p-autonat-svc"
-	circuit "github.com/libp2p/go-libp2p-circuit"
-	connmgr "github.com/libp2p/go-libp2p-connmgr"
-	"github.com/libp2p/go-libp2p-core/host"
-	"github.com/libp2p/go-libp2p-core/metrics"
-	p2pnet "github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
-	"github.com/libp2p/go-libp2p-core/routing"
-	dht "github.com/libp2p/go-libp2p-kad-dht"
-	dhtopts "github.com/libp2p/go-libp2p-kad-dht/opts"
-	peerstore "github.com/libp2p/go-libp2p-peerstore"
-	"github.com/libp2p/go-libp2p-peerstore/pstoreds"
 	"github.com/libp2p/go-libp2p/p2p/host/relay"
-	filter "github.com/libp2p/go-maddr-filter"
 	ma "github.com/multiformats/go-multiaddr"
-	sqlds "github.com/opaolini/go-ds-sql"
 	"github.com/plaid/go-envvar/envvar"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	// peerGraceDuration is the amount of time a newly opened connection is given
-	// before it becomes subject to pruning.
-	peerGraceDuration = 10 * time.Second
 	// defaultNetworkTimeout is the default timeout for network requests (e.g.
 	// connecting to a new peer).
 	defaultNetworkTimeout = 30 * time.Second
@@ -59,6 +42,7 @@ const (
 	// DataStoreType constants
 	leveldbDataStore = "leveldb"
 	sqlDataStore     = "sqldb"
+	memoryDataStore  = "memory"
 )
 
 // Config contains configuration options for a Node.
@@ -73,7 +57,8 @@ type Config struct {
 	P2PAdvertiseAddrs string `envvar:"P2P_ADVERTISE_ADDRS"`
 	// DataStoreType is the data store which will be used to store DHT data
 	// for the bootstrap node.
-	// DataStoreType can be either: leveldb or sqldb
+	// DataStoreType can be either: leveldb, sqldb or memory. Data is not
+	// persisted across restarts when using memory.
 	DataStoreType string `envvar:"DATA_STORE_TYPE" default:"leveldb"`
 	// LevelDBDataDir is the directory used for storing data when using leveldb as data store type.
 	LevelDBDataDir string `envvar:"LEVELDB_DATA_DIR" default:"0x_mesh"`
@@ -94,11 +79,15 @@ type Config struct {
 	// using postgres as data store type.
 	SQLDBPassword string `envvar:"SQL_DB_PASSWORD" default:"" json:"-"`
 	// SQLDBName is the database name to connect to when using
-	// postgres as data store type.
+	// postgres as data store type. When using sqlite3, the database is stored
+	// in a file with this name (plus a ".sqlite" extension) in
+	// LEVELDB_DATA_DIR/p2p.
 	SQLDBName string `envvar:"SQL_DB_NAME" default:"datastore" json:"-"`
 	// SQLDBEngine is the underyling database engine to use as the
-	// database driver.
-	// NOTE: Currently only `postgres` driver is supported.
+	// database driver. It can be either: postgres or sqlite3. When using
+	// sqlite3, SQL_DB_CONNECTION_STRING can be set to a SQLite file name or
+	// URI instead of using SQL_DB_NAME. Using WAL mode (_journal_mode=WAL) is
+	// recommended in that case.
 	SQLDBEngine string `envvar:"SQL_DB_ENGINE" default:"postgres"`
 	// BootstrapList is a comma-separated list of multiaddresses to use for
 	// bootstrapping the DHT (e.g.,
@@ -120,6 +109,24 @@ type Config struct {
 	// TODO(albrow): Reduce this limit once we have a better picture of what real
 	// world bandwidth should be. Defaults to 100 MiB.
 	MaxBytesPerSecond float64 `envvar:"MAX_BYTES_PER_SECOND" default:"104857600"`
+	// AdminHTTPAddr is the address (e.g. "localhost:60560") on which to serve
+	// the admin HTTP API. It reports connected peers, the DHT routing table
+	// size, relayed connections, bandwidth usage and banned IP addresses and
+	// can be used to ban, unban and protect peers. If empty, the admin API is
+	// disabled. It should only be exposed publicly if AdminHTTPToken is set.
+	AdminHTTPAddr string `envvar:"ADMIN_HTTP_ADDR" default:""`
+	// AdminHTTPToken is an optional secret for the admin HTTP API. If set,
+	// requests must include an "Authorization: Bearer <token>" header.
+	AdminHTTPToken string `envvar:"ADMIN_HTTP_TOKEN" default:"" json:"-"`
+	// PrivateKeyPassphrase is an optional passphrase used to encrypt the libp2p
+	// private key (LEVELDB_DATA_DIR/keys/privkey). If it is set and the
+	// existing private key is in plaintext, the private key is encrypted the
+	// next time the bootstrap node starts up.
+	PrivateKeyPassphrase string `envvar:"PRIVATE_KEY_PASSPHRASE" default:"" json:"-"`
+	// PrivateKeyPassphraseFile is the path of a file which contains the
+	// passphrase for the libp2p private key. It can be used instead of
+	// PrivateKeyPassphrase, but not together with it.
+	PrivateKeyPassphraseFile string `envvar:"PRIVATE_KEY_PASSPHRASE_FILE" default:""`
 }
 
 func init() {
@@ -146,7 +153,11 @@ func main() {
 	log.AddHook(loghooks.NewKeySuffixHook())
 
 	// Parse private key file and add peer ID log hook
-	privKey, err := initPrivateKey(getPrivateKeyPath(config))
+	privKeyPassphrase, err := keys.GetPassphrase(config.PrivateKeyPassphrase, config.PrivateKeyPassphraseFile)
+	if err != nil {
+		log.WithField("error", err).Fatal("could not get private key passphrase")
+	}
+	privKey, err := keys.InitPrivateKey(getPrivateKeyPath(config), privKeyPassphrase)
 	if err != nil {
 		log.WithField("error", err).Fatal("could not initialize private key")
 	}
@@ -156,71 +167,9 @@ func main() {
 	}
 	log.AddHook(loghooks.NewPeerIDHook(peerID))
 
-	// We need to declare the newDHT function ahead of time so we can use it in
-	// the libp2p.Routing option.
-	var kadDHT *dht.IpfsDHT
-	var newDHT func(h host.Host) (routing.PeerRouting, error)
-
-	var peerStore peerstore.Peerstore
-
-	// TODO(oskar) - Figure out why returning an anonymous function from
-	// getNewDHT() is making kadDHT.runBootstrap panicing.
-	// When solved this big switch case can be removed from main()
-	switch config.DataStoreType {
-	case leveldbDataStore:
-		newDHT = func(h host.Host) (routing.PeerRouting, error) {
-			var err error
-			dhtDir := getDHTDir(config)
-			kadDHT, err = p2p.NewDHT(ctx, dhtDir, h)
-			if err != nil {
-				log.WithField("error", err).Fatal("could not create DHT")
-			}
-			return kadDHT, err
-		}
-
-		// Set up the peerstore to use LevelDB.
-		store, err := leveldbStore.NewDatastore(getPeerstoreDir(config), nil)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		peerStore, err = pstoreds.NewPeerstore(ctx, store, pstoreds.DefaultOpts())
-		if err != nil {
-			log.Fatal(err)
-		}
-
-	case sqlDataStore:
-		db, err := getSQLDatabase(config)
-		if err != nil {
-			log.WithField("error", err).Fatal("could not create SQL database")
-		}
-
-		err = prepareSQLDatabase(db)
-		if err != nil {
-			log.WithField("error", err).Fatal("failed to repare SQL tables for datastores")
-		}
-
-		newDHT = func(h host.Host) (routing.PeerRouting, error) {
-			var err error
-			dstore := sqlds.NewDatastore(db, sqlds.NewQueriesForTable(dhtTableName))
-
-			kadDHT, err = NewDHTWithDatastore(ctx, dstore, h)
-			if err != nil {
-				log.WithField("error", err).Fatal("could not create DHT")
-			}
-
-			return kadDHT, err
-		}
-
-		pstore := sqlds.NewDatastore(db, sqlds.NewQueriesForTable(peerStoreTableName))
-		peerStore, err = pstoreds.NewPeerstore(ctx, pstore, pstoreds.DefaultOpts())
-		if err != nil {
-			log.WithField("error", err).Fatal("could not create peerStore")
-		}
-
-	default:
-		log.Fatalf("invalid datastore configured: %s. Expected either %s or %s", config.DataStoreType, leveldbDataStore, sqlDataStore)
-
+	datastoreProvider, err := newDatastoreProvider(config)
+	if err != nil {
+		log.WithField("error", err).Fatal("could not create datastores")
 	}
 
 	// Parse multiaddresses given in the config
@@ -232,80 +181,39 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	// Initialize filters.
-	filters := filter.NewFilters()
-
-	// Set up the transport and the host.
-	connManager := connmgr.NewConnManager(config.PeerCountLow, config.PeerCountHigh, peerGraceDuration)
-	bandwidthCounter := metrics.NewBandwidthCounter()
-	opts := []libp2p.Option{
-		libp2p.ListenAddrs(bindAddrs...),
-		libp2p.Identity(privKey),
-		libp2p.ConnectionManager(connManager),
-		libp2p.EnableAutoRelay(),
-		libp2p.Routing(newDHT),
-		libp2p.AddrsFactory(newAddrsFactory(advertiseAddrs)),
-		libp2p.BandwidthReporter(bandwidthCounter),
-		libp2p.Peerstore(peerStore),
-		p2p.Filters(filters),
-	}
-
-	if config.EnableRelayHost {
-		opts = append(opts, libp2p.EnableRelay(circuit.OptHop))
-	} else {
-		opts = append(opts, libp2p.EnableRelay())
-	}
-	basicHost, err := libp2p.New(ctx, opts...)
-	if err != nil {
-		log.WithField("error", err).Fatal("could not create host")
-	}
-
-	// Set up the notifee.
-	basicHost.Network().Notify(&notifee{})
-
-	// Enable AutoNAT service.
-	if _, err := autonat.NewAutoNATService(ctx, basicHost); err != nil {
-		log.WithField("error", err).Fatal("could not enable AutoNAT service")
-	}
-
-	// Initialize the DHT and then connect to the other bootstrap nodes.
-	if err := kadDHT.Bootstrap(ctx); err != nil {
-		log.WithField("error", err).Fatal("could not bootstrap DHT")
-	}
 	bootstrapList := p2p.DefaultBootstrapList
 	if config.BootstrapList != "" {
 		bootstrapList = strings.Split(config.BootstrapList, ",")
 	}
-	if err := p2p.ConnectToBootstrapList(ctx, basicHost, bootstrapList); err != nil {
-		log.WithField("error", err).Fatal("could not connect to bootstrap peers")
-	}
 
-	// Configure banner.
-	banner := banner.New(ctx, banner.Config{
-		Host:                   basicHost,
-		Filters:                filters,
-		BandwidthCounter:       bandwidthCounter,
-		MaxBytesPerSecond:      config.MaxBytesPerSecond,
-		LogBandwidthUsageStats: true,
+	node, err := bootstrap.New(ctx, bootstrap.Config{
+		PrivateKey:        privKey,
+		DatastoreProvider: datastoreProvider,
+		BindAddrs:         bindAddrs,
+		AdvertiseAddrs:    advertiseAddrs,
+		BootstrapList:     bootstrapList,
+		EnableRelayHost:   config.EnableRelayHost,
+		PeerCountLow:      config.PeerCountLow,
+		PeerCountHigh:     config.PeerCountHigh,
+		MaxBytesPerSecond: config.MaxBytesPerSecond,
 	})
-
-	// Protect each other bootstrap peer via the connection manager so that we
-	// maintain an active connection to them. Also prevent other bootstrap nodes
-	// from being banned.
-	bootstrapAddrInfos, err := p2p.BootstrapListToAddrInfos(bootstrapList)
 	if err != nil {
-		log.WithField("error", err).Fatal("could not parse bootstrap list")
+		log.WithField("error", err).Fatal("could not start bootstrap node")
 	}
-	for _, addrInfo := range bootstrapAddrInfos {
-		connManager.Protect(addrInfo.ID, "bootstrap-peer")
-		for _, addr := range addrInfo.Addrs {
-			_ = banner.ProtectIP(addr)
-		}
+
+	// Start the admin HTTP API if it is enabled.
+	if config.AdminHTTPAddr != "" {
+		admin := newAdminServer(node, config.AdminHTTPToken)
+		go func() {
+			if err := admin.Listen(ctx, config.AdminHTTPAddr); err != nil {
+				log.WithField("error", err).Fatal("admin HTTP API stopped")
+			}
+		}()
+		log.WithField("addr", config.AdminHTTPAddr).Info("started admin HTTP API")
 	}
 
 	log.WithFields(map[string]interface{}{
-		"addrs":  basicHost.Addrs(),
+		"addrs":  node.Host().Addrs(),
 		"config": config,
 	}).Info("started bootstrap node")
 
@@ -313,42 +221,26 @@ func main() {
 	select {}
 }
 
-// notifee receives notifications for network-related events.
-type notifee struct{}
-
-var _ p2pnet.Notifiee = &notifee{}
-
-// Listen is called when network starts listening on an addr
-func (n *notifee) Listen(p2pnet.Network, ma.Multiaddr) {}
-
-// ListenClose is called when network stops listening on an addr
-func (n *notifee) ListenClose(p2pnet.Network, ma.Multiaddr) {}
-
-// Connected is called when a connection opened
-func (n *notifee) Connected(network p2pnet.Network, conn p2pnet.Conn) {
-	log.WithFields(map[string]interface{}{
-		"remotePeerID":       conn.RemotePeer(),
-		"remoteMultiaddress": conn.RemoteMultiaddr(),
-	}).Info("connected to peer")
-}
-
-// Disconnected is called when a connection closed
-func (n *notifee) Disconnected(network p2pnet.Network, conn p2pnet.Conn) {
-	log.WithFields(map[string]interface{}{
-		"remotePeerID":       conn.RemotePeer(),
-		"remoteMultiaddress": conn.RemoteMultiaddr(),
-	}).Info("disconnected from peer")
-}
-
-// OpenedStream is called when a stream opened
-func (n *notifee) OpenedStream(network p2pnet.Network, stream p2pnet.Stream) {}
-
-// ClosedStream is called when a stream closed
-func (n *notifee) ClosedStream(network p2pnet.Network, stream p2pnet.Stream) {}
-
-func newAddrsFactory(advertiseAddrs []ma.Multiaddr) func([]ma.Multiaddr) []ma.Multiaddr {
-	return func([]ma.Multiaddr) []ma.Multiaddr {
-		return advertiseAddrs
+// newDatastoreProvider returns the DatastoreProvider for config.DataStoreType.
+func newDatastoreProvider(config Config) (bootstrap.DatastoreProvider, error) {
+	switch config.DataStoreType {
+	case leveldbDataStore:
+		return bootstrap.NewLevelDBDatastoreProvider(getLevelDBDir(config))
+	case sqlDataStore:
+		db, err := getSQLDatabase(config)
+		if err != nil {
+			return nil, err
+		}
+		provider, err := bootstrap.NewSQLDatastoreProvider(db, config.SQLDBEngine)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		return provider, nil
+	case memoryDataStore:
+		return bootstrap.NewInMemoryDatastoreProvider(), nil
+	default:
+		return nil, fmt.Errorf("invalid datastore configured: %s. Expected either %s, %s or %s", config.DataStoreType, leveldbDataStore, sqlDataStore, memoryDataStore)
 	}
 }
 
@@ -379,9 +271,3 @@ func continuoslyCheckBandwidth(ctx context.Context, banner *banner.Banner) error
 		}
 	}
 }
-
-// NewDHTWithDatastore returns a new Kademlia DHT instance configured with store
-// as the persistant storage interface.
-func NewDHTWithDatastore(ctx context.Context, store datastore.Batching, host host.Host) (*dht.IpfsDHT, error) {
-	return dht.New(ctx, host, dhtopts.Datastore(store), dhtopts.Protocols(p2p.DHTProtocolID))
-}
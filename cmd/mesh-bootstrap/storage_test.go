@@ -0,0 +1,48 @@
+// +build !js
+
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/bootstrap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLiteConfig(t *testing.T) Config {
+	dataDir, err := ioutil.TempDir("", "mesh-bootstrap-sqlite")
+	require.NoError(t, err)
+	return Config{
+		DataStoreType:  sqlDataStore,
+		SQLDBEngine:    bootstrap.SQLiteEngine,
+		SQLDBName:      "datastore",
+		LevelDBDataDir: dataDir,
+	}
+}
+
+func TestNewSQLiteDatastoreProvider(t *testing.T) {
+	t.Parallel()
+	config := newTestSQLiteConfig(t)
+	provider, err := newDatastoreProvider(config)
+	require.NoError(t, err)
+	defer provider.Close()
+	assert.FileExists(t, getSQLitePath(config))
+}
+
+func TestNewDatastoreProvider(t *testing.T) {
+	t.Parallel()
+	provider, err := newDatastoreProvider(Config{DataStoreType: memoryDataStore})
+	require.NoError(t, err)
+	assert.IsType(t, &bootstrap.InMemoryDatastoreProvider{}, provider)
+
+	_, err = newDatastoreProvider(Config{DataStoreType: "foo"})
+	assert.Error(t, err)
+}
+
+func TestUnsupportedSQLEngine(t *testing.T) {
+	t.Parallel()
+	_, err := getSQLDatabase(Config{SQLDBEngine: "mysql"})
+	assert.Error(t, err)
+}
@@ -1,9 +1,23 @@
 // +build !js
 
 // mesh-keygen is a short program that can be used to generate private keys.
+// It can also encrypt existing private keys and change the passphrase of
+// encrypted private keys.
+//
+// Usage:
+//
+//	mesh-keygen [generate]         generate a new private key
+//	mesh-keygen encrypt            encrypt an existing plaintext private key
+//	mesh-keygen change-passphrase  change the passphrase of an encrypted private key
+//
+// The private key is encrypted with PRIVATE_KEY_PASSPHRASE (or the contents
+// of PRIVATE_KEY_PASSPHRASE_FILE). When generating a new private key, it is
+// saved in plaintext if neither is set.
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
 
@@ -14,6 +28,17 @@ import (
 type envVars struct {
 	// PrivateKeyPath is the path where the private key will be written.
 	PrivateKeyPath string `envvar:"PRIVATE_KEY_PATH" default:"0x_mesh/keys/privkey"`
+	// PrivateKeyPassphrase is the passphrase used to encrypt the private key.
+	// For change-passphrase, it is the current passphrase.
+	PrivateKeyPassphrase string `envvar:"PRIVATE_KEY_PASSPHRASE" default:""`
+	// PrivateKeyPassphraseFile is the path of a file which contains
+	// PrivateKeyPassphrase.
+	PrivateKeyPassphraseFile string `envvar:"PRIVATE_KEY_PASSPHRASE_FILE" default:""`
+	// NewPrivateKeyPassphrase is the new passphrase for change-passphrase.
+	NewPrivateKeyPassphrase string `envvar:"NEW_PRIVATE_KEY_PASSPHRASE" default:""`
+	// NewPrivateKeyPassphraseFile is the path of a file which contains
+	// NewPrivateKeyPassphrase.
+	NewPrivateKeyPassphraseFile string `envvar:"NEW_PRIVATE_KEY_PASSPHRASE_FILE" default:""`
 }
 
 func main() {
@@ -21,10 +46,66 @@ func main() {
 	if err := envvar.Parse(&env); err != nil {
 		log.Fatal(err)
 	}
-	if _, err := os.Stat(env.PrivateKeyPath); !os.IsNotExist(err) {
-		log.Fatalf("Key file: %s already exists. If you really want to overwrite it, delete the file and try again.", env.PrivateKeyPath)
+	passphrase, err := keys.GetPassphrase(env.PrivateKeyPassphrase, env.PrivateKeyPassphraseFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	command := "generate"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
 	}
-	if _, err := keys.GenerateAndSavePrivateKey(env.PrivateKeyPath); err != nil {
+	switch command {
+	case "generate":
+		err = generate(env.PrivateKeyPath, passphrase)
+	case "encrypt":
+		err = encrypt(env.PrivateKeyPath, passphrase)
+	case "change-passphrase":
+		var newPassphrase string
+		newPassphrase, err = keys.GetPassphrase(env.NewPrivateKeyPassphrase, env.NewPrivateKeyPassphraseFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = changePassphrase(env.PrivateKeyPath, passphrase, newPassphrase)
+	default:
+		err = fmt.Errorf("unknown command: %s. Expected one of: generate, encrypt, change-passphrase", command)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+func generate(path string, passphrase string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return fmt.Errorf("Key file: %s already exists. If you really want to overwrite it, delete the file and try again.", path)
+	}
+	_, err := keys.GenerateAndSavePrivateKeyWithPassphrase(path, passphrase)
+	return err
+}
+
+func encrypt(path string, passphrase string) error {
+	if passphrase == "" {
+		return errors.New("PRIVATE_KEY_PASSPHRASE or PRIVATE_KEY_PASSPHRASE_FILE is required to encrypt a private key")
+	}
+	encrypted, err := keys.IsPrivateKeyEncrypted(path)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		return fmt.Errorf("Key file: %s is already encrypted. Use change-passphrase to change its passphrase.", path)
+	}
+	return keys.ChangePassphrase(path, "", passphrase)
+}
+
+func changePassphrase(path string, oldPassphrase string, newPassphrase string) error {
+	if newPassphrase == "" {
+		return errors.New("NEW_PRIVATE_KEY_PASSPHRASE or NEW_PRIVATE_KEY_PASSPHRASE_FILE is required to change the passphrase of a private key")
+	}
+	encrypted, err := keys.IsPrivateKeyEncrypted(path)
+	if err != nil {
+		return err
+	}
+	if !encrypted {
+		return fmt.Errorf("Key file: %s is not encrypted. Use encrypt to encrypt it.", path)
+	}
+	return keys.ChangePassphrase(path, oldPassphrase, newPassphrase)
+}
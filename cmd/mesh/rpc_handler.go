@@ -14,11 +14,17 @@ import (
 
 	"github.com/0xProject/0x-mesh/constants"
 	"github.com/0xProject/0x-mesh/core"
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/0xProject/0x-mesh/p2p/banner"
 	"github.com/0xProject/0x-mesh/rpc"
 	"github.com/0xProject/0x-mesh/zeroex"
 	"github.com/0xProject/0x-mesh/zeroex/ordervalidator"
+	"github.com/ethereum/go-ethereum/common"
 	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	peer "github.com/libp2p/go-libp2p-peer"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	ma "github.com/multiformats/go-multiaddr"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -123,6 +129,77 @@ func (handler *rpcHandler) AddOrders(signedOrdersRaw []*json.RawMessage, opts rp
 	return validationResults, nil
 }
 
+// ValidateOrders is called when an RPC client calls ValidateOrders.
+func (handler *rpcHandler) ValidateOrders(signedOrdersRaw []*json.RawMessage, opts rpc.ValidateOrdersOpts) (results *ordervalidator.ValidationResults, err error) {
+	blockNumber := ethrpc.LatestBlockNumber
+	if opts.BlockNumber != nil {
+		blockNumber = ethrpc.BlockNumber(*opts.BlockNumber)
+	}
+	log.WithFields(log.Fields{
+		"count":       len(signedOrdersRaw),
+		"blockNumber": blockNumber,
+	}).Info("received ValidateOrders request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "ValidateOrders",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in ValidateOrders RPC call (check logs for stack trace)")
+		}
+	}()
+	validationResults, err := handler.app.ValidateOrders(signedOrdersRaw, blockNumber)
+	if err != nil {
+		// We don't want to leak internal error details to the RPC client.
+		log.WithField("error", err.Error()).Error("internal error in ValidateOrders RPC call")
+		return nil, constants.ErrInternal
+	}
+	return validationResults, nil
+}
+
+// GetOrderStateAtBlock is called when an RPC client calls GetOrderStateAtBlock.
+func (handler *rpcHandler) GetOrderStateAtBlock(orderHashes []common.Hash, signedOrdersRaw []*json.RawMessage, blockNumber int) (results *ordervalidator.ValidationResults, err error) {
+	log.WithFields(log.Fields{
+		"numOrderHashes": len(orderHashes),
+		"numOrders":      len(signedOrdersRaw),
+		"blockNumber":    blockNumber,
+	}).Info("received GetOrderStateAtBlock request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetOrderStateAtBlock",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetOrderStateAtBlock RPC call (check logs for stack trace)")
+		}
+	}()
+	validationResults, err := handler.app.GetOrderStateAtBlock(orderHashes, signedOrdersRaw, ethrpc.BlockNumber(blockNumber))
+	if err != nil {
+		switch err.(type) {
+		case core.ErrBlockStateUnavailable, core.ErrBlockNotProcessed:
+			return nil, err
+		}
+		// We don't want to leak internal error details to the RPC client.
+		log.WithField("error", err.Error()).Error("internal error in GetOrderStateAtBlock RPC call")
+		return nil, constants.ErrInternal
+	}
+	return validationResults, nil
+}
+
 // AddPeer is called when an RPC client calls AddPeer,
 func (handler *rpcHandler) AddPeer(peerInfo peerstore.PeerInfo) (err error) {
 	log.Debug("received AddPeer request via RPC")
@@ -176,6 +253,279 @@ func (handler *rpcHandler) GetStats() (result *rpc.GetStatsResponse, err error)
 	return getStatsResponse, nil
 }
 
+// BackupDatabase is called when an RPC client calls BackupDatabase.
+func (handler *rpcHandler) BackupDatabase(path string) (err error) {
+	log.WithField("path", path).Info("received BackupDatabase request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "BackupDatabase",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in BackupDatabase RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.BackupDatabase(path); err != nil {
+		if _, ok := err.(db.InvalidBackupPathError); ok {
+			// Let the client know why the request failed.
+			return err
+		}
+		log.WithField("error", err.Error()).Error("internal error in BackupDatabase RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// CompactDatabase is called when an RPC client calls CompactDatabase.
+func (handler *rpcHandler) CompactDatabase() (err error) {
+	log.Info("received CompactDatabase request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "CompactDatabase",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in CompactDatabase RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.CompactDatabase(); err != nil {
+		log.WithField("error", err.Error()).Error("internal error in CompactDatabase RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// GetPeers is called when an RPC client calls GetPeers.
+func (handler *rpcHandler) GetPeers() (result []*rpc.PeerInfo, err error) {
+	log.Debug("received GetPeers request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "GetPeers",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in GetPeers RPC call (check logs for stack trace)")
+		}
+	}()
+	peers, err := handler.app.GetPeers()
+	if err != nil {
+		log.WithField("error", err.Error()).Error("internal error in GetPeers RPC call")
+		return nil, constants.ErrInternal
+	}
+	return peers, nil
+}
+
+// BanPeer is called when an RPC client calls BanPeer.
+func (handler *rpcHandler) BanPeer(peerID peer.ID) (err error) {
+	log.WithField("peerID", peerID.Pretty()).Info("received BanPeer request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "BanPeer",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in BanPeer RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.BanPeer(peerID); err != nil {
+		if err == banner.ErrProtectedIP || err == p2p.ErrProtectedPeer {
+			// Let the client know why the request failed.
+			return err
+		}
+		log.WithField("error", err.Error()).Error("internal error in BanPeer RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// BanIP is called when an RPC client calls BanIP.
+func (handler *rpcHandler) BanIP(maddr ma.Multiaddr) (err error) {
+	log.WithField("maddr", maddr.String()).Info("received BanIP request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "BanIP",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in BanIP RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.BanIP(maddr); err != nil {
+		if err == banner.ErrProtectedIP {
+			// Let the client know why the request failed.
+			return err
+		}
+		log.WithField("error", err.Error()).Error("internal error in BanIP RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// UnbanPeer is called when an RPC client calls UnbanPeer.
+func (handler *rpcHandler) UnbanPeer(peerID peer.ID) (err error) {
+	log.WithField("peerID", peerID.Pretty()).Info("received UnbanPeer request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "UnbanPeer",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in UnbanPeer RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.UnbanPeer(peerID); err != nil {
+		log.WithField("error", err.Error()).Error("internal error in UnbanPeer RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// UnbanIP is called when an RPC client calls UnbanIP.
+func (handler *rpcHandler) UnbanIP(maddr ma.Multiaddr) (err error) {
+	log.WithField("maddr", maddr.String()).Info("received UnbanIP request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "UnbanIP",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in UnbanIP RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.UnbanIP(maddr); err != nil {
+		log.WithField("error", err.Error()).Error("internal error in UnbanIP RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// ProtectPeer is called when an RPC client calls ProtectPeer.
+func (handler *rpcHandler) ProtectPeer(peerID peer.ID) (err error) {
+	log.WithField("peerID", peerID.Pretty()).Info("received ProtectPeer request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "ProtectPeer",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in ProtectPeer RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.ProtectPeer(peerID); err != nil {
+		log.WithField("error", err.Error()).Error("internal error in ProtectPeer RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// ProtectIP is called when an RPC client calls ProtectIP.
+func (handler *rpcHandler) ProtectIP(maddr ma.Multiaddr) (err error) {
+	log.WithField("maddr", maddr.String()).Info("received ProtectIP request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "ProtectIP",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in ProtectIP RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.ProtectIP(maddr); err != nil {
+		log.WithField("error", err.Error()).Error("internal error in ProtectIP RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
+// DisconnectPeer is called when an RPC client calls DisconnectPeer.
+func (handler *rpcHandler) DisconnectPeer(peerID peer.ID) (err error) {
+	log.WithField("peerID", peerID.Pretty()).Info("received DisconnectPeer request via RPC")
+	// Catch panics, log stack trace and return RPC error message
+	defer func() {
+		if r := recover(); r != nil {
+			internalErr, ok := r.(error)
+			if !ok {
+				// If r is not of type error, convert it.
+				internalErr = fmt.Errorf("Recovered from non-error: (%T) %v", r, r)
+			}
+			log.WithFields(log.Fields{
+				"error":      internalErr,
+				"method":     "DisconnectPeer",
+				"stackTrace": string(debug.Stack()),
+			}).Error("RPC method handler crashed")
+			err = errors.New("method handler crashed in DisconnectPeer RPC call (check logs for stack trace)")
+		}
+	}()
+	if err := handler.app.DisconnectPeer(peerID); err != nil {
+		log.WithField("error", err.Error()).Error("internal error in DisconnectPeer RPC call")
+		return constants.ErrInternal
+	}
+	return nil
+}
+
 // SubscribeToOrders is called when an RPC client sends a `mesh_subscribe` request with the `orders` topic parameter
 func (handler *rpcHandler) SubscribeToOrders(ctx context.Context) (result *ethrpc.Subscription, err error) {
 	log.Debug("received order event subscription request via RPC")
@@ -75,6 +75,10 @@ func init() {
 // is more than 10x the size of a typical ERC20 order to account for multiAsset orders.
 const MaxOrderSizeInBytes = 8192
 
+// MaxMessageSizeInBytes is the maximum number of bytes allowed for messages
+// shared with peers via GossipSub. A single message can contain several orders.
+const MaxMessageSizeInBytes = 8 * MaxOrderSizeInBytes
+
 // MaxBlocksStoredInNonArchiveNode is the max number of historical blocks for which a regular Ethereum
 // node stores archive-level state. One cannot make `eth_call` requests specifying blocks earlier than
 // 128 blocks ago on non-archive nodes.
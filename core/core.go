@@ -4,8 +4,8 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/core/ordersync"
 	"github.com/0xProject/0x-mesh/db"
 	"github.com/0xProject/0x-mesh/ethereum"
 	"github.com/0xProject/0x-mesh/ethereum/blockwatch"
@@ -34,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	ethrpc "github.com/ethereum/go-ethereum/rpc"
 	"github.com/google/uuid"
+	"github.com/libp2p/go-libp2p-core/network"
 	p2pcrypto "github.com/libp2p/go-libp2p-crypto"
 	peer "github.com/libp2p/go-libp2p-peer"
 	peerstore "github.com/libp2p/go-libp2p-peerstore"
@@ -53,9 +55,21 @@ const (
 	defaultNonPollingEthRPCRequestBuffer = 82720
 	// logStatsInterval is how often to log stats for this node.
 	logStatsInterval = 5 * time.Minute
-	version          = "6.1.2-beta"
+	// orderSyncMinPeers is the number of peers to sync orders with (via the
+	// order sync protocol) after starting up.
+	orderSyncMinPeers = 5
+	// pubSubTopicVersion is the version of the pubsub topic on which orders are
+	// shared. Version 2 uses the binary message format, which can contain
+	// several orders per message.
+	pubSubTopicVersion = 2
+	version            = "6.1.2-beta"
 )
 
+// legacyPubSubTopicVersions are the versions of the pubsub topic which were
+// used by older versions of Mesh. Version 1 uses the JSON message format, with
+// exactly one order per message.
+var legacyPubSubTopicVersions = []int{1}
+
 // Note(albrow): The Config type is currently copied to browser/ts/index.ts. We
 // need to keep both definitions in sync, so if you change one you must also
 // change the other.
@@ -130,6 +144,99 @@ type Config struct {
 	// enforcing a limit on maximum expiration time for incoming orders and remove
 	// any orders with an expiration time too far in the future.
 	MaxOrdersInStorage int `envvar:"MAX_ORDERS_IN_STORAGE" default:"100000"`
+	// MaxOrdersPerMaker is the maximum number of orders from a single maker
+	// address that Mesh will keep in storage. Incoming orders from a maker that
+	// has reached this limit will be rejected. Pinned orders are never rejected
+	// because of this limit. A value of 0 means there is no limit.
+	MaxOrdersPerMaker int `envvar:"MAX_ORDERS_PER_MAKER" default:"0"`
+	// MaxOrdersPerFeeRecipient is the maximum number of orders with a single fee
+	// recipient address that Mesh will keep in storage. Incoming orders with a
+	// fee recipient that has reached this limit will be rejected. Pinned orders
+	// are never rejected because of this limit. A value of 0 means there is no
+	// limit.
+	MaxOrdersPerFeeRecipient int `envvar:"MAX_ORDERS_PER_FEE_RECIPIENT" default:"0"`
+	// StorageEvictionPolicy determines which orders are removed when the number
+	// of orders in storage reaches MaxOrdersInStorage. "expirationTime" removes
+	// the orders with the highest expiration time first. "fairShareByMaker"
+	// removes orders from the makers with the most orders first.
+	StorageEvictionPolicy string `envvar:"STORAGE_EVICTION_POLICY" default:"expirationTime"`
+	// OrderSharingStrategy determines which orders are shared with peers.
+	// "priority" shares recently added orders, pinned orders, and orders which
+	// have not been shared yet first while still eventually sharing every order.
+	// "roundRobin" shares all orders in turn.
+	OrderSharingStrategy string `envvar:"ORDER_SHARING_STRATEGY" default:"roundRobin"`
+	// EnableSetReconciliation determines whether Mesh periodically compares its
+	// orders with those of its peers and requests only the orders it is missing.
+	// When enabled, the "priority" OrderSharingStrategy stops re-sharing old
+	// orders via GossipSub, which greatly reduces upload bandwidth. It is
+	// disabled by default because peers running older versions of Mesh don't
+	// support set reconciliation and would stop receiving old orders.
+	EnableSetReconciliation bool `envvar:"ENABLE_SET_RECONCILIATION" default:"false"`
+	// PeerBanThreshold is the score below which a peer that keeps sending
+	// invalid messages is banned. Each protocol-level fault (e.g. a message
+	// which is too large, can't be decoded or fails schema validation) lowers
+	// the score by 5. Orders which are rejected because of their on-chain state
+	// (e.g. because they were filled before we validated them) don't count.
+	// Scores and bans are persisted across restarts and bans expire after 24
+	// hours. A value of 0 (the default) disables automatic banning.
+	PeerBanThreshold int `envvar:"PEER_BAN_THRESHOLD" default:"0"`
+	// PrivateNetworkKey is an optional hex-encoded 32 byte pre-shared key (e.g.
+	// generated with `openssl rand -hex 32`). If set, Mesh only connects to
+	// peers which use the same key and all traffic between them is encrypted
+	// with it. Because the public bootstrap nodes don't know the key, a custom
+	// BootstrapList is required unless UseBootstrapList is false.
+	PrivateNetworkKey string `envvar:"PRIVATE_NETWORK_KEY" default:"" json:"-"`
+	// PrivateNetworkNamespace is an optional name for a private network. If
+	// set, Mesh shares orders on a pubsub topic and advertises itself under a
+	// rendezvous string which include the namespace, so that it never gossips
+	// with or discovers nodes in the public network or in other private
+	// networks.
+	PrivateNetworkNamespace string `envvar:"PRIVATE_NETWORK_NAMESPACE" default:""`
+	// PeerAllowlist is an optional comma-separated list of peer IDs. If set,
+	// Mesh only stays connected to and accepts orders from these peers and the
+	// peers in BootstrapList.
+	PeerAllowlist string `envvar:"PEER_ALLOWLIST" default:""`
+	// EnableMDNS determines whether Mesh uses mDNS to discover and connect to
+	// other Mesh nodes for the same chain (and PrivateNetworkNamespace, if any)
+	// on the local network. This is useful for local clusters which can't reach
+	// the bootstrap nodes.
+	EnableMDNS bool `envvar:"ENABLE_MDNS" default:"false"`
+	// EnableAdaptivePubSubRateLimits determines whether the per-peer limits for
+	// receiving orders via GossipSub depend on the score of each peer. If true,
+	// peers which have sent us orders that we stored are allowed to send bigger
+	// bursts of messages and peers which have sent us invalid messages are
+	// limited to a lower rate. Both depend on the total score of each peer,
+	// which includes the score for stored orders as well as the penalties for
+	// invalid messages.
+	EnableAdaptivePubSubRateLimits bool `envvar:"ENABLE_ADAPTIVE_PUBSUB_RATE_LIMITS" default:"false"`
+	// PeerCountLow is the target number of peers to connect to at any given
+	// time.
+	PeerCountLow int `envvar:"PEER_COUNT_LOW" default:"100"`
+	// PeerCountHigh is the maximum number of peers to be connected to. If the
+	// number of connections exceeds this number, Mesh will prune connections
+	// until it reaches PeerCountLow.
+	PeerCountHigh int `envvar:"PEER_COUNT_HIGH" default:"110"`
+	// MaxPeersPerSubnet is the maximum number of peers with IP addresses in the
+	// same /24 (IPv4) or /48 (IPv6) subnet that Mesh will be connected to.
+	// Along with MinOutboundPeers, it makes it harder for an attacker to
+	// surround a node with peers they control (an eclipse attack). A value of
+	// 0 means there is no limit.
+	MaxPeersPerSubnet int `envvar:"MAX_PEERS_PER_SUBNET" default:"0"`
+	// MinOutboundPeers is the minimum number of peers that Mesh dialed itself
+	// to stay connected to. These peers are never pruned in favor of peers
+	// which dialed Mesh. Must not be greater than PeerCountLow.
+	MinOutboundPeers int `envvar:"MIN_OUTBOUND_PEERS" default:"0"`
+	// PrivateKeyPassphrase is an optional passphrase used to encrypt the
+	// libp2p private key (DataDir/keys/privkey) with scrypt and AES-GCM. If it
+	// is set and the existing private key is in plaintext, the private key is
+	// encrypted the next time Mesh starts up. Encrypted private keys can't be
+	// loaded without the passphrase.
+	PrivateKeyPassphrase string `envvar:"PRIVATE_KEY_PASSPHRASE" default:"" json:"-"`
+	// PrivateKeyPassphraseFile is the path of a file which contains the
+	// passphrase for the libp2p private key. It can be used instead of
+	// PrivateKeyPassphrase, but not together with it. A trailing newline in the
+	// file is ignored.
+	PrivateKeyPassphraseFile string `envvar:"PRIVATE_KEY_PASSPHRASE_FILE" default:""`
 }
 
 type snapshotInfo struct {
@@ -153,8 +260,11 @@ type App struct {
 	idToSnapshotInfo          map[string]snapshotInfo
 	ethRPCRateLimiter         ratelimit.RateLimiter
 	ethRPCClient              ethrpcclient.Client
-	orderSelector             *orderSelector
+	sharingStrategy           sharingStrategy
+	ordersyncService          *ordersync.Service
 	db                        *meshdb.MeshDB
+	privateNetworkKey         []byte
+	peerAllowlist             []peer.ID
 
 	// started is closed to signal that the App has been started. Some methods
 	// will block until after the App is started.
@@ -176,8 +286,12 @@ func New(config Config) (*App, error) {
 	}
 
 	// Load private key and add peer ID hook.
+	privKeyPassphrase, err := keys.GetPassphrase(config.PrivateKeyPassphrase, config.PrivateKeyPassphraseFile)
+	if err != nil {
+		return nil, err
+	}
 	privKeyPath := filepath.Join(config.DataDir, "keys", "privkey")
-	privKey, err := initPrivateKey(privKeyPath)
+	privKey, err := keys.InitPrivateKey(privKeyPath, privKeyPassphrase)
 	if err != nil {
 		return nil, err
 	}
@@ -192,6 +306,11 @@ func New(config Config) (*App, error) {
 	}
 	config = unquoteConfig(config)
 
+	privateNetworkKey, peerAllowlist, err := parsePrivateNetworkConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	// Ensure ETHEREUM_RPC_MAX_REQUESTS_PER_24_HR_UTC is reasonably set given BLOCK_POLLING_INTERVAL
 	per24HrPollingRequests := int((24 * time.Hour) / config.BlockPollingInterval)
 	minNumOfEthRPCRequestsIn24HrPeriod := per24HrPollingRequests + defaultNonPollingEthRPCRequestBuffer
@@ -258,13 +377,20 @@ func New(config Config) (*App, error) {
 	}
 
 	// Initialize order watcher (but don't start it yet).
+	evictionPolicy, err := orderwatch.EvictionPolicyFromName(config.StorageEvictionPolicy)
+	if err != nil {
+		return nil, err
+	}
 	orderWatcher, err := orderwatch.New(orderwatch.Config{
-		MeshDB:            meshDB,
-		BlockWatcher:      blockWatcher,
-		OrderValidator:    orderValidator,
-		ChainID:           config.EthereumChainID,
-		MaxOrders:         config.MaxOrdersInStorage,
-		MaxExpirationTime: metadata.MaxExpirationTime,
+		MeshDB:                   meshDB,
+		BlockWatcher:             blockWatcher,
+		OrderValidator:           orderValidator,
+		ChainID:                  config.EthereumChainID,
+		MaxOrders:                config.MaxOrdersInStorage,
+		MaxExpirationTime:        metadata.MaxExpirationTime,
+		MaxOrdersPerMaker:        config.MaxOrdersPerMaker,
+		MaxOrdersPerFeeRecipient: config.MaxOrdersPerFeeRecipient,
+		EvictionPolicy:           evictionPolicy,
 	})
 	if err != nil {
 		return nil, err
@@ -280,9 +406,9 @@ func New(config Config) (*App, error) {
 	if err != nil {
 		return nil, err
 	}
-	orderSelector := &orderSelector{
-		nextOffset: 0,
-		db:         meshDB,
+	sharingStrategy, err := sharingStrategyFromName(config.OrderSharingStrategy, meshDB, config.EnableSetReconciliation)
+	if err != nil {
+		return nil, err
 	}
 
 	app := &App{
@@ -298,10 +424,12 @@ func New(config Config) (*App, error) {
 		meshMessageJSONSchema:     meshMessageJSONSchema,
 		snapshotExpirationWatcher: snapshotExpirationWatcher,
 		idToSnapshotInfo:          map[string]snapshotInfo{},
-		orderSelector:             orderSelector,
+		sharingStrategy:           sharingStrategy,
 		ethRPCRateLimiter:         ethRPCRateLimiter,
 		ethRPCClient:              ethClient,
 		db:                        meshDB,
+		privateNetworkKey:         privateNetworkKey,
+		peerAllowlist:             peerAllowlist,
 	}
 
 	log.WithFields(map[string]interface{}{
@@ -323,26 +451,66 @@ func unquoteConfig(config Config) Config {
 	return config
 }
 
-func getPubSubTopic(chainID int) string {
-	return fmt.Sprintf("/0x-orders/network/%d/version/1", chainID)
+// parsePrivateNetworkConfig parses and validates the options for running a
+// private network. It returns a nil key and allowlist if the options are not
+// set.
+func parsePrivateNetworkConfig(config Config) ([]byte, []peer.ID, error) {
+	var privateNetworkKey []byte
+	if config.PrivateNetworkKey != "" {
+		key, err := p2p.ParsePrivateNetworkKey(config.PrivateNetworkKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		if config.UseBootstrapList && config.BootstrapList == "" {
+			return nil, nil, errors.New("BOOTSTRAP_LIST must be set when PRIVATE_NETWORK_KEY is set and USE_BOOTSTRAP_LIST is true (the default bootstrap nodes are not part of private networks)")
+		}
+		privateNetworkKey = key
+	}
+	var peerAllowlist []peer.ID
+	if config.PeerAllowlist != "" {
+		for _, encoded := range strings.Split(config.PeerAllowlist, ",") {
+			peerID, err := peer.IDB58Decode(strings.TrimSpace(encoded))
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid peer ID in PEER_ALLOWLIST (%q): %s", encoded, err.Error())
+			}
+			peerAllowlist = append(peerAllowlist, peerID)
+		}
+	}
+	return privateNetworkKey, peerAllowlist, nil
 }
 
-func getRendezvous(chainID int) string {
-	return fmt.Sprintf("/0x-mesh/network/%d/version/1", chainID)
+// getPubSubTopic returns the pubsub topic on which orders are shared. The
+// topic version determines the message format. If namespace is not empty, the
+// topic is specific to the private network with that namespace.
+func getPubSubTopic(chainID int, namespace string) string {
+	if namespace != "" {
+		return fmt.Sprintf("/0x-orders/private/%s/network/%d/version/%d", namespace, chainID, pubSubTopicVersion)
+	}
+	return fmt.Sprintf("/0x-orders/network/%d/version/%d", chainID, pubSubTopicVersion)
 }
 
-func initPrivateKey(path string) (p2pcrypto.PrivKey, error) {
-	privKey, err := keys.GetPrivateKeyFromPath(path)
-	if err == nil {
-		return privKey, nil
-	} else if os.IsNotExist(err) {
-		// If the private key doesn't exist, generate one.
-		log.Info("No private key found. Generating a new one.")
-		return keys.GenerateAndSavePrivateKey(path)
+// getLegacyPubSubTopics returns the pubsub topics which were used by older
+// versions of Mesh. We still receive orders on these topics so that we don't
+// miss orders from peers which have not upgraded yet, and also share orders on
+// them in the legacy format so that those peers don't miss our orders. Private
+// networks didn't exist in older versions, so there are no legacy topics for
+// them.
+func getLegacyPubSubTopics(chainID int, namespace string) []string {
+	if namespace != "" {
+		return nil
 	}
+	topics := make([]string, len(legacyPubSubTopicVersions))
+	for i, version := range legacyPubSubTopicVersions {
+		topics[i] = fmt.Sprintf("/0x-orders/network/%d/version/%d", chainID, version)
+	}
+	return topics
+}
 
-	// For any other type of error, return it.
-	return nil, err
+func getRendezvous(chainID int, namespace string) string {
+	if namespace != "" {
+		return fmt.Sprintf("/0x-mesh/private/%s/network/%d/version/1", namespace, chainID)
+	}
+	return fmt.Sprintf("/0x-mesh/network/%d/version/1", chainID)
 }
 
 func initMetadata(chainID int, meshDB *meshdb.MeshDB) (*meshdb.Metadata, error) {
@@ -462,22 +630,46 @@ func (app *App) Start(ctx context.Context) error {
 		bootstrapList = strings.Split(app.config.BootstrapList, ",")
 	}
 	nodeConfig := p2p.Config{
-		Topic:            getPubSubTopic(app.config.EthereumChainID),
-		TCPPort:          app.config.P2PTCPPort,
-		WebSocketsPort:   app.config.P2PWebSocketsPort,
-		Insecure:         false,
-		PrivateKey:       app.privKey,
-		MessageHandler:   app,
-		RendezvousString: getRendezvous(app.config.EthereumChainID),
-		UseBootstrapList: app.config.UseBootstrapList,
-		BootstrapList:    bootstrapList,
-		DataDir:          filepath.Join(app.config.DataDir, "p2p"),
+		Topic:                    getPubSubTopic(app.config.EthereumChainID, app.config.PrivateNetworkNamespace),
+		LegacyTopics:             getLegacyPubSubTopics(app.config.EthereumChainID, app.config.PrivateNetworkNamespace),
+		EncodeLegacyMessages:     encodeLegacyOrderMessages,
+		TCPPort:                  app.config.P2PTCPPort,
+		WebSocketsPort:           app.config.P2PWebSocketsPort,
+		Insecure:                 false,
+		PrivateKey:               app.privKey,
+		MessageHandler:           app,
+		RendezvousString:         getRendezvous(app.config.EthereumChainID, app.config.PrivateNetworkNamespace),
+		UseBootstrapList:         app.config.UseBootstrapList,
+		BootstrapList:            bootstrapList,
+		DataDir:                  filepath.Join(app.config.DataDir, "p2p"),
+		PrivateNetworkKey:        app.privateNetworkKey,
+		PeerAllowlist:            app.peerAllowlist,
+		EnableMDNS:               app.config.EnableMDNS,
+		AdaptivePubSubRateLimits: app.config.EnableAdaptivePubSubRateLimits,
+		PeerCountLow:             app.config.PeerCountLow,
+		PeerCountHigh:            app.config.PeerCountHigh,
+		MaxPeersPerSubnet:        app.config.MaxPeersPerSubnet,
+		MinOutboundPeers:         app.config.MinOutboundPeers,
+	}
+	if app.config.EnableSetReconciliation {
+		nodeConfig.SetReconciliationHandler = app
 	}
 	app.node, err = p2p.New(innerCtx, nodeConfig)
 	if err != nil {
 		return err
 	}
 
+	// Set up the order sync service, which lets peers request orders from us
+	// directly and lets us request orders from them.
+	app.ordersyncService, err = ordersync.New(ordersync.Config{
+		Node:          app.node,
+		OrderProvider: app,
+		OrderHandler:  app,
+	})
+	if err != nil {
+		return err
+	}
+
 	// Start the p2p node.
 	p2pErrChan := make(chan error, 1)
 	wg.Add(1)
@@ -497,6 +689,17 @@ func (app *App) Start(ctx context.Context) error {
 		p2pErrChan <- app.node.Start()
 	}()
 
+	// Request orders directly from some of our peers. Orders shared via
+	// GossipSub trickle in slowly, so this helps new and reconnecting nodes
+	// catch up much faster.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := app.ordersyncService.SyncWithPeers(innerCtx, orderSyncMinPeers); err != nil && err != context.Canceled {
+			log.WithError(err).Warn("could not sync orders with peers")
+		}
+	}()
+
 	// Start loop for periodically logging stats.
 	wg.Add(1)
 	go func() {
@@ -668,6 +871,207 @@ func (app *App) AddOrders(signedOrdersRaw []*json.RawMessage, pinned bool) (*ord
 		Accepted: []*ordervalidator.AcceptedOrderInfo{},
 		Rejected: []*ordervalidator.RejectedOrderInfo{},
 	}
+	schemaValidOrders, schemaRejectedOrderInfos, err := app.schemaValidateOrders(signedOrdersRaw)
+	if err != nil {
+		return nil, err
+	}
+	allValidationResults.Rejected = append(allValidationResults.Rejected, schemaRejectedOrderInfos...)
+
+	validationResults, err := app.validateOrders(schemaValidOrders, ethrpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	for _, orderInfo := range validationResults.Accepted {
+		allValidationResults.Accepted = append(allValidationResults.Accepted, orderInfo)
+	}
+	for _, orderInfo := range validationResults.Rejected {
+		allValidationResults.Rejected = append(allValidationResults.Rejected, orderInfo)
+	}
+
+	// Orders that can't be stored are moved from Accepted to Rejected, so we
+	// build up a new list of accepted orders instead of modifying the original
+	// list while iterating over it.
+	acceptedOrderInfos := allValidationResults.Accepted
+	allValidationResults.Accepted = []*ordervalidator.AcceptedOrderInfo{}
+	for _, acceptedOrderInfo := range acceptedOrderInfos {
+		// If the order isn't new, we don't add to OrderWatcher, log it's receipt
+		// or share the order with peers.
+		if !acceptedOrderInfo.IsNew {
+			allValidationResults.Accepted = append(allValidationResults.Accepted, acceptedOrderInfo)
+			continue
+		}
+		// Add the order to the OrderWatcher. This also saves the order in the
+		// database.
+		err = app.orderWatcher.Add(acceptedOrderInfo, pinned)
+		if err != nil {
+			if err == meshdb.ErrDBFilledWithPinnedOrders {
+				allValidationResults.Rejected = append(allValidationResults.Rejected, &ordervalidator.RejectedOrderInfo{
+					OrderHash:   acceptedOrderInfo.OrderHash,
+					SignedOrder: acceptedOrderInfo.SignedOrder,
+					Kind:        ordervalidator.MeshError,
+					Status:      ordervalidator.RODatabaseFullOfOrders,
+				})
+				continue
+			} else if status, isQuotaErr := quotaErrToRejectedOrderStatus(err); isQuotaErr {
+				allValidationResults.Rejected = append(allValidationResults.Rejected, &ordervalidator.RejectedOrderInfo{
+					OrderHash:   acceptedOrderInfo.OrderHash,
+					SignedOrder: acceptedOrderInfo.SignedOrder,
+					Kind:        ordervalidator.MeshValidation,
+					Status:      status,
+				})
+				continue
+			}
+			return nil, err
+		}
+		allValidationResults.Accepted = append(allValidationResults.Accepted, acceptedOrderInfo)
+		log.WithFields(log.Fields{
+			"orderHash": acceptedOrderInfo.OrderHash.String(),
+		}).Debug("added new valid order via RPC or browser callback")
+
+		// Share the order with our peers.
+		if err := app.shareOrder(acceptedOrderInfo.SignedOrder); err != nil {
+			return nil, err
+		}
+	}
+	return allValidationResults, nil
+}
+
+// ValidateOrders validates the given orders the same way AddOrders does
+// (schema, Mesh-specific, Coordinator and on-chain validation) at the given
+// block number, but never stores the orders or shares them with peers. Orders
+// which are already stored are only re-validated if blockNumber is not
+// rpc.LatestBlockNumber, since stored orders are kept up-to-date with the
+// latest block. In that case, IsNew is true for all accepted orders.
+func (app *App) ValidateOrders(signedOrdersRaw []*json.RawMessage, blockNumber ethrpc.BlockNumber) (*ordervalidator.ValidationResults, error) {
+	<-app.started
+
+	allValidationResults := &ordervalidator.ValidationResults{
+		Accepted: []*ordervalidator.AcceptedOrderInfo{},
+		Rejected: []*ordervalidator.RejectedOrderInfo{},
+	}
+	schemaValidOrders, schemaRejectedOrderInfos, err := app.schemaValidateOrders(signedOrdersRaw)
+	if err != nil {
+		return nil, err
+	}
+	allValidationResults.Rejected = append(allValidationResults.Rejected, schemaRejectedOrderInfos...)
+
+	validationResults, err := app.validateOrders(schemaValidOrders, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	allValidationResults.Accepted = append(allValidationResults.Accepted, validationResults.Accepted...)
+	allValidationResults.Rejected = append(allValidationResults.Rejected, validationResults.Rejected...)
+	return allValidationResults, nil
+}
+
+// ErrBlockStateUnavailable is the error returned when the Ethereum RPC endpoint
+// doesn't have the state needed to validate orders at a particular block.
+type ErrBlockStateUnavailable struct {
+	blockNumber ethrpc.BlockNumber
+}
+
+func (e ErrBlockStateUnavailable) Error() string {
+	return fmt.Sprintf("Ethereum RPC endpoint does not have the state for block %d. Validating orders at older blocks requires an archive node", e.blockNumber)
+}
+
+// ErrBlockNotProcessed is the error returned when orders are validated at a
+// block which is newer than the latest block processed by the Mesh node.
+type ErrBlockNotProcessed struct {
+	blockNumber       ethrpc.BlockNumber
+	latestBlockNumber int64
+}
+
+func (e ErrBlockNotProcessed) Error() string {
+	return fmt.Sprintf("block %d is newer than the latest block processed by this Mesh node (%d)", e.blockNumber, e.latestBlockNumber)
+}
+
+// GetOrderStateAtBlock validates the given orders at the given block and
+// returns their state, i.e. their fillable taker asset amount if they were
+// fillable and the reason they were not otherwise. Orders can be given by hash,
+// in which case they must be stored by the Mesh node (even if they have since
+// been removed), or as raw signed orders. Unlike ValidateOrders, Mesh-specific
+// validation (e.g. the max expiration time) is skipped, since it only applies
+// to the orders a Mesh node currently stores. It returns ErrBlockStateUnavailable
+// if the Ethereum RPC endpoint doesn't have the state for the block, which is
+// usually the case for older blocks unless it is an archive node.
+func (app *App) GetOrderStateAtBlock(orderHashes []common.Hash, signedOrdersRaw []*json.RawMessage, blockNumber ethrpc.BlockNumber) (*ordervalidator.ValidationResults, error) {
+	<-app.started
+
+	latestBlockHeader, err := app.blockWatcher.GetLatestBlockProcessed()
+	if err != nil {
+		return nil, err
+	}
+	if latestBlockHeader == nil || latestBlockHeader.Number.Int64() < blockNumber.Int64() {
+		latestBlockNumber := int64(0)
+		if latestBlockHeader != nil {
+			latestBlockNumber = latestBlockHeader.Number.Int64()
+		}
+		return nil, ErrBlockNotProcessed{blockNumber: blockNumber, latestBlockNumber: latestBlockNumber}
+	}
+
+	allValidationResults := &ordervalidator.ValidationResults{
+		Accepted: []*ordervalidator.AcceptedOrderInfo{},
+		Rejected: []*ordervalidator.RejectedOrderInfo{},
+	}
+	orderHashesSeen := map[common.Hash]struct{}{}
+	signedOrders := []*zeroex.SignedOrder{}
+	for _, orderHash := range orderHashes {
+		if _, alreadySeen := orderHashesSeen[orderHash]; alreadySeen {
+			continue
+		}
+		orderHashesSeen[orderHash] = struct{}{}
+		var dbOrder meshdb.Order
+		if err := app.db.Orders.FindByID(orderHash.Bytes(), &dbOrder); err != nil {
+			if _, ok := err.(db.NotFoundError); ok {
+				allValidationResults.Rejected = append(allValidationResults.Rejected, &ordervalidator.RejectedOrderInfo{
+					OrderHash: orderHash,
+					Kind:      ordervalidator.MeshValidation,
+					Status:    ordervalidator.ROOrderNotFound,
+				})
+				continue
+			}
+			return nil, err
+		}
+		signedOrders = append(signedOrders, dbOrder.SignedOrder)
+	}
+
+	schemaValidOrders, schemaRejectedOrderInfos, err := app.schemaValidateOrders(signedOrdersRaw)
+	if err != nil {
+		return nil, err
+	}
+	allValidationResults.Rejected = append(allValidationResults.Rejected, schemaRejectedOrderInfos...)
+	for _, signedOrder := range schemaValidOrders {
+		orderHash, err := signedOrder.ComputeOrderHash()
+		if err != nil {
+			return nil, err
+		}
+		if _, alreadySeen := orderHashesSeen[orderHash]; alreadySeen {
+			continue
+		}
+		orderHashesSeen[orderHash] = struct{}{}
+		signedOrders = append(signedOrders, signedOrder)
+	}
+
+	// This timeout of 1min is for limiting how long this call should block at the ETH RPC rate limiter
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+	areNewOrders := false
+	validationResults := app.orderValidator.BatchValidate(ctx, signedOrders, areNewOrders, blockNumber)
+	for _, rejectedOrderInfo := range validationResults.Rejected {
+		if rejectedOrderInfo.Status == ordervalidator.ROBlockStateUnavailable {
+			return nil, ErrBlockStateUnavailable{blockNumber: blockNumber}
+		}
+	}
+	allValidationResults.Accepted = append(allValidationResults.Accepted, validationResults.Accepted...)
+	allValidationResults.Rejected = append(allValidationResults.Rejected, validationResults.Rejected...)
+	return allValidationResults, nil
+}
+
+// schemaValidateOrders unmarshals the given orders and validates them against
+// the order JSON schema. It returns the orders which passed schema validation,
+// without duplicates, and RejectedOrderInfos for the orders which did not.
+func (app *App) schemaValidateOrders(signedOrdersRaw []*json.RawMessage) ([]*zeroex.SignedOrder, []*ordervalidator.RejectedOrderInfo, error) {
+	rejectedOrderInfos := []*ordervalidator.RejectedOrderInfo{}
 	orderHashesSeen := map[common.Hash]struct{}{}
 	schemaValidOrders := []*zeroex.SignedOrder{}
 	for _, signedOrderRaw := range signedOrdersRaw {
@@ -679,7 +1083,7 @@ func (app *App) AddOrders(signedOrdersRaw []*json.RawMessage, pinned bool) (*ord
 				signedOrder = nil
 			}
 			log.WithField("signedOrderRaw", string(signedOrderBytes)).Info("Unexpected error while attempting to validate signedOrderJSON against schema")
-			allValidationResults.Rejected = append(allValidationResults.Rejected, &ordervalidator.RejectedOrderInfo{
+			rejectedOrderInfos = append(rejectedOrderInfos, &ordervalidator.RejectedOrderInfo{
 				SignedOrder: signedOrder,
 				Kind:        ordervalidator.MeshValidation,
 				Status: ordervalidator.RejectedOrderStatus{
@@ -699,7 +1103,7 @@ func (app *App) AddOrders(signedOrdersRaw []*json.RawMessage, pinned bool) (*ord
 			if err := signedOrder.UnmarshalJSON(signedOrderBytes); err != nil {
 				signedOrder = nil
 			}
-			allValidationResults.Rejected = append(allValidationResults.Rejected, &ordervalidator.RejectedOrderInfo{
+			rejectedOrderInfos = append(rejectedOrderInfos, &ordervalidator.RejectedOrderInfo{
 				SignedOrder: signedOrder,
 				Kind:        ordervalidator.MeshValidation,
 				Status:      status,
@@ -711,12 +1115,12 @@ func (app *App) AddOrders(signedOrdersRaw []*json.RawMessage, pinned bool) (*ord
 		if err := signedOrder.UnmarshalJSON(signedOrderBytes); err != nil {
 			// This error should never happen since the signedOrder already passed the JSON schema validation above
 			log.WithField("signedOrderRaw", string(signedOrderBytes)).Error("Failed to unmarshal SignedOrder")
-			return nil, err
+			return nil, nil, err
 		}
 
 		orderHash, err := signedOrder.ComputeOrderHash()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if _, alreadySeen := orderHashesSeen[orderHash]; alreadySeen {
 			continue
@@ -726,56 +1130,28 @@ func (app *App) AddOrders(signedOrdersRaw []*json.RawMessage, pinned bool) (*ord
 		orderHashesSeen[orderHash] = struct{}{}
 	}
 
-	validationResults, err := app.validateOrders(schemaValidOrders)
-	if err != nil {
-		return nil, err
-	}
-	for _, orderInfo := range validationResults.Accepted {
-		allValidationResults.Accepted = append(allValidationResults.Accepted, orderInfo)
-	}
-	for _, orderInfo := range validationResults.Rejected {
-		allValidationResults.Rejected = append(allValidationResults.Rejected, orderInfo)
-	}
-
-	for i, acceptedOrderInfo := range allValidationResults.Accepted {
-		// If the order isn't new, we don't add to OrderWatcher, log it's receipt
-		// or share the order with peers.
-		if !acceptedOrderInfo.IsNew {
-			continue
-		}
-		// Add the order to the OrderWatcher. This also saves the order in the
-		// database.
-		err = app.orderWatcher.Add(acceptedOrderInfo, pinned)
-		if err != nil {
-			if err == meshdb.ErrDBFilledWithPinnedOrders {
-				allValidationResults.Accepted = append(allValidationResults.Accepted[:i], allValidationResults.Accepted[i+1:]...)
-				allValidationResults.Rejected = append(allValidationResults.Rejected, &ordervalidator.RejectedOrderInfo{
-					OrderHash:   acceptedOrderInfo.OrderHash,
-					SignedOrder: acceptedOrderInfo.SignedOrder,
-					Kind:        ordervalidator.MeshError,
-					Status:      ordervalidator.RODatabaseFullOfOrders,
-				})
-			} else {
-				return nil, err
-			}
-		}
-		log.WithFields(log.Fields{
-			"orderHash": acceptedOrderInfo.OrderHash.String(),
-		}).Debug("added new valid order via RPC or browser callback")
+	return schemaValidOrders, rejectedOrderInfos, nil
+}
 
-		// Share the order with our peers.
-		if err := app.shareOrder(acceptedOrderInfo.SignedOrder); err != nil {
-			return nil, err
-		}
+// quotaErrToRejectedOrderStatus converts a quota error returned by
+// orderwatch.Watcher.Add to the corresponding RejectedOrderStatus. The second
+// return value is false if err is not a quota error.
+func quotaErrToRejectedOrderStatus(err error) (ordervalidator.RejectedOrderStatus, bool) {
+	switch err {
+	case orderwatch.ErrMakerQuotaExceeded:
+		return ordervalidator.ROMakerQuotaExceeded, true
+	case orderwatch.ErrFeeRecipientQuotaExceeded:
+		return ordervalidator.ROFeeRecipientQuotaExceeded, true
+	default:
+		return ordervalidator.RejectedOrderStatus{}, false
 	}
-	return allValidationResults, nil
 }
 
 // shareOrder immediately shares the given order on the GossipSub network.
 func (app *App) shareOrder(order *zeroex.SignedOrder) error {
 	<-app.started
 
-	encoded, err := encodeOrder(order)
+	encoded, err := encodeOrderMessage(order)
 	if err != nil {
 		return err
 	}
@@ -789,6 +1165,119 @@ func (app *App) AddPeer(peerInfo peerstore.PeerInfo) error {
 	return app.node.Connect(peerInfo, peerConnectTimeout)
 }
 
+// GetPeers returns information about every peer the node is connected to.
+func (app *App) GetPeers() ([]*rpc.PeerInfo, error) {
+	<-app.started
+
+	peerInfos := app.node.Peers()
+	result := make([]*rpc.PeerInfo, len(peerInfos))
+	for i, peerInfo := range peerInfos {
+		multiaddrs := make([]string, len(peerInfo.Addrs))
+		for j, addr := range peerInfo.Addrs {
+			multiaddrs[j] = addr.String()
+		}
+		result[i] = &rpc.PeerInfo{
+			ID:         peerInfo.ID.Pretty(),
+			Multiaddrs: multiaddrs,
+			Direction:  directionToString(peerInfo.Direction),
+			Latency:    peerInfo.Latency,
+			Scores:     peerInfo.Scores,
+			BytesIn:    peerInfo.Bandwidth.TotalIn,
+			BytesOut:   peerInfo.Bandwidth.TotalOut,
+			RateIn:     peerInfo.Bandwidth.RateIn,
+			RateOut:    peerInfo.Bandwidth.RateOut,
+			Messages: rpc.MessageStats{
+				Accepted:    peerInfo.Messages.Accepted,
+				RateLimited: peerInfo.Messages.RateLimited,
+				Oversize:    peerInfo.Messages.Oversize,
+				Invalid:     peerInfo.Messages.Invalid,
+			},
+		}
+	}
+	return result, nil
+}
+
+func directionToString(direction network.Direction) string {
+	switch direction {
+	case network.DirInbound:
+		return "inbound"
+	case network.DirOutbound:
+		return "outbound"
+	default:
+		return "unknown"
+	}
+}
+
+// BanPeer bans the given peer and the IP addresses it is connected from, and
+// disconnects from it.
+func (app *App) BanPeer(peerID peer.ID) error {
+	<-app.started
+
+	return app.node.BanPeer(peerID)
+}
+
+// BanIP bans the IP address of the given multiaddress.
+func (app *App) BanIP(maddr ma.Multiaddr) error {
+	<-app.started
+
+	return app.node.BanIP(maddr)
+}
+
+// UnbanPeer removes the ban for the given peer and the IP addresses that were
+// banned along with it.
+func (app *App) UnbanPeer(peerID peer.ID) error {
+	<-app.started
+
+	return app.node.UnbanPeer(peerID)
+}
+
+// UnbanIP removes the ban for the IP address of the given multiaddress.
+func (app *App) UnbanIP(maddr ma.Multiaddr) error {
+	<-app.started
+
+	return app.node.UnbanIP(maddr)
+}
+
+// ProtectPeer protects the given peer from being banned or disconnected.
+func (app *App) ProtectPeer(peerID peer.ID) error {
+	<-app.started
+
+	return app.node.ProtectPeer(peerID)
+}
+
+// ProtectIP protects the IP address of the given multiaddress from being
+// banned.
+func (app *App) ProtectIP(maddr ma.Multiaddr) error {
+	<-app.started
+
+	return app.node.ProtectIP(maddr)
+}
+
+// DisconnectPeer closes all connections to the given peer.
+func (app *App) DisconnectPeer(peerID peer.ID) error {
+	<-app.started
+
+	return app.node.DisconnectPeer(peerID)
+}
+
+// BackupDatabase writes a consistent copy of the database to the given path.
+// The copy can be restored by using it in place of `DataDir/db`. It is safe to
+// call while the node is running.
+func (app *App) BackupDatabase(path string) error {
+	<-app.started
+
+	return app.db.Backup(path)
+}
+
+// CompactDatabase compacts the storage used for orders and block headers,
+// reclaiming space left behind by removed orders. It is safe to call while the
+// node is running.
+func (app *App) CompactDatabase() error {
+	<-app.started
+
+	return app.db.Compact()
+}
+
 // GetStats retrieves stats about the Mesh node
 func (app *App) GetStats() (*rpc.GetStatsResponse, error) {
 	<-app.started
@@ -824,8 +1313,8 @@ func (app *App) GetStats() (*rpc.GetStatsResponse, error) {
 
 	response := &rpc.GetStatsResponse{
 		Version:                           version,
-		PubSubTopic:                       getPubSubTopic(app.config.EthereumChainID),
-		Rendezvous:                        getRendezvous(app.config.EthereumChainID),
+		PubSubTopic:                       getPubSubTopic(app.config.EthereumChainID, app.config.PrivateNetworkNamespace),
+		Rendezvous:                        getRendezvous(app.config.EthereumChainID, app.config.PrivateNetworkNamespace),
 		PeerID:                            app.peerID.String(),
 		EthereumChainID:                   app.config.EthereumChainID,
 		LatestBlock:                       latestBlock,
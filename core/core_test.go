@@ -28,3 +28,51 @@ func TestEthereumChainDetection(t *testing.T) {
 	_, err = initMetadata(2, meshDB)
 	assert.Error(t, err)
 }
+
+func TestParsePrivateNetworkConfig(t *testing.T) {
+	key := "0x000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	peerID := "16Uiu2HAmGx8Z6gdq5T5AQE54GMtqDhDFhizywTy1o28NJbAMMumF"
+
+	// No options means a public network.
+	parsedKey, allowlist, err := parsePrivateNetworkConfig(Config{UseBootstrapList: true})
+	require.NoError(t, err)
+	assert.Nil(t, parsedKey)
+	assert.Nil(t, allowlist)
+
+	parsedKey, allowlist, err = parsePrivateNetworkConfig(Config{
+		UseBootstrapList:  true,
+		BootstrapList:     "/ip4/127.0.0.1/tcp/60558/ipfs/" + peerID,
+		PrivateNetworkKey: key,
+		PeerAllowlist:     peerID + ", " + peerID,
+	})
+	require.NoError(t, err)
+	assert.Len(t, parsedKey, 32)
+	require.Len(t, allowlist, 2)
+	assert.Equal(t, peerID, allowlist[0].Pretty())
+
+	// The default bootstrap nodes are not part of any private network.
+	_, _, err = parsePrivateNetworkConfig(Config{
+		UseBootstrapList:  true,
+		PrivateNetworkKey: key,
+	})
+	assert.Error(t, err)
+	_, _, err = parsePrivateNetworkConfig(Config{
+		UseBootstrapList:  false,
+		PrivateNetworkKey: key,
+	})
+	assert.NoError(t, err)
+
+	_, _, err = parsePrivateNetworkConfig(Config{PrivateNetworkKey: "0x1234"})
+	assert.Error(t, err)
+	_, _, err = parsePrivateNetworkConfig(Config{PeerAllowlist: "not a peer ID"})
+	assert.Error(t, err)
+}
+
+func TestPrivateNetworkNamespace(t *testing.T) {
+	assert.Equal(t, "/0x-orders/network/1/version/2", getPubSubTopic(1, ""))
+	assert.Equal(t, "/0x-orders/private/test/network/1/version/2", getPubSubTopic(1, "test"))
+	assert.Equal(t, []string{"/0x-orders/network/1/version/1"}, getLegacyPubSubTopics(1, ""))
+	assert.Empty(t, getLegacyPubSubTopics(1, "test"))
+	assert.Equal(t, "/0x-mesh/network/1/version/1", getRendezvous(1, ""))
+	assert.Equal(t, "/0x-mesh/private/test/network/1/version/1", getRendezvous(1, "test"))
+}
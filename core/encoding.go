@@ -2,11 +2,35 @@ package core
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"strings"
 
+	"github.com/0xProject/0x-mesh/constants"
 	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gogo/protobuf/proto"
 )
 
+const (
+	// orderMessageVersion is the version of the binary message envelope. It is
+	// incremented whenever the envelope changes in a way that is not backwards
+	// compatible.
+	orderMessageVersion = 1
+	// maxMessageDataSize is the maximum size of the data for a single message
+	// which contains several orders. It leaves room for the fields that
+	// GossipSub adds to each message (e.g. the sender and signature), which
+	// count towards constants.MaxMessageSizeInBytes.
+	maxMessageDataSize = constants.MaxMessageSizeInBytes - 1024
+)
+
+var errEmptyOrderMessage = errors.New("message does not contain any orders")
+
+// orderMessage is the legacy message format, which is used on version 1 of
+// the pubsub topic. Each message contains exactly one order encoded as JSON.
+// The size limit for orders is still defined in terms of this format (see
+// validateOrderSize).
 type orderMessage struct {
 	MessageType string
 	Order       *zeroex.SignedOrder
@@ -29,3 +53,292 @@ func decodeOrder(data []byte) (*zeroex.SignedOrder, error) {
 	}
 	return orderMessage.Order, nil
 }
+
+// encodeLegacyOrderMessages converts a message in the binary format to messages
+// in the legacy JSON format, which contain exactly one order each. It is used to
+// keep sharing orders on the legacy pubsub topics while peers upgrade.
+func encodeLegacyOrderMessages(data []byte) ([][]byte, error) {
+	orders, err := decodeOrderMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([][]byte, len(orders))
+	for i, order := range orders {
+		encoded, err := encodeOrder(order)
+		if err != nil {
+			return nil, err
+		}
+		messages[i] = encoded
+	}
+	return messages, nil
+}
+
+// isLegacyMessage returns true if the given message data uses the legacy JSON
+// format. Messages in the binary format always start with the protobuf key
+// for the version field, which is never "{".
+func isLegacyMessage(data []byte) bool {
+	return len(data) > 0 && data[0] == '{'
+}
+
+// The binary message format is encoded with protobuf. It is equivalent to the
+// following schema:
+//
+//     message OrderMessage {
+//       uint32 version = 1;
+//       repeated SignedOrder orders = 2;
+//     }
+//
+//     message SignedOrder {
+//       bytes maker_address = 1;
+//       bytes maker_asset_data = 2;
+//       bytes maker_asset_amount = 3;
+//       bytes maker_fee = 4;
+//       bytes taker_address = 5;
+//       bytes taker_asset_data = 6;
+//       bytes taker_asset_amount = 7;
+//       bytes taker_fee = 8;
+//       bytes sender_address = 9;
+//       bytes exchange_address = 10;
+//       bytes fee_recipient_address = 11;
+//       bytes expiration_time_seconds = 12;
+//       bytes salt = 13;
+//       bytes signature = 14;
+//     }
+//
+// Addresses are encoded as 20 bytes and amounts are encoded as big-endian
+// unsigned integers.
+
+// binaryOrderMessage is the versioned envelope for the binary message format.
+type binaryOrderMessage struct {
+	Version uint32         `protobuf:"varint,1,opt,name=version,proto3"`
+	Orders  []*binaryOrder `protobuf:"bytes,2,rep,name=orders,proto3"`
+}
+
+func (m *binaryOrderMessage) Reset()         { *m = binaryOrderMessage{} }
+func (m *binaryOrderMessage) String() string { return proto.CompactTextString(m) }
+func (*binaryOrderMessage) ProtoMessage()    {}
+
+// binaryOrder is a signed order in the binary message format.
+type binaryOrder struct {
+	MakerAddress          []byte `protobuf:"bytes,1,opt,name=maker_address,proto3"`
+	MakerAssetData        []byte `protobuf:"bytes,2,opt,name=maker_asset_data,proto3"`
+	MakerAssetAmount      []byte `protobuf:"bytes,3,opt,name=maker_asset_amount,proto3"`
+	MakerFee              []byte `protobuf:"bytes,4,opt,name=maker_fee,proto3"`
+	TakerAddress          []byte `protobuf:"bytes,5,opt,name=taker_address,proto3"`
+	TakerAssetData        []byte `protobuf:"bytes,6,opt,name=taker_asset_data,proto3"`
+	TakerAssetAmount      []byte `protobuf:"bytes,7,opt,name=taker_asset_amount,proto3"`
+	TakerFee              []byte `protobuf:"bytes,8,opt,name=taker_fee,proto3"`
+	SenderAddress         []byte `protobuf:"bytes,9,opt,name=sender_address,proto3"`
+	ExchangeAddress       []byte `protobuf:"bytes,10,opt,name=exchange_address,proto3"`
+	FeeRecipientAddress   []byte `protobuf:"bytes,11,opt,name=fee_recipient_address,proto3"`
+	ExpirationTimeSeconds []byte `protobuf:"bytes,12,opt,name=expiration_time_seconds,proto3"`
+	Salt                  []byte `protobuf:"bytes,13,opt,name=salt,proto3"`
+	Signature             []byte `protobuf:"bytes,14,opt,name=signature,proto3"`
+}
+
+func (o *binaryOrder) Reset()         { *o = binaryOrder{} }
+func (o *binaryOrder) String() string { return proto.CompactTextString(o) }
+func (*binaryOrder) ProtoMessage()    {}
+
+// encodeOrderMessages encodes the given orders in the binary message format.
+// As many orders as possible are included in each message without exceeding
+// maxMessageDataSize.
+func encodeOrderMessages(orders []*zeroex.SignedOrder) ([][]byte, error) {
+	messages := [][]byte{}
+	batch := &binaryOrderMessage{Version: orderMessageVersion}
+	batchSize := proto.Size(batch)
+	for _, order := range orders {
+		encodedOrder, err := newBinaryOrder(order)
+		if err != nil {
+			return nil, err
+		}
+		// Each order in the batch is prefixed by its key and length.
+		orderSize := proto.Size(encodedOrder)
+		orderSize += 1 + proto.SizeVarint(uint64(orderSize))
+		if len(batch.Orders) > 0 && batchSize+orderSize > maxMessageDataSize {
+			encoded, err := proto.Marshal(batch)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, encoded)
+			batch = &binaryOrderMessage{Version: orderMessageVersion}
+			batchSize = proto.Size(batch)
+		}
+		batch.Orders = append(batch.Orders, encodedOrder)
+		batchSize += orderSize
+	}
+	if len(batch.Orders) > 0 {
+		encoded, err := proto.Marshal(batch)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, encoded)
+	}
+	return messages, nil
+}
+
+// encodeOrderMessage encodes a single order in the binary message format.
+func encodeOrderMessage(order *zeroex.SignedOrder) ([]byte, error) {
+	encodedOrder, err := newBinaryOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&binaryOrderMessage{
+		Version: orderMessageVersion,
+		Orders:  []*binaryOrder{encodedOrder},
+	})
+}
+
+// decodeOrderMessage decodes a message in the binary message format. It
+// returns an error if the message is malformed, uses an unsupported version,
+// or does not contain any orders.
+func decodeOrderMessage(data []byte) ([]*zeroex.SignedOrder, error) {
+	var message binaryOrderMessage
+	if err := proto.Unmarshal(data, &message); err != nil {
+		return nil, err
+	}
+	if message.Version != orderMessageVersion {
+		return nil, fmt.Errorf("unsupported message version: %d", message.Version)
+	}
+	if len(message.Orders) == 0 {
+		return nil, errEmptyOrderMessage
+	}
+	orders := make([]*zeroex.SignedOrder, len(message.Orders))
+	for i, encodedOrder := range message.Orders {
+		order, err := encodedOrder.signedOrder()
+		if err != nil {
+			return nil, err
+		}
+		orders[i] = order
+	}
+	return orders, nil
+}
+
+// decodeMessage decodes the orders contained in a message received from a
+// peer. Both the binary format and the legacy JSON format are supported.
+// Messages in the legacy format are validated against the JSON schema before
+// being decoded.
+func (app *App) decodeMessage(data []byte) ([]*zeroex.SignedOrder, error) {
+	if !isLegacyMessage(data) {
+		return decodeOrderMessage(data)
+	}
+	result, err := app.schemaValidateMeshMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Valid() {
+		formattedErrors := make([]string, len(result.Errors()))
+		for i, resultError := range result.Errors() {
+			formattedErrors[i] = resultError.String()
+		}
+		return nil, fmt.Errorf("message failed schema validation: %s", strings.Join(formattedErrors, "; "))
+	}
+	order, err := decodeOrder(data)
+	if err != nil {
+		return nil, err
+	}
+	return []*zeroex.SignedOrder{order}, nil
+}
+
+func newBinaryOrder(order *zeroex.SignedOrder) (*binaryOrder, error) {
+	makerAssetAmount, err := encodeUint(order.MakerAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	makerFee, err := encodeUint(order.MakerFee)
+	if err != nil {
+		return nil, err
+	}
+	takerAssetAmount, err := encodeUint(order.TakerAssetAmount)
+	if err != nil {
+		return nil, err
+	}
+	takerFee, err := encodeUint(order.TakerFee)
+	if err != nil {
+		return nil, err
+	}
+	expirationTimeSeconds, err := encodeUint(order.ExpirationTimeSeconds)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := encodeUint(order.Salt)
+	if err != nil {
+		return nil, err
+	}
+	return &binaryOrder{
+		MakerAddress:          order.MakerAddress.Bytes(),
+		MakerAssetData:        order.MakerAssetData,
+		MakerAssetAmount:      makerAssetAmount,
+		MakerFee:              makerFee,
+		TakerAddress:          order.TakerAddress.Bytes(),
+		TakerAssetData:        order.TakerAssetData,
+		TakerAssetAmount:      takerAssetAmount,
+		TakerFee:              takerFee,
+		SenderAddress:         order.SenderAddress.Bytes(),
+		ExchangeAddress:       order.ExchangeAddress.Bytes(),
+		FeeRecipientAddress:   order.FeeRecipientAddress.Bytes(),
+		ExpirationTimeSeconds: expirationTimeSeconds,
+		Salt:                  salt,
+		Signature:             order.Signature,
+	}, nil
+}
+
+func (o *binaryOrder) signedOrder() (*zeroex.SignedOrder, error) {
+	makerAddress, err := decodeAddress(o.MakerAddress)
+	if err != nil {
+		return nil, err
+	}
+	takerAddress, err := decodeAddress(o.TakerAddress)
+	if err != nil {
+		return nil, err
+	}
+	senderAddress, err := decodeAddress(o.SenderAddress)
+	if err != nil {
+		return nil, err
+	}
+	exchangeAddress, err := decodeAddress(o.ExchangeAddress)
+	if err != nil {
+		return nil, err
+	}
+	feeRecipientAddress, err := decodeAddress(o.FeeRecipientAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &zeroex.SignedOrder{
+		Order: zeroex.Order{
+			MakerAddress:          makerAddress,
+			MakerAssetData:        o.MakerAssetData,
+			MakerAssetAmount:      new(big.Int).SetBytes(o.MakerAssetAmount),
+			MakerFee:              new(big.Int).SetBytes(o.MakerFee),
+			TakerAddress:          takerAddress,
+			TakerAssetData:        o.TakerAssetData,
+			TakerAssetAmount:      new(big.Int).SetBytes(o.TakerAssetAmount),
+			TakerFee:              new(big.Int).SetBytes(o.TakerFee),
+			SenderAddress:         senderAddress,
+			ExchangeAddress:       exchangeAddress,
+			FeeRecipientAddress:   feeRecipientAddress,
+			ExpirationTimeSeconds: new(big.Int).SetBytes(o.ExpirationTimeSeconds),
+			Salt:                  new(big.Int).SetBytes(o.Salt),
+		},
+		Signature: o.Signature,
+	}, nil
+}
+
+// encodeUint encodes a non-negative big.Int as a big-endian byte slice. Like
+// the JSON schema for orders, it does not allow nil or negative numbers.
+func encodeUint(i *big.Int) ([]byte, error) {
+	if i == nil {
+		return nil, errors.New("cannot encode nil amount")
+	}
+	if i.Sign() < 0 {
+		return nil, fmt.Errorf("cannot encode negative amount: %s", i)
+	}
+	return i.Bytes(), nil
+}
+
+func decodeAddress(data []byte) (common.Address, error) {
+	if len(data) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("invalid address length: %d", len(data))
+	}
+	return common.BytesToAddress(data), nil
+}
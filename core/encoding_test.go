@@ -0,0 +1,301 @@
+// +build !js
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// benchmarkBatchSize is the number of orders encoded and decoded in each
+// iteration of the benchmarks.
+const benchmarkBatchSize = 50
+
+func TestEncodeOrderMessagesRoundTrip(t *testing.T) {
+	orders := newOffChainTestOrders(t, 10)
+	messages, err := encodeOrderMessages(orders)
+	require.NoError(t, err)
+	// All of the orders should fit in a single message.
+	require.Len(t, messages, 1)
+	decoded, err := decodeOrderMessage(messages[0])
+	require.NoError(t, err)
+	assertOrderHashesEqual(t, orders, decoded)
+}
+
+func TestEncodeOrderMessagesSplitsLargeBatches(t *testing.T) {
+	orders := newOffChainTestOrders(t, 300)
+	messages, err := encodeOrderMessages(orders)
+	require.NoError(t, err)
+	require.True(t, len(messages) > 1, "expected orders to be split into several messages")
+
+	decoded := []*zeroex.SignedOrder{}
+	for _, message := range messages {
+		assert.True(t, len(message) <= maxMessageDataSize, "message size %d exceeds maximum of %d", len(message), maxMessageDataSize)
+		messageOrders, err := decodeOrderMessage(message)
+		require.NoError(t, err)
+		decoded = append(decoded, messageOrders...)
+	}
+	assertOrderHashesEqual(t, orders, decoded)
+}
+
+func TestEncodeOrderMessagesRejectsInvalidAmounts(t *testing.T) {
+	order := newOffChainTestOrders(t, 1)[0]
+	order.MakerFee = big.NewInt(-1)
+	_, err := encodeOrderMessages([]*zeroex.SignedOrder{order})
+	assert.Error(t, err)
+	order.MakerFee = nil
+	_, err = encodeOrderMessage(order)
+	assert.Error(t, err)
+}
+
+func TestDecodeOrderMessageInvalid(t *testing.T) {
+	order := newOffChainTestOrders(t, 1)[0]
+	encodedOrder, err := newBinaryOrder(order)
+	require.NoError(t, err)
+	invalidAddressOrder := *encodedOrder
+	invalidAddressOrder.MakerAddress = invalidAddressOrder.MakerAddress[1:]
+
+	testCases := []struct {
+		name    string
+		message *binaryOrderMessage
+	}{
+		{
+			name:    "unsupported version",
+			message: &binaryOrderMessage{Version: orderMessageVersion + 1, Orders: []*binaryOrder{encodedOrder}},
+		},
+		{
+			name:    "missing version",
+			message: &binaryOrderMessage{Orders: []*binaryOrder{encodedOrder}},
+		},
+		{
+			name:    "no orders",
+			message: &binaryOrderMessage{Version: orderMessageVersion},
+		},
+		{
+			name:    "invalid address",
+			message: &binaryOrderMessage{Version: orderMessageVersion, Orders: []*binaryOrder{&invalidAddressOrder}},
+		},
+	}
+	for _, testCase := range testCases {
+		data, err := proto.Marshal(testCase.message)
+		require.NoError(t, err)
+		_, err = decodeOrderMessage(data)
+		assert.Error(t, err, testCase.name)
+	}
+
+	_, err = decodeOrderMessage([]byte{0xff, 0xff, 0xff})
+	assert.Error(t, err, "malformed data")
+}
+
+func TestDecodeMessage(t *testing.T) {
+	app := newEncodingTestApp(t)
+	orders := newOffChainTestOrders(t, 2)
+
+	// Messages in the binary format.
+	binaryMessage, err := encodeOrderMessages(orders)
+	require.NoError(t, err)
+	require.Len(t, binaryMessage, 1)
+	decoded, err := app.decodeMessage(binaryMessage[0])
+	require.NoError(t, err)
+	assertOrderHashesEqual(t, orders, decoded)
+
+	// Messages in the legacy JSON format.
+	legacyMessage, err := encodeOrder(orders[0])
+	require.NoError(t, err)
+	require.True(t, isLegacyMessage(legacyMessage))
+	decoded, err = app.decodeMessage(legacyMessage)
+	require.NoError(t, err)
+	assertOrderHashesEqual(t, orders[:1], decoded)
+
+	// Legacy messages which fail schema validation.
+	_, err = app.decodeMessage([]byte(`{"MessageType":"order","Order":{"makerAddress":"0x1"}}`))
+	assert.Error(t, err)
+}
+
+func TestEncodeLegacyOrderMessages(t *testing.T) {
+	app := newEncodingTestApp(t)
+	orders := newOffChainTestOrders(t, 3)
+
+	binaryMessages, err := encodeOrderMessages(orders)
+	require.NoError(t, err)
+	require.Len(t, binaryMessages, 1)
+
+	// Each order is converted to its own message in the legacy JSON format,
+	// which nodes that only subscribe to the legacy topic can decode.
+	legacyMessages, err := encodeLegacyOrderMessages(binaryMessages[0])
+	require.NoError(t, err)
+	require.Len(t, legacyMessages, len(orders))
+	for i, legacyMessage := range legacyMessages {
+		require.True(t, isLegacyMessage(legacyMessage))
+		decoded, err := decodeOrder(legacyMessage)
+		require.NoError(t, err)
+		assertOrderHashesEqual(t, orders[i:i+1], []*zeroex.SignedOrder{decoded})
+		decoded2, err := app.decodeMessage(legacyMessage)
+		require.NoError(t, err)
+		assertOrderHashesEqual(t, orders[i:i+1], decoded2)
+	}
+
+	_, err = encodeLegacyOrderMessages([]byte{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestValidateMessageSize(t *testing.T) {
+	// Legacy messages are limited to the maximum order size.
+	legacyMessage := make([]byte, constants.MaxOrderSizeInBytes+1)
+	legacyMessage[0] = '{'
+	assert.Equal(t, errMaxSize, validateMessageSize(&p2p.Message{Data: legacyMessage}))
+
+	// Binary messages can contain several orders.
+	binaryMessage := make([]byte, constants.MaxOrderSizeInBytes+1)
+	assert.NoError(t, validateMessageSize(&p2p.Message{Data: binaryMessage}))
+	binaryMessage = make([]byte, constants.MaxMessageSizeInBytes+1)
+	assert.Equal(t, errMaxMessageSize, validateMessageSize(&p2p.Message{Data: binaryMessage}))
+}
+
+func BenchmarkEncodeLegacyJSON(b *testing.B) {
+	orders := newOffChainTestOrders(b, benchmarkBatchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, order := range orders {
+			if _, err := encodeOrder(order); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkEncodeBinary(b *testing.B) {
+	orders := newOffChainTestOrders(b, benchmarkBatchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := encodeOrderMessages(orders); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecodeLegacyJSON measures the cost of validating and decoding
+// legacy messages, including schemaValidateMeshMessage.
+func BenchmarkDecodeLegacyJSON(b *testing.B) {
+	app := newEncodingTestApp(b)
+	orders := newOffChainTestOrders(b, benchmarkBatchSize)
+	messages := make([][]byte, len(orders))
+	for i, order := range orders {
+		encoded, err := encodeOrder(order)
+		require.NoError(b, err)
+		messages[i] = encoded
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, message := range messages {
+			if _, err := app.decodeMessage(message); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkSchemaValidateMeshMessage measures the cost of
+// schemaValidateMeshMessage on its own, which is only required for legacy
+// messages.
+func BenchmarkSchemaValidateMeshMessage(b *testing.B) {
+	app := newEncodingTestApp(b)
+	orders := newOffChainTestOrders(b, benchmarkBatchSize)
+	messages := make([][]byte, len(orders))
+	for i, order := range orders {
+		encoded, err := encodeOrder(order)
+		require.NoError(b, err)
+		messages[i] = encoded
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, message := range messages {
+			if _, err := app.schemaValidateMeshMessage(message); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkDecodeBinary measures the cost of decoding binary messages. Binary
+// messages don't need to be validated against the JSON schema because their
+// structure is enforced by the decoder.
+func BenchmarkDecodeBinary(b *testing.B) {
+	app := newEncodingTestApp(b)
+	orders := newOffChainTestOrders(b, benchmarkBatchSize)
+	messages, err := encodeOrderMessages(orders)
+	require.NoError(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, message := range messages {
+			if _, err := app.decodeMessage(message); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// newEncodingTestApp returns an App which can only be used for encoding and
+// decoding messages.
+func newEncodingTestApp(t require.TestingT) *App {
+	meshMessageJSONSchema, err := setupMeshMessageSchemaValidator()
+	require.NoError(t, err)
+	return &App{meshMessageJSONSchema: meshMessageJSONSchema}
+}
+
+var offChainTestOrderSalt int64
+
+// newOffChainTestOrders returns count new signed orders. Unlike
+// signedTestOrders, the orders are not backed by any on-chain state so tests
+// which use them do not require Ganache.
+func newOffChainTestOrders(t require.TestingT, count int) []*zeroex.SignedOrder {
+	contractAddresses, err := ethereum.GetContractAddressesForChainID(constants.TestChainID)
+	require.NoError(t, err)
+	orders := make([]*zeroex.SignedOrder, count)
+	for i := range orders {
+		offChainTestOrderSalt++
+		order := &zeroex.Order{
+			MakerAddress:          constants.GanacheAccount0,
+			TakerAddress:          constants.NullAddress,
+			SenderAddress:         constants.NullAddress,
+			FeeRecipientAddress:   constants.NullAddress,
+			MakerAssetData:        common.Hex2Bytes("f47261b0000000000000000000000000871dd7c2b4b25e1aa18728e9d5f2af4c4e431f5c"),
+			TakerAssetData:        common.Hex2Bytes("f47261b00000000000000000000000000b1ba0af832d7c05fd64161e0db78e85978e8082"),
+			Salt:                  big.NewInt(offChainTestOrderSalt),
+			MakerFee:              big.NewInt(0),
+			TakerFee:              big.NewInt(0),
+			MakerAssetAmount:      big.NewInt(1000),
+			TakerAssetAmount:      big.NewInt(2000),
+			ExpirationTimeSeconds: big.NewInt(time.Now().Add(24 * time.Hour).Unix()),
+			ExchangeAddress:       contractAddresses.Exchange,
+		}
+		signedOrder, err := zeroex.SignTestOrder(order)
+		require.NoError(t, err)
+		orders[i] = signedOrder
+	}
+	return orders
+}
+
+// assertOrderHashesEqual asserts that actual contains the same orders as
+// expected, in the same order.
+func assertOrderHashesEqual(t *testing.T, expected []*zeroex.SignedOrder, actual []*zeroex.SignedOrder) {
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		expectedHash, err := expected[i].ComputeOrderHash()
+		require.NoError(t, err)
+		actualHash, err := actual[i].ComputeOrderHash()
+		require.NoError(t, err)
+		assert.Equal(t, expectedHash, actualHash)
+		assert.Equal(t, expected[i].Signature, actual[i].Signature)
+	}
+}
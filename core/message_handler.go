@@ -1,23 +1,40 @@
 package core
 
 import (
-	"github.com/0xProject/0x-mesh/constants"
+	"encoding/json"
+
+	"github.com/0xProject/0x-mesh/core/ordersync"
+	"github.com/0xProject/0x-mesh/db"
 	"github.com/0xProject/0x-mesh/meshdb"
 	"github.com/0xProject/0x-mesh/p2p"
 	"github.com/0xProject/0x-mesh/zeroex"
 	"github.com/0xProject/0x-mesh/zeroex/ordervalidator"
+	"github.com/0xProject/0x-mesh/zeroex/orderwatch"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	peer "github.com/libp2p/go-libp2p-peer"
 	log "github.com/sirupsen/logrus"
 )
 
 // Ensure that App implements p2p.MessageHandler.
 var _ p2p.MessageHandler = &App{}
 
+// Ensure that App implements ordersync.OrderProvider and ordersync.OrderHandler.
+var _ ordersync.OrderProvider = &App{}
+var _ ordersync.OrderHandler = &App{}
+
+// Ensure that App implements p2p.SetReconciliationHandler.
+var _ p2p.SetReconciliationHandler = &App{}
+
+// orderSelector is a sharingStrategy which round-robins through all stored
+// orders.
 type orderSelector struct {
 	nextOffset int
 	db         *meshdb.MeshDB
 }
 
+var _ sharingStrategy = &orderSelector{}
+
 func min(a int, b int) int {
 	if a < b {
 		return a
@@ -26,11 +43,20 @@ func min(a int, b int) int {
 }
 
 func (app *App) GetMessagesToShare(max int) ([][]byte, error) {
-	return app.orderSelector.GetMessagesToShare(max)
+	return app.sharingStrategy.GetMessagesToShare(max)
 }
 
 func (orderSelector *orderSelector) GetMessagesToShare(max int) ([][]byte, error) {
-	// For now, we use a round robin strategy to select a set of orders to share.
+	selectedOrders, err := orderSelector.selectOrders(max)
+	if err != nil {
+		return nil, err
+	}
+	return encodeOrdersToShare(selectedOrders, max)
+}
+
+// selectOrders selects up to max orders to share, starting where the previous
+// call left off.
+func (orderSelector *orderSelector) selectOrders(max int) ([]*meshdb.Order, error) {
 	// We might return less than max even if there are max or greater orders
 	// currently stored.
 	// Use a snapshot to make sure state doesn't change between our two queries.
@@ -79,24 +105,59 @@ func (orderSelector *orderSelector) GetMessagesToShare(max int) ([][]byte, error
 			orderSelector.nextOffset = 0
 		}
 	}
+	return selectedOrders, nil
+}
 
+// encodeOrdersToShare encodes the selected orders to the message data format.
+// Several orders are included in each message, so fewer than len(selectedOrders)
+// messages are typically returned.
+func encodeOrdersToShare(selectedOrders []*meshdb.Order, max int) ([][]byte, error) {
+	if len(selectedOrders) == 0 {
+		return nil, nil
+	}
 	log.WithFields(map[string]interface{}{
 		"maxNumberToShare":    max,
 		"actualNumberToShare": len(selectedOrders),
 	}).Trace("preparing to share orders with peers")
 
-	// After we have selected all the orders to share, we need to encode them to
-	// the message data format.
-	messageData := make([][]byte, len(selectedOrders))
+	signedOrders := make([]*zeroex.SignedOrder, len(selectedOrders))
 	for i, order := range selectedOrders {
 		log.WithFields(map[string]interface{}{
 			"order": order,
 		}).Trace("selected order to share")
-		encoded, err := encodeOrder(order.SignedOrder)
+		signedOrders[i] = order.SignedOrder
+	}
+	return encodeOrderMessages(signedOrders)
+}
+
+// MessageIDs returns the hashes of all orders which have not been removed. It
+// implements p2p.SetReconciliationHandler.
+func (app *App) MessageIDs() ([][]byte, error) {
+	notRemovedFilter := app.db.Orders.IsRemovedIndex.ValueFilter([]byte{0})
+	return app.db.Orders.NewQuery(notRemovedFilter).IDs()
+}
+
+// MessagesByID returns the encoded orders with the given hashes. Orders which
+// are not found or have been removed are skipped. It implements
+// p2p.SetReconciliationHandler.
+func (app *App) MessagesByID(ids [][]byte) ([][]byte, error) {
+	messageData := [][]byte{}
+	for _, id := range ids {
+		var order meshdb.Order
+		if err := app.db.Orders.FindByID(id, &order); err != nil {
+			if _, ok := err.(db.NotFoundError); ok {
+				continue
+			}
+			return nil, err
+		}
+		if order.IsRemoved {
+			continue
+		}
+		encoded, err := encodeOrderMessage(order.SignedOrder)
 		if err != nil {
 			return nil, err
 		}
-		messageData[i] = encoded
+		messageData = append(messageData, encoded)
 	}
 	return messageData, nil
 }
@@ -104,67 +165,99 @@ func (orderSelector *orderSelector) GetMessagesToShare(max int) ([][]byte, error
 func (app *App) HandleMessages(messages []*p2p.Message) error {
 	// First we validate the messages and decode them into orders.
 	orders := []*zeroex.SignedOrder{}
-	orderHashToMessage := map[common.Hash]*p2p.Message{}
+	orderHashToFrom := map[common.Hash]peer.ID{}
 
 	for _, msg := range messages {
 		if err := validateMessageSize(msg); err != nil {
 			log.WithFields(map[string]interface{}{
-				"error":               err,
-				"from":                msg.From,
-				"maxOrderSizeInBytes": constants.MaxOrderSizeInBytes,
-				"actualSizeInBytes":   len(msg.Data),
+				"error":             err,
+				"from":              msg.From,
+				"actualSizeInBytes": len(msg.Data),
 			}).Trace("received message that exceeds maximum size")
 			app.handlePeerScoreEvent(msg.From, psInvalidMessage)
 			continue
 		}
 
-		result, err := app.schemaValidateMeshMessage(msg.Data)
+		msgOrders, err := app.decodeMessage(msg.Data)
 		if err != nil {
 			log.WithFields(map[string]interface{}{
 				"error": err,
 				"from":  msg.From,
-			}).Trace("could not schema validate message")
+			}).Trace("could not decode received message")
 			app.handlePeerScoreEvent(msg.From, psInvalidMessage)
 			continue
 		}
-		if !result.Valid() {
-			formattedErrors := make([]string, len(result.Errors()))
-			for i, resultError := range result.Errors() {
-				formattedErrors[i] = resultError.String()
+		for _, order := range msgOrders {
+			orderHash, err := order.ComputeOrderHash()
+			if err != nil {
+				return err
 			}
+			// Validate doesn't guarantee there are no duplicates so we keep track of
+			// which orders we've already seen.
+			if _, alreadySeen := orderHashToFrom[orderHash]; alreadySeen {
+				continue
+			}
+			orders = append(orders, order)
+			orderHashToFrom[orderHash] = msg.From
+		}
+		app.handlePeerScoreEvent(msg.From, psValidMessage)
+	}
+
+	return app.validateAndStoreOrdersFromPeers(orders, orderHashToFrom)
+}
+
+// HandleOrderSyncOrders implements ordersync.OrderHandler. It validates and
+// stores the orders received from a peer via the order sync protocol.
+func (app *App) HandleOrderSyncOrders(from peer.ID, filter *ordersync.Filter, rawOrders []json.RawMessage) error {
+	orders := []*zeroex.SignedOrder{}
+	orderHashToFrom := map[common.Hash]peer.ID{}
+	for _, rawOrder := range rawOrders {
+		result, err := app.schemaValidateOrder(rawOrder)
+		if err != nil || !result.Valid() {
 			log.WithFields(map[string]interface{}{
-				"errors": formattedErrors,
-				"from":   msg.From,
-			}).Trace("order schema validation failed for message")
-			app.handlePeerScoreEvent(msg.From, psInvalidMessage)
+				"from": from,
+			}).Trace("order schema validation failed for order received via order sync")
+			app.handlePeerScoreEvent(from, psInvalidMessage)
 			continue
 		}
-
-		order, err := decodeOrder(msg.Data)
-		if err != nil {
+		order := &zeroex.SignedOrder{}
+		if err := order.UnmarshalJSON(rawOrder); err != nil {
 			log.WithFields(map[string]interface{}{
 				"error": err,
-				"from":  msg.From,
-			}).Trace("could not decode received message")
-			app.handlePeerScoreEvent(msg.From, psInvalidMessage)
+				"from":  from,
+			}).Trace("could not decode order received via order sync")
+			app.handlePeerScoreEvent(from, psInvalidMessage)
+			continue
+		}
+		if !filter.Matches(order) {
+			// We only asked for orders which match the filter.
+			log.WithFields(map[string]interface{}{
+				"from": from,
+			}).Trace("received order which does not match filter via order sync")
+			app.handlePeerScoreEvent(from, psInvalidMessage)
 			continue
 		}
 		orderHash, err := order.ComputeOrderHash()
 		if err != nil {
 			return err
 		}
-		// Validate doesn't guarantee there are no duplicates so we keep track of
-		// which orders we've already seen.
-		if _, alreadySeen := orderHashToMessage[orderHash]; alreadySeen {
+		if _, alreadySeen := orderHashToFrom[orderHash]; alreadySeen {
 			continue
 		}
 		orders = append(orders, order)
-		orderHashToMessage[orderHash] = msg
-		app.handlePeerScoreEvent(msg.From, psValidMessage)
+		orderHashToFrom[orderHash] = from
+		app.handlePeerScoreEvent(from, psValidMessage)
 	}
 
-	// Next, we validate the orders.
-	validationResults, err := app.validateOrders(orders)
+	return app.validateAndStoreOrdersFromPeers(orders, orderHashToFrom)
+}
+
+// validateAndStoreOrdersFromPeers validates the given orders, stores the valid
+// ones, and updates the score of the peer each order was received from.
+// orderHashToFrom must contain the peer ID for each order.
+func (app *App) validateAndStoreOrdersFromPeers(orders []*zeroex.SignedOrder, orderHashToFrom map[common.Hash]peer.ID) error {
+	// First, we validate the orders.
+	validationResults, err := app.validateOrders(orders, rpc.LatestBlockNumber)
 	if err != nil {
 		return err
 	}
@@ -174,18 +267,18 @@ func (app *App) HandleMessages(messages []*p2p.Message) error {
 		if !acceptedOrderInfo.IsNew {
 			continue
 		}
-		msg := orderHashToMessage[acceptedOrderInfo.OrderHash]
+		from := orderHashToFrom[acceptedOrderInfo.OrderHash]
 		// If we've reached this point, the message is valid and we were able to
 		// decode it into an order. Append it to the list of orders to validate and
 		// update peer scores accordingly.
 		log.WithFields(map[string]interface{}{
 			"orderHash": acceptedOrderInfo.OrderHash.Hex(),
-			"from":      msg.From.String(),
+			"from":      from.String(),
 		}).Info("received new valid order from peer")
 		log.WithFields(map[string]interface{}{
 			"order":     acceptedOrderInfo.SignedOrder,
 			"orderHash": acceptedOrderInfo.OrderHash.Hex(),
-			"from":      msg.From.String(),
+			"from":      from.String(),
 		}).Trace("all fields for new valid order received from peer")
 		// Add stores the message in the database.
 		if err := app.orderWatcher.Add(acceptedOrderInfo, false); err != nil {
@@ -194,32 +287,56 @@ func (app *App) HandleMessages(messages []*p2p.Message) error {
 				log.WithFields(map[string]interface{}{
 					"error":     err.Error(),
 					"orderHash": acceptedOrderInfo.OrderHash.Hex(),
-					"from":      msg.From.String(),
+					"from":      from.String(),
 				}).Error("could not store valid order because database is full")
 				continue
 			}
+			if err == orderwatch.ErrMakerQuotaExceeded || err == orderwatch.ErrFeeRecipientQuotaExceeded {
+				// If the maker or fee recipient has too many orders, log and then
+				// continue. The peer did nothing wrong by sharing a valid order, so
+				// we don't penalize them.
+				log.WithFields(map[string]interface{}{
+					"error":     err.Error(),
+					"orderHash": acceptedOrderInfo.OrderHash.Hex(),
+					"from":      from.String(),
+				}).Debug("not storing valid order because a quota was exceeded")
+				continue
+			}
 			// For any other type of error, return it.
 			return err
 		}
-		app.handlePeerScoreEvent(msg.From, psOrderStored)
+		app.handlePeerScoreEvent(from, psOrderStored)
 	}
 
 	// We don't store invalid orders, but in some cases still need to update peer
 	// scores.
-	for _, rejectedOrderInfo := range validationResults.Rejected {
-		msg := orderHashToMessage[rejectedOrderInfo.OrderHash]
+	app.handleRejectedOrdersFromPeers(validationResults.Rejected, orderHashToFrom)
+	return nil
+}
+
+// handleRejectedOrdersFromPeers updates the score of the peer each rejected
+// order was received from. Peers are not penalized for rejections which might
+// not be their fault (e.g. our Ethereum RPC endpoint failing). Orders which
+// exceed the maximum size are protocol-level faults. All other rejections
+// (e.g. expired, filled or unfunded orders) only lower a score which never
+// causes the peer to be banned, since the order's state may have changed
+// after the peer shared it.
+func (app *App) handleRejectedOrdersFromPeers(rejectedOrderInfos []*ordervalidator.RejectedOrderInfo, orderHashToFrom map[common.Hash]peer.ID) {
+	for _, rejectedOrderInfo := range rejectedOrderInfos {
+		from := orderHashToFrom[rejectedOrderInfo.OrderHash]
 		log.WithFields(map[string]interface{}{
 			"rejectedOrderInfo": rejectedOrderInfo,
-			"from":              msg.From.String(),
+			"from":              from.String(),
 		}).Trace("not storing rejected order received from peer")
 		switch rejectedOrderInfo.Status {
-		case ordervalidator.ROInternalError, ordervalidator.ROEthRPCRequestFailed, ordervalidator.ROCoordinatorRequestFailed:
+		case ordervalidator.ROInternalError, ordervalidator.ROEthRPCRequestFailed, ordervalidator.ROBlockStateUnavailable, ordervalidator.ROCoordinatorRequestFailed:
 			// Don't incur a negative score for these status types (it might not be
 			// their fault).
+		case ordervalidator.ROMaxOrderSizeExceeded:
+			app.handlePeerScoreEvent(from, psInvalidMessage)
 		default:
 			// For other status types, we need to update the peer's score
-			app.handlePeerScoreEvent(msg.From, psInvalidMessage)
+			app.handlePeerScoreEvent(from, psInvalidOrder)
 		}
 	}
-	return nil
 }
@@ -12,6 +12,8 @@ import (
 	"github.com/0xProject/0x-mesh/ethereum"
 	"github.com/0xProject/0x-mesh/meshdb"
 	"github.com/0xProject/0x-mesh/scenario"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	ethrpc "github.com/ethereum/go-ethereum/rpc"
 	"github.com/google/uuid"
@@ -168,6 +170,32 @@ func TestMessagesSharedSerial(t *testing.T) {
 	verifyRoundRobinSharing(t, selector, selector.nextOffset, 7)
 }
 
+func TestSetReconciliationHandler(t *testing.T) {
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/" + uuid.New().String())
+	require.NoError(t, err)
+	defer meshDB.Close()
+	app := &App{db: meshDB}
+
+	orders := insertSharingTestOrders(t, meshDB, 3, time.Now(), false)
+	removedOrder := orders[2]
+	removedOrder.IsRemoved = true
+	require.NoError(t, meshDB.Orders.Update(removedOrder))
+
+	// Removed orders should not be included in the message IDs.
+	ids, err := app.MessageIDs()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, [][]byte{orders[0].Hash.Bytes(), orders[1].Hash.Bytes()}, ids)
+
+	// Removed orders and unknown orders should be skipped.
+	unknownHash := common.HexToHash("0x1")
+	messages, err := app.MessagesByID([][]byte{orders[0].Hash.Bytes(), removedOrder.Hash.Bytes(), unknownHash.Bytes()})
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	decoded, err := decodeOrderMessage(messages[0])
+	require.NoError(t, err)
+	assertOrderHashesEqual(t, []*zeroex.SignedOrder{orders[0].SignedOrder}, decoded)
+}
+
 // Verify that the correct messages are shared by `GetMessagesToShare` given `orders`, a `nextOffset`, and `max`
 func verifyRoundRobinSharing(t *testing.T, selector *orderSelector, nextOffset int, max int) {
 	notRemovedFilter := selector.db.Orders.IsRemovedIndex.ValueFilter([]byte{0})
@@ -177,7 +205,7 @@ func verifyRoundRobinSharing(t *testing.T, selector *orderSelector, nextOffset i
 	require.NoError(t, err)
 
 	expectedOrdersLength := min(max, count)
-	expectedOrders := make([][]byte, expectedOrdersLength)
+	expectedOrders := make([]*zeroex.SignedOrder, expectedOrdersLength)
 
 	// Get all of the orders in the database.
 	var orderList []*meshdb.Order
@@ -191,18 +219,22 @@ func verifyRoundRobinSharing(t *testing.T, selector *orderSelector, nextOffset i
 
 	// Calculate the orders that we expect to be shared
 	for i := 0; i < expectedOrdersLength; i++ {
-		encodedOrder, err := encodeOrder(orderList[(nextOffset+i)%count].SignedOrder)
-		require.NoError(t, err)
-
-		expectedOrders[i] = encodedOrder
+		expectedOrders[i] = orderList[(nextOffset+i)%count].SignedOrder
 	}
 
-	// Get the actual list of orders that are shared
-	actualOrders, err := selector.GetMessagesToShare(max)
+	// Get the actual list of orders that are shared. Several orders can be
+	// shared in a single message.
+	messages, err := selector.GetMessagesToShare(max)
 	require.NoError(t, err)
+	actualOrders := []*zeroex.SignedOrder{}
+	for _, message := range messages {
+		messageOrders, err := decodeOrderMessage(message)
+		require.NoError(t, err)
+		actualOrders = append(actualOrders, messageOrders...)
+	}
 
 	// Ensure that the result from `GetMessagesToShare` matches the expected result.
-	assert.Equal(t, expectedOrders, actualOrders)
+	assertOrderHashesEqual(t, expectedOrders, actualOrders)
 }
 
 func deleteOrders(t *testing.T, selector *orderSelector, orders []*meshdb.Order) {
@@ -0,0 +1,464 @@
+// Package ordersync implements a request/response protocol which can be used
+// to request orders directly from connected peers. It complements GossipSub,
+// which only shares a small number of orders at a time and can take a long
+// time to deliver the full set of orders to new or reconnecting nodes.
+package ordersync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/0xProject/0x-mesh/rpc"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/libp2p/go-libp2p-core/helpers"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// ProtocolID is the ID for the order sync protocol.
+	ProtocolID = protocol.ID("/0x-mesh/order-sync/1.0.0")
+	// DefaultPerPage is the default number of orders requested per page.
+	DefaultPerPage = 200
+	// MaxPerPage is the maximum number of orders that will be sent in response
+	// to a single request.
+	MaxPerPage = 500
+	// DefaultPerPeerRequestLimit is the default value for
+	// Config.PerPeerRequestLimit.
+	DefaultPerPeerRequestLimit = rate.Limit(1)
+	// DefaultPerPeerRequestBurst is the default value for
+	// Config.PerPeerRequestBurst.
+	DefaultPerPeerRequestBurst = 5
+	// maxRequestSizeInBytes is the maximum size of a request. Requests only
+	// contain pagination info and a small filter so this is generous.
+	maxRequestSizeInBytes = 16 * 1024
+	// maxResponseOverheadInBytes is an upper bound on the size of everything in
+	// a response other than the orders themselves.
+	maxResponseOverheadInBytes = 16 * 1024
+	// maxPagesPerSync is the maximum number of pages that will be requested from
+	// a single peer during one sync. It prevents a misbehaving peer from
+	// sending us orders forever.
+	maxPagesPerSync = 1000
+	// streamTimeout is the maximum amount of time that a single request and
+	// response is allowed to take.
+	streamTimeout = 30 * time.Second
+	// maxPeerLimiters is the maximum number of per-peer rate limiters that are
+	// kept in memory.
+	maxPeerLimiters = 1000
+	// syncInterval is how often SyncWithPeers checks for new peers to sync with.
+	syncInterval = 5 * time.Second
+	// maxSyncRounds is the maximum number of times SyncWithPeers checks for new
+	// peers to sync with before giving up.
+	maxSyncRounds = 60
+)
+
+// Peer score tags and values used by the Service.
+const (
+	invalidMessageScoreTag   = "order-sync-invalid-message"
+	invalidMessageScoreDiff  = -5
+	rateLimitedScoreTag      = "order-sync-rate-limited"
+	rateLimitedScoreDiff     = -5
+	successfulSyncScoreTag   = "order-sync-successful"
+	successfulSyncScoreValue = 5
+)
+
+var (
+	// ErrRateLimited is returned by SyncOrders when the peer refused a request
+	// because we sent too many requests.
+	ErrRateLimited = errors.New("order sync request was rate limited by peer")
+	// errInvalidResponse is returned by SyncOrders when the peer sent a response
+	// which does not correspond to our request.
+	errInvalidResponse = errors.New("received invalid order sync response")
+)
+
+// rateLimitedMessage is the error message sent to peers which have exceeded
+// the rate limit.
+const rateLimitedMessage = "rate limit exceeded"
+
+// Filter can be used to only request orders which match certain criteria.
+// Fields which are not set match any order.
+type Filter struct {
+	MakerAddress        *common.Address `json:"makerAddress,omitempty"`
+	FeeRecipientAddress *common.Address `json:"feeRecipientAddress,omitempty"`
+	MakerAssetData      hexutil.Bytes   `json:"makerAssetData,omitempty"`
+	TakerAssetData      hexutil.Bytes   `json:"takerAssetData,omitempty"`
+}
+
+// Matches returns true if the given order matches the filter. A nil filter
+// matches all orders.
+func (f *Filter) Matches(order *zeroex.SignedOrder) bool {
+	if f == nil {
+		return true
+	}
+	if f.MakerAddress != nil && order.MakerAddress != *f.MakerAddress {
+		return false
+	}
+	if f.FeeRecipientAddress != nil && order.FeeRecipientAddress != *f.FeeRecipientAddress {
+		return false
+	}
+	if len(f.MakerAssetData) != 0 && string(order.MakerAssetData) != string(f.MakerAssetData) {
+		return false
+	}
+	if len(f.TakerAssetData) != 0 && string(order.TakerAssetData) != string(f.TakerAssetData) {
+		return false
+	}
+	return true
+}
+
+// Request is a request for a single page of orders.
+type Request struct {
+	// SnapshotID is the ID of the snapshot returned in response to the first
+	// request. It should be empty for the first request.
+	SnapshotID string `json:"snapshotID"`
+	// Page is the page number to request, starting at 0.
+	Page int `json:"page"`
+	// PerPage is the number of orders per page. The peer may cap this value.
+	PerPage int `json:"perPage"`
+	// Filter is an optional filter. If set, the response will only contain
+	// orders which match the filter. Pages may then contain less than PerPage
+	// orders.
+	Filter *Filter `json:"filter,omitempty"`
+}
+
+// Response is a response containing a single page of orders.
+type Response struct {
+	SnapshotID string `json:"snapshotID"`
+	Page       int    `json:"page"`
+	// Orders are the JSON-encoded signed orders for this page. They are kept
+	// encoded so that the receiver can validate each order against the order
+	// JSON schema before decoding it.
+	Orders []json.RawMessage `json:"orders"`
+	// Complete is true if there are no more pages.
+	Complete bool `json:"complete"`
+	// Error is set if the request could not be handled.
+	Error string `json:"error,omitempty"`
+}
+
+// OrderProvider provides the orders which are sent to peers.
+type OrderProvider interface {
+	// GetOrders returns a page of orders from the snapshot with the given ID.
+	// If snapshotID is empty, a new snapshot should be created.
+	GetOrders(page, perPage int, snapshotID string) (*rpc.GetOrdersResponse, error)
+}
+
+// OrderHandler handles the orders received from peers.
+type OrderHandler interface {
+	// HandleOrderSyncOrders is called with each page of orders received from a
+	// peer. The orders have not been validated and the peer may have sent
+	// orders which do not match the filter.
+	HandleOrderSyncOrders(from peer.ID, filter *Filter, rawOrders []json.RawMessage) error
+}
+
+// Config contains configuration options for a Service.
+type Config struct {
+	// Node is the p2p node used to open streams and update peer scores.
+	Node *p2p.Node
+	// OrderProvider provides the orders sent in response to requests from peers.
+	OrderProvider OrderProvider
+	// OrderHandler handles the orders received from peers.
+	OrderHandler OrderHandler
+	// PerPage is the number of orders to request per page. Defaults to
+	// DefaultPerPage.
+	PerPage int
+	// PerPeerRequestLimit is the maximum number of requests per second that each
+	// peer is allowed to send. Additional requests are refused. Defaults to
+	// DefaultPerPeerRequestLimit.
+	PerPeerRequestLimit rate.Limit
+	// PerPeerRequestBurst is the maximum number of requests that each peer is
+	// allowed to send at once. Defaults to DefaultPerPeerRequestBurst.
+	PerPeerRequestBurst int
+}
+
+// Service serves order sync requests from peers and sends order sync requests
+// to peers.
+type Service struct {
+	node          *p2p.Node
+	orderProvider OrderProvider
+	orderHandler  OrderHandler
+	perPage       int
+	perPeerLimit  rate.Limit
+	perPeerBurst  int
+	// peerLimiters holds a *rate.Limiter for each peer which has recently sent
+	// us a request.
+	peerLimiters *lru.Cache
+	mu           sync.Mutex
+}
+
+// New creates a new Service and registers the order sync protocol with the
+// given node.
+func New(config Config) (*Service, error) {
+	if config.Node == nil {
+		return nil, errors.New("config.Node is required")
+	} else if config.OrderProvider == nil {
+		return nil, errors.New("config.OrderProvider is required")
+	} else if config.OrderHandler == nil {
+		return nil, errors.New("config.OrderHandler is required")
+	}
+	if config.PerPage == 0 {
+		config.PerPage = DefaultPerPage
+	}
+	if config.PerPage < 0 || config.PerPage > MaxPerPage {
+		return nil, fmt.Errorf("config.PerPage must be between 1 and %d", MaxPerPage)
+	}
+	if config.PerPeerRequestLimit == 0 {
+		config.PerPeerRequestLimit = DefaultPerPeerRequestLimit
+	}
+	if config.PerPeerRequestBurst == 0 {
+		config.PerPeerRequestBurst = DefaultPerPeerRequestBurst
+	}
+	// lru.New only returns an error if size is <= 0.
+	peerLimiters, _ := lru.New(maxPeerLimiters)
+	s := &Service{
+		node:          config.Node,
+		orderProvider: config.OrderProvider,
+		orderHandler:  config.OrderHandler,
+		perPage:       config.PerPage,
+		perPeerLimit:  config.PerPeerRequestLimit,
+		perPeerBurst:  config.PerPeerRequestBurst,
+		peerLimiters:  peerLimiters,
+	}
+	s.node.SetStreamHandler(ProtocolID, s.handleStream)
+	return s, nil
+}
+
+// SyncWithPeers syncs orders with connected peers until it has successfully
+// synced with minPeers different peers. Each peer is only tried once, so peers
+// which don't support the order sync protocol are not dialed again. Connected
+// peers are checked every syncInterval, and SyncWithPeers returns as soon as
+// there are no new peers left to try, after maxSyncRounds, or when ctx is
+// canceled.
+func (s *Service) SyncWithPeers(ctx context.Context, minPeers int) error {
+	triedPeers := map[peer.ID]struct{}{}
+	numSynced := 0
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for round := 0; round < maxSyncRounds; round++ {
+		foundNewPeers := false
+		for _, peerID := range s.node.ConnectedPeers() {
+			if numSynced >= minPeers {
+				return nil
+			}
+			if _, tried := triedPeers[peerID]; tried {
+				continue
+			}
+			foundNewPeers = true
+			triedPeers[peerID] = struct{}{}
+			numOrders, err := s.SyncOrders(ctx, peerID, nil)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.WithFields(map[string]interface{}{
+					"error":  err.Error(),
+					"peerID": peerID.String(),
+				}).Debug("could not sync orders with peer")
+				continue
+			}
+			log.WithFields(map[string]interface{}{
+				"peerID":    peerID.String(),
+				"numOrders": numOrders,
+			}).Info("synced orders with peer")
+			numSynced++
+		}
+		if numSynced >= minPeers {
+			return nil
+		}
+		// Stop once every connected peer has been tried and no new peers
+		// connected since the last round.
+		if !foundNewPeers && len(triedPeers) > 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	log.WithFields(map[string]interface{}{
+		"numSynced": numSynced,
+		"numTried":  len(triedPeers),
+		"minPeers":  minPeers,
+	}).Info("finished syncing orders with peers")
+	return nil
+}
+
+// SyncOrders requests all orders matching filter from the given peer one page
+// at a time and passes each page to the OrderHandler. It returns the number of
+// orders received. filter may be nil.
+func (s *Service) SyncOrders(ctx context.Context, peerID peer.ID, filter *Filter) (int, error) {
+	// Peers are assumed to use the same per-peer request limit that we do, so
+	// we pace our own requests accordingly.
+	limiter := rate.NewLimiter(s.perPeerLimit, s.perPeerBurst)
+	numOrders := 0
+	snapshotID := ""
+	for page := 0; page < maxPagesPerSync; page++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return numOrders, err
+		}
+		req := &Request{
+			SnapshotID: snapshotID,
+			Page:       page,
+			PerPage:    s.perPage,
+			Filter:     filter,
+		}
+		res, err := s.sendRequest(ctx, peerID, req)
+		if err != nil {
+			return numOrders, err
+		}
+		if res.Error != "" {
+			if res.Error == rateLimitedMessage {
+				return numOrders, ErrRateLimited
+			}
+			return numOrders, fmt.Errorf("peer could not handle order sync request: %s", res.Error)
+		}
+		if res.Page != page || len(res.Orders) > s.perPage || (snapshotID != "" && res.SnapshotID != snapshotID) {
+			s.node.AddPeerScore(peerID, invalidMessageScoreTag, invalidMessageScoreDiff)
+			return numOrders, errInvalidResponse
+		}
+		if len(res.Orders) > 0 {
+			if err := s.orderHandler.HandleOrderSyncOrders(peerID, filter, res.Orders); err != nil {
+				return numOrders, err
+			}
+			numOrders += len(res.Orders)
+		}
+		if res.Complete {
+			s.node.SetPeerScore(peerID, successfulSyncScoreTag, successfulSyncScoreValue)
+			return numOrders, nil
+		}
+		snapshotID = res.SnapshotID
+	}
+	return numOrders, nil
+}
+
+func (s *Service) sendRequest(ctx context.Context, peerID peer.ID, req *Request) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, streamTimeout)
+	defer cancel()
+	stream, err := s.node.NewStream(ctx, peerID, ProtocolID)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+	if err := json.NewEncoder(stream).Encode(req); err != nil {
+		_ = stream.Reset()
+		return nil, err
+	}
+	var res Response
+	maxResponseSize := int64(s.perPage*constants.MaxOrderSizeInBytes + maxResponseOverheadInBytes)
+	if err := json.NewDecoder(io.LimitReader(stream, maxResponseSize)).Decode(&res); err != nil {
+		_ = stream.Reset()
+		s.node.AddPeerScore(peerID, invalidMessageScoreTag, invalidMessageScoreDiff)
+		return nil, err
+	}
+	_ = helpers.FullClose(stream)
+	return &res, nil
+}
+
+func (s *Service) handleStream(stream network.Stream) {
+	requester := stream.Conn().RemotePeer()
+	_ = stream.SetDeadline(time.Now().Add(streamTimeout))
+	var req Request
+	if err := json.NewDecoder(io.LimitReader(stream, maxRequestSizeInBytes)).Decode(&req); err != nil {
+		log.WithFields(map[string]interface{}{
+			"error":     err.Error(),
+			"requester": requester.String(),
+		}).Trace("could not decode order sync request")
+		s.node.AddPeerScore(requester, invalidMessageScoreTag, invalidMessageScoreDiff)
+		_ = stream.Reset()
+		return
+	}
+	res := s.handleRequest(requester, &req)
+	if err := json.NewEncoder(stream).Encode(res); err != nil {
+		log.WithFields(map[string]interface{}{
+			"error":     err.Error(),
+			"requester": requester.String(),
+		}).Trace("could not send order sync response")
+		_ = stream.Reset()
+		return
+	}
+	_ = helpers.FullClose(stream)
+}
+
+func (s *Service) handleRequest(requester peer.ID, req *Request) *Response {
+	if !s.getPeerLimiter(requester).Allow() {
+		log.WithField("requester", requester.String()).Debug("order sync request rate limit exceeded")
+		s.node.AddPeerScore(requester, rateLimitedScoreTag, rateLimitedScoreDiff)
+		return &Response{
+			SnapshotID: req.SnapshotID,
+			Page:       req.Page,
+			Error:      rateLimitedMessage,
+		}
+	}
+	if req.Page < 0 || req.PerPage <= 0 {
+		s.node.AddPeerScore(requester, invalidMessageScoreTag, invalidMessageScoreDiff)
+		return &Response{
+			SnapshotID: req.SnapshotID,
+			Page:       req.Page,
+			Error:      "page must be non-negative and perPage must be positive",
+		}
+	}
+	perPage := req.PerPage
+	if perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+	ordersRes, err := s.orderProvider.GetOrders(req.Page, perPage, req.SnapshotID)
+	if err != nil {
+		log.WithFields(map[string]interface{}{
+			"error":     err.Error(),
+			"requester": requester.String(),
+		}).Debug("could not get orders for order sync request")
+		return &Response{
+			SnapshotID: req.SnapshotID,
+			Page:       req.Page,
+			Error:      err.Error(),
+		}
+	}
+	rawOrders := []json.RawMessage{}
+	for _, orderInfo := range ordersRes.OrdersInfos {
+		if !req.Filter.Matches(orderInfo.SignedOrder) {
+			continue
+		}
+		encoded, err := json.Marshal(orderInfo.SignedOrder)
+		if err != nil {
+			log.WithFields(map[string]interface{}{
+				"error":     err.Error(),
+				"orderHash": orderInfo.OrderHash.Hex(),
+			}).Error("could not encode order for order sync response")
+			continue
+		}
+		rawOrders = append(rawOrders, encoded)
+	}
+	return &Response{
+		SnapshotID: ordersRes.SnapshotID,
+		Page:       req.Page,
+		Orders:     rawOrders,
+		Complete:   len(ordersRes.OrdersInfos) < perPage,
+	}
+}
+
+// getPeerLimiter returns the rate limiter for the given peer, creating one if
+// needed.
+func (s *Service) getPeerLimiter(id peer.ID) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limiter, found := s.peerLimiters.Get(id); found {
+		return limiter.(*rate.Limiter)
+	}
+	limiter := rate.NewLimiter(s.perPeerLimit, s.perPeerBurst)
+	s.peerLimiters.Add(id, limiter)
+	return limiter
+}
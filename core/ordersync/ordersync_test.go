@@ -0,0 +1,309 @@
+// +build !js
+
+package ordersync
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/0xProject/0x-mesh/rpc"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+const testConnectionTimeout = 1 * time.Second
+
+// dummyMessageHandler satisfies the p2p.MessageHandler interface but doesn't
+// receive or share any messages.
+type dummyMessageHandler struct{}
+
+func (*dummyMessageHandler) HandleMessages([]*p2p.Message) error {
+	return nil
+}
+
+func (*dummyMessageHandler) GetMessagesToShare(max int) ([][]byte, error) {
+	return nil, nil
+}
+
+// inMemoryOrderProvider serves orders from a fixed list. It does not support
+// snapshots, so the list must not change during a test.
+type inMemoryOrderProvider struct {
+	orders []*zeroex.SignedOrder
+}
+
+func (p *inMemoryOrderProvider) GetOrders(page, perPage int, snapshotID string) (*rpc.GetOrdersResponse, error) {
+	ordersInfos := []*rpc.OrderInfo{}
+	for i := page * perPage; i < (page+1)*perPage && i < len(p.orders); i++ {
+		orderHash, err := p.orders[i].ComputeOrderHash()
+		if err != nil {
+			return nil, err
+		}
+		ordersInfos = append(ordersInfos, &rpc.OrderInfo{
+			OrderHash:                orderHash,
+			SignedOrder:              p.orders[i],
+			FillableTakerAssetAmount: p.orders[i].TakerAssetAmount,
+		})
+	}
+	if snapshotID == "" {
+		snapshotID = uuid.New().String()
+	}
+	return &rpc.GetOrdersResponse{
+		SnapshotID:  snapshotID,
+		OrdersInfos: ordersInfos,
+	}, nil
+}
+
+// recordingOrderHandler decodes and records all orders it receives.
+type recordingOrderHandler struct {
+	mu     sync.Mutex
+	orders []*zeroex.SignedOrder
+	pages  int
+}
+
+func (h *recordingOrderHandler) HandleOrderSyncOrders(from peer.ID, filter *Filter, rawOrders []json.RawMessage) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pages++
+	for _, rawOrder := range rawOrders {
+		order := &zeroex.SignedOrder{}
+		if err := order.UnmarshalJSON(rawOrder); err != nil {
+			return err
+		}
+		h.orders = append(h.orders, order)
+	}
+	return nil
+}
+
+func TestSyncOrders(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	orders := newTestOrders(t, 450, constants.GanacheAccount0)
+	_, _, node1 := newTestService(t, ctx, Config{
+		OrderProvider: &inMemoryOrderProvider{orders: orders},
+		// Allow the requests for every page at once.
+		PerPeerRequestBurst: 10,
+	})
+	service0, handler0, node0 := newTestService(t, ctx, Config{
+		PerPage:             100,
+		PerPeerRequestBurst: 10,
+	})
+	connectTestNodes(t, node0, node1)
+
+	numOrders, err := service0.SyncOrders(ctx, node1.ID(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, len(orders), numOrders)
+	assert.Equal(t, 5, handler0.pages)
+	assertOrdersEqual(t, orders, handler0.orders)
+}
+
+func TestSyncOrdersWithFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	matchingOrders := newTestOrders(t, 3, constants.GanacheAccount0)
+	otherOrders := newTestOrders(t, 5, constants.GanacheAccount1)
+	_, _, node1 := newTestService(t, ctx, Config{
+		OrderProvider: &inMemoryOrderProvider{orders: append(otherOrders, matchingOrders...)},
+	})
+	service0, handler0, node0 := newTestService(t, ctx, Config{})
+	connectTestNodes(t, node0, node1)
+
+	makerAddress := constants.GanacheAccount0
+	numOrders, err := service0.SyncOrders(ctx, node1.ID(), &Filter{MakerAddress: &makerAddress})
+	require.NoError(t, err)
+	assert.Equal(t, len(matchingOrders), numOrders)
+	assertOrdersEqual(t, matchingOrders, handler0.orders)
+}
+
+func TestSyncOrdersRateLimited(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	orders := newTestOrders(t, 10, constants.GanacheAccount0)
+	_, _, node1 := newTestService(t, ctx, Config{
+		OrderProvider:       &inMemoryOrderProvider{orders: orders},
+		PerPeerRequestLimit: rate.Every(time.Hour),
+		PerPeerRequestBurst: 1,
+	})
+	// node0 paces its requests to two per second, which is still too fast for
+	// node1.
+	service0, handler0, node0 := newTestService(t, ctx, Config{
+		PerPage:             5,
+		PerPeerRequestLimit: 2,
+		PerPeerRequestBurst: 1,
+	})
+	connectTestNodes(t, node0, node1)
+
+	numOrders, err := service0.SyncOrders(ctx, node1.ID(), nil)
+	assert.Equal(t, ErrRateLimited, err)
+	assert.Equal(t, 5, numOrders)
+	assertOrdersEqual(t, orders[:5], handler0.orders)
+}
+
+func TestSyncWithPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	service0, handler0, node0 := newTestService(t, ctx, Config{})
+	var allOrders []*zeroex.SignedOrder
+	for i := 0; i < 3; i++ {
+		orders := newTestOrders(t, 2, constants.GanacheAccount0)
+		allOrders = append(allOrders, orders...)
+		_, _, node := newTestService(t, ctx, Config{
+			OrderProvider: &inMemoryOrderProvider{orders: orders},
+		})
+		connectTestNodes(t, node0, node)
+	}
+
+	syncCtx, syncCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer syncCancel()
+	require.NoError(t, service0.SyncWithPeers(syncCtx, 3))
+	assertOrdersEqual(t, allOrders, handler0.orders)
+}
+
+func TestSyncWithPeersStopsAfterTryingAllPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	service0, handler0, node0 := newTestService(t, ctx, Config{})
+	orders := newTestOrders(t, 2, constants.GanacheAccount0)
+	_, _, node1 := newTestService(t, ctx, Config{
+		OrderProvider: &inMemoryOrderProvider{orders: orders},
+	})
+	connectTestNodes(t, node0, node1)
+	// These peers don't support the order sync protocol.
+	for i := 0; i < 2; i++ {
+		connectTestNodes(t, node0, newTestNode(t, ctx))
+	}
+
+	// There are fewer peers than minPeers, so SyncWithPeers should give up once
+	// every peer has been tried instead of retrying them until ctx is canceled.
+	syncCtx, syncCancel := context.WithTimeout(ctx, 4*syncInterval)
+	defer syncCancel()
+	start := time.Now()
+	require.NoError(t, service0.SyncWithPeers(syncCtx, 5))
+	assert.True(t, time.Since(start) < 3*syncInterval, "SyncWithPeers took too long: %s", time.Since(start))
+	assertOrdersEqual(t, orders, handler0.orders)
+}
+
+func TestFilterMatches(t *testing.T) {
+	order := newTestOrders(t, 1, constants.GanacheAccount0)[0]
+	makerAddress := constants.GanacheAccount0
+	otherAddress := constants.GanacheAccount1
+	testCases := []struct {
+		filter   *Filter
+		expected bool
+	}{
+		{filter: nil, expected: true},
+		{filter: &Filter{}, expected: true},
+		{filter: &Filter{MakerAddress: &makerAddress}, expected: true},
+		{filter: &Filter{MakerAddress: &otherAddress}, expected: false},
+		{filter: &Filter{FeeRecipientAddress: &otherAddress}, expected: false},
+		{filter: &Filter{MakerAssetData: order.MakerAssetData}, expected: true},
+		{filter: &Filter{MakerAssetData: order.TakerAssetData}, expected: false},
+		{filter: &Filter{TakerAssetData: order.TakerAssetData}, expected: true},
+		{filter: &Filter{MakerAddress: &makerAddress, TakerAssetData: order.MakerAssetData}, expected: false},
+	}
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.expected, testCase.filter.Matches(order), "test case %d", i)
+	}
+}
+
+// newTestService creates a new Service with a new p2p.Node. If
+// config.OrderProvider is nil, the Service does not serve any orders.
+// newTestNode creates a node which does not support the order sync protocol.
+func newTestNode(t *testing.T, ctx context.Context) *p2p.Node {
+	privKey, _, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	node, err := p2p.New(ctx, p2p.Config{
+		Topic:            "0x-mesh-testing",
+		PrivateKey:       privKey,
+		MessageHandler:   &dummyMessageHandler{},
+		RendezvousString: "0x-mesh-testing-rendezvous",
+		UseBootstrapList: false,
+		DataDir:          "/tmp/0x-mesh/ordersync-testing/" + uuid.New().String(),
+	})
+	require.NoError(t, err)
+	return node
+}
+
+func newTestService(t *testing.T, ctx context.Context, config Config) (*Service, *recordingOrderHandler, *p2p.Node) {
+	node := newTestNode(t, ctx)
+	handler := &recordingOrderHandler{}
+	config.Node = node
+	config.OrderHandler = handler
+	if config.OrderProvider == nil {
+		config.OrderProvider = &inMemoryOrderProvider{}
+	}
+	service, err := New(config)
+	require.NoError(t, err)
+	return service, handler, node
+}
+
+func connectTestNodes(t *testing.T, node0, node1 *p2p.Node) {
+	node1PeerInfo := peer.AddrInfo{
+		ID:    node1.ID(),
+		Addrs: node1.Multiaddrs(),
+	}
+	require.NoError(t, node0.Connect(node1PeerInfo, testConnectionTimeout))
+}
+
+var testOrderSalt int64
+
+func newTestOrders(t *testing.T, count int, makerAddress common.Address) []*zeroex.SignedOrder {
+	orders := make([]*zeroex.SignedOrder, count)
+	for i := range orders {
+		testOrderSalt++
+		order := &zeroex.Order{
+			MakerAddress:          makerAddress,
+			TakerAddress:          constants.NullAddress,
+			SenderAddress:         constants.NullAddress,
+			FeeRecipientAddress:   constants.NullAddress,
+			MakerAssetData:        common.Hex2Bytes("f47261b0000000000000000000000000871dd7c2b4b25e1aa18728e9d5f2af4c4e431f5c"),
+			TakerAssetData:        common.Hex2Bytes("f47261b00000000000000000000000000b1ba0af832d7c05fd64161e0db78e85978e8082"),
+			Salt:                  big.NewInt(testOrderSalt),
+			MakerFee:              big.NewInt(0),
+			TakerFee:              big.NewInt(0),
+			MakerAssetAmount:      big.NewInt(1000),
+			TakerAssetAmount:      big.NewInt(2000),
+			ExpirationTimeSeconds: big.NewInt(time.Now().Add(24 * time.Hour).Unix()),
+			ExchangeAddress:       constants.NullAddress,
+		}
+		signedOrder, err := zeroex.SignTestOrder(order)
+		require.NoError(t, err)
+		orders[i] = signedOrder
+	}
+	return orders
+}
+
+// assertOrdersEqual asserts that actual contains exactly the same orders as
+// expected, in any order.
+func assertOrdersEqual(t *testing.T, expected []*zeroex.SignedOrder, actual []*zeroex.SignedOrder) {
+	expectedHashes := make([]common.Hash, len(expected))
+	for i, order := range expected {
+		orderHash, err := order.ComputeOrderHash()
+		require.NoError(t, err)
+		expectedHashes[i] = orderHash
+	}
+	actualHashes := make([]common.Hash, len(actual))
+	for i, order := range actual {
+		orderHash, err := order.ComputeOrderHash()
+		require.NoError(t, err)
+		actualHashes[i] = orderHash
+	}
+	assert.ElementsMatch(t, expectedHashes, actualHashes)
+}
@@ -1,16 +1,36 @@
 package core
 
 import (
+	"github.com/0xProject/0x-mesh/p2p"
 	peer "github.com/libp2p/go-libp2p-peer"
 	log "github.com/sirupsen/logrus"
 )
 
 type peerScoreEvent uint
 
+const (
+	// invalidMessageTag is the tag used for the score of peers who send us
+	// invalid messages.
+	invalidMessageTag = "invalid-message"
+	// invalidMessageScoreDiff is added to the score of a peer each time it sends
+	// us an invalid message.
+	invalidMessageScoreDiff = -5
+	// invalidOrderTag is the tag used for the score of peers who send us orders
+	// which are rejected because of their on-chain state (e.g. expired, filled
+	// or unfunded orders). Honest peers can send these orders since an order's
+	// state may change between the time it is shared and the time we validate
+	// it, so this score never causes a peer to be banned.
+	invalidOrderTag = "invalid-order"
+	// invalidOrderScoreDiff is added to the score of a peer each time it sends
+	// us an order which is rejected because of its on-chain state.
+	invalidOrderScoreDiff = -1
+)
+
 const (
 	psInvalidMessage peerScoreEvent = iota
 	psValidMessage
 	psOrderStored
+	psInvalidOrder
 )
 
 func (app *App) handlePeerScoreEvent(id peer.ID, event peerScoreEvent) {
@@ -21,12 +41,44 @@ func (app *App) handlePeerScoreEvent(id peer.ID, event peerScoreEvent) {
 	// spam the network with valid messages).
 	switch event {
 	case psInvalidMessage:
-		app.node.AddPeerScore(id, "invalid-message", -5)
+		app.node.AddPeerScore(id, invalidMessageTag, invalidMessageScoreDiff)
+		app.node.RecordInvalidMessage(id)
+		app.banPeerIfScoreTooLow(id)
 	case psValidMessage:
 		app.node.SetPeerScore(id, "valid-message", 5)
 	case psOrderStored:
 		app.node.SetPeerScore(id, "order-stored", 10)
+	case psInvalidOrder:
+		app.node.AddPeerScore(id, invalidOrderTag, invalidOrderScoreDiff)
 	default:
 		log.WithField("event", event).Error("unknown peerScoreEvent")
 	}
 }
+
+// banPeerIfScoreTooLow bans the given peer if its cumulative score for invalid
+// messages is below the configured PeerBanThreshold. Only protocol-level faults
+// (psInvalidMessage) count toward the threshold.
+func (app *App) banPeerIfScoreTooLow(id peer.ID) {
+	if app.config.PeerBanThreshold == 0 || app.node.IsPeerBanned(id) {
+		return
+	}
+	score := app.node.GetPeerScore(id, invalidMessageTag)
+	if score >= app.config.PeerBanThreshold {
+		return
+	}
+	log.WithFields(map[string]interface{}{
+		"peerID":    id,
+		"score":     score,
+		"threshold": app.config.PeerBanThreshold,
+	}).Warn("banning peer for sending too many invalid messages")
+	if err := app.node.BanPeer(id); err != nil {
+		if err == p2p.ErrProtectedPeer {
+			log.WithField("peerID", id).Debug("not banning protected peer")
+			return
+		}
+		log.WithFields(map[string]interface{}{
+			"error":  err.Error(),
+			"peerID": id,
+		}).Error("could not ban peer")
+	}
+}
@@ -0,0 +1,148 @@
+// +build !js
+
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/p2p"
+	"github.com/0xProject/0x-mesh/zeroex/ordervalidator"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	peer "github.com/libp2p/go-libp2p-peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidMessagesBanPeer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app := newPeerScoreTestApp(t, ctx, -100)
+	id := newPeerScoreTestPeerID(t)
+
+	// Each invalid message lowers the score by 5 so the peer should only be
+	// banned once its score drops below -100.
+	for i := 0; i < 20; i++ {
+		app.handlePeerScoreEvent(id, psInvalidMessage)
+	}
+	assert.Equal(t, -100, app.node.GetPeerScore(id, invalidMessageTag))
+	assert.False(t, app.node.IsPeerBanned(id))
+	app.handlePeerScoreEvent(id, psInvalidMessage)
+	assert.True(t, app.node.IsPeerBanned(id))
+}
+
+func TestInvalidMessagesBanPeerDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app := newPeerScoreTestApp(t, ctx, 0)
+	id := newPeerScoreTestPeerID(t)
+
+	for i := 0; i < 100; i++ {
+		app.handlePeerScoreEvent(id, psInvalidMessage)
+	}
+	assert.False(t, app.node.IsPeerBanned(id))
+}
+
+func TestRejectedOrdersFromPeersNotTheirFault(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app := newPeerScoreTestApp(t, ctx, -100)
+	id := newPeerScoreTestPeerID(t)
+
+	statuses := []ordervalidator.RejectedOrderStatus{
+		ordervalidator.ROInternalError,
+		ordervalidator.ROEthRPCRequestFailed,
+		ordervalidator.ROBlockStateUnavailable,
+		ordervalidator.ROCoordinatorRequestFailed,
+	}
+	rejectedOrderInfos := []*ordervalidator.RejectedOrderInfo{}
+	orderHashToFrom := map[common.Hash]peer.ID{}
+	for i, status := range statuses {
+		orderHash := common.BigToHash(big.NewInt(int64(i)))
+		rejectedOrderInfos = append(rejectedOrderInfos, &ordervalidator.RejectedOrderInfo{
+			OrderHash: orderHash,
+			Kind:      ordervalidator.MeshError,
+			Status:    status,
+		})
+		orderHashToFrom[orderHash] = id
+	}
+	app.handleRejectedOrdersFromPeers(rejectedOrderInfos, orderHashToFrom)
+	assert.Equal(t, 0, app.node.GetPeerScore(id, invalidMessageTag))
+	assert.Equal(t, 0, app.node.GetPeerScore(id, invalidOrderTag))
+}
+
+func TestRejectedOrdersFromPeersDontBanPeer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app := newPeerScoreTestApp(t, ctx, -100)
+	id := newPeerScoreTestPeerID(t)
+
+	// Orders can be filled, cancelled or expire between the time a peer shares
+	// them and the time we validate them, so these rejections must never cause
+	// an honest peer to be banned.
+	statuses := []ordervalidator.RejectedOrderStatus{
+		ordervalidator.ROExpired,
+		ordervalidator.ROFullyFilled,
+		ordervalidator.ROCancelled,
+		ordervalidator.ROUnfunded,
+	}
+	rejectedOrderInfos := []*ordervalidator.RejectedOrderInfo{}
+	orderHashToFrom := map[common.Hash]peer.ID{}
+	for i := 0; i < 100; i++ {
+		orderHash := common.BigToHash(big.NewInt(int64(i)))
+		rejectedOrderInfos = append(rejectedOrderInfos, &ordervalidator.RejectedOrderInfo{
+			OrderHash: orderHash,
+			Kind:      ordervalidator.ZeroExValidation,
+			Status:    statuses[i%len(statuses)],
+		})
+		orderHashToFrom[orderHash] = id
+	}
+	app.handleRejectedOrdersFromPeers(rejectedOrderInfos, orderHashToFrom)
+	assert.Equal(t, 0, app.node.GetPeerScore(id, invalidMessageTag))
+	assert.Equal(t, -100, app.node.GetPeerScore(id, invalidOrderTag))
+	assert.False(t, app.node.IsPeerBanned(id))
+
+	// Orders which exceed the maximum size are a protocol-level fault.
+	orderHash := common.BigToHash(big.NewInt(100))
+	app.handleRejectedOrdersFromPeers([]*ordervalidator.RejectedOrderInfo{
+		{
+			OrderHash: orderHash,
+			Kind:      ordervalidator.MeshValidation,
+			Status:    ordervalidator.ROMaxOrderSizeExceeded,
+		},
+	}, map[common.Hash]peer.ID{orderHash: id})
+	assert.Equal(t, invalidMessageScoreDiff, app.node.GetPeerScore(id, invalidMessageTag))
+}
+
+// newPeerScoreTestApp returns an App with a p2p.Node which has not been
+// started.
+func newPeerScoreTestApp(t *testing.T, ctx context.Context, peerBanThreshold int) *App {
+	app := &App{
+		config: Config{
+			PeerBanThreshold: peerBanThreshold,
+		},
+	}
+	privKey, _, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	app.node, err = p2p.New(ctx, p2p.Config{
+		Topic:            "0x-mesh-peer-score-testing",
+		PrivateKey:       privKey,
+		MessageHandler:   app,
+		RendezvousString: "0x-mesh-peer-score-testing-rendezvous",
+		DataDir:          "/tmp/0x-mesh/core-testing/" + uuid.New().String(),
+	})
+	require.NoError(t, err)
+	return app
+}
+
+func newPeerScoreTestPeerID(t *testing.T) peer.ID {
+	_, pubKey, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	id, err := peer.IDFromPublicKey(pubKey)
+	require.NoError(t, err)
+	return id
+}
@@ -0,0 +1,233 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Names for the built-in order sharing strategies.
+const (
+	// roundRobinSharingStrategyName is the name of the strategy implemented by
+	// orderSelector.
+	roundRobinSharingStrategyName = "roundRobin"
+	// prioritySharingStrategyName is the name of the strategy implemented by
+	// prioritySharingStrategy.
+	prioritySharingStrategyName = "priority"
+)
+
+const (
+	// recentOrderWindow is how long after being added or updated an order is
+	// considered recent by prioritySharingStrategy.
+	recentOrderWindow = 5 * time.Minute
+	// priorityReshareInterval is the minimum amount of time that must pass before
+	// prioritySharingStrategy will share the same order with priority again.
+	priorityReshareInterval = 1 * time.Minute
+	// maxPriorityCandidates is the maximum number of recent orders and the
+	// maximum number of pinned orders that prioritySharingStrategy will consider
+	// each time it selects orders to share. It bounds the amount of work done per
+	// call when there are a large number of recent or pinned orders. Orders
+	// beyond this limit are still shared via round robin.
+	maxPriorityCandidates = 500
+)
+
+// sharingStrategy determines which orders are shared with peers.
+type sharingStrategy interface {
+	// GetMessagesToShare selects up to max orders which should be shared with
+	// peers and returns them encoded as messages. Each message may contain
+	// several orders.
+	GetMessagesToShare(max int) ([][]byte, error)
+}
+
+// sharingStrategyFromName returns the built-in sharing strategy with the given
+// name. An empty name corresponds to the default strategy, which shares all
+// orders in turn. If setReconciliationEnabled is true, peers receive
+// the orders they are missing via set reconciliation, so
+// prioritySharingStrategy only shares priority orders.
+func sharingStrategyFromName(name string, meshDB *meshdb.MeshDB, setReconciliationEnabled bool) (sharingStrategy, error) {
+	switch name {
+	case prioritySharingStrategyName:
+		strategy := newPrioritySharingStrategy(meshDB)
+		strategy.skipRoundRobin = setReconciliationEnabled
+		return strategy, nil
+	case "", roundRobinSharingStrategyName:
+		return &orderSelector{nextOffset: 0, db: meshDB}, nil
+	default:
+		return nil, fmt.Errorf("unknown order sharing strategy: %q", name)
+	}
+}
+
+// prioritySharingStrategy is a sharingStrategy which shares orders that were
+// recently added, pinned orders, and orders which have not been shared yet
+// before other orders. A portion of each batch is always reserved for orders
+// selected via round robin so that every order is eventually shared, no matter
+// how many priority orders there are.
+type prioritySharingStrategy struct {
+	db         *meshdb.MeshDB
+	roundRobin *orderSelector
+	// skipRoundRobin disables sharing orders via round robin. It should only be
+	// set when peers have some other way of receiving the orders they are
+	// missing (e.g. set reconciliation).
+	skipRoundRobin bool
+	// now returns the current time. It can be overridden in tests.
+	now func() time.Time
+
+	mu sync.Mutex
+	// orderHashToLastShared is the last time that each order was shared.
+	orderHashToLastShared map[common.Hash]time.Time
+	lastPruned            time.Time
+}
+
+var _ sharingStrategy = &prioritySharingStrategy{}
+
+func newPrioritySharingStrategy(meshDB *meshdb.MeshDB) *prioritySharingStrategy {
+	return &prioritySharingStrategy{
+		db: meshDB,
+		roundRobin: &orderSelector{
+			nextOffset: 0,
+			db:         meshDB,
+		},
+		now:                   time.Now,
+		orderHashToLastShared: map[common.Hash]time.Time{},
+	}
+}
+
+// GetMessagesToShare implements sharingStrategy.
+func (s *prioritySharingStrategy) GetMessagesToShare(max int) ([][]byte, error) {
+	selectedOrders, err := s.selectOrders(max)
+	if err != nil {
+		return nil, err
+	}
+	return encodeOrdersToShare(selectedOrders, max)
+}
+
+func (s *prioritySharingStrategy) selectOrders(max int) ([]*meshdb.Order, error) {
+	if max <= 0 {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	// Reserve roughly a quarter of each batch (but always at least one slot when
+	// possible) for round robin so that the full set of orders is covered.
+	roundRobinSlots := max / 4
+	if roundRobinSlots == 0 && max > 1 {
+		roundRobinSlots = 1
+	}
+	if s.skipRoundRobin {
+		roundRobinSlots = 0
+	}
+	prioritySlots := max - roundRobinSlots
+
+	candidates, err := s.findPriorityCandidates(now)
+	if err != nil {
+		return nil, err
+	}
+	selectedOrders := make([]*meshdb.Order, 0, max)
+	selectedHashes := map[common.Hash]struct{}{}
+	for _, order := range candidates {
+		if len(selectedOrders) >= prioritySlots {
+			break
+		}
+		selectedOrders = append(selectedOrders, order)
+		selectedHashes[order.Hash] = struct{}{}
+	}
+
+	// Fill the rest of the batch via round robin. Orders which were already
+	// selected are skipped, which means we might return less than max orders.
+	if !s.skipRoundRobin {
+		roundRobinOrders, err := s.roundRobin.selectOrders(max - len(selectedOrders))
+		if err != nil {
+			return nil, err
+		}
+		for _, order := range roundRobinOrders {
+			if _, found := selectedHashes[order.Hash]; found {
+				continue
+			}
+			selectedOrders = append(selectedOrders, order)
+			selectedHashes[order.Hash] = struct{}{}
+		}
+	}
+
+	for _, order := range selectedOrders {
+		s.orderHashToLastShared[order.Hash] = now
+	}
+	s.pruneLastShared(now)
+	return selectedOrders, nil
+}
+
+// findPriorityCandidates returns the recent and pinned orders which are due to
+// be shared. Orders which have never been shared come first, followed by the
+// orders which were shared least recently. Ties are broken by sharing the most
+// recently updated orders first.
+func (s *prioritySharingStrategy) findPriorityCandidates(now time.Time) ([]*meshdb.Order, error) {
+	ordersSnapshot, err := s.db.Orders.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer ordersSnapshot.Release()
+
+	var recentOrders []*meshdb.Order
+	recentFilter := s.db.Orders.LastUpdatedIndex.RangeFilter(
+		[]byte(now.Add(-recentOrderWindow).UTC().Format(time.RFC3339Nano)),
+		[]byte(now.Add(time.Second).UTC().Format(time.RFC3339Nano)),
+	)
+	if err := ordersSnapshot.NewQuery(recentFilter).Reverse().Max(maxPriorityCandidates).Run(&recentOrders); err != nil {
+		return nil, err
+	}
+	var pinnedOrders []*meshdb.Order
+	pinnedFilter := s.db.Orders.ExpirationTimeIndex.PrefixFilter([]byte("1|"))
+	if err := ordersSnapshot.NewQuery(pinnedFilter).Max(maxPriorityCandidates).Run(&pinnedOrders); err != nil {
+		return nil, err
+	}
+
+	seen := map[common.Hash]struct{}{}
+	candidates := []*meshdb.Order{}
+	for _, order := range append(recentOrders, pinnedOrders...) {
+		if order.IsRemoved {
+			continue
+		}
+		if _, found := seen[order.Hash]; found {
+			continue
+		}
+		seen[order.Hash] = struct{}{}
+		if lastShared, found := s.orderHashToLastShared[order.Hash]; found && now.Sub(lastShared) < priorityReshareInterval {
+			continue
+		}
+		candidates = append(candidates, order)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		lastSharedI, sharedI := s.orderHashToLastShared[candidates[i].Hash]
+		lastSharedJ, sharedJ := s.orderHashToLastShared[candidates[j].Hash]
+		if sharedI != sharedJ {
+			return !sharedI
+		}
+		if !lastSharedI.Equal(lastSharedJ) {
+			return lastSharedI.Before(lastSharedJ)
+		}
+		return candidates[i].LastUpdated.After(candidates[j].LastUpdated)
+	})
+	return candidates, nil
+}
+
+// pruneLastShared removes last shared times which are old enough that they no
+// longer affect which orders are selected. This prevents the map from growing
+// without bound as orders are removed. It runs at most once per
+// recentOrderWindow.
+func (s *prioritySharingStrategy) pruneLastShared(now time.Time) {
+	if now.Sub(s.lastPruned) < recentOrderWindow {
+		return
+	}
+	for orderHash, lastShared := range s.orderHashToLastShared {
+		if now.Sub(lastShared) >= recentOrderWindow {
+			delete(s.orderHashToLastShared, orderHash)
+		}
+	}
+	s.lastPruned = now
+}
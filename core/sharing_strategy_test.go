@@ -0,0 +1,276 @@
+// +build !js
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/meshdb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharingStrategyFromName(t *testing.T) {
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/" + uuid.New().String())
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	strategy, err := sharingStrategyFromName("", meshDB, false)
+	require.NoError(t, err)
+	assert.IsType(t, &orderSelector{}, strategy)
+	strategy, err = sharingStrategyFromName(prioritySharingStrategyName, meshDB, false)
+	require.NoError(t, err)
+	require.IsType(t, &prioritySharingStrategy{}, strategy)
+	assert.False(t, strategy.(*prioritySharingStrategy).skipRoundRobin)
+	strategy, err = sharingStrategyFromName(prioritySharingStrategyName, meshDB, true)
+	require.NoError(t, err)
+	require.IsType(t, &prioritySharingStrategy{}, strategy)
+	assert.True(t, strategy.(*prioritySharingStrategy).skipRoundRobin)
+	strategy, err = sharingStrategyFromName(roundRobinSharingStrategyName, meshDB, false)
+	require.NoError(t, err)
+	assert.IsType(t, &orderSelector{}, strategy)
+	_, err = sharingStrategyFromName("invalid", meshDB, false)
+	assert.Error(t, err)
+}
+
+func TestPrioritySharingStrategySharesNewOrdersFirst(t *testing.T) {
+	strategy, clock := newTestPrioritySharingStrategy(t)
+	defer strategy.db.Close()
+	oldOrders := insertSharingTestOrders(t, strategy.db, 100, clock.now().Add(-time.Hour), false)
+
+	// Share a few batches so that the round robin offset isn't at the start.
+	for i := 0; i < 3; i++ {
+		_, err := strategy.selectOrders(5)
+		require.NoError(t, err)
+		clock.advance(time.Second)
+	}
+
+	newOrders := insertSharingTestOrders(t, strategy.db, 3, clock.now(), false)
+	selectedOrders, err := strategy.selectOrders(5)
+	require.NoError(t, err)
+	require.Len(t, selectedOrders, 5)
+	assertContainsOrders(t, selectedOrders[:3], newOrders)
+	assertContainsOrders(t, oldOrders, selectedOrders[3:])
+}
+
+func TestPrioritySharingStrategySharesPinnedOrdersFirst(t *testing.T) {
+	strategy, clock := newTestPrioritySharingStrategy(t)
+	defer strategy.db.Close()
+	insertSharingTestOrders(t, strategy.db, 100, clock.now().Add(-time.Hour), false)
+	pinnedOrders := insertSharingTestOrders(t, strategy.db, 2, clock.now().Add(-time.Hour), true)
+
+	selectedOrders, err := strategy.selectOrders(5)
+	require.NoError(t, err)
+	assertContainsOrders(t, selectedOrders, pinnedOrders)
+
+	// Pinned orders should not be shared with priority again until
+	// priorityReshareInterval has passed.
+	clock.advance(priorityReshareInterval / 2)
+	candidates, err := strategy.findPriorityCandidates(clock.now())
+	require.NoError(t, err)
+	assertNotContainsOrders(t, candidates, pinnedOrders)
+
+	clock.advance(priorityReshareInterval)
+	selectedOrders, err = strategy.selectOrders(5)
+	require.NoError(t, err)
+	assertContainsOrders(t, selectedOrders, pinnedOrders)
+}
+
+func TestPrioritySharingStrategySkipRoundRobin(t *testing.T) {
+	strategy, clock := newTestPrioritySharingStrategy(t)
+	defer strategy.db.Close()
+	strategy.skipRoundRobin = true
+	insertSharingTestOrders(t, strategy.db, 100, clock.now().Add(-time.Hour), false)
+	newOrders := insertSharingTestOrders(t, strategy.db, 2, clock.now(), false)
+
+	// Only the new orders should be shared.
+	selectedOrders, err := strategy.selectOrders(5)
+	require.NoError(t, err)
+	assert.Len(t, selectedOrders, len(newOrders))
+	assertContainsOrders(t, selectedOrders, newOrders)
+
+	// Once the new orders have been shared, nothing else should be shared.
+	clock.advance(time.Second)
+	selectedOrders, err = strategy.selectOrders(5)
+	require.NoError(t, err)
+	assert.Empty(t, selectedOrders)
+}
+
+func TestPrioritySharingStrategyDoesNotShareRemovedOrders(t *testing.T) {
+	strategy, clock := newTestPrioritySharingStrategy(t)
+	defer strategy.db.Close()
+	orders := insertSharingTestOrders(t, strategy.db, 3, clock.now(), true)
+	removedOrder := orders[0]
+	removedOrder.IsRemoved = true
+	require.NoError(t, strategy.db.Orders.Update(removedOrder))
+
+	for i := 0; i < 5; i++ {
+		selectedOrders, err := strategy.selectOrders(5)
+		require.NoError(t, err)
+		assertNotContainsOrders(t, selectedOrders, []*meshdb.Order{removedOrder})
+		clock.advance(priorityReshareInterval)
+	}
+}
+
+// TestPrioritySharingStrategyPropagationFairness simulates a node which
+// receives a steady stream of new orders while also storing a large number of
+// old orders and some pinned orders. It measures how long it takes for new
+// orders to be shared and makes sure that old orders are still shared evenly.
+func TestPrioritySharingStrategyPropagationFairness(t *testing.T) {
+	const (
+		numOldOrders    = 200
+		numPinnedOrders = 10
+		// A new order is added every newOrderInterval rounds.
+		newOrderInterval = 5
+		numRounds        = 400
+		// batchSize is much smaller than the batch size used by p2p.Node so
+		// that priority orders and round robin orders compete for slots.
+		batchSize = 5
+	)
+
+	strategy, clock := newTestPrioritySharingStrategy(t)
+	defer strategy.db.Close()
+	oldOrders := insertSharingTestOrders(t, strategy.db, numOldOrders, clock.now().Add(-time.Hour), false)
+	pinnedOrders := insertSharingTestOrders(t, strategy.db, numPinnedOrders, clock.now().Add(-time.Hour), true)
+
+	orderHashToShareCount := map[common.Hash]int{}
+	orderHashToFirstShareRound := map[common.Hash]int{}
+	newOrderHashToAddedRound := map[common.Hash]int{}
+	for round := 0; round < numRounds; round++ {
+		if round%newOrderInterval == 0 {
+			newOrders := insertSharingTestOrders(t, strategy.db, 1, clock.now(), false)
+			newOrderHashToAddedRound[newOrders[0].Hash] = round
+		}
+		selectedOrders, err := strategy.selectOrders(batchSize)
+		require.NoError(t, err)
+		for _, order := range selectedOrders {
+			orderHashToShareCount[order.Hash]++
+			if _, found := orderHashToFirstShareRound[order.Hash]; !found {
+				orderHashToFirstShareRound[order.Hash] = round
+			}
+		}
+		// Mesh shares a batch of orders roughly once per second.
+		clock.advance(time.Second)
+	}
+
+	// Every new order should be shared in the same round that it was added.
+	for orderHash, addedRound := range newOrderHashToAddedRound {
+		firstShareRound, found := orderHashToFirstShareRound[orderHash]
+		require.True(t, found, "new order was never shared: %s", orderHash.Hex())
+		assert.Equal(t, addedRound, firstShareRound, "new order was not shared immediately: %s", orderHash.Hex())
+	}
+
+	// Pinned orders should be shared again every priorityReshareInterval.
+	expectedPinnedShareCount := int((numRounds * time.Second) / priorityReshareInterval)
+	for _, order := range pinnedOrders {
+		assert.True(t, orderHashToShareCount[order.Hash] >= expectedPinnedShareCount, "pinned order was shared %d times but expected at least %d", orderHashToShareCount[order.Hash], expectedPinnedShareCount)
+	}
+
+	// Even though there is a steady stream of new orders, old orders should
+	// still be shared regularly. New orders shift the round robin offsets, so
+	// old orders aren't shared exactly evenly, but no old order should be shared
+	// more than twice as often as any other.
+	minShareCount, maxShareCount := numRounds, 0
+	for _, order := range oldOrders {
+		count := orderHashToShareCount[order.Hash]
+		if count < minShareCount {
+			minShareCount = count
+		}
+		if count > maxShareCount {
+			maxShareCount = count
+		}
+	}
+	assert.True(t, minShareCount >= 1, "some old orders were never shared")
+	assert.True(t, maxShareCount <= 2*minShareCount, "old orders were not shared evenly (min: %d, max: %d)", minShareCount, maxShareCount)
+}
+
+func TestPrioritySharingStrategyPrunesLastShared(t *testing.T) {
+	strategy, clock := newTestPrioritySharingStrategy(t)
+	defer strategy.db.Close()
+	orders := insertSharingTestOrders(t, strategy.db, 3, clock.now(), false)
+	_, err := strategy.selectOrders(5)
+	require.NoError(t, err)
+	assert.Len(t, strategy.orderHashToLastShared, len(orders))
+
+	for _, order := range orders {
+		require.NoError(t, strategy.db.Orders.Delete(order.ID()))
+	}
+	clock.advance(recentOrderWindow)
+	_, err = strategy.selectOrders(5)
+	require.NoError(t, err)
+	assert.Empty(t, strategy.orderHashToLastShared)
+}
+
+type fakeClock struct {
+	currentTime time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.currentTime
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.currentTime = c.currentTime.Add(d)
+}
+
+// newTestPrioritySharingStrategy returns a prioritySharingStrategy backed by a
+// new database and a fake clock. Callers are responsible for closing
+// strategy.db.
+func newTestPrioritySharingStrategy(t *testing.T) (*prioritySharingStrategy, *fakeClock) {
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/" + uuid.New().String())
+	require.NoError(t, err)
+	clock := &fakeClock{currentTime: time.Now().UTC()}
+	strategy := newPrioritySharingStrategy(meshDB)
+	strategy.now = clock.now
+	return strategy, clock
+}
+
+// insertSharingTestOrders inserts count new orders into the database. Unlike
+// signedTestOrders, the orders are not backed by any on-chain state so these
+// tests do not require Ganache.
+func insertSharingTestOrders(t *testing.T, meshDB *meshdb.MeshDB, count int, lastUpdated time.Time, isPinned bool) []*meshdb.Order {
+	signedOrders := newOffChainTestOrders(t, count)
+	orders := make([]*meshdb.Order, count)
+	for i, signedOrder := range signedOrders {
+		orderHash, err := signedOrder.ComputeOrderHash()
+		require.NoError(t, err)
+		orders[i] = &meshdb.Order{
+			Hash:                     orderHash,
+			SignedOrder:              signedOrder,
+			LastUpdated:              lastUpdated,
+			FillableTakerAssetAmount: signedOrder.TakerAssetAmount,
+			IsPinned:                 isPinned,
+		}
+		require.NoError(t, meshDB.Orders.Insert(orders[i]))
+	}
+	return orders
+}
+
+// assertContainsOrders asserts that every order in expected is contained in
+// actual.
+func assertContainsOrders(t *testing.T, actual []*meshdb.Order, expected []*meshdb.Order) {
+	actualHashes := map[common.Hash]struct{}{}
+	for _, order := range actual {
+		actualHashes[order.Hash] = struct{}{}
+	}
+	for _, order := range expected {
+		_, found := actualHashes[order.Hash]
+		assert.True(t, found, "expected order to be selected: %s", order.Hash.Hex())
+	}
+}
+
+// assertNotContainsOrders asserts that no order in unexpected is contained in
+// actual.
+func assertNotContainsOrders(t *testing.T, actual []*meshdb.Order, unexpected []*meshdb.Order) {
+	actualHashes := map[common.Hash]struct{}{}
+	for _, order := range actual {
+		actualHashes[order.Hash] = struct{}{}
+	}
+	for _, order := range unexpected {
+		_, found := actualHashes[order.Hash]
+		assert.False(t, found, "expected order not to be selected: %s", order.Hash.Hex())
+	}
+}
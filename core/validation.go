@@ -17,7 +17,10 @@ import (
 	"github.com/xeipuuv/gojsonschema"
 )
 
-var errMaxSize = fmt.Errorf("message exceeds maximum size of %d bytes", constants.MaxOrderSizeInBytes)
+var (
+	errMaxSize        = fmt.Errorf("message exceeds maximum size of %d bytes", constants.MaxOrderSizeInBytes)
+	errMaxMessageSize = fmt.Errorf("message exceeds maximum size of %d bytes", constants.MaxMessageSizeInBytes)
+)
 
 // JSON-schema schemas
 var (
@@ -97,8 +100,10 @@ func (app *App) schemaValidateMeshMessage(o []byte) (*gojsonschema.Result, error
 }
 
 // validateOrders applies general 0x validation and Mesh-specific validation to
-// the given orders.
-func (app *App) validateOrders(orders []*zeroex.SignedOrder) (*ordervalidator.ValidationResults, error) {
+// the given orders. On-chain validation is performed at the given block number.
+// Orders which are already stored are accepted or rejected based on their stored
+// state when validating at the latest block and re-validated otherwise.
+func (app *App) validateOrders(orders []*zeroex.SignedOrder, blockNumber rpc.BlockNumber) (*ordervalidator.ValidationResults, error) {
 	results := &ordervalidator.ValidationResults{}
 	validMeshOrders := []*zeroex.SignedOrder{}
 	contractAddresses, err := ethereum.GetContractAddressesForChainID(app.chainID)
@@ -171,6 +176,13 @@ func (app *App) validateOrders(orders []*zeroex.SignedOrder) (*ordervalidator.Va
 			}
 		}
 
+		// Stored orders are only kept up-to-date with the latest block, so
+		// validation at any other block can't rely on their stored state.
+		if blockNumber != rpc.LatestBlockNumber {
+			validMeshOrders = append(validMeshOrders, order)
+			continue
+		}
+
 		// Check if order is already stored in DB
 		var dbOrder meshdb.Order
 		err = app.db.Orders.FindByID(orderHash.Bytes(), &dbOrder)
@@ -207,15 +219,24 @@ func (app *App) validateOrders(orders []*zeroex.SignedOrder) (*ordervalidator.Va
 	// This timeout of 1min is for limiting how long this call should block at the ETH RPC rate limiter
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer cancel()
-	zeroexResults := app.orderValidator.BatchValidate(ctx, validMeshOrders, areNewOrders, rpc.LatestBlockNumber)
+	zeroexResults := app.orderValidator.BatchValidate(ctx, validMeshOrders, areNewOrders, blockNumber)
 	zeroexResults.Accepted = append(zeroexResults.Accepted, results.Accepted...)
 	zeroexResults.Rejected = append(zeroexResults.Rejected, results.Rejected...)
 	return zeroexResults, nil
 }
 
+// validateMessageSize checks the size of a message received from a peer.
+// Messages in the legacy format contain exactly one order, so they are subject
+// to the same limit as orders.
 func validateMessageSize(message *p2p.Message) error {
-	if len(message.Data) > constants.MaxOrderSizeInBytes {
-		return errMaxSize
+	if isLegacyMessage(message.Data) {
+		if len(message.Data) > constants.MaxOrderSizeInBytes {
+			return errMaxSize
+		}
+		return nil
+	}
+	if len(message.Data) > constants.MaxMessageSizeInBytes {
+		return errMaxMessageSize
 	}
 	return nil
 }
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"errors"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// backupBatchSize is the maximum number of keys written to the backup database
+// in a single batch.
+const backupBatchSize = 10000
+
+// Backup writes a copy of the entire database to a new database at the given
+// path. The copy is taken from a consistent snapshot, so it is safe to call
+// Backup while other goroutines are reading from or writing to the database.
+// The backup can be restored by simply opening it with Open (e.g. after moving
+// it to the original database path). Backup returns an error if a file or
+// directory already exists at the given path. Errors caused by the path itself
+// are of type InvalidBackupPathError. It returns the number of keys that were
+// copied.
+func (db *DB) Backup(path string) (int, error) {
+	if path == "" {
+		return 0, InvalidBackupPathError{Path: path, Err: errors.New("path must not be empty")}
+	}
+	if _, err := os.Stat(path); err == nil {
+		return 0, InvalidBackupPathError{Path: path, Err: errors.New("file or directory already exists")}
+	} else if !os.IsNotExist(err) {
+		return 0, InvalidBackupPathError{Path: path, Err: err}
+	}
+
+	snapshot, err := db.ldb.GetSnapshot()
+	if err != nil {
+		return 0, err
+	}
+	defer snapshot.Release()
+
+	backupDB, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return 0, InvalidBackupPathError{Path: path, Err: err}
+	}
+	defer backupDB.Close()
+
+	iter := snapshot.NewIterator(nil, nil)
+	defer iter.Release()
+	batch := new(leveldb.Batch)
+	numKeys := 0
+	for iter.Next() {
+		// Note: We need to make copies of the key and value because the underlying
+		// arrays may be modified by subsequent calls to iter.Next.
+		key := make([]byte, len(iter.Key()))
+		copy(key, iter.Key())
+		value := make([]byte, len(iter.Value()))
+		copy(value, iter.Value())
+		batch.Put(key, value)
+		numKeys++
+		if batch.Len() >= backupBatchSize {
+			if err := backupDB.Write(batch, nil); err != nil {
+				return numKeys, err
+			}
+			batch.Reset()
+			log.WithFields(log.Fields{
+				"path":    path,
+				"numKeys": numKeys,
+			}).Info("database backup in progress")
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return numKeys, err
+	}
+	if err := backupDB.Write(batch, nil); err != nil {
+		return numKeys, err
+	}
+	return numKeys, nil
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackup(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+	ageIndex := col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+
+	expected := []*testModel{}
+	for i := 0; i < 5; i++ {
+		model := &testModel{
+			Name: "Person_" + strconv.Itoa(i),
+			Age:  42,
+		}
+		require.NoError(t, col.Insert(model))
+		expected = append(expected, model)
+	}
+
+	backupPath := "/tmp/leveldb_testing/" + uuid.New().String()
+	numKeys, err := db.Backup(backupPath)
+	require.NoError(t, err)
+	// Each model has a primary key and an index key. There is also one key for
+	// the count.
+	assert.Equal(t, len(expected)*2+1, numKeys)
+
+	// Changes made after the backup should not affect the backup.
+	require.NoError(t, col.Delete(expected[0].ID()))
+
+	// Backing up to the same path a second time should fail.
+	_, err = db.Backup(backupPath)
+	assert.IsType(t, InvalidBackupPathError{}, err)
+	_, err = db.Backup("")
+	assert.IsType(t, InvalidBackupPathError{}, err)
+
+	restoredDB, err := Open(backupPath)
+	require.NoError(t, err)
+	defer restoredDB.Close()
+	restoredCol, err := restoredDB.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+	restoredAgeIndex := restoredCol.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+	var actual []*testModel
+	require.NoError(t, restoredCol.FindAll(&actual))
+	assert.Equal(t, expected, actual)
+	count, err := restoredCol.Count()
+	require.NoError(t, err)
+	assert.Equal(t, len(expected), count)
+	var actualByAge []*testModel
+	filter := restoredAgeIndex.ValueFilter([]byte("42"))
+	require.NoError(t, restoredCol.NewQuery(filter).Run(&actualByAge))
+	assert.Equal(t, expected, actualByAge)
+	require.NoError(t, restoredDB.CheckIntegrity())
+
+	// The original database should be unaffected.
+	var remaining []*testModel
+	require.NoError(t, col.NewQuery(ageIndex.ValueFilter([]byte("42"))).Run(&remaining))
+	assert.Equal(t, expected[1:], remaining)
+}
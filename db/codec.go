@@ -0,0 +1,41 @@
+package db
+
+import (
+	"encoding/json"
+)
+
+// Codec is used to encode models before they are stored in the database and
+// to decode them when they are retrieved. Each collection has exactly one
+// codec. By default, collections use JSONCodec.
+type Codec interface {
+	// Encode encodes the given model into bytes.
+	Encode(model Model) ([]byte, error)
+	// Decode decodes the given data into model. As in the Unmarshal and Decode
+	// methods in the encoding/json package, model will always be a pointer to
+	// the model type for the collection.
+	Decode(data []byte, model interface{}) error
+}
+
+// JSONCodec is a Codec which uses the encoding/json package. It is the default
+// codec for all collections.
+type JSONCodec struct{}
+
+var _ Codec = JSONCodec{}
+
+// Encode implements Codec.
+func (JSONCodec) Encode(model Model) ([]byte, error) {
+	return json.Marshal(model)
+}
+
+// Decode implements Codec.
+func (JSONCodec) Decode(data []byte, model interface{}) error {
+	return json.Unmarshal(data, model)
+}
+
+// IsJSON returns true if data looks like it was encoded by JSONCodec. Since
+// models are always encoded as JSON objects, JSON data always starts with "{".
+// Codecs which replace JSONCodec for an existing collection can use IsJSON to
+// detect and decode legacy data.
+func IsJSON(data []byte) bool {
+	return len(data) > 0 && data[0] == '{'
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkFindAllJSONCodec1000(b *testing.B) {
+	benchmarkFindAllWithCodec(b, JSONCodec{}, 1000)
+}
+
+func BenchmarkFindAllBinaryCodec1000(b *testing.B) {
+	benchmarkFindAllWithCodec(b, testBinaryCodec{}, 1000)
+}
+
+func BenchmarkTransactionInsertJSONCodec1000(b *testing.B) {
+	benchmarkTransactionInsertWithCodec(b, JSONCodec{}, 1000)
+}
+
+func BenchmarkTransactionInsertBinaryCodec1000(b *testing.B) {
+	benchmarkTransactionInsertWithCodec(b, testBinaryCodec{}, 1000)
+}
+
+func benchmarkFindAllWithCodec(b *testing.B, codec Codec, count int) {
+	b.Helper()
+	db := newTestDB(b)
+	defer db.Close()
+	col, err := db.NewCollectionWithCodec("people", &testModel{}, codec)
+	require.NoError(b, err)
+	for i := 0; i < count; i++ {
+		model := &testModel{
+			Name:      fmt.Sprintf("person_%d", i),
+			Age:       i,
+			Nicknames: []string{"foo", "bar"},
+		}
+		require.NoError(b, col.Insert(model))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var models []*testModel
+		err := col.FindAll(&models)
+		b.StopTimer()
+		require.NoError(b, err)
+		b.StartTimer()
+	}
+}
+
+func benchmarkTransactionInsertWithCodec(b *testing.B, codec Codec, count int) {
+	b.Helper()
+	db := newTestDB(b)
+	defer db.Close()
+	col, err := db.NewCollectionWithCodec("people", &testModel{}, codec)
+	require.NoError(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		txn := col.OpenTransaction()
+		defer func() {
+			_ = txn.Discard()
+		}()
+		for j := 0; j < count; j++ {
+			model := &testModel{
+				Name:      fmt.Sprintf("person_%d_%d", i, j),
+				Age:       j,
+				Nicknames: []string{"foo", "bar"},
+			}
+			err := txn.Insert(model)
+			b.StopTimer()
+			require.NoError(b, err)
+			b.StartTimer()
+		}
+		err := txn.Commit()
+		b.StopTimer()
+		require.NoError(b, err)
+		b.StartTimer()
+	}
+}
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBinaryCodec is a simple binary Codec for testModel. It is able to decode
+// data written by JSONCodec, which makes it possible to test migrations.
+type testBinaryCodec struct{}
+
+var _ Codec = testBinaryCodec{}
+
+const testBinaryCodecVersion = 1
+
+func (testBinaryCodec) Encode(model Model) ([]byte, error) {
+	tm, ok := model.(*testModel)
+	if !ok {
+		return nil, fmt.Errorf("testBinaryCodec: unexpected model type %T", model)
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteByte(testBinaryCodecVersion)
+	writeTestString(buf, tm.Name)
+	varintBuf := make([]byte, binary.MaxVarintLen64)
+	buf.Write(varintBuf[:binary.PutVarint(varintBuf, int64(tm.Age))])
+	buf.Write(varintBuf[:binary.PutUvarint(varintBuf, uint64(len(tm.Nicknames)))])
+	for _, nickname := range tm.Nicknames {
+		writeTestString(buf, nickname)
+	}
+	return buf.Bytes(), nil
+}
+
+func (testBinaryCodec) Decode(data []byte, model interface{}) error {
+	if IsJSON(data) {
+		return JSONCodec{}.Decode(data, model)
+	}
+	var tm *testModel
+	switch m := model.(type) {
+	case *testModel:
+		tm = m
+	case **testModel:
+		tm = &testModel{}
+		*m = tm
+	default:
+		return fmt.Errorf("testBinaryCodec: unexpected model type %T", model)
+	}
+	r := bytes.NewReader(data)
+	if version, err := r.ReadByte(); err != nil {
+		return err
+	} else if version != testBinaryCodecVersion {
+		return errors.New("testBinaryCodec: unsupported version")
+	}
+	var err error
+	if tm.Name, err = readTestString(r); err != nil {
+		return err
+	}
+	age, err := binary.ReadVarint(r)
+	if err != nil {
+		return err
+	}
+	tm.Age = int(age)
+	numNicknames, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	tm.Nicknames = nil
+	for i := uint64(0); i < numNicknames; i++ {
+		nickname, err := readTestString(r)
+		if err != nil {
+			return err
+		}
+		tm.Nicknames = append(tm.Nicknames, nickname)
+	}
+	return nil
+}
+
+func writeTestString(buf *bytes.Buffer, s string) {
+	varintBuf := make([]byte, binary.MaxVarintLen64)
+	buf.Write(varintBuf[:binary.PutUvarint(varintBuf, uint64(len(s)))])
+	buf.WriteString(s)
+}
+
+func readTestString(r *bytes.Reader) (string, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if length > uint64(r.Len()) {
+		return "", errors.New("testBinaryCodec: unexpected end of data")
+	}
+	s := make([]byte, length)
+	if _, err := r.Read(s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+func TestNewCollectionWithCodec(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	defer db.Close()
+	col, err := db.NewCollectionWithCodec("people", &testModel{}, testBinaryCodec{})
+	require.NoError(t, err)
+	ageIndex := col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+	expected := &testModel{
+		Name:      "foo",
+		Age:       42,
+		Nicknames: []string{"bar", "baz"},
+	}
+	require.NoError(t, col.Insert(expected))
+
+	// Check that the data was actually stored in the binary format.
+	data, err := db.ldb.Get([]byte("model:people:foo"), nil)
+	require.NoError(t, err)
+	assert.False(t, IsJSON(data), "Model was not encoded with the collection codec")
+
+	actual := &testModel{}
+	require.NoError(t, col.FindByID(expected.ID(), actual))
+	assert.Equal(t, expected, actual)
+
+	var allModels []*testModel
+	require.NoError(t, col.FindAll(&allModels))
+	assert.Equal(t, []*testModel{expected}, allModels)
+
+	var queryModels []*testModel
+	require.NoError(t, col.NewQuery(ageIndex.ValueFilter([]byte("42"))).Run(&queryModels))
+	assert.Equal(t, []*testModel{expected}, queryModels)
+
+	require.NoError(t, db.CheckIntegrity())
+}
+
+func TestReencode(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	defer db.Close()
+
+	// Insert some models using the default JSONCodec.
+	jsonCol, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+	expected := []*testModel{}
+	for i := 0; i < 10; i++ {
+		model := &testModel{
+			Name: fmt.Sprintf("person_%d", i),
+			Age:  i,
+		}
+		require.NoError(t, jsonCol.Insert(model))
+		expected = append(expected, model)
+	}
+
+	// Simulate changing the codec for the collection. Collection names must be
+	// unique for each DB, so we create a new collection with a new DB that
+	// shares the same underlying leveldb.DB.
+	binaryDB := &DB{ldb: db.ldb}
+	binaryCol, err := binaryDB.NewCollectionWithCodec("people", &testModel{}, testBinaryCodec{})
+	require.NoError(t, err)
+
+	// Legacy data should still be readable before the migration.
+	var actual []*testModel
+	require.NoError(t, binaryCol.FindAll(&actual))
+	assert.Equal(t, expected, actual)
+
+	numReencoded, err := binaryCol.Reencode(IsJSON)
+	require.NoError(t, err)
+	assert.Equal(t, len(expected), numReencoded)
+
+	// Running Reencode again should be a no-op since all data has been migrated.
+	numReencoded, err = binaryCol.Reencode(IsJSON)
+	require.NoError(t, err)
+	assert.Equal(t, 0, numReencoded)
+
+	for _, model := range expected {
+		data, err := db.ldb.Get(binaryCol.info.primaryKeyForModel(model), nil)
+		require.NoError(t, err)
+		assert.False(t, IsJSON(data), "Model was not re-encoded")
+	}
+	actual = []*testModel{}
+	require.NoError(t, binaryCol.FindAll(&actual))
+	assert.Equal(t, expected, actual)
+	count, err := binaryCol.Count()
+	require.NoError(t, err)
+	assert.Equal(t, len(expected), count)
+}
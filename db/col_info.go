@@ -11,6 +11,7 @@ type colInfo struct {
 	db        *DB
 	name      string
 	modelType reflect.Type
+	codec     Codec
 	indexes   []*Index
 	// indexMut protects the indexes slice.
 	indexMut sync.RWMutex
@@ -32,6 +33,7 @@ func (info *colInfo) copy() *colInfo {
 		db:        info.db,
 		name:      info.name,
 		modelType: info.modelType,
+		codec:     info.codec,
 		indexes:   indexes,
 		writeMut:  info.writeMut,
 	}
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // Collection represents a set of a specific type of model.
@@ -18,13 +19,24 @@ type Collection struct {
 // model type. You should create exactly one collection for each model type. The
 // collection should typically be created once at the start of your application
 // and re-used. NewCollection returns an error if a collection has already been
-// created with the given name for this db.
+// created with the given name for this db. Models in the collection are encoded
+// with JSONCodec.
 func (db *DB) NewCollection(name string, typ Model) (*Collection, error) {
+	return db.NewCollectionWithCodec(name, typ, JSONCodec{})
+}
+
+// NewCollectionWithCodec is like NewCollection but uses the given codec to
+// encode and decode models instead of JSONCodec. Changing the codec for an
+// existing collection requires that the new codec is able to decode any data
+// written by the old codec. Reencode can then be used to migrate existing data
+// to the new format.
+func (db *DB) NewCollectionWithCodec(name string, typ Model, codec Codec) (*Collection, error) {
 	col := &Collection{
 		info: &colInfo{
 			db:        db,
 			name:      name,
 			modelType: reflect.TypeOf(typ),
+			codec:     codec,
 			writeMut:  &sync.Mutex{},
 		},
 		ldb: db.ldb,
@@ -65,6 +77,49 @@ func (c *Collection) Count() (int, error) {
 	return count(c.info, c.ldb)
 }
 
+// Reencode decodes and then re-encodes models in the collection using the
+// codec for the collection. If shouldReencode is not nil, only models for which
+// shouldReencode returns true for the raw stored data will be re-encoded.
+// Reencode is typically used to migrate existing data after changing the codec
+// for a collection. It returns the number of models that were re-encoded.
+// Indexes are not affected.
+func (c *Collection) Reencode(shouldReencode func(data []byte) bool) (int, error) {
+	txn := c.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+	numReencoded, err := reencodeWithTransaction(txn.colInfo, txn.readWriter, shouldReencode)
+	if err != nil {
+		return 0, err
+	}
+	if err := txn.Commit(); err != nil {
+		return 0, err
+	}
+	return numReencoded, nil
+}
+
+// Compact compacts the underlying storage for all models and indexes in the
+// collection. Compaction discards deleted and overwritten data (i.e.
+// "tombstones") and can significantly reduce the size of the database after a
+// large number of models have been deleted. It is safe to call Compact while
+// other goroutines are reading from or writing to the database.
+func (c *Collection) Compact() error {
+	ranges := []*util.Range{
+		util.BytesPrefix([]byte(fmt.Sprintf("%s:", c.info.prefix()))),
+	}
+	c.info.indexMut.RLock()
+	for _, index := range c.info.indexes {
+		ranges = append(ranges, util.BytesPrefix([]byte(fmt.Sprintf("%s:", index.prefix()))))
+	}
+	c.info.indexMut.RUnlock()
+	for _, r := range ranges {
+		if err := c.ldb.CompactRange(*r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Insert inserts the given model into the database. It returns an error if a
 // model with the same id already exists.
 func (c *Collection) Insert(model Model) error {
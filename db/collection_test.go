@@ -138,6 +138,43 @@ func TestCount(t *testing.T) {
 	}
 }
 
+func TestCompact(t *testing.T) {
+	t.Parallel()
+	db := newTestDB(t)
+	defer db.Close()
+	col, err := db.NewCollection("people", &testModel{})
+	require.NoError(t, err)
+	ageIndex := col.AddIndex("age", func(m Model) []byte {
+		return []byte(fmt.Sprint(m.(*testModel).Age))
+	})
+
+	// Insert some test models and then delete half of them.
+	expected := []*testModel{}
+	for i := 0; i < 10; i++ {
+		model := &testModel{
+			Name: "Person_" + strconv.Itoa(i),
+			Age:  42,
+		}
+		require.NoError(t, col.Insert(model))
+		if i%2 == 0 {
+			require.NoError(t, col.Delete(model.ID()))
+		} else {
+			expected = append(expected, model)
+		}
+	}
+
+	require.NoError(t, col.Compact())
+
+	// Compaction should not affect the models which were not deleted.
+	var actual []*testModel
+	require.NoError(t, col.FindAll(&actual))
+	assert.Equal(t, expected, actual)
+	var actualByAge []*testModel
+	require.NoError(t, col.NewQuery(ageIndex.ValueFilter([]byte("42"))).Run(&actualByAge))
+	assert.Equal(t, expected, actualByAge)
+	require.NoError(t, db.CheckIntegrity())
+}
+
 func TestDelete(t *testing.T) {
 	t.Parallel()
 	db := newTestDB(t)
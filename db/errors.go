@@ -24,3 +24,15 @@ type AlreadyExistsError struct {
 func (e AlreadyExistsError) Error() string {
 	return fmt.Sprintf("model already exists with the given ID: %s", hex.EncodeToString(e.ID))
 }
+
+// InvalidBackupPathError is returned whenever the database can't be backed up
+// to a specific path, e.g. because a file or directory already exists there or
+// it is not writable.
+type InvalidBackupPathError struct {
+	Path string
+	Err  error
+}
+
+func (e InvalidBackupPathError) Error() string {
+	return fmt.Sprintf("cannot backup database to %q: %s", e.Path, e.Err.Error())
+}
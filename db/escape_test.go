@@ -60,5 +60,8 @@ func TestFindWithValueWithEscape(t *testing.T) {
 		require.NoError(t, query.Run(&actual), "testModel %d", i)
 		require.Len(t, actual, 1, "testModel %d", i)
 		assert.Equal(t, expected, actual[0])
+		ids, err := query.IDs()
+		require.NoError(t, err, "testModel %d", i)
+		assert.Equal(t, [][]byte{expected.ID()}, ids, "testModel %d", i)
 	}
 }
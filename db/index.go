@@ -74,3 +74,11 @@ func (index *Index) primaryKeyFromIndexKey(key []byte) []byte {
 	split := strings.Split(pkAndVal, ":")
 	return index.colInfo.primaryKeyForIDWithoutEscape([]byte(split[2]))
 }
+
+// idFromIndexKey extracts and returns the (unescaped) model ID from the given
+// index key.
+func (index *Index) idFromIndexKey(key []byte) ([]byte, error) {
+	pkAndVal := strings.TrimPrefix(string(key), string(index.prefix()))
+	split := strings.Split(pkAndVal, ":")
+	return unescape([]byte(split[2]))
+}
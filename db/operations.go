@@ -1,7 +1,6 @@
 package db
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -24,7 +23,7 @@ func findByID(info *colInfo, reader dbReader, id []byte, model Model) error {
 		}
 		return err
 	}
-	return json.Unmarshal(data, model)
+	return info.codec.Decode(data, model)
 }
 
 func findAll(info *colInfo, reader dbReader, models interface{}) error {
@@ -44,7 +43,7 @@ func findWithIterator(info *colInfo, iter iterator.Iterator, models interface{})
 		// model.
 		data := iter.Value()
 		model := reflect.New(info.modelType)
-		if err := json.Unmarshal(data, model.Interface()); err != nil {
+		if err := info.codec.Decode(data, model.Interface()); err != nil {
 			return err
 		}
 		modelsVal.Set(reflect.Append(modelsVal, model.Elem()))
@@ -63,7 +62,7 @@ func findExistingModelByPrimaryKeyWithTransaction(info *colInfo, readWriter dbRe
 	}
 	// Use reflect to create a new reference for the model type.
 	modelRef := reflect.New(info.modelType).Interface()
-	if err := json.Unmarshal(data, modelRef); err != nil {
+	if err := info.codec.Decode(data, modelRef); err != nil {
 		return nil, err
 	}
 	model := reflect.ValueOf(modelRef).Elem().Interface().(Model)
@@ -77,7 +76,7 @@ func insertWithTransaction(info *colInfo, readWriter dbReadWriter, model Model)
 	if err := info.checkModelType(model); err != nil {
 		return err
 	}
-	data, err := json.Marshal(model)
+	data, err := info.codec.Encode(model)
 	if err != nil {
 		return err
 	}
@@ -122,7 +121,7 @@ func updateWithTransaction(info *colInfo, readWriter dbReadWriter, model Model)
 	}
 
 	// Save the new data and add the new indexes.
-	newData, err := json.Marshal(model)
+	newData, err := info.codec.Encode(model)
 	if err != nil {
 		return err
 	}
@@ -164,6 +163,43 @@ func deleteWithTransaction(info *colInfo, readWriter dbReadWriter, id []byte) er
 	return nil
 }
 
+// reencodeWithTransaction re-encodes the stored data for all models in the
+// collection for which shouldReencode returns true (or all models if
+// shouldReencode is nil). It *doesn't* discard the transaction if there is an
+// error.
+func reencodeWithTransaction(info *colInfo, readWriter dbReadWriter, shouldReencode func(data []byte) bool) (int, error) {
+	prefixRange := util.BytesPrefix([]byte(fmt.Sprintf("%s:", info.prefix())))
+	iter := readWriter.NewIterator(prefixRange, nil)
+	defer iter.Release()
+	numReencoded := 0
+	for iter.Next() {
+		data := iter.Value()
+		if shouldReencode != nil && !shouldReencode(data) {
+			continue
+		}
+		modelRef := reflect.New(info.modelType).Interface()
+		if err := info.codec.Decode(data, modelRef); err != nil {
+			return 0, err
+		}
+		newData, err := info.codec.Encode(reflect.ValueOf(modelRef).Elem().Interface().(Model))
+		if err != nil {
+			return 0, err
+		}
+		// Note that iter.Key() may be overwritten by the next call to iter.Next,
+		// so we need to copy it.
+		pk := make([]byte, len(iter.Key()))
+		copy(pk, iter.Key())
+		if err := readWriter.Put(pk, newData, nil); err != nil {
+			return 0, err
+		}
+		numReencoded++
+	}
+	if err := iter.Error(); err != nil {
+		return 0, err
+	}
+	return numReencoded, nil
+}
+
 func saveIndexesWithTransaction(info *colInfo, readWriter dbReadWriter, model Model) error {
 	info.indexMut.RLock()
 	defer info.indexMut.RUnlock()
@@ -1,7 +1,6 @@
 package db
 
 import (
-	"encoding/json"
 	"fmt"
 	"reflect"
 
@@ -144,6 +143,46 @@ func (q *Query) Count() (int, error) {
 	return len(pkSet), nil
 }
 
+// IDs runs the query and returns the IDs of the models that match it. Unlike
+// Run, it only reads the index and does not need to read or decode the models
+// themselves, which makes it much faster when only the IDs are needed. It
+// respects q.Offset, q.Max, and q.Reverse.
+func (q *Query) IDs() ([][]byte, error) {
+	iter := q.reader.NewIterator(q.filter.slice, nil)
+	defer iter.Release()
+	next := iter.Next
+	if q.reverse {
+		// Move the iterator to the last key and then iterate backwards.
+		iter.Last()
+		iter.Next()
+		next = iter.Prev
+	}
+	idSet := stringset.New()
+	ids := [][]byte{}
+	for i := 0; next() && iter.Error() == nil; i++ {
+		if i < q.offset {
+			continue
+		}
+		id, err := q.filter.index.idFromIndexKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		// MultiIndexes can result in the same ID being included more than once.
+		if idSet.Contains(string(id)) {
+			continue
+		}
+		idSet.Add(string(id))
+		ids = append(ids, id)
+		if q.max != 0 && len(ids) >= q.max {
+			break
+		}
+	}
+	if iter.Error() != nil {
+		return nil, iter.Error()
+	}
+	return ids, nil
+}
+
 func (q *Query) getModelsWithIteratorForward(iter iterator.Iterator, models interface{}) error {
 	// MultiIndexes can result in the same model being included more than once. To
 	// prevent this, we keep track of the primaryKeys we have already seen using
@@ -206,7 +245,7 @@ func (q *Query) getAndAppendModelIfUnique(index *Index, pkSet stringset.Set, key
 		return err
 	}
 	model := reflect.New(q.colInfo.modelType)
-	if err := json.Unmarshal(data, model.Interface()); err != nil {
+	if err := q.colInfo.codec.Decode(data, model.Interface()); err != nil {
 		return err
 	}
 	modelsVal.Set(reflect.Append(modelsVal, model.Elem()))
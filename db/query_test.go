@@ -314,6 +314,13 @@ func testQueryWithFilter(t *testing.T, col *Collection, filter *Filter, expected
 		actualCount, err := tc.query.Count()
 		require.NoError(t, err, "test case %d", i)
 		assert.Equal(t, len(tc.expected), actualCount, "test case %d", i)
+		actualIDs, err := tc.query.IDs()
+		require.NoError(t, err, "test case %d", i)
+		expectedIDs := make([][]byte, len(tc.expected))
+		for j, model := range tc.expected {
+			expectedIDs[j] = model.ID()
+		}
+		assert.Equal(t, expectedIDs, actualIDs, "test case %d", i)
 	}
 }
 
@@ -0,0 +1,41 @@
+// +build !js
+
+package signer
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+// KeystoreSigner is a signer that produces signatures locally using a private key
+// which is loaded from an encrypted JSON keystore file, as created by geth and
+// other Ethereum clients. It supports the same methods as LocalSigner.
+type KeystoreSigner struct {
+	*LocalSigner
+}
+
+// NewKeystoreSigner instantiates a new KeystoreSigner by decrypting the given
+// keystore JSON with the supplied passphrase
+func NewKeystoreSigner(keyJSON []byte, passphrase string) (*KeystoreSigner, error) {
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt keystore file: %s", err.Error())
+	}
+	return &KeystoreSigner{
+		LocalSigner: &LocalSigner{
+			privateKey: key.PrivateKey,
+		},
+	}, nil
+}
+
+// NewKeystoreSignerFromFile instantiates a new KeystoreSigner by reading the keystore
+// file at the given path and decrypting it with the supplied passphrase
+func NewKeystoreSignerFromFile(path string, passphrase string) (*KeystoreSigner, error) {
+	keyJSON, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeystoreSigner(keyJSON, passphrase)
+}
@@ -0,0 +1,70 @@
+// +build !js
+
+package signer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKeystorePassphrase = "password"
+
+// newTestKeystoreJSON returns the encrypted keystore JSON for the private key of
+// the given Ganache account.
+func newTestKeystoreJSON(t *testing.T, address common.Address) []byte {
+	privateKey, err := crypto.ToECDSA(constants.GanacheAccountToPrivateKey[address])
+	require.NoError(t, err)
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{
+		Address:    address,
+		PrivateKey: privateKey,
+	}, testKeystorePassphrase, keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+	return keyJSON
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	// Test parameters lifted from @0x/order-utils' `signature_utils_test.ts`
+	signerAddress := constants.GanacheAccount0
+	message := common.Hex2Bytes("6927e990021d23b1eb7b8789f6a6feaf98fe104bb0cf8259421b79f9a34222b0")
+	expectedSignature := &ECSignature{
+		V: byte(27),
+		R: common.HexToHash("61a3ed31b43c8780e905a260a35faefcc527be7516aa11c0256729b5b351bc33"),
+		S: common.HexToHash("40349190569279751135161d22529dc25add4f6069af05be04cacbda2ace2254"),
+	}
+
+	keystoreSigner, err := NewKeystoreSigner(newTestKeystoreJSON(t, signerAddress), testKeystorePassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, signerAddress, keystoreSigner.GetSignerAddress())
+	actualSignature, err := keystoreSigner.EthSign(message, signerAddress)
+	require.NoError(t, err)
+	assert.Equal(t, expectedSignature, actualSignature)
+
+	_, err = keystoreSigner.EthSign(message, constants.GanacheAccount1)
+	assert.Error(t, err, "should not be able to sign for a different address")
+}
+
+func TestKeystoreSignerFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore-signer-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keyfile")
+	require.NoError(t, ioutil.WriteFile(path, newTestKeystoreJSON(t, constants.GanacheAccount0), 0600))
+
+	keystoreSigner, err := NewKeystoreSignerFromFile(path, testKeystorePassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, constants.GanacheAccount0, keystoreSigner.GetSignerAddress())
+
+	_, err = NewKeystoreSignerFromFile(path, "wrong password")
+	assert.Error(t, err)
+	_, err = NewKeystoreSignerFromFile(filepath.Join(dir, "nonexistent"), testKeystorePassphrase)
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// defaultRemoteSignerTimeout is the timeout for requests to the remote signing
+// service if no HTTP client is supplied.
+const defaultRemoteSignerTimeout = 10 * time.Second
+
+// RemoteSignerConfig contains the options for a RemoteSigner
+type RemoteSignerConfig struct {
+	// URL is the URL of the remote signing service. Signing requests are sent
+	// to it via HTTP POST.
+	URL string
+	// AuthToken is an optional bearer token which is sent in the Authorization
+	// header of each request.
+	AuthToken string
+	// HTTPClient is the HTTP client used to send requests. Defaults to a client
+	// with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+// RemoteSignRequest is the JSON body of a request sent to a remote signing
+// service. Hash is the 32 byte hash which should be signed as-is, i.e. without
+// adding any message prefix.
+type RemoteSignRequest struct {
+	SignerAddress common.Address `json:"signerAddress"`
+	Hash          hexutil.Bytes  `json:"hash"`
+}
+
+// RemoteSignResponse is the JSON body of a successful response from a remote
+// signing service. Signature must be in the [R || S || V] format where V is 0,
+// 1, 27 or 28.
+type RemoteSignResponse struct {
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// RemoteSigner is a signer that asks a remote signing service to sign hashes over
+// HTTP. `eth_sign`-compatible signatures are produced by adding the message prefix
+// locally, so the remote signing service only ever signs 32 byte hashes.
+type RemoteSigner struct {
+	url        string
+	authToken  string
+	httpClient *http.Client
+}
+
+// NewRemoteSigner instantiates a new RemoteSigner
+func NewRemoteSigner(config RemoteSignerConfig) (*RemoteSigner, error) {
+	if config.URL == "" {
+		return nil, errors.New("URL is required")
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout: defaultRemoteSignerTimeout,
+		}
+	}
+	return &RemoteSigner{
+		url:        config.URL,
+		authToken:  config.AuthToken,
+		httpClient: httpClient,
+	}, nil
+}
+
+// EthSign produces an `eth_sign`-compatible signature by asking the remote signing
+// service to sign the prefixed hash of the message
+func (r *RemoteSigner) EthSign(message []byte, signerAddress common.Address) (*ECSignature, error) {
+	messageWithPrefix, _ := textAndHash(message)
+	return r.sign(messageWithPrefix, signerAddress)
+}
+
+// EIP712Sign asks the remote signing service to sign the EIP-712 hash
+func (r *RemoteSigner) EIP712Sign(hash []byte, signerAddress common.Address) (*ECSignature, error) {
+	return r.sign(hash, signerAddress)
+}
+
+// sign sends the hash to the remote signing service and checks that the returned
+// signature was produced by signerAddress
+func (r *RemoteSigner) sign(hash []byte, signerAddress common.Address) (*ECSignature, error) {
+	if len(hash) != common.HashLength {
+		return nil, fmt.Errorf("invalid hash length: expected %d bytes but got %d", common.HashLength, len(hash))
+	}
+	body, err := json.Marshal(RemoteSignRequest{
+		SignerAddress: signerAddress,
+		Hash:          hash,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.authToken)
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote signer returned status %d: %s", resp.StatusCode, string(bytes.TrimSpace(respBody)))
+	}
+	var signResponse RemoteSignResponse
+	if err := json.Unmarshal(respBody, &signResponse); err != nil {
+		return nil, fmt.Errorf("could not parse remote signer response: %s", err.Error())
+	}
+	ecSignature, err := parseSignature(signResponse.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't trust the remote signing service blindly. A signature by the wrong
+	// key would only be noticed once the order is rejected.
+	actualSignerAddress, err := recoverSignerAddress(hash, ecSignature)
+	if err != nil {
+		return nil, err
+	}
+	if actualSignerAddress != signerAddress {
+		return nil, fmt.Errorf("remote signer signed with %s instead of %s", actualSignerAddress.Hex(), signerAddress.Hex())
+	}
+	return ecSignature, nil
+}
+
+// recoverSignerAddress returns the address of the private key which produced the
+// signature of the hash
+func recoverSignerAddress(hash []byte, ecSignature *ECSignature) (common.Address, error) {
+	signatureBytes := make([]byte, 65)
+	copy(signatureBytes[0:32], ecSignature.R[:])
+	copy(signatureBytes[32:64], ecSignature.S[:])
+	signatureBytes[64] = ecSignature.V - 27
+	publicKey, err := crypto.SigToPub(hash, signatureBytes)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
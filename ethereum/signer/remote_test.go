@@ -0,0 +1,100 @@
+// +build !js
+
+package signer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeRemoteSigningService returns a test server which acts like a remote
+// signing service. It signs hashes with the private key of the given Ganache
+// account, regardless of the requested signer address.
+func newFakeRemoteSigningService(t *testing.T, account common.Address, authToken string) *httptest.Server {
+	privateKey, err := crypto.ToECDSA(constants.GanacheAccountToPrivateKey[account])
+	require.NoError(t, err)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if authToken != "" && r.Header.Get("Authorization") != "Bearer "+authToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var signRequest RemoteSignRequest
+		if err := json.NewDecoder(r.Body).Decode(&signRequest); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		signature, err := crypto.Sign(signRequest.Hash, privateKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(RemoteSignResponse{Signature: signature})
+	}))
+}
+
+func TestRemoteSigner(t *testing.T) {
+	// Test parameters lifted from @0x/order-utils' `signature_utils_test.ts`
+	signerAddress := constants.GanacheAccount0
+	message := common.Hex2Bytes("6927e990021d23b1eb7b8789f6a6feaf98fe104bb0cf8259421b79f9a34222b0")
+	expectedSignature := &ECSignature{
+		V: byte(27),
+		R: common.HexToHash("61a3ed31b43c8780e905a260a35faefcc527be7516aa11c0256729b5b351bc33"),
+		S: common.HexToHash("40349190569279751135161d22529dc25add4f6069af05be04cacbda2ace2254"),
+	}
+
+	server := newFakeRemoteSigningService(t, signerAddress, "secret")
+	defer server.Close()
+	remoteSigner, err := NewRemoteSigner(RemoteSignerConfig{
+		URL:       server.URL,
+		AuthToken: "secret",
+	})
+	require.NoError(t, err)
+
+	actualSignature, err := remoteSigner.EthSign(message, signerAddress)
+	require.NoError(t, err)
+	assert.Equal(t, expectedSignature, actualSignature)
+
+	expectedEIP712Signature, err := NewTestSigner().(*TestSigner).EIP712Sign(message, signerAddress)
+	require.NoError(t, err)
+	actualEIP712Signature, err := remoteSigner.EIP712Sign(message, signerAddress)
+	require.NoError(t, err)
+	assert.Equal(t, expectedEIP712Signature, actualEIP712Signature)
+
+	_, err = remoteSigner.EIP712Sign([]byte{1, 2, 3}, signerAddress)
+	assert.Error(t, err, "should not send hashes with an invalid length")
+}
+
+func TestRemoteSignerErrors(t *testing.T) {
+	message := common.Hex2Bytes("6927e990021d23b1eb7b8789f6a6feaf98fe104bb0cf8259421b79f9a34222b0")
+	server := newFakeRemoteSigningService(t, constants.GanacheAccount0, "secret")
+	defer server.Close()
+
+	// Missing auth token.
+	remoteSigner, err := NewRemoteSigner(RemoteSignerConfig{URL: server.URL})
+	require.NoError(t, err)
+	_, err = remoteSigner.EthSign(message, constants.GanacheAccount0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+
+	// The remote signing service signs with a different key than requested.
+	remoteSigner, err = NewRemoteSigner(RemoteSignerConfig{URL: server.URL, AuthToken: "secret"})
+	require.NoError(t, err)
+	_, err = remoteSigner.EthSign(message, constants.GanacheAccount1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "instead of")
+
+	_, err = NewRemoteSigner(RemoteSignerConfig{})
+	assert.Error(t, err)
+}
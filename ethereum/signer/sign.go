@@ -17,6 +17,13 @@ type Signer interface {
 	EthSign(message []byte, signerAddress common.Address) (*ECSignature, error)
 }
 
+// EIP712Signer is a Signer which can also sign EIP-712 hashes directly, i.e.
+// without adding the `eth_sign` message prefix.
+type EIP712Signer interface {
+	Signer
+	EIP712Sign(hash []byte, signerAddress common.Address) (*ECSignature, error)
+}
+
 // ECSignature contains the parameters of an elliptic curve signature
 type ECSignature struct {
 	V byte
@@ -44,20 +51,7 @@ func (e *EthRPCSigner) EthSign(message []byte, signerAddress common.Address) (*E
 		return nil, err
 	}
 	// `eth_sign` returns the signature in the [R || S || V] format where V is 0 or 1.
-	signatureBytes := common.Hex2Bytes(signatureHex[2:])
-	vParam := signatureBytes[64]
-	if vParam == byte(0) {
-		vParam = byte(27)
-	} else if vParam == byte(1) {
-		vParam = byte(28)
-	}
-
-	ecSignature := &ECSignature{
-		V: vParam,
-		R: common.BytesToHash(signatureBytes[0:32]),
-		S: common.BytesToHash(signatureBytes[32:64]),
-	}
-	return ecSignature, nil
+	return parseSignature(common.FromHex(signatureHex))
 }
 
 // LocalSigner is a signer that produces an `eth_sign`-compatible signature locally using
@@ -114,21 +108,13 @@ func (l *LocalSigner) sign(message []byte, signerAddress common.Address) (*ECSig
 	if err != nil {
 		return nil, err
 	}
+	return parseSignature(signatureBytes)
+}
 
-	vParam := signatureBytes[64]
-	if vParam == byte(0) {
-		vParam = byte(27)
-	} else if vParam == byte(1) {
-		vParam = byte(28)
-	}
-
-	ecSignature := &ECSignature{
-		V: vParam,
-		R: common.BytesToHash(signatureBytes[0:32]),
-		S: common.BytesToHash(signatureBytes[32:64]),
-	}
-
-	return ecSignature, nil
+// EIP712Sign signs the EIP-712 hash with its supplied private key without adding the
+// `eth_sign` message prefix
+func (l *LocalSigner) EIP712Sign(hash []byte, signerAddress common.Address) (*ECSignature, error) {
+	return l.sign(hash, signerAddress)
 }
 
 // TestSigner generates `eth_sign` signatures for test accounts available on the test
@@ -156,6 +142,22 @@ func (t *TestSigner) EthSign(message []byte, signerAddress common.Address) (*ECS
 	return localSigner.EthSign(message, signerAddress)
 }
 
+// EIP712Sign signs the EIP-712 hash using an public/private key pair hard-coded in the
+// constants package.
+func (t *TestSigner) EIP712Sign(hash []byte, signerAddress common.Address) (*ECSignature, error) {
+	pkBytes, ok := constants.GanacheAccountToPrivateKey[signerAddress]
+	if !ok {
+		return nil, errors.New("Unrecognized Ganache account supplied to ECSignForTests")
+	}
+	privateKey, err := crypto.ToECDSA(pkBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	localSigner := NewLocalSigner(privateKey)
+	return localSigner.(*LocalSigner).EIP712Sign(hash, signerAddress)
+}
+
 // SignTx signs an Ethereum transaction with a public/private key pair hard-coded in the constants package.
 // It returns the transaction signature.
 func (t *TestSigner) SignTx(message []byte, signerAddress common.Address) ([]byte, error) {
@@ -177,6 +179,27 @@ func (t *TestSigner) SignTx(message []byte, signerAddress common.Address) ([]byt
 	return signature, nil
 }
 
+// parseSignature parses a signature in the [R || S || V] format where V is 0, 1, 27 or
+// 28 into an ECSignature. V is always 27 or 28 in the result.
+func parseSignature(signatureBytes []byte) (*ECSignature, error) {
+	if len(signatureBytes) != 65 {
+		return nil, fmt.Errorf("invalid signature length: expected 65 bytes but got %d", len(signatureBytes))
+	}
+	vParam := signatureBytes[64]
+	if vParam == byte(0) {
+		vParam = byte(27)
+	} else if vParam == byte(1) {
+		vParam = byte(28)
+	}
+
+	ecSignature := &ECSignature{
+		V: vParam,
+		R: common.BytesToHash(signatureBytes[0:32]),
+		S: common.BytesToHash(signatureBytes[32:64]),
+	}
+	return ecSignature, nil
+}
+
 // textAndHash is a helper function that calculates a hash for the given message that can be
 // safely used to calculate a signature from.
 //
@@ -47,3 +47,22 @@ func TestTestSigner(t *testing.T) {
 
 	assert.Equal(t, expectedSignature, actualSignature)
 }
+
+func TestTestSignerEIP712Sign(t *testing.T) {
+	signerAddress := constants.GanacheAccount0
+	hash := common.Hex2Bytes("6927e990021d23b1eb7b8789f6a6feaf98fe104bb0cf8259421b79f9a34222b0")
+
+	testSigner := NewTestSigner().(*TestSigner)
+	ecSignature, err := testSigner.EIP712Sign(hash, signerAddress)
+	require.NoError(t, err)
+
+	// The hash is signed as-is, so the signer address can be recovered from the
+	// hash itself.
+	actualSignerAddress, err := recoverSignerAddress(hash, ecSignature)
+	require.NoError(t, err)
+	assert.Equal(t, signerAddress, actualSignerAddress)
+
+	ethSignSignature, err := testSigner.EthSign(hash, signerAddress)
+	require.NoError(t, err)
+	assert.NotEqual(t, ethSignSignature, ecSignature)
+}
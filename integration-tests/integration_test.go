@@ -136,6 +136,14 @@ func TestBrowserIntegration(t *testing.T) {
 		require.NoError(t, err, "RPC server didn't start")
 		rpcClient, err := rpc.NewClient(standaloneRPCEndpoint)
 		require.NoError(t, err)
+		// Validating the order first must not store it or share it with peers.
+		validationResults, err := rpcClient.ValidateOrders([]*zeroex.SignedOrder{standaloneOrder})
+		require.NoError(t, err)
+		assert.Len(t, validationResults.Accepted, 1, "Expected 1 order to be valid over RPC")
+		assert.Len(t, validationResults.Rejected, 0, "Expected 0 orders to be invalid over RPC")
+		stats, err := rpcClient.GetStats()
+		require.NoError(t, err)
+		assert.Equal(t, 0, stats.NumOrders, "Expected ValidateOrders not to store the order")
 		results, err := rpcClient.AddOrders([]*zeroex.SignedOrder{standaloneOrder})
 		require.NoError(t, err)
 		assert.Len(t, results.Accepted, 1, "Expected 1 order to be accepted over RPC")
@@ -0,0 +1,205 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// encryptedKeyVersion is the version of the encrypted key file format.
+	encryptedKeyVersion = 1
+	// encryptedKeyCipher is the cipher used to encrypt private keys.
+	encryptedKeyCipher = "aes-256-gcm"
+	// encryptedKeyKDF is the key derivation function used to derive the
+	// encryption key from the passphrase.
+	encryptedKeyKDF = "scrypt"
+	// scryptR is the scrypt block size parameter.
+	scryptR = 8
+	// scryptDKLen is the length of the derived key. 32 bytes selects AES-256.
+	scryptDKLen = 32
+	// saltLength is the length of the random salt used for scrypt.
+	saltLength = 32
+)
+
+var (
+	// ErrPassphraseRequired is returned when trying to load an encrypted
+	// private key without a passphrase.
+	ErrPassphraseRequired = errors.New("private key is encrypted and a passphrase is required to decrypt it")
+	// ErrIncorrectPassphrase is returned when an encrypted private key can't be
+	// decrypted with the given passphrase (or the key file was modified).
+	ErrIncorrectPassphrase = errors.New("could not decrypt private key: incorrect passphrase")
+)
+
+// encryptedKey is the JSON format for encrypted private keys. It is similar to
+// the format of Ethereum keystore files, but uses AES-GCM instead of AES-CTR
+// and a separate MAC.
+type encryptedKey struct {
+	Version int                `json:"version"`
+	Crypto  encryptedKeyCrypto `json:"crypto"`
+}
+
+type encryptedKeyCrypto struct {
+	Cipher     string       `json:"cipher"`
+	CipherText string       `json:"ciphertext"`
+	Nonce      string       `json:"nonce"`
+	KDF        string       `json:"kdf"`
+	KDFParams  scryptParams `json:"kdfparams"`
+}
+
+type scryptParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+// EncryptPrivateKey encrypts the given private key with the given passphrase.
+// It returns the contents of an encrypted key file.
+func EncryptPrivateKey(privKey p2pcrypto.PrivKey, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	keyBytes, err := p2pcrypto.MarshalPrivateKey(privKey)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	params := scryptParams{
+		N:     scryptN,
+		R:     scryptR,
+		P:     scryptP,
+		DKLen: scryptDKLen,
+		Salt:  hex.EncodeToString(salt),
+	}
+	aead, err := newAEAD(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	cipherText := aead.Seal(nil, nonce, keyBytes, nil)
+	return json.Marshal(encryptedKey{
+		Version: encryptedKeyVersion,
+		Crypto: encryptedKeyCrypto{
+			Cipher:     encryptedKeyCipher,
+			CipherText: hex.EncodeToString(cipherText),
+			Nonce:      hex.EncodeToString(nonce),
+			KDF:        encryptedKeyKDF,
+			KDFParams:  params,
+		},
+	})
+}
+
+// DecryptPrivateKey decrypts the contents of an encrypted key file with the
+// given passphrase. It returns ErrIncorrectPassphrase if the passphrase is
+// incorrect.
+func DecryptPrivateKey(data []byte, passphrase string) (p2pcrypto.PrivKey, error) {
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	var key encryptedKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("could not parse encrypted private key: %s", err.Error())
+	}
+	if key.Version != encryptedKeyVersion {
+		return nil, fmt.Errorf("unsupported encrypted private key version: %d", key.Version)
+	}
+	if key.Crypto.Cipher != encryptedKeyCipher {
+		return nil, fmt.Errorf("unsupported encrypted private key cipher: %s", key.Crypto.Cipher)
+	}
+	if key.Crypto.KDF != encryptedKeyKDF {
+		return nil, fmt.Errorf("unsupported encrypted private key KDF: %s", key.Crypto.KDF)
+	}
+	if key.Crypto.KDFParams.DKLen != scryptDKLen {
+		return nil, fmt.Errorf("unsupported encrypted private key dklen: %d", key.Crypto.KDFParams.DKLen)
+	}
+	cipherText, err := hex.DecodeString(key.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode encrypted private key: %s", err.Error())
+	}
+	nonce, err := hex.DecodeString(key.Crypto.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode encrypted private key nonce: %s", err.Error())
+	}
+	aead, err := newAEAD(passphrase, key.Crypto.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted private key nonce length: %d", len(nonce))
+	}
+	keyBytes, err := aead.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+	return p2pcrypto.UnmarshalPrivateKey(keyBytes)
+}
+
+// newAEAD derives a key from the passphrase with scrypt and returns an
+// AES-GCM cipher which uses it. Scrypt parameters above the ones used by
+// EncryptPrivateKey are rejected, so that a modified key file can't make us
+// use an arbitrary amount of memory and CPU time.
+func newAEAD(passphrase string, params scryptParams) (cipher.AEAD, error) {
+	if params.N > scryptN || params.R > scryptR || params.P > scryptP {
+		return nil, fmt.Errorf("unsupported encrypted private key scrypt params: n=%d, r=%d, p=%d", params.N, params.R, params.P)
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode encrypted private key salt: %s", err.Error())
+	}
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, params.DKLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isEncrypted returns true if the given key file contents are in the
+// encrypted (JSON) format. Plaintext keys are base64 encoded, so they never
+// start with "{".
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
+
+// GetPassphrase returns the passphrase for a private key from either the
+// passphrase itself or the path of a file which contains it. At most one of
+// them may be set. It returns an empty string if neither is set.
+func GetPassphrase(passphrase string, passphraseFile string) (string, error) {
+	if passphraseFile == "" {
+		return passphrase, nil
+	}
+	if passphrase != "" {
+		return "", errors.New("only one of the passphrase and the passphrase file can be set")
+	}
+	data, err := readFile(passphraseFile)
+	if err != nil {
+		return "", err
+	}
+	// Files usually end with a newline which is not part of the passphrase.
+	passphrase = strings.TrimRight(string(data), "\r\n")
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase file is empty: %s", passphraseFile)
+	}
+	return passphrase, nil
+}
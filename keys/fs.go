@@ -15,6 +15,12 @@ func mkdirAll(dir string) error {
 	return os.MkdirAll(dir, os.ModePerm)
 }
 
+// writeFile writes data to a temporary file and then renames it so that an
+// existing key is never left partially overwritten.
 func writeFile(path string, data []byte) error {
-	return ioutil.WriteFile(path, data, os.ModePerm)
+	tempPath := path + ".tmp"
+	if err := ioutil.WriteFile(tempPath, data, os.ModePerm); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
 }
@@ -0,0 +1,13 @@
+// +build !js
+
+package keys
+
+const (
+	// scryptN is the scrypt CPU/memory cost parameter used when encrypting
+	// private keys. Together with scryptR it uses 256 MiB of memory, which is
+	// the same as Ethereum keystore files.
+	scryptN = 1 << 18
+	// scryptP is the scrypt parallelization parameter used when encrypting
+	// private keys.
+	scryptP = 1
+)
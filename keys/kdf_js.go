@@ -0,0 +1,13 @@
+// +build js,wasm
+
+package keys
+
+const (
+	// scryptN is the scrypt CPU/memory cost parameter used when encrypting
+	// private keys. Browsers can't use as much memory as native environments,
+	// so these are the same "light" parameters used by Ethereum keystores.
+	scryptN = 1 << 12
+	// scryptP is the scrypt parallelization parameter used when encrypting
+	// private keys.
+	scryptP = 6
+)
@@ -2,16 +2,30 @@ package keys
 
 import (
 	"crypto/rand"
+	"os"
 	"path/filepath"
 
 	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	log "github.com/sirupsen/logrus"
 )
 
+// GetPrivateKeyFromPath reads a plaintext private key from the given path. It
+// returns ErrPassphraseRequired if the private key is encrypted.
 func GetPrivateKeyFromPath(path string) (p2pcrypto.PrivKey, error) {
+	return GetPrivateKeyFromPathWithPassphrase(path, "")
+}
+
+// GetPrivateKeyFromPathWithPassphrase reads a private key from the given path.
+// If the private key is encrypted, it is decrypted with the given passphrase.
+// Plaintext private keys are read as-is and the passphrase is ignored.
+func GetPrivateKeyFromPathWithPassphrase(path string, passphrase string) (p2pcrypto.PrivKey, error) {
 	keyBytes, err := readFile(path)
 	if err != nil {
 		return nil, err
 	}
+	if isEncrypted(keyBytes) {
+		return DecryptPrivateKey(keyBytes, passphrase)
+	}
 	decodedKey, err := p2pcrypto.ConfigDecodeKey(string(keyBytes))
 	if err != nil {
 		return nil, err
@@ -23,22 +37,100 @@ func GetPrivateKeyFromPath(path string) (p2pcrypto.PrivKey, error) {
 	return priv, nil
 }
 
-func GenerateAndSavePrivateKey(path string) (p2pcrypto.PrivKey, error) {
-	dir := filepath.Dir(path)
-	if err := mkdirAll(dir); err != nil {
-		return nil, err
-	}
-	privKey, _, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+// IsPrivateKeyEncrypted returns true if the private key at the given path is
+// encrypted.
+func IsPrivateKeyEncrypted(path string) (bool, error) {
+	keyBytes, err := readFile(path)
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	return isEncrypted(keyBytes), nil
+}
+
+// InitPrivateKey loads the private key at the given path, or generates a new
+// one if it doesn't exist. If passphrase is not empty, new private keys are
+// encrypted with it and existing plaintext private keys are encrypted in
+// place.
+func InitPrivateKey(path string, passphrase string) (p2pcrypto.PrivKey, error) {
+	privKey, err := GetPrivateKeyFromPathWithPassphrase(path, passphrase)
+	if err == nil {
+		if passphrase == "" {
+			return privKey, nil
+		}
+		encrypted, err := IsPrivateKeyEncrypted(path)
+		if err != nil {
+			return nil, err
+		}
+		if !encrypted {
+			log.Info("Encrypting existing plaintext private key.")
+			if err := SavePrivateKey(path, privKey, passphrase); err != nil {
+				return nil, err
+			}
+		}
+		return privKey, nil
+	} else if os.IsNotExist(err) {
+		// If the private key doesn't exist, generate one.
+		log.Info("No private key found. Generating a new one.")
+		return GenerateAndSavePrivateKeyWithPassphrase(path, passphrase)
 	}
-	keyBytes, err := p2pcrypto.MarshalPrivateKey(privKey)
+
+	// For any other type of error, return it.
+	return nil, err
+}
+
+// GenerateAndSavePrivateKey generates a new private key and saves it in
+// plaintext to the given path.
+func GenerateAndSavePrivateKey(path string) (p2pcrypto.PrivKey, error) {
+	return GenerateAndSavePrivateKeyWithPassphrase(path, "")
+}
+
+// GenerateAndSavePrivateKeyWithPassphrase generates a new private key and
+// saves it to the given path, encrypted with the given passphrase. If the
+// passphrase is empty, the private key is saved in plaintext.
+func GenerateAndSavePrivateKeyWithPassphrase(path string, passphrase string) (p2pcrypto.PrivKey, error) {
+	privKey, _, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
 	if err != nil {
 		return nil, err
 	}
-	encodedKey := p2pcrypto.ConfigEncodeKey(keyBytes)
-	if err := writeFile(path, []byte(encodedKey)); err != nil {
+	if err := SavePrivateKey(path, privKey, passphrase); err != nil {
 		return nil, err
 	}
 	return privKey, nil
 }
+
+// SavePrivateKey saves the given private key to the given path, replacing any
+// existing file. The private key is encrypted with the given passphrase, or
+// saved in plaintext if the passphrase is empty.
+func SavePrivateKey(path string, privKey p2pcrypto.PrivKey, passphrase string) error {
+	dir := filepath.Dir(path)
+	if err := mkdirAll(dir); err != nil {
+		return err
+	}
+	var data []byte
+	if passphrase != "" {
+		encryptedKey, err := EncryptPrivateKey(privKey, passphrase)
+		if err != nil {
+			return err
+		}
+		data = encryptedKey
+	} else {
+		keyBytes, err := p2pcrypto.MarshalPrivateKey(privKey)
+		if err != nil {
+			return err
+		}
+		data = []byte(p2pcrypto.ConfigEncodeKey(keyBytes))
+	}
+	return writeFile(path, data)
+}
+
+// ChangePassphrase re-encrypts the private key at the given path with a new
+// passphrase. If the private key is currently in plaintext, oldPassphrase is
+// ignored and the private key is encrypted. If newPassphrase is empty, the
+// private key is saved in plaintext.
+func ChangePassphrase(path string, oldPassphrase string, newPassphrase string) error {
+	privKey, err := GetPrivateKeyFromPathWithPassphrase(path, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	return SavePrivateKey(path, privKey, newPassphrase)
+}
@@ -1,10 +1,16 @@
 package keys
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/uuid"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -24,3 +30,148 @@ func TestGetKeyNotExists(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, os.IsNotExist(err), "error should be a NotExist error, but got: (%T) %s", err, err)
 }
+
+func TestGenerateAndGetEncryptedKey(t *testing.T) {
+	path := "/tmp/keys/" + uuid.New().String()
+	generatedKey, err := GenerateAndSavePrivateKeyWithPassphrase(path, "passphrase")
+	require.NoError(t, err)
+	encrypted, err := IsPrivateKeyEncrypted(path)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+	gotKey, err := GetPrivateKeyFromPathWithPassphrase(path, "passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, generatedKey, gotKey)
+
+	// Reading an encrypted key requires the correct passphrase.
+	_, err = GetPrivateKeyFromPath(path)
+	assert.Equal(t, ErrPassphraseRequired, err)
+	_, err = GetPrivateKeyFromPathWithPassphrase(path, "wrong passphrase")
+	assert.Equal(t, ErrIncorrectPassphrase, err)
+}
+
+func TestGetPlaintextKeyWithPassphrase(t *testing.T) {
+	path := "/tmp/keys/" + uuid.New().String()
+	generatedKey, err := GenerateAndSavePrivateKey(path)
+	require.NoError(t, err)
+	encrypted, err := IsPrivateKeyEncrypted(path)
+	require.NoError(t, err)
+	assert.False(t, encrypted)
+	// The passphrase is ignored for plaintext keys.
+	gotKey, err := GetPrivateKeyFromPathWithPassphrase(path, "passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, generatedKey, gotKey)
+}
+
+func TestDecryptModifiedKey(t *testing.T) {
+	privKey, _, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	data, err := EncryptPrivateKey(privKey, "passphrase")
+	require.NoError(t, err)
+	var key encryptedKey
+	require.NoError(t, json.Unmarshal(data, &key))
+
+	// Flip a bit in the ciphertext.
+	cipherText, err := hex.DecodeString(key.Crypto.CipherText)
+	require.NoError(t, err)
+	cipherText[0] ^= 1
+	key.Crypto.CipherText = hex.EncodeToString(cipherText)
+	modifiedData, err := json.Marshal(key)
+	require.NoError(t, err)
+	_, err = DecryptPrivateKey(modifiedData, "passphrase")
+	assert.Equal(t, ErrIncorrectPassphrase, err)
+
+	key.Crypto.Cipher = "aes-128-ctr"
+	modifiedData, err = json.Marshal(key)
+	require.NoError(t, err)
+	_, err = DecryptPrivateKey(modifiedData, "passphrase")
+	assert.EqualError(t, err, "unsupported encrypted private key cipher: aes-128-ctr")
+}
+
+func TestDecryptKeyWithExpensiveScryptParams(t *testing.T) {
+	privKey, _, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	data, err := EncryptPrivateKey(privKey, "passphrase")
+	require.NoError(t, err)
+	var key encryptedKey
+	require.NoError(t, json.Unmarshal(data, &key))
+
+	for _, params := range []scryptParams{
+		{N: scryptN * 2, R: scryptR, P: scryptP},
+		{N: scryptN, R: scryptR * 2, P: scryptP},
+		{N: scryptN, R: scryptR, P: scryptP * 2},
+	} {
+		params.DKLen = key.Crypto.KDFParams.DKLen
+		params.Salt = key.Crypto.KDFParams.Salt
+		modifiedKey := key
+		modifiedKey.Crypto.KDFParams = params
+		modifiedData, err := json.Marshal(modifiedKey)
+		require.NoError(t, err)
+		_, err = DecryptPrivateKey(modifiedData, "passphrase")
+		assert.EqualError(t, err, fmt.Sprintf("unsupported encrypted private key scrypt params: n=%d, r=%d, p=%d", params.N, params.R, params.P))
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	path := "/tmp/keys/" + uuid.New().String()
+	generatedKey, err := GenerateAndSavePrivateKey(path)
+	require.NoError(t, err)
+
+	// Encrypt the plaintext key.
+	require.NoError(t, ChangePassphrase(path, "", "first passphrase"))
+	encrypted, err := IsPrivateKeyEncrypted(path)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+
+	// Change the passphrase. The old passphrase should no longer work.
+	assert.Equal(t, ErrIncorrectPassphrase, ChangePassphrase(path, "wrong passphrase", "second passphrase"))
+	require.NoError(t, ChangePassphrase(path, "first passphrase", "second passphrase"))
+	_, err = GetPrivateKeyFromPathWithPassphrase(path, "first passphrase")
+	assert.Equal(t, ErrIncorrectPassphrase, err)
+	gotKey, err := GetPrivateKeyFromPathWithPassphrase(path, "second passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, generatedKey, gotKey)
+}
+
+func TestInitPrivateKeyEncryptsPlaintextKey(t *testing.T) {
+	path := "/tmp/keys/" + uuid.New().String()
+	plaintextKey, err := InitPrivateKey(path, "")
+	require.NoError(t, err)
+	encrypted, err := IsPrivateKeyEncrypted(path)
+	require.NoError(t, err)
+	assert.False(t, encrypted)
+
+	// Starting up with a passphrase should encrypt the existing key in place.
+	privKey, err := InitPrivateKey(path, "passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, plaintextKey, privKey)
+	encrypted, err = IsPrivateKeyEncrypted(path)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+
+	// After that, the key can only be loaded with the passphrase.
+	_, err = InitPrivateKey(path, "")
+	assert.Equal(t, ErrPassphraseRequired, err)
+	privKey, err = InitPrivateKey(path, "passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, plaintextKey, privKey)
+}
+
+func TestGetPassphrase(t *testing.T) {
+	passphrase, err := GetPassphrase("passphrase", "")
+	require.NoError(t, err)
+	assert.Equal(t, "passphrase", passphrase)
+
+	path := "/tmp/keys/" + uuid.New().String()
+	require.NoError(t, mkdirAll(filepath.Dir(path)))
+	require.NoError(t, writeFile(path, []byte("passphrase from file\n")))
+	passphrase, err = GetPassphrase("", path)
+	require.NoError(t, err)
+	assert.Equal(t, "passphrase from file", passphrase)
+
+	_, err = GetPassphrase("passphrase", path)
+	assert.Error(t, err, "setting both the passphrase and the passphrase file should not be allowed")
+
+	require.NoError(t, writeFile(path, []byte("\n")))
+	_, err = GetPassphrase("", path)
+	assert.Error(t, err, "empty passphrase files should not be allowed")
+}
@@ -0,0 +1,193 @@
+package meshdb
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// assetDataDecoder is shared by all callers in this package. It is safe for
+// concurrent use since it never mutates any internal state after it is created.
+var assetDataDecoder = zeroex.NewAssetDataDecoder()
+
+// ParsedAssetData is the decoded representation of 0x asset data. It is stored
+// alongside each order so that asset data only needs to be ABI decoded once
+// when the order is first added, instead of each time it is needed (e.g. when
+// computing indexes or tracking contract events).
+type ParsedAssetData struct {
+	// Name is the name of the asset proxy type, as returned by
+	// zeroex.AssetDataDecoder.GetName (e.g. "ERC20Token" or "MultiAsset").
+	Name string
+	// Address is the address of the token contract. For StaticCall it is the
+	// address of the contract which is called. It is not set for MultiAsset.
+	Address common.Address
+	// TokenIDs holds the token ID for ERC721Token and the token IDs for
+	// ERC1155Assets.
+	TokenIDs []*big.Int
+	// Values holds the token values for ERC1155Assets.
+	Values []*big.Int
+	// CallbackData holds the callback data for ERC1155Assets.
+	CallbackData []byte
+	// Amounts holds the amount of each nested asset for MultiAsset.
+	Amounts []*big.Int
+	// NestedAssetData holds the parsed nested asset data for MultiAsset.
+	NestedAssetData []*ParsedAssetData
+	// StaticCallData holds the call data for StaticCall.
+	StaticCallData []byte
+	// ExpectedReturnDataHash holds the expected hash of the data returned by
+	// the call for StaticCall.
+	ExpectedReturnDataHash common.Hash
+	// BridgeAddress holds the address of the bridge contract for ERC20Bridge.
+	BridgeAddress common.Address
+	// BridgeData holds the bridge data for ERC20Bridge.
+	BridgeData []byte
+}
+
+// ParseAssetData decodes the given asset data into its components. It returns
+// an error if the asset data is invalid or uses an unsupported asset proxy.
+func ParseAssetData(assetData []byte) (*ParsedAssetData, error) {
+	assetDataName, err := assetDataDecoder.GetName(assetData)
+	if err != nil {
+		return nil, err
+	}
+	switch assetDataName {
+	case "ERC20Token":
+		var decodedAssetData zeroex.ERC20AssetData
+		if err := assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil, err
+		}
+		return &ParsedAssetData{
+			Name:    assetDataName,
+			Address: decodedAssetData.Address,
+		}, nil
+	case "ERC721Token":
+		var decodedAssetData zeroex.ERC721AssetData
+		if err := assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil, err
+		}
+		return &ParsedAssetData{
+			Name:     assetDataName,
+			Address:  decodedAssetData.Address,
+			TokenIDs: []*big.Int{decodedAssetData.TokenId},
+		}, nil
+	case "ERC1155Assets":
+		var decodedAssetData zeroex.ERC1155AssetData
+		if err := assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil, err
+		}
+		return &ParsedAssetData{
+			Name:         assetDataName,
+			Address:      decodedAssetData.Address,
+			TokenIDs:     decodedAssetData.Ids,
+			Values:       decodedAssetData.Values,
+			CallbackData: decodedAssetData.CallbackData,
+		}, nil
+	case "MultiAsset":
+		var decodedAssetData zeroex.MultiAssetData
+		if err := assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil, err
+		}
+		parsed := &ParsedAssetData{
+			Name:            assetDataName,
+			Amounts:         decodedAssetData.Amounts,
+			NestedAssetData: make([]*ParsedAssetData, len(decodedAssetData.NestedAssetData)),
+		}
+		for i, nestedAssetData := range decodedAssetData.NestedAssetData {
+			parsedNestedAssetData, err := ParseAssetData(nestedAssetData)
+			if err != nil {
+				return nil, err
+			}
+			parsed.NestedAssetData[i] = parsedNestedAssetData
+		}
+		return parsed, nil
+	case "StaticCall":
+		var decodedAssetData zeroex.StaticCallAssetData
+		if err := assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil, err
+		}
+		return &ParsedAssetData{
+			Name:                   assetDataName,
+			Address:                decodedAssetData.StaticCallTargetAddress,
+			StaticCallData:         decodedAssetData.StaticCallData,
+			ExpectedReturnDataHash: decodedAssetData.ExpectedReturnDataHash,
+		}, nil
+	case "ERC20Bridge":
+		var decodedAssetData zeroex.ERC20BridgeAssetData
+		if err := assetDataDecoder.Decode(assetData, &decodedAssetData); err != nil {
+			return nil, err
+		}
+		return &ParsedAssetData{
+			Name:          assetDataName,
+			Address:       decodedAssetData.TokenAddress,
+			BridgeAddress: decodedAssetData.BridgeAddress,
+			BridgeData:    decodedAssetData.BridgeData,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized assetData type name found: %s", assetDataName)
+	}
+}
+
+// GetParsedMakerAssetData returns the parsed maker asset data for the order.
+// Typically this simply returns o.ParsedMakerAssetData, but if the field was not
+// set, the maker asset data is parsed on the fly.
+func (o *Order) GetParsedMakerAssetData() (*ParsedAssetData, error) {
+	if o.ParsedMakerAssetData != nil {
+		return o.ParsedMakerAssetData, nil
+	}
+	return ParseAssetData(o.SignedOrder.MakerAssetData)
+}
+
+type singleAssetData struct {
+	Address common.Address
+	TokenID *big.Int
+}
+
+// singleAssetDatas flattens the parsed asset data into a list of token
+// addresses and token IDs. Nested asset data is flattened recursively and an
+// entry is included for each token ID.
+func (p *ParsedAssetData) singleAssetDatas() []singleAssetData {
+	singleAssetDatas := []singleAssetData{}
+	switch p.Name {
+	case "MultiAsset":
+		for _, nestedAssetData := range p.NestedAssetData {
+			singleAssetDatas = append(singleAssetDatas, nestedAssetData.singleAssetDatas()...)
+		}
+	case "StaticCall":
+		// StaticCall asset data doesn't transfer any tokens. It only adds a
+		// condition which must be met for the order to be fillable.
+	case "ERC721Token", "ERC1155Assets":
+		for _, tokenID := range p.TokenIDs {
+			singleAssetDatas = append(singleAssetDatas, singleAssetData{
+				Address: p.Address,
+				TokenID: tokenID,
+			})
+		}
+	default:
+		// For ERC20Bridge, Address is the address of the token which is
+		// transferred by the bridge.
+		singleAssetDatas = append(singleAssetDatas, singleAssetData{
+			Address: p.Address,
+		})
+	}
+	return singleAssetDatas
+}
+
+// ContainsStaticCall returns true if the parsed asset data is StaticCall asset
+// data or MultiAsset asset data which contains StaticCall asset data. The
+// fillability of orders with StaticCall asset data can change without any
+// contract events being emitted.
+func (p *ParsedAssetData) ContainsStaticCall() bool {
+	switch p.Name {
+	case "StaticCall":
+		return true
+	case "MultiAsset":
+		for _, nestedAssetData := range p.NestedAssetData {
+			if nestedAssetData.ContainsStaticCall() {
+				return true
+			}
+		}
+	}
+	return false
+}
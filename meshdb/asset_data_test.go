@@ -0,0 +1,124 @@
+package meshdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const multiAssetDataHex = "94cfcdd7000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004600000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000024f47261b00000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000"
+
+func TestParseAssetDataMultiAsset(t *testing.T) {
+	parsed, err := ParseAssetData(common.Hex2Bytes(multiAssetDataHex))
+	require.NoError(t, err)
+	tokenAddress := common.HexToAddress("0x1dc4c1cefef38a777b15aa20260a54e584b16c48")
+	expected := &ParsedAssetData{
+		Name:    "MultiAsset",
+		Amounts: []*big.Int{big.NewInt(70), big.NewInt(1)},
+		NestedAssetData: []*ParsedAssetData{
+			{
+				Name:    "ERC20Token",
+				Address: tokenAddress,
+			},
+			{
+				Name:     "ERC721Token",
+				Address:  tokenAddress,
+				TokenIDs: []*big.Int{big.NewInt(1)},
+			},
+		},
+	}
+	assert.Equal(t, expected, parsed)
+}
+
+func TestParseAssetDataStaticCallAndERC20Bridge(t *testing.T) {
+	encoder := zeroex.NewAssetDataEncoder()
+	tokenAddress := common.HexToAddress("0x1dc4c1cefef38a777b15aa20260a54e584b16c48")
+	bridgeAddress := common.HexToAddress("0x38ae374ecf4db50b0ff37125b591a04997106a32")
+	expectedReturnDataHash := common.HexToHash("0x0101010101010101010101010101010101010101010101010101010101010101")
+	staticCallAssetData, err := encoder.Encode(zeroex.StaticCallAssetData{
+		StaticCallTargetAddress: tokenAddress,
+		StaticCallData:          common.Hex2Bytes("deadbeef"),
+		ExpectedReturnDataHash:  expectedReturnDataHash,
+	})
+	require.NoError(t, err)
+	erc20BridgeAssetData, err := encoder.Encode(zeroex.ERC20BridgeAssetData{
+		TokenAddress:  tokenAddress,
+		BridgeAddress: bridgeAddress,
+		BridgeData:    common.Hex2Bytes("beef"),
+	})
+	require.NoError(t, err)
+	multiAssetData, err := encoder.Encode(zeroex.MultiAssetData{
+		Amounts:         []*big.Int{big.NewInt(1), big.NewInt(1)},
+		NestedAssetData: [][]byte{staticCallAssetData, erc20BridgeAssetData},
+	})
+	require.NoError(t, err)
+
+	parsed, err := ParseAssetData(multiAssetData)
+	require.NoError(t, err)
+	expected := &ParsedAssetData{
+		Name:    "MultiAsset",
+		Amounts: []*big.Int{big.NewInt(1), big.NewInt(1)},
+		NestedAssetData: []*ParsedAssetData{
+			{
+				Name:                   "StaticCall",
+				Address:                tokenAddress,
+				StaticCallData:         common.Hex2Bytes("deadbeef"),
+				ExpectedReturnDataHash: expectedReturnDataHash,
+			},
+			{
+				Name:          "ERC20Bridge",
+				Address:       tokenAddress,
+				BridgeAddress: bridgeAddress,
+				BridgeData:    common.Hex2Bytes("beef"),
+			},
+		},
+	}
+	assert.Equal(t, expected, parsed)
+	assert.True(t, parsed.ContainsStaticCall())
+	assert.False(t, parsed.NestedAssetData[1].ContainsStaticCall())
+
+	// Only the token transferred by the bridge should be indexed.
+	assert.Equal(t, []singleAssetData{{Address: tokenAddress}}, parsed.singleAssetDatas())
+
+	// The new fields should survive a round trip through the order codec.
+	order := newTestOrder(t)
+	order.SignedOrder.MakerAssetData = multiAssetData
+	order.ParsedMakerAssetData = parsed
+	data, err := orderCodec{}.Encode(order)
+	require.NoError(t, err)
+	var decoded Order
+	require.NoError(t, orderCodec{}.Decode(data, &decoded))
+	assert.Equal(t, order, &decoded)
+}
+
+func TestParseAssetDataInvalid(t *testing.T) {
+	_, err := ParseAssetData(common.Hex2Bytes("deadbeef"))
+	assert.Error(t, err)
+}
+
+func TestOrderCodecRoundTripMultiAsset(t *testing.T) {
+	order := newTestOrder(t)
+	order.SignedOrder.MakerAssetData = common.Hex2Bytes(multiAssetDataHex)
+	parsed, err := ParseAssetData(order.SignedOrder.MakerAssetData)
+	require.NoError(t, err)
+	order.ParsedMakerAssetData = parsed
+
+	data, err := orderCodec{}.Encode(order)
+	require.NoError(t, err)
+	var decoded Order
+	require.NoError(t, orderCodec{}.Decode(data, &decoded))
+	assert.Equal(t, order, &decoded)
+}
+
+func TestGetParsedMakerAssetDataFallsBackToParsing(t *testing.T) {
+	order := newTestOrder(t)
+	expected := order.ParsedMakerAssetData
+	order.ParsedMakerAssetData = nil
+	actual, err := order.GetParsedMakerAssetData()
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,562 @@
+package meshdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/0xProject/0x-mesh/ethereum/miniheader"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// binaryEncodingVersion is the first byte of any model encoded with one of the
+// binary codecs in this package. Models that were stored before the binary
+// codecs were introduced are encoded as JSON objects, which always start with
+// "{". This allows the codecs to detect and decode legacy data.
+//
+// Version 2 added the StaticCall and ERC20Bridge fields of ParsedAssetData.
+// Data encoded with version 1 can still be decoded.
+const binaryEncodingVersion byte = 2
+
+// minBinaryEncodingVersion is the oldest binary encoding version which can
+// still be decoded.
+const minBinaryEncodingVersion byte = 1
+
+var errUnexpectedEndOfData = errors.New("unexpected end of data while decoding model")
+
+// orderCodec is a db.Codec for Order which uses a compact binary encoding. It
+// is significantly faster than db.JSONCodec, mostly because big.Ints don't need
+// to be converted to and from strings.
+type orderCodec struct{}
+
+var _ db.Codec = orderCodec{}
+
+// Encode implements db.Codec.
+func (orderCodec) Encode(model db.Model) ([]byte, error) {
+	var order *Order
+	switch m := model.(type) {
+	case *Order:
+		order = m
+	case Order:
+		order = &m
+	default:
+		return nil, fmt.Errorf("orderCodec: unexpected model type %T", model)
+	}
+	if order.SignedOrder == nil {
+		return nil, errors.New("orderCodec: cannot encode order with nil SignedOrder")
+	}
+	w := newBinaryWriter()
+	w.writeByte(binaryEncodingVersion)
+	w.writeHash(order.Hash)
+	w.writeSignedOrder(order.SignedOrder)
+	if err := w.writeTime(order.LastUpdated); err != nil {
+		return nil, err
+	}
+	w.writeBigInt(order.FillableTakerAssetAmount)
+	w.writeBool(order.IsRemoved)
+	w.writeBool(order.IsPinned)
+	w.writeParsedAssetData(order.ParsedMakerAssetData)
+	return w.bytes(), nil
+}
+
+// Decode implements db.Codec.
+func (orderCodec) Decode(data []byte, model interface{}) error {
+	if db.IsJSON(data) {
+		return decodeLegacyJSONOrder(data, model)
+	}
+	var order *Order
+	switch m := model.(type) {
+	case *Order:
+		order = m
+	case **Order:
+		order = &Order{}
+		*m = order
+	default:
+		return fmt.Errorf("orderCodec: unexpected model type %T", model)
+	}
+	r, err := newBinaryReader(data)
+	if err != nil {
+		return err
+	}
+	order.Hash = r.readHash()
+	order.SignedOrder = r.readSignedOrder()
+	order.LastUpdated = r.readTime()
+	order.FillableTakerAssetAmount = r.readBigInt()
+	order.IsRemoved = r.readBool()
+	order.IsPinned = r.readBool()
+	order.ParsedMakerAssetData = r.readParsedAssetData()
+	return r.err
+}
+
+// decodeLegacyJSONOrder decodes an order that was stored as JSON by an older
+// version of Mesh. These orders predate ParsedMakerAssetData, so we parse the
+// maker asset data here. This way the field is populated when legacy orders
+// are migrated to the binary encoding.
+func decodeLegacyJSONOrder(data []byte, model interface{}) error {
+	if err := (db.JSONCodec{}).Decode(data, model); err != nil {
+		return err
+	}
+	var order *Order
+	switch m := model.(type) {
+	case *Order:
+		order = m
+	case **Order:
+		order = *m
+	}
+	if order == nil || order.SignedOrder == nil || order.ParsedMakerAssetData != nil {
+		return nil
+	}
+	parsedMakerAssetData, err := ParseAssetData(order.SignedOrder.MakerAssetData)
+	if err != nil {
+		return err
+	}
+	order.ParsedMakerAssetData = parsedMakerAssetData
+	return nil
+}
+
+// miniHeaderCodec is a db.Codec for miniheader.MiniHeader which uses a compact
+// binary encoding.
+type miniHeaderCodec struct{}
+
+var _ db.Codec = miniHeaderCodec{}
+
+// Encode implements db.Codec.
+func (miniHeaderCodec) Encode(model db.Model) ([]byte, error) {
+	header, ok := model.(*miniheader.MiniHeader)
+	if !ok {
+		return nil, fmt.Errorf("miniHeaderCodec: unexpected model type %T", model)
+	}
+	w := newBinaryWriter()
+	w.writeByte(binaryEncodingVersion)
+	w.writeHash(header.Hash)
+	w.writeHash(header.Parent)
+	w.writeBigInt(header.Number)
+	if err := w.writeTime(header.Timestamp); err != nil {
+		return nil, err
+	}
+	w.writeSliceLength(header.Logs == nil, len(header.Logs))
+	for _, log := range header.Logs {
+		w.writeLog(log)
+	}
+	return w.bytes(), nil
+}
+
+// Decode implements db.Codec.
+func (miniHeaderCodec) Decode(data []byte, model interface{}) error {
+	if db.IsJSON(data) {
+		return db.JSONCodec{}.Decode(data, model)
+	}
+	var header *miniheader.MiniHeader
+	switch m := model.(type) {
+	case *miniheader.MiniHeader:
+		header = m
+	case **miniheader.MiniHeader:
+		header = &miniheader.MiniHeader{}
+		*m = header
+	default:
+		return fmt.Errorf("miniHeaderCodec: unexpected model type %T", model)
+	}
+	r, err := newBinaryReader(data)
+	if err != nil {
+		return err
+	}
+	header.Hash = r.readHash()
+	header.Parent = r.readHash()
+	header.Number = r.readBigInt()
+	header.Timestamp = r.readTime()
+	if isNil, numLogs := r.readSliceLength(); !isNil {
+		header.Logs = make([]types.Log, 0, numLogs)
+		for i := 0; i < numLogs && r.err == nil; i++ {
+			header.Logs = append(header.Logs, r.readLog())
+		}
+	}
+	return r.err
+}
+
+// binaryWriter is used to encode models in a compact binary format.
+type binaryWriter struct {
+	buf       *bytes.Buffer
+	varintBuf [binary.MaxVarintLen64]byte
+}
+
+func newBinaryWriter() *binaryWriter {
+	return &binaryWriter{
+		buf: &bytes.Buffer{},
+	}
+}
+
+func (w *binaryWriter) bytes() []byte {
+	return w.buf.Bytes()
+}
+
+func (w *binaryWriter) writeByte(b byte) {
+	w.buf.WriteByte(b)
+}
+
+func (w *binaryWriter) writeBool(b bool) {
+	if b {
+		w.buf.WriteByte(1)
+	} else {
+		w.buf.WriteByte(0)
+	}
+}
+
+func (w *binaryWriter) writeUvarint(i uint64) {
+	n := binary.PutUvarint(w.varintBuf[:], i)
+	w.buf.Write(w.varintBuf[:n])
+}
+
+// writeSliceLength writes the length of a slice. It distinguishes between nil
+// and empty slices so that they are decoded the same way as with JSON.
+func (w *binaryWriter) writeSliceLength(isNil bool, length int) {
+	if isNil {
+		w.writeUvarint(0)
+		return
+	}
+	w.writeUvarint(uint64(length) + 1)
+}
+
+// writeBytes writes a length-prefixed byte slice.
+func (w *binaryWriter) writeBytes(b []byte) {
+	w.writeUvarint(uint64(len(b)))
+	w.buf.Write(b)
+}
+
+func (w *binaryWriter) writeHash(hash common.Hash) {
+	w.buf.Write(hash.Bytes())
+}
+
+func (w *binaryWriter) writeAddress(address common.Address) {
+	w.buf.Write(address.Bytes())
+}
+
+// Each big.Int is prefixed by a single byte which indicates whether it is nil,
+// positive, or negative.
+const (
+	bigIntNil byte = iota
+	bigIntNonNegative
+	bigIntNegative
+)
+
+func (w *binaryWriter) writeBigInt(i *big.Int) {
+	switch {
+	case i == nil:
+		w.writeByte(bigIntNil)
+		return
+	case i.Sign() < 0:
+		w.writeByte(bigIntNegative)
+	default:
+		w.writeByte(bigIntNonNegative)
+	}
+	w.writeBytes(i.Bytes())
+}
+
+func (w *binaryWriter) writeTime(t time.Time) error {
+	// time.Time.MarshalBinary preserves the time zone offset, which keeps the
+	// behavior consistent with the JSON encoding.
+	data, err := t.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	w.writeBytes(data)
+	return nil
+}
+
+func (w *binaryWriter) writeSignedOrder(signedOrder *zeroex.SignedOrder) {
+	w.writeAddress(signedOrder.MakerAddress)
+	w.writeBytes(signedOrder.MakerAssetData)
+	w.writeBigInt(signedOrder.MakerAssetAmount)
+	w.writeBigInt(signedOrder.MakerFee)
+	w.writeAddress(signedOrder.TakerAddress)
+	w.writeBytes(signedOrder.TakerAssetData)
+	w.writeBigInt(signedOrder.TakerAssetAmount)
+	w.writeBigInt(signedOrder.TakerFee)
+	w.writeAddress(signedOrder.SenderAddress)
+	w.writeAddress(signedOrder.ExchangeAddress)
+	w.writeAddress(signedOrder.FeeRecipientAddress)
+	w.writeBigInt(signedOrder.ExpirationTimeSeconds)
+	w.writeBigInt(signedOrder.Salt)
+	w.writeBytes(signedOrder.Signature)
+}
+
+// writeBigInts writes a slice of big.Ints.
+func (w *binaryWriter) writeBigInts(ints []*big.Int) {
+	w.writeSliceLength(ints == nil, len(ints))
+	for _, i := range ints {
+		w.writeBigInt(i)
+	}
+}
+
+// writeParsedAssetData writes the parsed asset data, including any nested
+// asset data.
+func (w *binaryWriter) writeParsedAssetData(parsed *ParsedAssetData) {
+	if parsed == nil {
+		w.writeBool(false)
+		return
+	}
+	w.writeBool(true)
+	w.writeBytes([]byte(parsed.Name))
+	w.writeAddress(parsed.Address)
+	w.writeBigInts(parsed.TokenIDs)
+	w.writeBigInts(parsed.Values)
+	w.writeBytes(parsed.CallbackData)
+	w.writeBigInts(parsed.Amounts)
+	w.writeSliceLength(parsed.NestedAssetData == nil, len(parsed.NestedAssetData))
+	for _, nested := range parsed.NestedAssetData {
+		w.writeParsedAssetData(nested)
+	}
+	w.writeBytes(parsed.StaticCallData)
+	w.writeHash(parsed.ExpectedReturnDataHash)
+	w.writeAddress(parsed.BridgeAddress)
+	w.writeBytes(parsed.BridgeData)
+}
+
+func (w *binaryWriter) writeLog(log types.Log) {
+	w.writeAddress(log.Address)
+	w.writeSliceLength(log.Topics == nil, len(log.Topics))
+	for _, topic := range log.Topics {
+		w.writeHash(topic)
+	}
+	w.writeBytes(log.Data)
+	w.writeUvarint(log.BlockNumber)
+	w.writeHash(log.TxHash)
+	w.writeUvarint(uint64(log.TxIndex))
+	w.writeHash(log.BlockHash)
+	w.writeUvarint(uint64(log.Index))
+	w.writeBool(log.Removed)
+}
+
+// binaryReader is used to decode models that were encoded with binaryWriter.
+// The first error encountered is stored in err and all subsequent reads are
+// no-ops, which means callers only need to check err once at the end.
+type binaryReader struct {
+	r   *bytes.Reader
+	err error
+	// version is the binary encoding version of the data being read.
+	version byte
+}
+
+func newBinaryReader(data []byte) (*binaryReader, error) {
+	r := &binaryReader{
+		r: bytes.NewReader(data),
+	}
+	version := r.readByte()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if version < minBinaryEncodingVersion || version > binaryEncodingVersion {
+		return nil, fmt.Errorf("unsupported binary encoding version: %d", version)
+	}
+	r.version = version
+	return r, nil
+}
+
+func (r *binaryReader) setErr(err error) {
+	if err == io.EOF {
+		err = errUnexpectedEndOfData
+	}
+	r.err = err
+}
+
+func (r *binaryReader) readByte() byte {
+	if r.err != nil {
+		return 0
+	}
+	b, err := r.r.ReadByte()
+	if err != nil {
+		r.setErr(err)
+		return 0
+	}
+	return b
+}
+
+func (r *binaryReader) readBool() bool {
+	return r.readByte() == 1
+}
+
+func (r *binaryReader) readUvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	i, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		r.setErr(err)
+		return 0
+	}
+	return i
+}
+
+// readLength reads a length prefix and checks that it does not exceed the
+// amount of remaining data. This prevents corrupted data from causing huge
+// allocations.
+func (r *binaryReader) readLength() int {
+	length := r.readUvarint()
+	if r.err != nil {
+		return 0
+	}
+	if length > uint64(r.r.Len()) {
+		r.setErr(errUnexpectedEndOfData)
+		return 0
+	}
+	return int(length)
+}
+
+// readSliceLength reads a slice length that was written by writeSliceLength.
+func (r *binaryReader) readSliceLength() (isNil bool, length int) {
+	encodedLength := r.readUvarint()
+	if r.err != nil || encodedLength == 0 {
+		return true, 0
+	}
+	// Each element is encoded with at least one byte.
+	if encodedLength-1 > uint64(r.r.Len()) {
+		r.setErr(errUnexpectedEndOfData)
+		return true, 0
+	}
+	return false, int(encodedLength - 1)
+}
+
+func (r *binaryReader) readFixed(length int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		r.setErr(err)
+		return nil
+	}
+	return b
+}
+
+// readBytes reads a length-prefixed byte slice. Empty byte slices are decoded
+// as nil, which matches the behavior of the JSON encoding.
+func (r *binaryReader) readBytes() []byte {
+	length := r.readLength()
+	if r.err != nil || length == 0 {
+		return nil
+	}
+	return r.readFixed(length)
+}
+
+func (r *binaryReader) readHash() common.Hash {
+	return common.BytesToHash(r.readFixed(common.HashLength))
+}
+
+func (r *binaryReader) readAddress() common.Address {
+	return common.BytesToAddress(r.readFixed(common.AddressLength))
+}
+
+func (r *binaryReader) readBigInt() *big.Int {
+	prefix := r.readByte()
+	if r.err != nil {
+		return nil
+	}
+	switch prefix {
+	case bigIntNil:
+		return nil
+	case bigIntNonNegative, bigIntNegative:
+		length := r.readLength()
+		i := new(big.Int).SetBytes(r.readFixed(length))
+		if prefix == bigIntNegative {
+			i.Neg(i)
+		}
+		return i
+	default:
+		r.setErr(fmt.Errorf("invalid big.Int prefix: %d", prefix))
+		return nil
+	}
+}
+
+func (r *binaryReader) readTime() time.Time {
+	var t time.Time
+	data := r.readBytes()
+	if r.err != nil {
+		return t
+	}
+	if err := t.UnmarshalBinary(data); err != nil {
+		r.setErr(err)
+	}
+	return t
+}
+
+func (r *binaryReader) readSignedOrder() *zeroex.SignedOrder {
+	signedOrder := &zeroex.SignedOrder{}
+	signedOrder.MakerAddress = r.readAddress()
+	signedOrder.MakerAssetData = r.readBytes()
+	signedOrder.MakerAssetAmount = r.readBigInt()
+	signedOrder.MakerFee = r.readBigInt()
+	signedOrder.TakerAddress = r.readAddress()
+	signedOrder.TakerAssetData = r.readBytes()
+	signedOrder.TakerAssetAmount = r.readBigInt()
+	signedOrder.TakerFee = r.readBigInt()
+	signedOrder.SenderAddress = r.readAddress()
+	signedOrder.ExchangeAddress = r.readAddress()
+	signedOrder.FeeRecipientAddress = r.readAddress()
+	signedOrder.ExpirationTimeSeconds = r.readBigInt()
+	signedOrder.Salt = r.readBigInt()
+	signedOrder.Signature = r.readBytes()
+	return signedOrder
+}
+
+func (r *binaryReader) readBigInts() []*big.Int {
+	isNil, length := r.readSliceLength()
+	if isNil {
+		return nil
+	}
+	ints := make([]*big.Int, 0, length)
+	for i := 0; i < length && r.err == nil; i++ {
+		ints = append(ints, r.readBigInt())
+	}
+	return ints
+}
+
+func (r *binaryReader) readParsedAssetData() *ParsedAssetData {
+	if isSet := r.readBool(); !isSet {
+		return nil
+	}
+	parsed := &ParsedAssetData{}
+	parsed.Name = string(r.readBytes())
+	parsed.Address = r.readAddress()
+	parsed.TokenIDs = r.readBigInts()
+	parsed.Values = r.readBigInts()
+	parsed.CallbackData = r.readBytes()
+	parsed.Amounts = r.readBigInts()
+	if isNil, numNested := r.readSliceLength(); !isNil {
+		parsed.NestedAssetData = make([]*ParsedAssetData, 0, numNested)
+		for i := 0; i < numNested && r.err == nil; i++ {
+			parsed.NestedAssetData = append(parsed.NestedAssetData, r.readParsedAssetData())
+		}
+	}
+	if r.version >= 2 {
+		parsed.StaticCallData = r.readBytes()
+		parsed.ExpectedReturnDataHash = r.readHash()
+		parsed.BridgeAddress = r.readAddress()
+		parsed.BridgeData = r.readBytes()
+	}
+	return parsed
+}
+
+func (r *binaryReader) readLog() types.Log {
+	log := types.Log{}
+	log.Address = r.readAddress()
+	if isNil, numTopics := r.readSliceLength(); !isNil {
+		log.Topics = make([]common.Hash, 0, numTopics)
+		for i := 0; i < numTopics && r.err == nil; i++ {
+			log.Topics = append(log.Topics, r.readHash())
+		}
+	}
+	log.Data = r.readBytes()
+	log.BlockNumber = r.readUvarint()
+	log.TxHash = r.readHash()
+	log.TxIndex = uint(r.readUvarint())
+	log.BlockHash = r.readHash()
+	log.Index = uint(r.readUvarint())
+	log.Removed = r.readBool()
+	return log
+}
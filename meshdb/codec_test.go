@@ -0,0 +1,271 @@
+package meshdb
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/db"
+	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/0xProject/0x-mesh/ethereum/miniheader"
+	"github.com/0xProject/0x-mesh/zeroex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOrder(t require.TestingT) *Order {
+	contractAddresses, err := ethereum.GetContractAddressesForChainID(constants.TestChainID)
+	require.NoError(t, err)
+	o := &zeroex.Order{
+		MakerAddress:          constants.GanacheAccount0,
+		TakerAddress:          constants.NullAddress,
+		SenderAddress:         constants.NullAddress,
+		FeeRecipientAddress:   common.HexToAddress("0xa258b39954cef5cb142fd567a46cddb31a670124"),
+		TakerAssetData:        common.Hex2Bytes("f47261b000000000000000000000000034d402f14d58e001d8efbe6585051bf9706aa064"),
+		MakerAssetData:        common.Hex2Bytes("025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001"),
+		Salt:                  big.NewInt(1548619145450),
+		MakerFee:              big.NewInt(0),
+		TakerFee:              big.NewInt(0),
+		MakerAssetAmount:      big.NewInt(3551808554499581700),
+		TakerAssetAmount:      big.NewInt(1),
+		ExpirationTimeSeconds: big.NewInt(1548619325),
+		ExchangeAddress:       contractAddresses.Exchange,
+	}
+	signedOrder, err := zeroex.SignTestOrder(o)
+	require.NoError(t, err)
+	orderHash, err := o.ComputeOrderHash()
+	require.NoError(t, err)
+	// We need to call ResetHash so that unexported hash field is equal in later
+	// assertions.
+	signedOrder.ResetHash()
+	parsedMakerAssetData, err := ParseAssetData(signedOrder.MakerAssetData)
+	require.NoError(t, err)
+	return &Order{
+		Hash:                     orderHash,
+		SignedOrder:              signedOrder,
+		FillableTakerAssetAmount: big.NewInt(1),
+		LastUpdated:              time.Now().UTC(),
+		IsRemoved:                false,
+		IsPinned:                 true,
+		ParsedMakerAssetData:     parsedMakerAssetData,
+	}
+}
+
+func newTestMiniHeader() *miniheader.MiniHeader {
+	return &miniheader.MiniHeader{
+		Hash:      common.HexToHash("0x26b13ac89500f7fcdd141b7d1b30f3a82178431eca325d1cf10998f9d68ff5ba"),
+		Parent:    common.HexToHash("0x3e5d2e4ff0b9ab16c0c5b7d5ee1e1f4eb3a08d0ab0ba4f0d4d2c2c4d6a1e7b8c"),
+		Number:    big.NewInt(5),
+		Timestamp: time.Now().UTC(),
+		Logs: []types.Log{
+			{
+				Address: common.HexToAddress("0x21ab6c9fac80c59d401b37cb43f81ea9dde7fe34"),
+				Topics: []common.Hash{
+					common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"),
+					common.HexToHash("0x0000000000000000000000004d8a4aa1f304f9632cf3877473445d85c577fe5d"),
+				},
+				Data:        common.Hex2Bytes("00000000000000000000000000000000000000000000000000000000337ad34c"),
+				BlockNumber: 5,
+				TxHash:      common.HexToHash("0xac0d6d4f1a5ab4f5e7cbe5a3b1f5d1a9f0fa7f0f2cb2a0bd2d6c3d4b1f4e4a1b"),
+				TxIndex:     2,
+				BlockHash:   common.HexToHash("0x26b13ac89500f7fcdd141b7d1b30f3a82178431eca325d1cf10998f9d68ff5ba"),
+				Index:       7,
+				Removed:     true,
+			},
+		},
+	}
+}
+
+func TestOrderCodecRoundTrip(t *testing.T) {
+	order := newTestOrder(t)
+	data, err := orderCodec{}.Encode(order)
+	require.NoError(t, err)
+	assert.False(t, db.IsJSON(data))
+
+	var decoded Order
+	require.NoError(t, orderCodec{}.Decode(data, &decoded))
+	assert.Equal(t, order, &decoded)
+
+	// The db package decodes into a pointer to a pointer when finding multiple
+	// models.
+	var decodedPtr *Order
+	require.NoError(t, orderCodec{}.Decode(data, &decodedPtr))
+	assert.Equal(t, order, decodedPtr)
+}
+
+func TestOrderCodecDecodesLegacyJSON(t *testing.T) {
+	order := newTestOrder(t)
+	data, err := json.Marshal(order)
+	require.NoError(t, err)
+	var decoded Order
+	require.NoError(t, orderCodec{}.Decode(data, &decoded))
+	assert.Equal(t, order, &decoded)
+}
+
+func TestOrderCodecPopulatesParsedMakerAssetDataForLegacyJSON(t *testing.T) {
+	order := newTestOrder(t)
+	legacyOrder := *order
+	legacyOrder.ParsedMakerAssetData = nil
+	data, err := json.Marshal(legacyOrder)
+	require.NoError(t, err)
+	var decoded *Order
+	require.NoError(t, orderCodec{}.Decode(data, &decoded))
+	assert.Equal(t, order, decoded)
+}
+
+func TestOrderCodecTruncatedData(t *testing.T) {
+	order := newTestOrder(t)
+	data, err := orderCodec{}.Encode(order)
+	require.NoError(t, err)
+	for _, length := range []int{0, 1, 10, len(data) / 2, len(data) - 1} {
+		var decoded Order
+		assert.Error(t, orderCodec{}.Decode(data[:length], &decoded), "expected error when decoding data truncated to length %d", length)
+	}
+}
+
+func TestOrderCodecDecodesVersion1(t *testing.T) {
+	order := newTestOrder(t)
+	data, err := orderCodec{}.Encode(order)
+	require.NoError(t, err)
+
+	// Version 1 is identical except that it doesn't include the StaticCall and
+	// ERC20Bridge fields of ParsedAssetData, which are written last.
+	w := newBinaryWriter()
+	w.writeBytes(nil)
+	w.writeHash(common.Hash{})
+	w.writeAddress(common.Address{})
+	w.writeBytes(nil)
+	v1Data := append([]byte{1}, data[1:len(data)-len(w.bytes())]...)
+
+	var decoded Order
+	require.NoError(t, orderCodec{}.Decode(v1Data, &decoded))
+	assert.Equal(t, order, &decoded)
+
+	unsupportedData := append([]byte{binaryEncodingVersion + 1}, data[1:]...)
+	assert.Error(t, orderCodec{}.Decode(unsupportedData, &decoded))
+}
+
+func TestMiniHeaderCodecRoundTrip(t *testing.T) {
+	testCases := []*miniheader.MiniHeader{
+		newTestMiniHeader(),
+		{
+			Hash:      common.HexToHash("0x1"),
+			Number:    big.NewInt(0),
+			Timestamp: time.Now().UTC(),
+		},
+		{
+			Hash:      common.HexToHash("0x2"),
+			Number:    big.NewInt(1),
+			Timestamp: time.Now().UTC(),
+			Logs:      []types.Log{},
+		},
+	}
+	for _, header := range testCases {
+		data, err := miniHeaderCodec{}.Encode(header)
+		require.NoError(t, err)
+		var decoded miniheader.MiniHeader
+		require.NoError(t, miniHeaderCodec{}.Decode(data, &decoded))
+		assert.Equal(t, header, &decoded)
+	}
+}
+
+func TestMigrateLegacyJSONEncoding(t *testing.T) {
+	path := "/tmp/meshdb_testing/" + uuid.New().String()
+
+	// Store some models using JSON, the way older versions of Mesh did.
+	legacyDB, err := db.Open(path)
+	require.NoError(t, err)
+	legacyOrders, err := legacyDB.NewCollection("order", &Order{})
+	require.NoError(t, err)
+	legacyMiniHeaders, err := legacyDB.NewCollection("miniHeader", &miniheader.MiniHeader{})
+	require.NoError(t, err)
+	// Legacy orders were stored without ParsedMakerAssetData.
+	order := newTestOrder(t)
+	legacyOrder := *order
+	legacyOrder.ParsedMakerAssetData = nil
+	require.NoError(t, legacyOrders.Insert(&legacyOrder))
+	header := newTestMiniHeader()
+	require.NoError(t, legacyMiniHeaders.Insert(header))
+	require.NoError(t, legacyDB.Close())
+
+	meshDB, err := New(path)
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	// All data should have been migrated, which means there is nothing left to
+	// re-encode.
+	numReencoded, err := meshDB.Orders.Reencode(db.IsJSON)
+	require.NoError(t, err)
+	assert.Equal(t, 0, numReencoded)
+	numReencoded, err = meshDB.MiniHeaders.Reencode(db.IsJSON)
+	require.NoError(t, err)
+	assert.Equal(t, 0, numReencoded)
+
+	var foundOrder Order
+	require.NoError(t, meshDB.Orders.FindByID(order.ID(), &foundOrder))
+	assert.Equal(t, order, &foundOrder)
+	var foundHeader miniheader.MiniHeader
+	require.NoError(t, meshDB.MiniHeaders.FindByID(header.ID(), &foundHeader))
+	assert.Equal(t, header, &foundHeader)
+}
+
+func BenchmarkOrderEncodeJSON(b *testing.B) {
+	benchmarkEncode(b, db.JSONCodec{}, newTestOrder(b))
+}
+
+func BenchmarkOrderEncodeBinary(b *testing.B) {
+	benchmarkEncode(b, orderCodec{}, newTestOrder(b))
+}
+
+func BenchmarkOrderDecodeJSON(b *testing.B) {
+	benchmarkDecode(b, db.JSONCodec{}, newTestOrder(b), func() interface{} { return &Order{} })
+}
+
+func BenchmarkOrderDecodeBinary(b *testing.B) {
+	benchmarkDecode(b, orderCodec{}, newTestOrder(b), func() interface{} { return &Order{} })
+}
+
+func BenchmarkMiniHeaderEncodeJSON(b *testing.B) {
+	benchmarkEncode(b, db.JSONCodec{}, newTestMiniHeader())
+}
+
+func BenchmarkMiniHeaderEncodeBinary(b *testing.B) {
+	benchmarkEncode(b, miniHeaderCodec{}, newTestMiniHeader())
+}
+
+func BenchmarkMiniHeaderDecodeJSON(b *testing.B) {
+	benchmarkDecode(b, db.JSONCodec{}, newTestMiniHeader(), func() interface{} { return &miniheader.MiniHeader{} })
+}
+
+func BenchmarkMiniHeaderDecodeBinary(b *testing.B) {
+	benchmarkDecode(b, miniHeaderCodec{}, newTestMiniHeader(), func() interface{} { return &miniheader.MiniHeader{} })
+}
+
+func benchmarkEncode(b *testing.B, codec db.Codec, model db.Model) {
+	b.Helper()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := codec.Encode(model)
+		b.StopTimer()
+		require.NoError(b, err)
+		b.StartTimer()
+	}
+}
+
+func benchmarkDecode(b *testing.B, codec db.Codec, model db.Model, newModel func() interface{}) {
+	b.Helper()
+	data, err := codec.Encode(model)
+	require.NoError(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := codec.Decode(data, newModel())
+		b.StopTimer()
+		require.NoError(b, err)
+		b.StartTimer()
+	}
+}
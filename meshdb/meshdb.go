@@ -1,6 +1,8 @@
 package meshdb
 
 import (
+	"bytes"
+	"container/heap"
 	"errors"
 	"fmt"
 	"math/big"
@@ -32,6 +34,11 @@ type Order struct {
 	// IsPinned indicates whether or not the order is pinned. Pinned orders are
 	// not removed from the database unless they become unfillable.
 	IsPinned bool
+	// ParsedMakerAssetData is the decoded representation of
+	// SignedOrder.MakerAssetData. It is computed once when the order is added so
+	// that indexes and the order watcher don't need to parse the asset data
+	// again.
+	ParsedMakerAssetData *ParsedAssetData
 }
 
 // ID returns the Order's ID
@@ -103,6 +110,10 @@ func New(path string) (*MeshDB, error) {
 		return nil, err
 	}
 
+	if err := migrateLegacyJSONEncoding(orders.Collection, miniHeaders.Collection); err != nil {
+		return nil, err
+	}
+
 	return &MeshDB{
 		database:    database,
 		metadata:    metadata,
@@ -112,7 +123,7 @@ func New(path string) (*MeshDB, error) {
 }
 
 func setupOrders(database *db.DB) (*OrdersCollection, error) {
-	col, err := database.NewCollection("order", &Order{})
+	col, err := database.NewCollectionWithCodec("order", &Order{}, orderCodec{})
 	if err != nil {
 		return nil, err
 	}
@@ -129,18 +140,20 @@ func setupOrders(database *db.DB) (*OrdersCollection, error) {
 		index := []byte(fmt.Sprintf("%s|%s", signedOrder.MakerAddress.Hex(), uint256ToConstantLengthBytes(signedOrder.Salt)))
 		return index
 	})
-	// TODO(fabio): Optimize this index callback since it gets called many times under-the-hood.
-	// We might want to parse the assetData once and store it's components in the DB. The trade-off
-	// here is compute time for storage space.
 	makerAddressTokenAddressTokenIDIndex := col.AddMultiIndex("makerAddressTokenAddressTokenId", func(m db.Model) [][]byte {
 		order := m.(*Order)
-		singleAssetDatas, err := parseContractAddressesAndTokenIdsFromAssetData(order.SignedOrder.MakerAssetData)
+		parsedMakerAssetData, err := order.GetParsedMakerAssetData()
 		if err != nil {
+			// This should never happen since orders with invalid asset data are
+			// rejected before they are stored. If it does, the order simply won't be
+			// included in this index.
 			log.WithFields(log.Fields{
-				"error": err.Error(),
-			}).Panic("Parsing assetData failed")
+				"error":     err.Error(),
+				"orderHash": order.Hash.Hex(),
+			}).Error("Parsing assetData failed")
+			return nil
 		}
-
+		singleAssetDatas := parsedMakerAssetData.singleAssetDatas()
 		indexValues := make([][]byte, len(singleAssetDatas))
 		for i, singleAssetData := range singleAssetDatas {
 			indexValue := []byte(order.SignedOrder.MakerAddress.Hex() + "|" + singleAssetData.Address.Hex() + "|")
@@ -184,7 +197,7 @@ func setupOrders(database *db.DB) (*OrdersCollection, error) {
 }
 
 func setupMiniHeaders(database *db.DB) (*MiniHeadersCollection, error) {
-	col, err := database.NewCollection("miniHeader", &miniheader.MiniHeader{})
+	col, err := database.NewCollectionWithCodec("miniHeader", &miniheader.MiniHeader{}, miniHeaderCodec{})
 	if err != nil {
 		return nil, err
 	}
@@ -211,11 +224,68 @@ func setupMetadata(database *db.DB) (*MetadataCollection, error) {
 	return &MetadataCollection{col}, nil
 }
 
+// migrateLegacyJSONEncoding re-encodes any models in the given collections
+// that were stored using JSON (before binary codecs were introduced) so that
+// they use the binary encoding for the collection. It is a no-op for
+// collections that don't contain any JSON-encoded models.
+func migrateLegacyJSONEncoding(cols ...*db.Collection) error {
+	for _, col := range cols {
+		numReencoded, err := col.Reencode(db.IsJSON)
+		if err != nil {
+			return err
+		}
+		if numReencoded > 0 {
+			log.WithFields(log.Fields{
+				"collection":   col.Name(),
+				"numReencoded": numReencoded,
+			}).Info("migrated legacy JSON-encoded models to binary encoding")
+		}
+	}
+	return nil
+}
+
 // Close closes the database connection
 func (m *MeshDB) Close() {
 	m.database.Close()
 }
 
+// Backup writes a consistent copy of all collections to a new database at the
+// given path. It is safe to call while the database is in use. The backup can
+// be restored by using it as the database path (e.g. by moving it to
+// `DataDir/db`) and calling New.
+func (m *MeshDB) Backup(path string) error {
+	log.WithField("path", path).Info("starting database backup")
+	start := time.Now()
+	numKeys, err := m.database.Backup(path)
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"path":     path,
+		"numKeys":  numKeys,
+		"duration": time.Since(start).String(),
+	}).Info("finished database backup")
+	return nil
+}
+
+// Compact compacts the underlying storage for orders and mini headers. This
+// discards data left behind by removed orders and old block headers, which
+// would otherwise cause the database to keep growing.
+func (m *MeshDB) Compact() error {
+	for _, col := range []*db.Collection{m.Orders.Collection, m.MiniHeaders.Collection} {
+		log.WithField("collection", col.Name()).Info("compacting database collection")
+		start := time.Now()
+		if err := col.Compact(); err != nil {
+			return err
+		}
+		log.WithFields(log.Fields{
+			"collection": col.Name(),
+			"duration":   time.Since(start).String(),
+		}).Info("finished compacting database collection")
+	}
+	return nil
+}
+
 // FindAllMiniHeadersSortedByNumber returns all MiniHeaders sorted by block number
 func (m *MeshDB) FindAllMiniHeadersSortedByNumber() ([]*miniheader.MiniHeader, error) {
 	miniHeaders := []*miniheader.MiniHeader{}
@@ -367,73 +437,6 @@ func (m *MeshDB) UpdateMetadata(updater func(oldmetadata Metadata) (newMetadata
 	return txn.Commit()
 }
 
-type singleAssetData struct {
-	Address common.Address
-	TokenID *big.Int
-}
-
-func parseContractAddressesAndTokenIdsFromAssetData(assetData []byte) ([]singleAssetData, error) {
-	singleAssetDatas := []singleAssetData{}
-	assetDataDecoder := zeroex.NewAssetDataDecoder()
-
-	assetDataName, err := assetDataDecoder.GetName(assetData)
-	if err != nil {
-		return nil, err
-	}
-	switch assetDataName {
-	case "ERC20Token":
-		var decodedAssetData zeroex.ERC20AssetData
-		err := assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return nil, err
-		}
-		a := singleAssetData{
-			Address: decodedAssetData.Address,
-		}
-		singleAssetDatas = append(singleAssetDatas, a)
-	case "ERC721Token":
-		var decodedAssetData zeroex.ERC721AssetData
-		err := assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return nil, err
-		}
-		a := singleAssetData{
-			Address: decodedAssetData.Address,
-			TokenID: decodedAssetData.TokenId,
-		}
-		singleAssetDatas = append(singleAssetDatas, a)
-	case "ERC1155Assets":
-		var decodedAssetData zeroex.ERC1155AssetData
-		err := assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return nil, err
-		}
-		for _, id := range decodedAssetData.Ids {
-			a := singleAssetData{
-				Address: decodedAssetData.Address,
-				TokenID: id,
-			}
-			singleAssetDatas = append(singleAssetDatas, a)
-		}
-	case "MultiAsset":
-		var decodedAssetData zeroex.MultiAssetData
-		err := assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return nil, err
-		}
-		for _, assetData := range decodedAssetData.NestedAssetData {
-			as, err := parseContractAddressesAndTokenIdsFromAssetData(assetData)
-			if err != nil {
-				return nil, err
-			}
-			singleAssetDatas = append(singleAssetDatas, as...)
-		}
-	default:
-		return nil, fmt.Errorf("unrecognized assetData type name found: %s", assetDataName)
-	}
-	return singleAssetDatas, nil
-}
-
 func uint256ToConstantLengthBytes(v *big.Int) []byte {
 	return []byte(fmt.Sprintf("%080s", v.String()))
 }
@@ -494,6 +497,114 @@ func (m *MeshDB) TrimOrdersByExpirationTime(targetMaxOrders int) (newMaxExpirati
 	return newMaxExpirationTime, removedOrders, nil
 }
 
+// TrimOrdersByMaker removes existing orders until the number of remaining
+// orders is <= targetMaxOrders. Unlike TrimOrdersByExpirationTime, it tries to
+// give each maker a fair share of the available space: orders are always
+// removed from whichever maker currently has the most non-pinned orders, and
+// for each maker the orders with the highest expiration time are removed first.
+// It returns any orders that were removed.
+func (m *MeshDB) TrimOrdersByMaker(targetMaxOrders int) (removedOrders []*Order, err error) {
+	txn := m.Orders.OpenTransaction()
+	defer func() {
+		_ = txn.Discard()
+	}()
+
+	numOrders, err := m.Orders.Count()
+	if err != nil {
+		return nil, err
+	}
+	if numOrders <= targetMaxOrders {
+		return nil, nil
+	}
+
+	// Find all non-pinned orders sorted by expiration time in descending order
+	// and then group them by maker. Since the orders are already sorted, the
+	// orders for each maker will also be sorted by expiration time in descending
+	// order.
+	var unpinnedOrders []*Order
+	filter := m.Orders.ExpirationTimeIndex.PrefixFilter([]byte("0|"))
+	if err := m.Orders.NewQuery(filter).Reverse().Run(&unpinnedOrders); err != nil {
+		return nil, err
+	}
+	makerToOrders := map[common.Address][]*Order{}
+	for _, order := range unpinnedOrders {
+		makerAddress := order.SignedOrder.MakerAddress
+		makerToOrders[makerAddress] = append(makerToOrders[makerAddress], order)
+	}
+	makers := &makerOrdersHeap{}
+	for makerAddress, orders := range makerToOrders {
+		*makers = append(*makers, &makerOrders{makerAddress: makerAddress, orders: orders})
+	}
+	heap.Init(makers)
+
+	// Repeatedly remove the order with the highest expiration time from the
+	// maker with the most orders.
+	numOrdersToRemove := numOrders - targetMaxOrders
+	for len(removedOrders) < numOrdersToRemove && makers.Len() > 0 {
+		largest := (*makers)[0]
+		removedOrders = append(removedOrders, largest.orders[0])
+		largest.orders = largest.orders[1:]
+		if len(largest.orders) == 0 {
+			heap.Pop(makers)
+		} else {
+			heap.Fix(makers, 0)
+		}
+	}
+
+	// Remove those orders and commit the transaction.
+	for _, order := range removedOrders {
+		if err := txn.Delete(order.Hash.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err := txn.Commit(); err != nil {
+		return nil, err
+	}
+
+	// If we could not remove numOrdersToRemove orders than it means the database
+	// is full of pinned orders. We still remove as many orders as we can and then
+	// return an error.
+	if len(removedOrders) < numOrdersToRemove {
+		return nil, ErrDBFilledWithPinnedOrders
+	}
+	return removedOrders, nil
+}
+
+// makerOrders holds the non-pinned orders for a single maker, sorted by
+// expiration time in descending order.
+type makerOrders struct {
+	makerAddress common.Address
+	orders       []*Order
+}
+
+// makerOrdersHeap is a max-heap of makerOrders ordered by the number of orders.
+// It implements heap.Interface.
+type makerOrdersHeap []*makerOrders
+
+func (h makerOrdersHeap) Len() int { return len(h) }
+
+func (h makerOrdersHeap) Less(i, j int) bool {
+	if len(h[i].orders) == len(h[j].orders) {
+		// Break ties by address so that the results are deterministic.
+		return bytes.Compare(h[i].makerAddress.Bytes(), h[j].makerAddress.Bytes()) == -1
+	}
+	return len(h[i].orders) > len(h[j].orders)
+}
+
+func (h makerOrdersHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *makerOrdersHeap) Push(x interface{}) {
+	*h = append(*h, x.(*makerOrders))
+}
+
+func (h *makerOrdersHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
 // CountPinnedOrders returns the number of pinned orders.
 func (m *MeshDB) CountPinnedOrders() (int, error) {
 	// We use a prefix filter of "1|" so that we only count pinned orders.
@@ -95,11 +95,12 @@ func TestOrderCRUDOperations(t *testing.T) {
 	assert.IsType(t, db.NotFoundError{}, err)
 }
 
-func TestParseContractAddressesAndTokenIdsFromAssetData(t *testing.T) {
+func TestParseAssetDataSingleAssetDatas(t *testing.T) {
 	// ERC20 AssetData
 	erc20AssetData := common.Hex2Bytes("f47261b000000000000000000000000038ae374ecf4db50b0ff37125b591a04997106a32")
-	singleAssetDatas, err := parseContractAddressesAndTokenIdsFromAssetData(erc20AssetData)
+	parsed, err := ParseAssetData(erc20AssetData)
 	require.NoError(t, err)
+	singleAssetDatas := parsed.singleAssetDatas()
 	assert.Len(t, singleAssetDatas, 1)
 	expectedAddress := common.HexToAddress("0x38ae374ecf4db50b0ff37125b591a04997106a32")
 	assert.Equal(t, expectedAddress, singleAssetDatas[0].Address)
@@ -108,8 +109,9 @@ func TestParseContractAddressesAndTokenIdsFromAssetData(t *testing.T) {
 
 	// ERC721 AssetData
 	erc721AssetData := common.Hex2Bytes("025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001")
-	singleAssetDatas, err = parseContractAddressesAndTokenIdsFromAssetData(erc721AssetData)
+	parsed, err = ParseAssetData(erc721AssetData)
 	require.NoError(t, err)
+	singleAssetDatas = parsed.singleAssetDatas()
 	assert.Equal(t, 1, len(singleAssetDatas))
 	expectedAddress = common.HexToAddress("0x1dC4c1cEFEF38a777b15aA20260a54E584b16C48")
 	assert.Equal(t, expectedAddress, singleAssetDatas[0].Address)
@@ -118,8 +120,9 @@ func TestParseContractAddressesAndTokenIdsFromAssetData(t *testing.T) {
 
 	// Multi AssetData
 	multiAssetData := common.Hex2Bytes("94cfcdd7000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004600000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000024f47261b00000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000x94cfcdd7000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004600000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000024f47261b00000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000")
-	singleAssetDatas, err = parseContractAddressesAndTokenIdsFromAssetData(multiAssetData)
+	parsed, err = ParseAssetData(multiAssetData)
 	require.NoError(t, err)
+	singleAssetDatas = parsed.singleAssetDatas()
 	assert.Equal(t, 2, len(singleAssetDatas))
 	expectedSingleAssetDatas := []singleAssetData{
 		singleAssetData{
@@ -285,6 +288,88 @@ func TestTrimOrdersByExpirationTime(t *testing.T) {
 	assert.EqualError(t, err, ErrDBFilledWithPinnedOrders.Error(), "expected ErrFilledWithPinnedOrders when targetMaxOrders is less than the number of pinned orders")
 }
 
+func TestTrimOrdersByMaker(t *testing.T) {
+	meshDB, err := New("/tmp/meshdb_testing/" + uuid.New().String())
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	contractAddresses, err := ethereum.GetContractAddressesForChainID(constants.TestChainID)
+	require.NoError(t, err)
+	newRawOrder := func(makerAddress common.Address, expirationTime int64) *zeroex.Order {
+		return &zeroex.Order{
+			MakerAddress:          makerAddress,
+			TakerAddress:          constants.NullAddress,
+			SenderAddress:         constants.NullAddress,
+			FeeRecipientAddress:   common.HexToAddress("0xa258b39954cef5cb142fd567a46cddb31a670124"),
+			TakerAssetData:        common.Hex2Bytes("f47261b000000000000000000000000034d402f14d58e001d8efbe6585051bf9706aa064"),
+			MakerAssetData:        common.Hex2Bytes("025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001"),
+			Salt:                  big.NewInt(expirationTime),
+			MakerFee:              big.NewInt(0),
+			TakerFee:              big.NewInt(0),
+			MakerAssetAmount:      big.NewInt(3551808554499581700),
+			TakerAssetAmount:      big.NewInt(1),
+			ExpirationTimeSeconds: big.NewInt(expirationTime),
+			ExchangeAddress:       contractAddresses.Exchange,
+		}
+	}
+
+	// The spammy maker has many more orders than anyone else. Note that the
+	// spammy maker's orders have the lowest expiration times, which means they
+	// would never be removed by TrimOrdersByExpirationTime.
+	spammyMaker := constants.GanacheAccount0
+	rawSpammyOrders := []*zeroex.Order{}
+	for i := int64(1); i <= 5; i++ {
+		rawSpammyOrders = append(rawSpammyOrders, newRawOrder(spammyMaker, i*100))
+	}
+	spammyOrders := insertRawOrders(t, meshDB, rawSpammyOrders, false)
+	otherMaker := constants.GanacheAccount1
+	otherOrders := insertRawOrders(t, meshDB, []*zeroex.Order{
+		newRawOrder(otherMaker, 1000),
+		newRawOrder(otherMaker, 2000),
+	}, false)
+	pinnedOrders := insertRawOrders(t, meshDB, []*zeroex.Order{
+		newRawOrder(constants.GanacheAccount2, 3000),
+	}, true)
+
+	// The first orders to be removed should all belong to the spammy maker and
+	// have the highest expiration times.
+	removedOrders, err := meshDB.TrimOrdersByMaker(5)
+	require.NoError(t, err)
+	expectedRemovedOrders := []*Order{spammyOrders[4], spammyOrders[3], spammyOrders[2]}
+	assertOrderHashesEqual(t, expectedRemovedOrders, removedOrders)
+
+	// Now both makers have the same number of orders, so orders should be
+	// removed from each in turn.
+	removedOrders, err = meshDB.TrimOrdersByMaker(3)
+	require.NoError(t, err)
+	expectedRemovedOrders = []*Order{spammyOrders[1], otherOrders[1]}
+	assertOrderHashesEqual(t, expectedRemovedOrders, removedOrders)
+
+	var remainingOrders []*Order
+	require.NoError(t, meshDB.Orders.FindAll(&remainingOrders))
+	assert.Len(t, remainingOrders, 3, "wrong number of orders remaining")
+	for _, pinnedOrder := range pinnedOrders {
+		require.NoError(t, meshDB.Orders.FindByID(pinnedOrder.Hash.Bytes(), &Order{}))
+	}
+
+	// Trying to trim orders when the database is full of pinned orders should
+	// return an error.
+	_, err = meshDB.TrimOrdersByMaker(0)
+	assert.EqualError(t, err, ErrDBFilledWithPinnedOrders.Error(), "expected ErrFilledWithPinnedOrders when targetMaxOrders is less than the number of pinned orders")
+}
+
+func assertOrderHashesEqual(t *testing.T, expected []*Order, actual []*Order) {
+	expectedHashes := make([]common.Hash, len(expected))
+	for i, order := range expected {
+		expectedHashes[i] = order.Hash
+	}
+	actualHashes := make([]common.Hash, len(actual))
+	for i, order := range actual {
+		actualHashes[i] = order.Hash
+	}
+	assert.Equal(t, expectedHashes, actualHashes)
+}
+
 func insertRawOrders(t *testing.T, meshDB *MeshDB, rawOrders []*zeroex.Order, isPinned bool) []*Order {
 	results := make([]*Order, len(rawOrders))
 	for i, order := range rawOrders {
@@ -307,3 +392,64 @@ func insertRawOrders(t *testing.T, meshDB *MeshDB, rawOrders []*zeroex.Order, is
 	}
 	return results
 }
+
+func TestBackupAndRestore(t *testing.T) {
+	meshDB, err := New("/tmp/meshdb_testing/" + uuid.New().String())
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	order := newTestOrder(t)
+	require.NoError(t, meshDB.Orders.Insert(order))
+	header := newTestMiniHeader()
+	require.NoError(t, meshDB.MiniHeaders.Insert(header))
+	metadata := &Metadata{
+		EthereumChainID:                   constants.TestChainID,
+		MaxExpirationTime:                 big.NewInt(1548619325),
+		EthRPCRequestsSentInCurrentUTCDay: 5,
+		StartOfCurrentUTCDay:              time.Now().UTC().Truncate(24 * time.Hour),
+	}
+	require.NoError(t, meshDB.SaveMetadata(metadata))
+
+	backupPath := "/tmp/meshdb_testing/" + uuid.New().String()
+	require.NoError(t, meshDB.Backup(backupPath))
+
+	// Changes made after the backup should not be included in the backup.
+	require.NoError(t, meshDB.Orders.Delete(order.ID()))
+
+	// Restore the backup into a fresh MeshDB.
+	restoredDB, err := New(backupPath)
+	require.NoError(t, err)
+	defer restoredDB.Close()
+
+	var foundOrders []*Order
+	require.NoError(t, restoredDB.Orders.FindAll(&foundOrders))
+	assert.Equal(t, []*Order{order}, foundOrders)
+	foundOrdersByMaker, err := restoredDB.FindOrdersByMakerAddress(order.SignedOrder.MakerAddress)
+	require.NoError(t, err)
+	assert.Equal(t, []*Order{order}, foundOrdersByMaker)
+	foundHeader, err := restoredDB.FindLatestMiniHeader()
+	require.NoError(t, err)
+	assert.Equal(t, header, foundHeader)
+	foundMetadata, err := restoredDB.GetMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, metadata, foundMetadata)
+}
+
+func TestCompact(t *testing.T) {
+	meshDB, err := New("/tmp/meshdb_testing/" + uuid.New().String())
+	require.NoError(t, err)
+	defer meshDB.Close()
+
+	order := newTestOrder(t)
+	require.NoError(t, meshDB.Orders.Insert(order))
+	removedOrder := newTestOrder(t)
+	removedOrder.Hash = common.HexToHash("0x1")
+	require.NoError(t, meshDB.Orders.Insert(removedOrder))
+	require.NoError(t, meshDB.Orders.Delete(removedOrder.ID()))
+
+	require.NoError(t, meshDB.Compact())
+
+	var foundOrders []*Order
+	require.NoError(t, meshDB.Orders.FindAll(&foundOrders))
+	assert.Equal(t, []*Order{order}, foundOrders)
+}
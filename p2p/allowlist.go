@@ -0,0 +1,69 @@
+package p2p
+
+import (
+	"context"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	log "github.com/sirupsen/logrus"
+)
+
+// peerAllowlist is the set of peers which the Node is allowed to connect to
+// and receive messages from. An empty allowlist allows all peers.
+type peerAllowlist map[peer.ID]struct{}
+
+func newPeerAllowlist(ids []peer.ID) peerAllowlist {
+	allowlist := peerAllowlist{}
+	for _, id := range ids {
+		allowlist[id] = struct{}{}
+	}
+	return allowlist
+}
+
+// isAllowed returns true if the given peer is allowed.
+func (allowlist peerAllowlist) isAllowed(id peer.ID) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	_, found := allowlist[id]
+	return found
+}
+
+// getPeerAllowlist returns the allowlist for the given config. If
+// config.PeerAllowlist is not empty, the peers in config.BootstrapList are
+// added to it so that the Node can still connect to its bootstrap peers.
+func getPeerAllowlist(config Config) (peerAllowlist, error) {
+	allowlist := newPeerAllowlist(config.PeerAllowlist)
+	if len(allowlist) == 0 || !config.UseBootstrapList {
+		return allowlist, nil
+	}
+	bootstrapList := config.BootstrapList
+	if len(bootstrapList) == 0 {
+		bootstrapList = DefaultBootstrapList
+	}
+	bootstrapAddrInfos, err := BootstrapListToAddrInfos(bootstrapList)
+	if err != nil {
+		return nil, err
+	}
+	for _, addrInfo := range bootstrapAddrInfos {
+		allowlist[addrInfo.ID] = struct{}{}
+	}
+	return allowlist, nil
+}
+
+// newAllowlistValidator returns a pubsub validator which rejects messages
+// created by peers which are not in the allowlist and otherwise calls next.
+// Without it, an allowed peer could forward messages from peers which we
+// aren't allowed to connect to.
+func newAllowlistValidator(allowlist peerAllowlist, next pubsub.Validator) pubsub.Validator {
+	return func(ctx context.Context, peerID peer.ID, msg *pubsub.Message) bool {
+		if !allowlist.isAllowed(msg.GetFrom()) {
+			log.WithFields(map[string]interface{}{
+				"from":         msg.GetFrom(),
+				"remotePeerID": peerID,
+			}).Trace("rejecting message from peer which is not in the allowlist")
+			return false
+		}
+		return next(ctx, peerID, msg)
+	}
+}
@@ -0,0 +1,65 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	p2pnet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerAllowlist(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	allowedNode := newTestNode(t, ctx, nil)
+	otherNode := newTestNode(t, ctx, nil)
+	disconnected := make(chan peer.ID, 1)
+	node0 := newTestPrivateNode(t, ctx, &p2pnet.NotifyBundle{
+		DisconnectedF: func(_ p2pnet.Network, conn p2pnet.Conn) {
+			select {
+			case disconnected <- conn.RemotePeer():
+			default:
+			}
+		},
+	}, nil, []peer.ID{allowedNode.ID()})
+
+	// Connections from peers which are not in the allowlist should be closed.
+	_ = otherNode.Connect(peer.AddrInfo{ID: node0.ID(), Addrs: node0.Multiaddrs()}, testConnectionTimeout)
+	select {
+	case id := <-disconnected:
+		assert.Equal(t, otherNode.ID(), id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for node0 to disconnect from otherNode")
+	}
+
+	// Connections from allowed peers should stay open.
+	connectTestNodes(t, allowedNode, node0)
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, p2pnet.Connected, node0.host.Network().Connectedness(allowedNode.ID()))
+}
+
+func TestPeerAllowlistIncludesBootstrapPeers(t *testing.T) {
+	t.Parallel()
+	allowedID := newTestPeerID(t)
+	bootstrapID := newTestPeerID(t)
+	allowlist, err := getPeerAllowlist(Config{
+		PeerAllowlist:    []peer.ID{allowedID},
+		UseBootstrapList: true,
+		BootstrapList:    []string{"/ip4/127.0.0.1/tcp/60558/ipfs/" + bootstrapID.Pretty()},
+	})
+	require.NoError(t, err)
+	assert.True(t, allowlist.isAllowed(allowedID))
+	assert.True(t, allowlist.isAllowed(bootstrapID))
+	assert.False(t, allowlist.isAllowed(newTestPeerID(t)))
+
+	// An empty allowlist allows all peers.
+	allowlist, err = getPeerAllowlist(Config{UseBootstrapList: true})
+	require.NoError(t, err)
+	assert.True(t, allowlist.isAllowed(newTestPeerID(t)))
+}
@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/albrow/stringset"
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/metrics"
+	"github.com/libp2p/go-libp2p-core/peer"
 	filter "github.com/libp2p/go-maddr-filter"
 	ma "github.com/multiformats/go-multiaddr"
 	log "github.com/sirupsen/logrus"
@@ -28,6 +30,9 @@ const (
 	// violationsTTL is the TTL for bandwidth violations. If a peer does not have
 	// any violations during this timespan, their violation count will be reset.
 	violationsTTL = 1 * time.Hour
+	// banExpirationCheckInterval is how often to check for and remove bans which
+	// have expired.
+	banExpirationCheckInterval = 1 * time.Minute
 )
 
 var ErrProtectedIP = errors.New("cannot ban protected IP address")
@@ -37,6 +42,10 @@ type Banner struct {
 	protectedIPsMut sync.RWMutex
 	protectedIPs    stringset.Set
 	violations      *violationsTracker
+	bansMut         sync.Mutex
+	// bans maps the string representation of each banned IP address to its
+	// ban.
+	bans map[string]Ban
 }
 
 type Config struct {
@@ -45,6 +54,22 @@ type Config struct {
 	BandwidthCounter       *metrics.BandwidthCounter
 	MaxBytesPerSecond      float64
 	LogBandwidthUsageStats bool
+	// BanDuration is how long IP addresses remain banned after calling BanIP.
+	// If it is zero, bans never expire.
+	BanDuration time.Duration
+}
+
+// Ban is an IP address which has been banned.
+type Ban struct {
+	IP net.IP `json:"ip"`
+	// Expiration is the time at which the ban expires. The zero value means
+	// that the ban never expires.
+	Expiration time.Time `json:"expiration"`
+}
+
+// isExpired returns true if the ban has expired as of the given time.
+func (ban Ban) isExpired(now time.Time) bool {
+	return !ban.Expiration.IsZero() && !now.Before(ban.Expiration)
 }
 
 func New(ctx context.Context, config Config) *Banner {
@@ -52,10 +77,12 @@ func New(ctx context.Context, config Config) *Banner {
 		config:       config,
 		protectedIPs: stringset.New(),
 		violations:   newViolationsTracker(ctx),
+		bans:         map[string]Ban{},
 	}
 	if config.LogBandwidthUsageStats {
 		go banner.continuouslyLogBandwidthUsage(ctx)
 	}
+	go banner.continuouslyRemoveExpiredBans(ctx)
 	return banner
 }
 
@@ -76,11 +103,24 @@ func (banner *Banner) ProtectIP(maddr ma.Multiaddr) error {
 }
 
 // BanIP adds the IP address of the given Multiaddr to the blacklist. The
-// node will no longer dial or accept connections from this IP address. However,
-// if the IP address is protected, calling BanIP will not ban the IP address and
-// will instead return errProtectedIP. BanIP does not automatically disconnect
-// from the given multiaddress if there is currently an open connection.
+// node will no longer dial or accept connections from this IP address until
+// config.BanDuration has passed. However, if the IP address is protected,
+// calling BanIP will not ban the IP address and will instead return
+// errProtectedIP. BanIP does not automatically disconnect from the given
+// multiaddress if there is currently an open connection.
 func (banner *Banner) BanIP(maddr ma.Multiaddr) error {
+	var expiration time.Time
+	if banner.config.BanDuration != 0 {
+		expiration = time.Now().Add(banner.config.BanDuration)
+	}
+	return banner.BanIPUntil(maddr, expiration)
+}
+
+// BanIPUntil is like BanIP but the ban expires at the given time instead of
+// after config.BanDuration. The zero value means the ban never expires. If the
+// IP address is already banned, the expiration of the existing ban is
+// replaced.
+func (banner *Banner) BanIPUntil(maddr ma.Multiaddr, expiration time.Time) error {
 	ipNet, err := ipNetFromMaddr(maddr)
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -89,13 +129,61 @@ func (banner *Banner) BanIP(maddr ma.Multiaddr) error {
 		}).Error("could not get IP address from multiaddress")
 		return err
 	}
+	return banner.banIPNet(ipNet, expiration)
+}
+
+// RestoreBan re-applies a ban which was previously returned by Bans. It is
+// used to restore bans after restarting. Bans which have already expired are
+// ignored.
+func (banner *Banner) RestoreBan(ban Ban) error {
+	if ban.isExpired(time.Now()) {
+		return nil
+	}
+	ip := ban.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		// Use the same representation as ipFromMaddr so that the ban can be
+		// removed by UnbanIP.
+		ip = ip4
+	} else if ip.To16() == nil {
+		return fmt.Errorf("invalid IP address in ban: %s", ban.IP)
+	}
+	return banner.banIPNet(net.IPNet{
+		IP:   ip,
+		Mask: getAllMaskForIP(ip),
+	}, ban.Expiration)
+}
+
+// Bans returns all IP addresses which are currently banned, sorted by IP
+// address.
+func (banner *Banner) Bans() []Ban {
+	banner.bansMut.Lock()
+	defer banner.bansMut.Unlock()
+	bans := make([]Ban, 0, len(banner.bans))
+	for _, ban := range banner.bans {
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].IP.String() < bans[j].IP.String()
+	})
+	return bans
+}
+
+func (banner *Banner) banIPNet(ipNet net.IPNet, expiration time.Time) error {
 	banner.protectedIPsMut.RLock()
 	defer banner.protectedIPsMut.RUnlock()
 	if banner.protectedIPs.Contains(ipNet.IP.String()) {
 		// IP address is protected. no-op.
 		return ErrProtectedIP
 	}
-	banner.config.Filters.AddFilter(ipNet, filter.ActionDeny)
+	banner.bansMut.Lock()
+	defer banner.bansMut.Unlock()
+	if _, alreadyBanned := banner.bans[ipNet.IP.String()]; !alreadyBanned {
+		banner.config.Filters.AddFilter(ipNet, filter.ActionDeny)
+	}
+	banner.bans[ipNet.IP.String()] = Ban{
+		IP:         ipNet.IP,
+		Expiration: expiration,
+	}
 	return nil
 }
 
@@ -114,11 +202,25 @@ func (banner *Banner) IsAddrBanned(maddr ma.Multiaddr) bool {
 	return banner.config.Filters.AddrBlocked(maddr)
 }
 
+// GetBandwidthForPeer returns the bandwidth used to communicate with the given
+// peer, as recorded by config.BandwidthCounter.
+func (banner *Banner) GetBandwidthForPeer(id peer.ID) metrics.Stats {
+	return banner.config.BandwidthCounter.GetBandwidthForPeer(id)
+}
+
 func (banner *Banner) SetMaxBytesPerSecond(limit float64) {
 	banner.config.MaxBytesPerSecond = limit
 }
 
 func (banner *Banner) unbanIPNet(ipNet net.IPNet) {
+	banner.bansMut.Lock()
+	defer banner.bansMut.Unlock()
+	banner.removeBan(ipNet)
+}
+
+// removeBan removes the ban for the given IPNet. bansMut must be held.
+func (banner *Banner) removeBan(ipNet net.IPNet) {
+	delete(banner.bans, ipNet.IP.String())
 	// There is no guarantee in the public API of the filters package that would
 	// prevent multiple filters being added for the same IPNet (though it
 	// shouldn't happen in practice). We use a for loop here to make sure we
@@ -128,6 +230,38 @@ func (banner *Banner) unbanIPNet(ipNet net.IPNet) {
 	}
 }
 
+func (banner *Banner) continuouslyRemoveExpiredBans(ctx context.Context) {
+	ticker := time.NewTicker(banExpirationCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			banner.removeExpiredBans(now)
+		}
+	}
+}
+
+// removeExpiredBans unbans all IP addresses whose bans have expired as of the
+// given time.
+func (banner *Banner) removeExpiredBans(now time.Time) {
+	banner.bansMut.Lock()
+	defer banner.bansMut.Unlock()
+	for _, ban := range banner.bans {
+		if ban.isExpired(now) {
+			log.WithFields(log.Fields{
+				"ip":         ban.IP.String(),
+				"expiration": ban.Expiration,
+			}).Debug("ban expired")
+			banner.removeBan(net.IPNet{
+				IP:   ban.IP,
+				Mask: getAllMaskForIP(ban.IP),
+			})
+		}
+	}
+}
+
 func (banner *Banner) continuouslyLogBandwidthUsage(ctx context.Context) {
 	logTicker := time.Tick(logBandwidthUsageInterval)
 	for {
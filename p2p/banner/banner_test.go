@@ -1,9 +1,13 @@
 package banner
 
 import (
+	"context"
+	"encoding/json"
 	"net"
 	"testing"
+	"time"
 
+	filter "github.com/libp2p/go-maddr-filter"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -56,3 +60,83 @@ func newMaddr(t *testing.T, s string) ma.Multiaddr {
 	require.NoError(t, err)
 	return maddr
 }
+
+func TestBanIPExpiration(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	banner := newTestBanner(ctx, time.Hour)
+
+	maddr := newMaddr(t, "/ip4/159.65.4.82/tcp/60558")
+	require.NoError(t, banner.BanIP(maddr))
+	assert.True(t, banner.IsAddrBanned(maddr))
+	bans := banner.Bans()
+	require.Len(t, bans, 1)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), bans[0].Expiration, time.Minute)
+
+	// The ban should not be removed before it expires.
+	banner.removeExpiredBans(time.Now())
+	assert.True(t, banner.IsAddrBanned(maddr))
+	banner.removeExpiredBans(time.Now().Add(2 * time.Hour))
+	assert.False(t, banner.IsAddrBanned(maddr))
+	assert.Empty(t, banner.Bans())
+}
+
+func TestBanIPWithoutExpiration(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	banner := newTestBanner(ctx, 0)
+
+	maddr := newMaddr(t, "/ip6/fe80:cd00:0000:0cde:1257:0000:211e:729c/tcp/60558")
+	require.NoError(t, banner.BanIP(maddr))
+	banner.removeExpiredBans(time.Now().Add(1000 * time.Hour))
+	assert.True(t, banner.IsAddrBanned(maddr))
+	require.NoError(t, banner.UnbanIP(maddr))
+	assert.False(t, banner.IsAddrBanned(maddr))
+	assert.Empty(t, banner.Bans())
+}
+
+func TestRestoreBan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	oldBanner := newTestBanner(ctx, time.Hour)
+	bannedMaddr := newMaddr(t, "/ip4/159.65.4.82/tcp/60558")
+	require.NoError(t, oldBanner.BanIP(bannedMaddr))
+	expiredMaddr := newMaddr(t, "/ip4/159.65.4.83/tcp/60558")
+	require.NoError(t, oldBanner.BanIPUntil(expiredMaddr, time.Now().Add(-time.Second)))
+
+	// Round trip the bans through JSON, which is how they are persisted.
+	encoded, err := json.Marshal(oldBanner.Bans())
+	require.NoError(t, err)
+	var bans []Ban
+	require.NoError(t, json.Unmarshal(encoded, &bans))
+
+	newBanner := newTestBanner(ctx, time.Hour)
+	for _, ban := range bans {
+		require.NoError(t, newBanner.RestoreBan(ban))
+	}
+	assert.True(t, newBanner.IsAddrBanned(bannedMaddr))
+	assert.False(t, newBanner.IsAddrBanned(expiredMaddr))
+
+	// Restored bans can be removed with UnbanIP.
+	require.NoError(t, newBanner.UnbanIP(bannedMaddr))
+	assert.False(t, newBanner.IsAddrBanned(bannedMaddr))
+	assert.Empty(t, newBanner.Bans())
+}
+
+func TestRestoreBanProtectedIP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	banner := newTestBanner(ctx, time.Hour)
+	maddr := newMaddr(t, "/ip4/159.65.4.82/tcp/60558")
+	require.NoError(t, banner.ProtectIP(maddr))
+	err := banner.RestoreBan(Ban{IP: net.ParseIP("159.65.4.82"), Expiration: time.Now().Add(time.Hour)})
+	assert.Equal(t, ErrProtectedIP, err)
+	assert.False(t, banner.IsAddrBanned(maddr))
+}
+
+func newTestBanner(ctx context.Context, banDuration time.Duration) *Banner {
+	return New(ctx, Config{
+		Filters:     filter.NewFilters(),
+		BanDuration: banDuration,
+	})
+}
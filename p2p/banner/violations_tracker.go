@@ -3,7 +3,7 @@ package banner
 import (
 	"context"
 
-	"github.com/karlseguin/ccache"
+	"github.com/0xProject/0x-mesh/p2p/ttlcache"
 	"github.com/libp2p/go-libp2p-core/peer"
 )
 
@@ -13,24 +13,15 @@ import (
 //
 // TODO(albrow): Could potentially remove this if the issue is resolved.
 type violationsTracker struct {
-	cache *ccache.Cache
+	cache *ttlcache.Cache
 }
 
-// BUG(albrow): newViolationsTracker currently leaks goroutines due to a
-// limitation of the caching library used under the hood.
+// newViolationsTracker creates and returns a new violationsTracker. Any
+// goroutines it starts exit when ctx is canceled.
 func newViolationsTracker(ctx context.Context) *violationsTracker {
-	cache := ccache.New(ccache.Configure().MaxSize(violationsCacheSize).ItemsToPrune(violationsCacheSize / 10))
-	// TODO(albrow): We should be calling Stop to cleanup any goroutines
-	// started by ccache, but doing so now results in a race condition. Figure
-	// out a workaround or use a different library, possibly one we write
-	// ourselves.
-	// go func() {
-	// 	// Stop the cache when the context is done.
-	// 	select {
-	// 	case <-ctx.Done():
-	// 		cache.Stop()
-	// 	}
-	// }()
+	// ttlcache.New only returns an error if MaxSize is <= 0, so we can safely
+	// ignore it.
+	cache, _ := ttlcache.New(ctx, ttlcache.Config{MaxSize: violationsCacheSize})
 	return &violationsTracker{
 		cache: cache,
 	}
@@ -40,9 +31,9 @@ func newViolationsTracker(ctx context.Context) *violationsTracker {
 // returns the new count.
 func (v *violationsTracker) add(peerID peer.ID) int {
 	newCount := 1
-	if item := v.cache.Get(peerID.String()); item != nil {
-		newCount = item.Value().(int) + 1
+	if count, found := v.cache.Get(string(peerID)); found {
+		newCount = count.(int) + 1
 	}
-	v.cache.Set(peerID.String(), newCount, violationsTTL)
+	v.cache.Set(string(peerID), newCount, violationsTTL)
 	return newCount
 }
@@ -0,0 +1,119 @@
+// Package bloom implements a simple Bloom filter which can be serialized and
+// sent to peers.
+package bloom
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	// headerSize is the size of the serialized seed and number of hash functions.
+	headerSize = 8 + 1
+	// maxNumHashes is the maximum number of hash functions a Filter can use.
+	maxNumHashes = 32
+)
+
+var errInvalidFilter = errors.New("invalid bloom filter")
+
+// Filter is a Bloom filter. It can be used to check whether an item is
+// probably in a set, using much less space than the set itself. Contains never
+// returns false for an item which was added, but may return true for an item
+// which was not added.
+type Filter struct {
+	seed      uint64
+	numHashes uint8
+	bits      []byte
+}
+
+// New creates a new, empty Filter which is sized so that the false positive
+// rate is approximately falsePositiveRate once expectedItems items have been
+// added. Filters with different seeds use different hash functions, so using a
+// new random seed each time a filter is created ensures that false positives
+// are not always the same.
+func New(expectedItems int, falsePositiveRate float64, seed uint64) *Filter {
+	if expectedItems < 1 {
+		expectedItems = 1
+	}
+	numBits := math.Ceil(-float64(expectedItems) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	if numBits < 8 {
+		numBits = 8
+	}
+	numHashes := math.Round(numBits / float64(expectedItems) * math.Ln2)
+	if numHashes < 1 {
+		numHashes = 1
+	} else if numHashes > maxNumHashes {
+		numHashes = maxNumHashes
+	}
+	return &Filter{
+		seed:      seed,
+		numHashes: uint8(numHashes),
+		bits:      make([]byte, int(math.Ceil(numBits/8))),
+	}
+}
+
+// Add adds the given item to the filter.
+func (f *Filter) Add(item []byte) {
+	h1, h2 := f.hash(item)
+	numBits := uint64(len(f.bits)) * 8
+	for i := uint64(0); i < uint64(f.numHashes); i++ {
+		bit := (h1 + i*h2) % numBits
+		f.bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Contains returns true if the given item is probably in the filter and false
+// if it is definitely not in the filter.
+func (f *Filter) Contains(item []byte) bool {
+	h1, h2 := f.hash(item)
+	numBits := uint64(len(f.bits)) * 8
+	for i := uint64(0); i < uint64(f.numHashes); i++ {
+		bit := (h1 + i*h2) % numBits
+		if f.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Size returns the size of the serialized filter in bytes.
+func (f *Filter) Size() int {
+	return headerSize + len(f.bits)
+}
+
+// hash returns two independent hashes of the given item which are combined to
+// simulate numHashes hash functions.
+func (f *Filter) hash(item []byte) (uint64, uint64) {
+	data := make([]byte, 8+len(item))
+	binary.BigEndian.PutUint64(data, f.seed)
+	copy(data[8:], item)
+	sum := sha256.Sum256(data)
+	return binary.BigEndian.Uint64(sum[0:8]), binary.BigEndian.Uint64(sum[8:16])
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	data := make([]byte, f.Size())
+	binary.BigEndian.PutUint64(data, f.seed)
+	data[8] = f.numHashes
+	copy(data[headerSize:], f.bits)
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	if len(data) <= headerSize {
+		return errInvalidFilter
+	}
+	numHashes := data[8]
+	if numHashes < 1 || numHashes > maxNumHashes {
+		return errInvalidFilter
+	}
+	f.seed = binary.BigEndian.Uint64(data)
+	f.numHashes = numHashes
+	f.bits = make([]byte, len(data)-headerSize)
+	copy(f.bits, data[headerSize:])
+	return nil
+}
@@ -0,0 +1,104 @@
+package bloom
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterContainsAddedItems(t *testing.T) {
+	filter := New(1000, 0.01, 42)
+	items := testItems(0, 1000)
+	for _, item := range items {
+		filter.Add(item)
+	}
+	for _, item := range items {
+		assert.True(t, filter.Contains(item))
+	}
+}
+
+func TestFilterFalsePositiveRate(t *testing.T) {
+	const (
+		numItems          = 10000
+		falsePositiveRate = 0.01
+	)
+	filter := New(numItems, falsePositiveRate, 42)
+	for _, item := range testItems(0, numItems) {
+		filter.Add(item)
+	}
+	falsePositives := 0
+	for _, item := range testItems(numItems, 2*numItems) {
+		if filter.Contains(item) {
+			falsePositives++
+		}
+	}
+	actualRate := float64(falsePositives) / numItems
+	assert.True(t, actualRate < 2*falsePositiveRate, "false positive rate was %f", actualRate)
+}
+
+func TestFilterSeedChangesFalsePositives(t *testing.T) {
+	const numItems = 1000
+	items := testItems(0, numItems)
+	filter0 := New(numItems, 0.05, 0)
+	filter1 := New(numItems, 0.05, 1)
+	for _, item := range items {
+		filter0.Add(item)
+		filter1.Add(item)
+	}
+	// An item which is a false positive for both filters should be rare.
+	bothFalsePositives := 0
+	for _, item := range testItems(numItems, 2*numItems) {
+		if filter0.Contains(item) && filter1.Contains(item) {
+			bothFalsePositives++
+		}
+	}
+	assert.True(t, bothFalsePositives < numItems/100, "%d items were false positives for both filters", bothFalsePositives)
+}
+
+func TestFilterMarshalRoundTrip(t *testing.T) {
+	filter := New(100, 0.01, 1234)
+	for _, item := range testItems(0, 100) {
+		filter.Add(item)
+	}
+	data, err := filter.MarshalBinary()
+	require.NoError(t, err)
+	assert.Len(t, data, filter.Size())
+
+	var decoded Filter
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, filter, &decoded)
+}
+
+func TestFilterUnmarshalInvalid(t *testing.T) {
+	validData, err := New(100, 0.01, 1234).MarshalBinary()
+	require.NoError(t, err)
+	invalidNumHashes := make([]byte, len(validData))
+	copy(invalidNumHashes, validData)
+	invalidNumHashes[8] = 0
+
+	testCases := [][]byte{
+		nil,
+		validData[:headerSize],
+		invalidNumHashes,
+	}
+	for i, data := range testCases {
+		var decoded Filter
+		assert.Error(t, decoded.UnmarshalBinary(data), "test case %d", i)
+	}
+}
+
+// testItems returns sha256 hashes of the integers in [start, end), which are
+// similar to the order hashes that will be added to filters in practice.
+func testItems(start, end int) [][]byte {
+	items := make([][]byte, 0, end-start)
+	for i := start; i < end; i++ {
+		var data [8]byte
+		binary.BigEndian.PutUint64(data[:], uint64(i))
+		sum := sha256.Sum256(data[:])
+		items = append(items, sum[:])
+	}
+	return items
+}
@@ -0,0 +1,190 @@
+package p2p
+
+import (
+	"net"
+	"sync"
+
+	connmgr "github.com/libp2p/go-libp2p-connmgr"
+	p2pnet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// outboundPeerTag is the tag used to protect outbound peers from being
+	// disconnected by the Connection Manager so that we keep at least
+	// MinOutboundPeers outbound connections.
+	outboundPeerTag = "outbound-peer"
+	// ipv4SubnetPrefixLength is the prefix length used to group IPv4 addresses
+	// into subnets.
+	ipv4SubnetPrefixLength = 24
+	// ipv6SubnetPrefixLength is the prefix length used to group IPv6 addresses
+	// into subnets. A /48 is typically the smallest block assigned to a single
+	// organization.
+	ipv6SubnetPrefixLength = 48
+)
+
+// peerDiversity keeps track of the subnet and direction of each connected peer
+// in order to enforce Config.MaxPeersPerSubnet and Config.MinOutboundPeers.
+// Together, these rules make it harder for an attacker who controls a small
+// number of IP ranges to eclipse a node (i.e. to become all of its peers).
+type peerDiversity struct {
+	mu                sync.Mutex
+	connManager       *connmgr.BasicConnMgr
+	maxPeersPerSubnet int
+	minOutboundPeers  int
+	peers             map[peer.ID]*diversityPeerInfo
+	subnetCounts      map[string]int
+	// protectedOutbound is the number of outbound peers which are currently
+	// protected with outboundPeerTag.
+	protectedOutbound int
+}
+
+type diversityPeerInfo struct {
+	// subnet is empty if the subnet of the peer is unknown (e.g. for relayed
+	// connections).
+	subnet    string
+	outbound  bool
+	protected bool
+}
+
+// newPeerDiversity creates and returns a new peerDiversity. A
+// maxPeersPerSubnet of 0 means there is no limit.
+func newPeerDiversity(connManager *connmgr.BasicConnMgr, maxPeersPerSubnet int, minOutboundPeers int) *peerDiversity {
+	return &peerDiversity{
+		connManager:       connManager,
+		maxPeersPerSubnet: maxPeersPerSubnet,
+		minOutboundPeers:  minOutboundPeers,
+		peers:             map[peer.ID]*diversityPeerInfo{},
+		subnetCounts:      map[string]int{},
+	}
+}
+
+// addPeer records a new connection. It returns false if the peer should be
+// disconnected because there are already too many peers in its subnet.
+// Subsequent connections to a peer that has already been added are ignored.
+func (d *peerDiversity) addPeer(peerID peer.ID, remoteAddr ma.Multiaddr, direction p2pnet.Direction) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, found := d.peers[peerID]; found {
+		return true
+	}
+	subnet, _ := getSubnet(remoteAddr)
+	if subnet != "" && d.maxPeersPerSubnet > 0 && d.subnetCounts[subnet] >= d.maxPeersPerSubnet {
+		return false
+	}
+	info := &diversityPeerInfo{
+		subnet:   subnet,
+		outbound: direction == p2pnet.DirOutbound,
+	}
+	d.peers[peerID] = info
+	if subnet != "" {
+		d.subnetCounts[subnet]++
+	}
+	if info.outbound && d.protectedOutbound < d.minOutboundPeers {
+		d.protect(peerID, info)
+	}
+	return true
+}
+
+// removePeer forgets about a peer which we are no longer connected to. If the
+// peer was a protected outbound peer, another outbound peer is protected in
+// its place (if there is one).
+func (d *peerDiversity) removePeer(peerID peer.ID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	info, found := d.peers[peerID]
+	if !found {
+		return
+	}
+	delete(d.peers, peerID)
+	if info.subnet != "" {
+		d.subnetCounts[info.subnet]--
+		if d.subnetCounts[info.subnet] <= 0 {
+			delete(d.subnetCounts, info.subnet)
+		}
+	}
+	if !info.protected {
+		return
+	}
+	d.connManager.Unprotect(peerID, outboundPeerTag)
+	d.protectedOutbound--
+	for otherID, otherInfo := range d.peers {
+		if otherInfo.outbound && !otherInfo.protected {
+			d.protect(otherID, otherInfo)
+			return
+		}
+	}
+}
+
+// protect protects the given outbound peer from being disconnected by the
+// Connection Manager. d.mu must be held when calling it.
+func (d *peerDiversity) protect(peerID peer.ID, info *diversityPeerInfo) {
+	d.connManager.Protect(peerID, outboundPeerTag)
+	info.protected = true
+	d.protectedOutbound++
+}
+
+// canDial returns true if at least one of the given addresses is in a subnet
+// which has not reached the maximum number of peers.
+func (d *peerDiversity) canDial(addrs []ma.Multiaddr) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.maxPeersPerSubnet <= 0 {
+		return true
+	}
+	for _, addr := range addrs {
+		subnet, _ := getSubnet(addr)
+		if subnet == "" || d.subnetCounts[subnet] < d.maxPeersPerSubnet {
+			return true
+		}
+	}
+	return false
+}
+
+// outboundPeersNeeded returns the number of additional outbound connections
+// needed to reach the minimum number of outbound peers.
+func (d *peerDiversity) outboundPeersNeeded() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	outbound := 0
+	for _, info := range d.peers {
+		if info.outbound {
+			outbound++
+		}
+	}
+	if outbound >= d.minOutboundPeers {
+		return 0
+	}
+	return d.minOutboundPeers - outbound
+}
+
+// getSubnet returns the subnet (as a string in CIDR notation) that the IP
+// address in the given multiaddress belongs to. It returns false if the
+// multiaddress does not contain an IP address.
+func getSubnet(addr ma.Multiaddr) (string, bool) {
+	if addr == nil {
+		return "", false
+	}
+	var ip net.IP
+	ma.ForEach(addr, func(c ma.Component) bool {
+		switch c.Protocol().Code {
+		case ma.P_IP6ZONE:
+			return true
+		case ma.P_IP4, ma.P_IP6:
+			ip = net.IP(c.RawValue())
+		}
+		return false
+	})
+	if ip == nil {
+		return "", false
+	}
+	var subnet net.IPNet
+	if ip4 := ip.To4(); ip4 != nil {
+		subnet = net.IPNet{IP: ip4, Mask: net.CIDRMask(ipv4SubnetPrefixLength, 8*net.IPv4len)}
+	} else {
+		subnet = net.IPNet{IP: ip, Mask: net.CIDRMask(ipv6SubnetPrefixLength, 8*net.IPv6len)}
+	}
+	subnet.IP = subnet.IP.Mask(subnet.Mask)
+	return subnet.String(), true
+}
@@ -0,0 +1,198 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	connmgr "github.com/libp2p/go-libp2p-connmgr"
+	p2pnet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSubnet(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		addr     string
+		expected string
+	}{
+		{"/ip4/3.214.190.67/tcp/60558", "3.214.190.0/24"},
+		{"/ip4/3.214.191.1/tcp/60559/ws", "3.214.191.0/24"},
+		{"/ip6/2001:db8:1234:5678::1/tcp/60558", "2001:db8:1234::/48"},
+		{"/ip6zone/eth0/ip6/fe80::1/tcp/60558", "fe80::/48"},
+	}
+	for _, testCase := range testCases {
+		subnet, ok := getSubnet(ma.StringCast(testCase.addr))
+		assert.True(t, ok, testCase.addr)
+		assert.Equal(t, testCase.expected, subnet, testCase.addr)
+	}
+
+	_, ok := getSubnet(ma.StringCast("/dns4/example.com/tcp/60558"))
+	assert.False(t, ok)
+	_, ok = getSubnet(nil)
+	assert.False(t, ok)
+}
+
+func TestPeerDiversityMaxPeersPerSubnet(t *testing.T) {
+	t.Parallel()
+	diversity := newPeerDiversity(connmgr.NewConnManager(10, 20, 0), 2, 0)
+	peerIDs := []peer.ID{newTestPeerID(t), newTestPeerID(t), newTestPeerID(t), newTestPeerID(t)}
+	sameSubnet := []ma.Multiaddr{
+		ma.StringCast("/ip4/3.214.190.1/tcp/60558"),
+		ma.StringCast("/ip4/3.214.190.2/tcp/60558"),
+		ma.StringCast("/ip4/3.214.190.3/tcp/60558"),
+	}
+	otherSubnet := ma.StringCast("/ip4/3.214.191.1/tcp/60558")
+
+	assert.True(t, diversity.addPeer(peerIDs[0], sameSubnet[0], p2pnet.DirInbound))
+	assert.True(t, diversity.addPeer(peerIDs[1], sameSubnet[1], p2pnet.DirInbound))
+	assert.True(t, diversity.addPeer(peerIDs[0], sameSubnet[0], p2pnet.DirInbound), "additional connections to the same peer should be allowed")
+	assert.False(t, diversity.addPeer(peerIDs[2], sameSubnet[2], p2pnet.DirInbound), "subnet should be full")
+	assert.True(t, diversity.addPeer(peerIDs[3], otherSubnet, p2pnet.DirInbound))
+
+	assert.False(t, diversity.canDial(sameSubnet[2:]))
+	assert.True(t, diversity.canDial([]ma.Multiaddr{sameSubnet[2], otherSubnet}))
+	assert.True(t, diversity.canDial([]ma.Multiaddr{ma.StringCast("/dns4/example.com/tcp/60558")}))
+
+	// Once a peer disconnects, there is room for another one.
+	diversity.removePeer(peerIDs[0])
+	assert.True(t, diversity.canDial(sameSubnet[2:]))
+	assert.True(t, diversity.addPeer(peerIDs[2], sameSubnet[2], p2pnet.DirInbound))
+}
+
+func TestPeerDiversityNoLimit(t *testing.T) {
+	t.Parallel()
+	diversity := newPeerDiversity(connmgr.NewConnManager(10, 20, 0), 0, 0)
+	addr := ma.StringCast("/ip4/3.214.190.1/tcp/60558")
+	for i := 0; i < 10; i++ {
+		assert.True(t, diversity.addPeer(newTestPeerID(t), addr, p2pnet.DirInbound))
+	}
+	assert.True(t, diversity.canDial([]ma.Multiaddr{addr}))
+	assert.Equal(t, 0, diversity.outboundPeersNeeded())
+}
+
+func TestPeerDiversityMinOutboundPeers(t *testing.T) {
+	t.Parallel()
+	diversity := newPeerDiversity(connmgr.NewConnManager(10, 20, 0), 0, 2)
+	addr := ma.StringCast("/ip4/3.214.190.1/tcp/60558")
+	assert.Equal(t, 2, diversity.outboundPeersNeeded())
+
+	inboundID := newTestPeerID(t)
+	outboundIDs := []peer.ID{newTestPeerID(t), newTestPeerID(t), newTestPeerID(t)}
+	require.True(t, diversity.addPeer(inboundID, addr, p2pnet.DirInbound))
+	assert.Equal(t, 2, diversity.outboundPeersNeeded())
+	for _, id := range outboundIDs {
+		require.True(t, diversity.addPeer(id, addr, p2pnet.DirOutbound))
+	}
+	assert.Equal(t, 0, diversity.outboundPeersNeeded())
+
+	// Only the first two outbound peers should be protected.
+	assert.False(t, diversity.peers[inboundID].protected)
+	assert.True(t, diversity.peers[outboundIDs[0]].protected)
+	assert.True(t, diversity.peers[outboundIDs[1]].protected)
+	assert.False(t, diversity.peers[outboundIDs[2]].protected)
+
+	// When a protected peer disconnects, the remaining outbound peer should be
+	// protected instead.
+	diversity.removePeer(outboundIDs[0])
+	assert.True(t, diversity.peers[outboundIDs[2]].protected)
+	assert.Equal(t, 2, diversity.protectedOutbound)
+	assert.Equal(t, 0, diversity.outboundPeersNeeded())
+
+	diversity.removePeer(outboundIDs[1])
+	assert.Equal(t, 1, diversity.protectedOutbound)
+	assert.Equal(t, 1, diversity.outboundPeersNeeded())
+}
+
+func TestMaxPeersPerSubnet(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	disconnected := make(chan peer.ID, 10)
+	// All test nodes listen on 127.0.0.1, so they are all in the same subnet.
+	node0 := newTestNodeWithConfig(t, ctx, &p2pnet.NotifyBundle{
+		DisconnectedF: func(_ p2pnet.Network, conn p2pnet.Conn) {
+			select {
+			case disconnected <- conn.RemotePeer():
+			default:
+			}
+		},
+	}, withTestConfigDefaults(Config{
+		MaxPeersPerSubnet: 1,
+	}))
+	node1 := newTestNode(t, ctx, nil)
+	node2 := newTestNode(t, ctx, nil)
+
+	// Only use the loopback addresses of node0 so that both connections are
+	// in the same subnet regardless of the network interfaces of the host.
+	var loopbackAddrs []ma.Multiaddr
+	for _, addr := range node0.Multiaddrs() {
+		if subnet, _ := getSubnet(addr); subnet == "127.0.0.0/24" {
+			loopbackAddrs = append(loopbackAddrs, addr)
+		}
+	}
+	require.NotEmpty(t, loopbackAddrs)
+	node0AddrInfo := peer.AddrInfo{ID: node0.ID(), Addrs: loopbackAddrs}
+	require.NoError(t, node1.Connect(node0AddrInfo, testConnectionTimeout))
+	_ = node2.Connect(node0AddrInfo, testConnectionTimeout)
+	timeout := time.After(5 * time.Second)
+	for disconnectedID := peer.ID(""); disconnectedID != node2.ID(); {
+		select {
+		case disconnectedID = <-disconnected:
+		case <-timeout:
+			t.Fatal("timed out waiting for node0 to disconnect from node2")
+		}
+	}
+
+	// node2 might keep trying to reconnect (possibly via a different subnet),
+	// but there should never be more than one peer per subnet.
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, p2pnet.Connected, node0.host.Network().Connectedness(node1.ID()))
+	node0.diversity.mu.Lock()
+	defer node0.diversity.mu.Unlock()
+	assert.Contains(t, node0.diversity.peers, node1.ID())
+	for subnet, count := range node0.diversity.subnetCounts {
+		assert.Equal(t, 1, count, "subnet %s", subnet)
+	}
+}
+
+func TestNewPeerCountConfig(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node := newTestNodeWithConfig(t, ctx, nil, withTestConfigDefaults(Config{}))
+	assert.Equal(t, defaultPeerCountLow, node.config.PeerCountLow)
+	assert.Equal(t, defaultPeerCountHigh, node.config.PeerCountHigh)
+
+	// If only PeerCountLow is set, PeerCountHigh should be adjusted so that it
+	// is not lower.
+	node = newTestNodeWithConfig(t, ctx, nil, withTestConfigDefaults(Config{PeerCountLow: defaultPeerCountHigh * 2}))
+	assert.Equal(t, defaultPeerCountHigh*2, node.config.PeerCountLow)
+	assert.Equal(t, defaultPeerCountHigh*2+defaultPeerCountHigh-defaultPeerCountLow, node.config.PeerCountHigh)
+
+	invalidConfigs := []Config{
+		{PeerCountLow: 20, PeerCountHigh: 10},
+		{MaxPeersPerSubnet: -1},
+		{PeerCountLow: 5, MinOutboundPeers: 6},
+	}
+	for _, config := range invalidConfigs {
+		_, err := New(ctx, withTestConfigDefaults(config))
+		assert.Error(t, err, "config: %+v", config)
+	}
+}
+
+// withTestConfigDefaults fills in the required fields of config with values
+// which are suitable for testing purposes.
+func withTestConfigDefaults(config Config) Config {
+	config.Topic = testTopic
+	config.MessageHandler = &dummyMessageHandler{}
+	config.RendezvousString = testRendezvousString
+	config.DataDir = newTestDataDir()
+	return config
+}
@@ -0,0 +1,49 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDoesNotLeakGoroutines(t *testing.T) {
+	// This test is intentionally not run in parallel since it counts the
+	// number of goroutines.
+
+	// Create and close one node first so that any goroutines which are started
+	// once per process (e.g. by package-level caches in libp2p) are not
+	// counted.
+	ctx, cancel := context.WithCancel(context.Background())
+	_ = newTestNode(t, ctx, nil)
+	cancel()
+	waitForGoroutines(runtime.NumGoroutine()-1, 5*time.Second)
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		_ = newTestNode(t, ctx, nil)
+		cancel()
+	}
+
+	// Some goroutines in libp2p take a moment to exit after the host is
+	// closed, so allow a small number of extra goroutines. Before the leak was
+	// fixed, each node left several goroutines running forever.
+	const slack = 5
+	actual := waitForGoroutines(before+slack, 10*time.Second)
+	assert.True(t, actual <= before+slack, "expected at most %d goroutines but got %d", before+slack, actual)
+}
+
+// waitForGoroutines waits until the number of goroutines is at most max or
+// timeout is reached. It returns the number of goroutines.
+func waitForGoroutines(max int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for runtime.NumGoroutine() > max && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return runtime.NumGoroutine()
+}
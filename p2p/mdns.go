@@ -0,0 +1,64 @@
+// +build !js
+
+package p2p
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	mdns "github.com/libp2p/go-libp2p/p2p/discovery"
+	log "github.com/sirupsen/logrus"
+)
+
+// mdnsInterval is how often we query the local network for other Mesh nodes
+// via mDNS.
+const mdnsInterval = 10 * time.Second
+
+// getMDNSServiceTag returns the mDNS service tag for the given rendezvous
+// string. Only nodes with the same rendezvous string find each other. The
+// rendezvous string is hashed because it can contain characters which are not
+// allowed in DNS labels.
+func getMDNSServiceTag(rendezvous string) string {
+	hash := sha256.Sum256([]byte(rendezvous))
+	return "_0x-mesh-" + hex.EncodeToString(hash[:8]) + "._udp"
+}
+
+// startMDNS starts advertising the Node on the local network via mDNS and
+// connects to any other Mesh nodes it finds. The service is stopped when the
+// Node's context is canceled.
+func (n *Node) startMDNS() error {
+	service, err := mdns.NewMdnsService(n.ctx, n.host, mdnsInterval, getMDNSServiceTag(n.config.RendezvousString))
+	if err != nil {
+		return err
+	}
+	service.RegisterNotifee(&mdnsNotifee{node: n})
+	go func() {
+		<-n.ctx.Done()
+		_ = service.Close()
+	}()
+	return nil
+}
+
+// mdnsNotifee connects to the peers found via mDNS.
+type mdnsNotifee struct {
+	node *Node
+}
+
+// HandlePeerFound is called when a new peer is found via mDNS.
+func (m *mdnsNotifee) HandlePeerFound(peerInfo peer.AddrInfo) {
+	n := m.node
+	if peerInfo.ID == n.host.ID() || !n.allowlist.isAllowed(peerInfo.ID) {
+		return
+	}
+	if len(n.host.Network().ConnsToPeer(peerInfo.ID)) > 0 {
+		return
+	}
+	log.WithFields(map[string]interface{}{
+		"peerInfo": peerInfo,
+	}).Trace("found peer via mDNS")
+	if err := n.Connect(peerInfo, defaultNetworkTimeout); err != nil {
+		logPeerConnectionError(peerInfo, err)
+	}
+}
@@ -0,0 +1,11 @@
+// +build js,wasm
+
+package p2p
+
+import "errors"
+
+// startMDNS returns an error because browsers can't send or receive mDNS
+// packets.
+func (n *Node) startMDNS() error {
+	return errors.New("mDNS discovery is not supported in the browser")
+}
@@ -0,0 +1,61 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMDNSServiceTag(t *testing.T) {
+	t.Parallel()
+	tag := getMDNSServiceTag("/0x-mesh/network/1/version/1")
+	assert.Equal(t, tag, getMDNSServiceTag("/0x-mesh/network/1/version/1"))
+	assert.NotEqual(t, tag, getMDNSServiceTag("/0x-mesh/network/3/version/1"))
+	// DNS labels can be at most 63 characters long.
+	assert.True(t, len(tag) <= 63)
+	assert.Regexp(t, "^_[a-z0-9-]+\\._udp$", tag)
+}
+
+func TestMDNS(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newMDNSNode := func(rendezvous string) *Node {
+		return newTestNodeWithConfig(t, ctx, nil, Config{
+			Topic:            testTopic,
+			MessageHandler:   &dummyMessageHandler{},
+			RendezvousString: rendezvous,
+			UseBootstrapList: false,
+			DataDir:          newTestDataDir(),
+			EnableMDNS:       true,
+		})
+	}
+	rendezvous := testRendezvousString + "-mdns-" + uuid.New().String()
+	node0 := newMDNSNode(rendezvous)
+	node1 := newMDNSNode(rendezvous)
+	otherNode := newMDNSNode(rendezvous + "-other")
+	for _, node := range []*Node{node0, node1, otherNode} {
+		go startNodeAndCheckError(t, node)
+	}
+
+	// node0 and node1 should find each other without being told about each
+	// other.
+	timeout := time.After(15 * time.Second)
+	for node0.host.Network().Connectedness(node1.ID()) != network.Connected {
+		select {
+		case <-timeout:
+			t.Fatal("timed out waiting for node0 and node1 to connect via mDNS")
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+
+	// Nodes with a different rendezvous string should not be found.
+	assert.NotEqual(t, network.Connected, node0.host.Network().Connectedness(otherNode.ID()))
+	assert.NotEqual(t, network.Connected, otherNode.host.Network().Connectedness(node1.ID()))
+}
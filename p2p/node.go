@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	mathrand "math/rand"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/0xProject/0x-mesh/constants"
@@ -21,7 +22,9 @@ import (
 	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/host"
 	metrics "github.com/libp2p/go-libp2p-core/metrics"
+	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
 	"github.com/libp2p/go-libp2p-core/routing"
 	discovery "github.com/libp2p/go-libp2p-discovery"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
@@ -76,8 +79,18 @@ const (
 	// defaultPerPeerPubSubMessageBurst is the default value for
 	// PerPeerPubSubMessageBurst.
 	defaultPerPeerPubSubMessageBurst = maxShareBatch * 5
+	// defaultHighScorePubSubThreshold is the default value for
+	// HighScorePubSubThreshold.
+	defaultHighScorePubSubThreshold = 10
+	// protectedPeerTag is the tag used to protect peers from being disconnected
+	// by the Connection Manager via ProtectPeer.
+	protectedPeerTag = "protected-peer"
 )
 
+// ErrProtectedPeer is returned by BanPeer when the peer was protected via
+// ProtectPeer.
+var ErrProtectedPeer = errors.New("cannot ban protected peer")
+
 // Node is the main type for the p2p package. It represents a particpant in the
 // 0x Mesh network who is capable of sending, receiving, validating, and storing
 // messages.
@@ -90,8 +103,22 @@ type Node struct {
 	dht              *dht.IpfsDHT
 	routingDiscovery discovery.Discovery
 	pubsub           *pubsub.PubSub
-	sub              *pubsub.Subscription
+	subscribeOnce    sync.Once
+	subscribeErr     error
+	incoming         chan *Message
 	banner           *banner.Banner
+	reputation       *reputation
+	allowlist        peerAllowlist
+	rateValidator    *ratevalidator.Validator
+	diversity        *peerDiversity
+	// protectedPeers holds the IDs of all peers which were protected via
+	// ProtectPeer.
+	protectedPeers   map[peer.ID]struct{}
+	protectedPeersMu sync.RWMutex
+	// setReconciliationLimiters holds a *rate.Limiter for each peer which has
+	// recently sent us a set reconciliation request.
+	setReconciliationLimiters   *lru.Cache
+	setReconciliationLimitersMu sync.Mutex
 }
 
 // Config contains configuration options for a Node.
@@ -99,6 +126,19 @@ type Config struct {
 	// Topic is a unique string representing the pubsub topic. Only Nodes which
 	// have the same topic will share messages with one another.
 	Topic string
+	// LegacyTopics is an optional list of pubsub topics which were used by
+	// older versions of the message format. The Node receives messages on these
+	// topics in addition to Topic. This makes it possible to introduce a new
+	// message format (negotiated by topic) without ignoring peers which have
+	// not upgraded yet.
+	LegacyTopics []string
+	// EncodeLegacyMessages optionally converts the data of a message sent on
+	// Topic to the data of one or more messages in the legacy format. If it is
+	// set, every message sent on Topic is also sent on each of the LegacyTopics
+	// in the legacy format, so that peers which have not upgraded yet keep
+	// receiving messages during the transition. If it is nil, messages are only
+	// sent on Topic.
+	EncodeLegacyMessages func(data []byte) ([][]byte, error)
 	// TCPPort is the port on which to listen for incoming TCP connections.
 	TCPPort int
 	// WebSocketsPort is the port on which to listen for incoming WebSockets
@@ -144,6 +184,76 @@ type Config struct {
 	// is allowed to send at once through the GossipSub network. Any additional
 	// messages will be dropped.
 	PerPeerPubSubMessageBurst int
+	// SetReconciliationHandler is an optional interface which provides the set of
+	// messages used for set reconciliation. If it is nil, set reconciliation is
+	// disabled and messages are only shared via GossipSub.
+	SetReconciliationHandler SetReconciliationHandler
+	// BanDuration is how long peers and IP addresses remain banned. Bans are
+	// persisted in DataDir so that they survive restarts. Defaults to 24 hours.
+	BanDuration time.Duration
+	// PeerScoreTTL is how long peer scores are remembered after they were last
+	// updated. Scores are persisted in DataDir so that they survive restarts
+	// and are re-applied when a peer reconnects. Defaults to 24 hours.
+	PeerScoreTTL time.Duration
+	// PrivateNetworkKey is an optional 32 byte pre-shared key. If set, all
+	// connections are encrypted with the key and the Node can only connect to
+	// peers which use the same key. This makes it possible to run a private
+	// network which is isolated from the public network.
+	PrivateNetworkKey []byte
+	// PeerAllowlist is an optional list of the only peers which the Node is
+	// allowed to connect to and receive messages from. Connections to other
+	// peers are closed as soon as they are established. Peers in BootstrapList
+	// are always allowed. If empty, all peers are allowed.
+	PeerAllowlist []peer.ID
+	// EnableMDNS determines whether or not to use mDNS to discover and connect
+	// to other Mesh nodes with the same RendezvousString on the local network.
+	// It is not supported in the browser.
+	EnableMDNS bool
+	// AdaptivePubSubRateLimits determines whether the per-peer GossipSub limits
+	// depend on the total score of each peer. If true, peers with a score of at
+	// least HighScorePubSubThreshold are allowed to send bigger bursts and peers
+	// with a score below LowScorePubSubThreshold are limited to a lower rate.
+	// The total score (the sum of the scores for all tags) is used instead of
+	// the score for a single tag such as "order-stored", because the scores for
+	// good behavior are never negative and on their own could not be used to
+	// limit peers which send us invalid messages.
+	AdaptivePubSubRateLimits bool
+	// HighScorePubSubThreshold is the minimum score for a peer to get a bigger
+	// burst when AdaptivePubSubRateLimits is true. Defaults to 10.
+	HighScorePubSubThreshold int
+	// HighScorePubSubBurstMultiplier is the factor by which
+	// PerPeerPubSubMessageBurst is multiplied for high score peers. Defaults to
+	// 4.
+	HighScorePubSubBurstMultiplier int
+	// LowScorePubSubThreshold is the score below which a peer is limited to a
+	// lower rate when AdaptivePubSubRateLimits is true. The default of 0 means
+	// that only peers with a negative score are affected.
+	LowScorePubSubThreshold int
+	// LowScorePubSubLimitDivisor is the factor by which both
+	// PerPeerPubSubMessageLimit and PerPeerPubSubMessageBurst are divided for
+	// low score peers. Defaults to 4.
+	LowScorePubSubLimitDivisor int
+	// PeerCountLow is the target number of peers to connect to at any given
+	// time. Defaults to 100 (10 in the browser).
+	PeerCountLow int
+	// PeerCountHigh is the maximum number of peers to be connected to. If the
+	// number of connections exceeds this number, the Connection Manager will
+	// prune connections until we reach PeerCountLow. Defaults to 110 (12 in the
+	// browser).
+	PeerCountHigh int
+	// MaxPeersPerSubnet is the maximum number of peers with IP addresses in the
+	// same subnet (a /24 for IPv4 or a /48 for IPv6) that we will be connected
+	// to. Connections to additional peers are closed as soon as they are
+	// established and peers in full subnets are not dialed. A value of 0 means
+	// there is no limit.
+	MaxPeersPerSubnet int
+	// MinOutboundPeers is the minimum number of peers that we dialed ourselves
+	// (as opposed to peers which dialed us) to stay connected to. Up to this
+	// many outbound peers are protected from being pruned by the Connection
+	// Manager and we look for new peers whenever we have fewer. This makes it
+	// harder for an attacker to replace all of our peers by dialing us
+	// repeatedly.
+	MinOutboundPeers int
 }
 
 func getPeerstoreDir(datadir string) string {
@@ -174,6 +284,45 @@ func New(ctx context.Context, config Config) (*Node, error) {
 	if config.PerPeerPubSubMessageBurst == 0 {
 		config.PerPeerPubSubMessageBurst = defaultPerPeerPubSubMessageBurst
 	}
+	if config.BanDuration == 0 {
+		config.BanDuration = defaultBanDuration
+	}
+	if config.PeerScoreTTL == 0 {
+		config.PeerScoreTTL = defaultPeerScoreTTL
+	}
+	if config.HighScorePubSubThreshold == 0 {
+		config.HighScorePubSubThreshold = defaultHighScorePubSubThreshold
+	}
+	if config.PeerCountLow == 0 {
+		config.PeerCountLow = defaultPeerCountLow
+	}
+	if config.PeerCountHigh == 0 {
+		config.PeerCountHigh = defaultPeerCountHigh
+		if config.PeerCountHigh < config.PeerCountLow {
+			config.PeerCountHigh = config.PeerCountLow + (defaultPeerCountHigh - defaultPeerCountLow)
+		}
+	}
+	if config.PeerCountLow < 0 || config.PeerCountHigh < config.PeerCountLow {
+		return nil, errors.New("config.PeerCountHigh must be greater than or equal to config.PeerCountLow")
+	}
+	if config.MaxPeersPerSubnet < 0 {
+		return nil, errors.New("config.MaxPeersPerSubnet must not be negative")
+	}
+	if config.MinOutboundPeers < 0 || config.MinOutboundPeers > config.PeerCountLow {
+		return nil, errors.New("config.MinOutboundPeers must be between 0 and config.PeerCountLow")
+	}
+
+	allowlist, err := getPeerAllowlist(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load any peer scores and bans from a previous run.
+	reputation := newReputation(getReputationPath(config.DataDir), config.PeerScoreTTL)
+	bannedIPs, err := reputation.load()
+	if err != nil {
+		return nil, fmt.Errorf("could not load peer reputation: %s", err.Error())
+	}
 
 	// We need to declare the newDHT function ahead of time so we can use it in
 	// the libp2p.Routing option.
@@ -199,7 +348,8 @@ func New(ctx context.Context, config Config) (*Node, error) {
 
 	// Set up and append environment agnostic host options.
 	bandwidthCounter := metrics.NewBandwidthCounter()
-	connManager := connmgr.NewConnManager(peerCountLow, peerCountHigh, peerGraceDuration)
+	connManager := connmgr.NewConnManager(config.PeerCountLow, config.PeerCountHigh, peerGraceDuration)
+	diversity := newPeerDiversity(connManager, config.MaxPeersPerSubnet, config.MinOutboundPeers)
 	opts = append(opts, []libp2p.Option{
 		libp2p.Routing(newDHT),
 		libp2p.ConnectionManager(connManager),
@@ -225,10 +375,19 @@ func New(ctx context.Context, config Config) (*Node, error) {
 		_ = basicHost.Close()
 	}()
 
+	// Messages we publish are validated too, so we always need to allow
+	// ourselves.
+	if len(allowlist) > 0 {
+		allowlist[basicHost.ID()] = struct{}{}
+	}
+
 	// Set up the notifee.
 	basicHost.Network().Notify(&notifee{
 		ctx:         ctx,
 		connManager: connManager,
+		reputation:  reputation,
+		allowlist:   allowlist,
+		diversity:   diversity,
 	})
 
 	// Set up DHT for peer discovery.
@@ -246,13 +405,27 @@ func New(ctx context.Context, config Config) (*Node, error) {
 		GlobalBurst:    config.GlobalPubSubMessageBurst,
 		PerPeerLimit:   config.PerPeerPubSubMessageLimit,
 		PerPeerBurst:   config.PerPeerPubSubMessageBurst,
-		MaxMessageSize: constants.MaxOrderSizeInBytes,
+		MaxMessageSize: constants.MaxMessageSizeInBytes,
+		AdaptiveLimits: config.AdaptivePubSubRateLimits,
+		PeerScore: func(id peer.ID) int {
+			return reputation.getTotalScore(id)
+		},
+		HighScoreThreshold:       config.HighScorePubSubThreshold,
+		HighScoreBurstMultiplier: config.HighScorePubSubBurstMultiplier,
+		LowScoreThreshold:        config.LowScorePubSubThreshold,
+		LowScoreLimitDivisor:     config.LowScorePubSubLimitDivisor,
 	})
 	if err != nil {
 		return nil, err
 	}
-	if err := ps.RegisterTopicValidator(config.Topic, rateValidator.Validate, pubsub.WithValidatorInline(true)); err != nil {
-		return nil, err
+	validator := rateValidator.Validate
+	if len(allowlist) > 0 {
+		validator = newAllowlistValidator(allowlist, validator)
+	}
+	for _, topic := range append([]string{config.Topic}, config.LegacyTopics...) {
+		if err := ps.RegisterTopicValidator(topic, validator, pubsub.WithValidatorInline(true)); err != nil {
+			return nil, err
+		}
 	}
 
 	// Configure banner.
@@ -262,9 +435,20 @@ func New(ctx context.Context, config Config) (*Node, error) {
 		BandwidthCounter:       bandwidthCounter,
 		MaxBytesPerSecond:      defaultMaxBytesPerSecond,
 		LogBandwidthUsageStats: true,
+		BanDuration:            config.BanDuration,
 	})
+	for _, ban := range bannedIPs {
+		if err := banner.RestoreBan(ban); err != nil {
+			log.WithFields(map[string]interface{}{
+				"error": err.Error(),
+				"ip":    ban.IP.String(),
+			}).Warn("could not restore IP ban")
+		}
+	}
 
 	// Create the Node.
+	// lru.New only returns an error if size is <= 0, so we can safely ignore it.
+	setReconciliationLimiters, _ := lru.New(config.PeerCountHigh * 2)
 	node := &Node{
 		ctx:              ctx,
 		config:           config,
@@ -274,8 +458,20 @@ func New(ctx context.Context, config Config) (*Node, error) {
 		dht:              kadDHT,
 		routingDiscovery: routingDiscovery,
 		pubsub:           ps,
+		incoming:         make(chan *Message),
 		banner:           banner,
+		reputation:       reputation,
+		allowlist:        allowlist,
+		rateValidator:    rateValidator,
+		diversity:        diversity,
+		protectedPeers:   map[peer.ID]struct{}{},
+
+		setReconciliationLimiters: setReconciliationLimiters,
 	}
+	if config.SetReconciliationHandler != nil {
+		basicHost.SetStreamHandler(setReconciliationProtocolID, node.handleSetReconciliationStream)
+	}
+	go node.periodicallySaveReputation()
 
 	return node, nil
 }
@@ -344,6 +540,19 @@ func (n *Node) Start() error {
 	// Advertise ourselves for the purposes of peer discovery.
 	discovery.Advertise(n.ctx, n.routingDiscovery, n.config.RendezvousString, discovery.TTL(advertiseTTL))
 
+	// If needed, also find peers on the local network.
+	if n.config.EnableMDNS {
+		if err := n.startMDNS(); err != nil {
+			return err
+		}
+	}
+
+	// Periodically reconcile with peers so that they can send us any messages we
+	// are missing.
+	if n.config.SetReconciliationHandler != nil {
+		go n.periodicallyReconcile()
+	}
+
 	return n.mainLoop()
 }
 
@@ -352,6 +561,7 @@ func (n *Node) Start() error {
 // associated with each tag. Peers that end up with a low total score will
 // eventually be disconnected.
 func (n *Node) AddPeerScore(id peer.ID, tag string, diff int) {
+	n.reputation.addScore(id, tag, diff)
 	n.connManager.UpsertTag(id, tag, func(current int) int { return current + diff })
 }
 
@@ -360,20 +570,229 @@ func (n *Node) AddPeerScore(id peer.ID, tag string, diff int) {
 // A peer's total score is the sum of the scores associated with each tag. Peers
 // that end up with a low total score will eventually be disconnected.
 func (n *Node) SetPeerScore(id peer.ID, tag string, val int) {
+	n.reputation.setScore(id, tag, val)
 	n.connManager.TagPeer(id, tag, val)
 }
 
 // UnsetPeerScore removes any scores associated with the given tag for a peer
 // (i.e., they will no longer be counted toward the peers total score).
 func (n *Node) UnsetPeerScore(id peer.ID, tag string) {
+	n.reputation.unsetScore(id, tag)
 	n.connManager.UntagPeer(id, tag)
 }
 
+// GetPeerScore returns the cumulative score associated with the given tag for
+// a peer. Unlike the scores used by the Connection Manager, it includes scores
+// from previous connections and previous runs as long as they were updated
+// within PeerScoreTTL.
+func (n *Node) GetPeerScore(id peer.ID, tag string) int {
+	return n.reputation.getScore(id, tag)
+}
+
+// BanPeer bans the given peer for BanDuration and disconnects from it. The IP
+// addresses of any open connections to the peer are banned as well. Any new
+// connections from a banned peer are closed immediately. Peers which were
+// protected via ProtectPeer can't be banned.
+func (n *Node) BanPeer(id peer.ID) error {
+	if n.isPeerProtected(id) {
+		return ErrProtectedPeer
+	}
+	expiration := time.Now().Add(n.config.BanDuration)
+	bannedAddrs := []ma.Multiaddr{}
+	for _, conn := range n.host.Network().ConnsToPeer(id) {
+		if err := n.banner.BanIPUntil(conn.RemoteMultiaddr(), expiration); err != nil {
+			if err == banner.ErrProtectedIP {
+				continue
+			}
+			return err
+		}
+		bannedAddrs = append(bannedAddrs, conn.RemoteMultiaddr())
+	}
+	n.reputation.ban(id, expiration, bannedAddrs)
+	return n.host.Network().ClosePeer(id)
+}
+
+// UnbanPeer removes the ban for the given peer, including any IP addresses
+// that were banned by BanPeer.
+func (n *Node) UnbanPeer(id peer.ID) error {
+	for _, addr := range n.reputation.unban(id) {
+		if err := n.banner.UnbanIP(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsPeerBanned returns true if the given peer is currently banned.
+func (n *Node) IsPeerBanned(id peer.ID) bool {
+	return n.reputation.isBanned(id)
+}
+
+// BanIP bans the IP address of the given Multiaddr for BanDuration. It does not
+// close any open connections from the IP address.
+func (n *Node) BanIP(maddr ma.Multiaddr) error {
+	return n.banner.BanIP(maddr)
+}
+
+// UnbanIP removes the ban for the IP address of the given Multiaddr.
+func (n *Node) UnbanIP(maddr ma.Multiaddr) error {
+	return n.banner.UnbanIP(maddr)
+}
+
+// ProtectIP permanently protects the IP address of the given Multiaddr from
+// being banned. If the IP address is currently banned, the ban is removed.
+func (n *Node) ProtectIP(maddr ma.Multiaddr) error {
+	return n.banner.ProtectIP(maddr)
+}
+
+// ProtectPeer protects the given peer from being disconnected by the
+// Connection Manager and protects the IP addresses of any open connections to
+// the peer from being banned. BanPeer returns ErrProtectedPeer for protected
+// peers. If the peer is currently banned, the ban is removed.
+func (n *Node) ProtectPeer(id peer.ID) error {
+	if err := n.UnbanPeer(id); err != nil {
+		return err
+	}
+	for _, conn := range n.host.Network().ConnsToPeer(id) {
+		if err := n.banner.ProtectIP(conn.RemoteMultiaddr()); err != nil {
+			return err
+		}
+	}
+	n.protectedPeersMu.Lock()
+	n.protectedPeers[id] = struct{}{}
+	n.protectedPeersMu.Unlock()
+	n.connManager.Protect(id, protectedPeerTag)
+	return nil
+}
+
+// isPeerProtected returns true if the given peer was protected via
+// ProtectPeer.
+func (n *Node) isPeerProtected(id peer.ID) bool {
+	n.protectedPeersMu.RLock()
+	defer n.protectedPeersMu.RUnlock()
+	_, found := n.protectedPeers[id]
+	return found
+}
+
+// DisconnectPeer closes all connections to the given peer. Unlike BanPeer, it
+// does not prevent the peer from connecting again.
+func (n *Node) DisconnectPeer(id peer.ID) error {
+	return n.host.Network().ClosePeer(id)
+}
+
+// PeerInfo contains information about a connected peer.
+type PeerInfo struct {
+	ID peer.ID
+	// Addrs are the remote addresses of all open connections to the peer.
+	Addrs []ma.Multiaddr
+	// Direction is the direction of the oldest open connection to the peer.
+	Direction network.Direction
+	// Latency is an exponentially weighted moving average of the observed
+	// latency to the peer.
+	Latency time.Duration
+	// Scores are the scores for the peer that are currently used by the
+	// Connection Manager, indexed by tag.
+	Scores map[string]int
+	// Bandwidth contains the total number of bytes sent to and received from
+	// the peer, as well as the current rates.
+	Bandwidth metrics.Stats
+	// Messages contains the number of GossipSub messages received from the
+	// peer, grouped by whether they were accepted, rate limited, too big, or
+	// found to be invalid.
+	Messages ratevalidator.PeerStats
+}
+
+// Peers returns information about every peer the node is connected to.
+func (n *Node) Peers() []PeerInfo {
+	peers := n.host.Network().Peers()
+	peerInfos := make([]PeerInfo, 0, len(peers))
+	for _, id := range peers {
+		conns := n.host.Network().ConnsToPeer(id)
+		if len(conns) == 0 {
+			// The peer disconnected after we got the list of peers.
+			continue
+		}
+		peerInfo := PeerInfo{
+			ID:        id,
+			Addrs:     make([]ma.Multiaddr, len(conns)),
+			Direction: conns[0].Stat().Direction,
+			Latency:   n.host.Peerstore().LatencyEWMA(id),
+			Scores:    map[string]int{},
+			Bandwidth: n.banner.GetBandwidthForPeer(id),
+			Messages:  n.rateValidator.PeerStats(id),
+		}
+		for i, conn := range conns {
+			peerInfo.Addrs[i] = conn.RemoteMultiaddr()
+		}
+		if tagInfo := n.connManager.GetTagInfo(id); tagInfo != nil {
+			for tag, val := range tagInfo.Tags {
+				peerInfo.Scores[tag] = val
+			}
+		}
+		peerInfos = append(peerInfos, peerInfo)
+	}
+	return peerInfos
+}
+
+// RecordInvalidMessage records that a message received from the given peer
+// was rejected by the MessageHandler. It is included in the message stats
+// returned by Peers.
+func (n *Node) RecordInvalidMessage(id peer.ID) {
+	n.rateValidator.RecordInvalidMessage(id)
+}
+
+// periodicallySaveReputation removes expired peer scores and bans and saves the
+// remaining ones to disk every reputationSaveInterval. It saves one last time
+// when the Node's context is canceled.
+func (n *Node) periodicallySaveReputation() {
+	ticker := time.NewTicker(reputationSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.ctx.Done():
+			n.saveReputation()
+			return
+		case <-ticker.C:
+			n.saveReputation()
+		}
+	}
+}
+
+// saveReputation removes expired peer scores and bans and saves the remaining
+// ones to disk.
+func (n *Node) saveReputation() {
+	for id, scores := range n.reputation.removeExpired() {
+		for tag := range scores {
+			n.connManager.UntagPeer(id, tag)
+		}
+	}
+	if err := n.reputation.save(n.banner.Bans()); err != nil {
+		log.WithField("error", err.Error()).Error("could not save peer reputation")
+	}
+}
+
 // GetNumPeers returns the number of peers the node is connected to
 func (n *Node) GetNumPeers() int {
 	return n.connManager.GetInfo().ConnCount
 }
 
+// ConnectedPeers returns the IDs of all peers the node is connected to.
+func (n *Node) ConnectedPeers() []peer.ID {
+	return n.host.Network().Peers()
+}
+
+// SetStreamHandler sets the handler for new streams opened by peers using the
+// given protocol ID. It can be used to implement additional protocols on top
+// of the underlying libp2p host.
+func (n *Node) SetStreamHandler(pid protocol.ID, handler network.StreamHandler) {
+	n.host.SetStreamHandler(pid, handler)
+}
+
+// NewStream opens a new stream to the given peer using the given protocol ID.
+func (n *Node) NewStream(ctx context.Context, id peer.ID, pid protocol.ID) (network.Stream, error) {
+	return n.host.NewStream(ctx, id, pid)
+}
+
 // Connect ensures there is a connection between this host and the peer with
 // given peerInfo. If there is not an active connection, Connect will dial the
 // peer, and block until a connection is open, timeout is exceeded, or an error
@@ -406,8 +825,12 @@ func (n *Node) mainLoop() error {
 // runOnce runs a single iteration of the main loop.
 func (n *Node) runOnce() error {
 	peerCount := n.connManager.GetInfo().ConnCount
-	if peerCount < peerCountLow {
-		if err := n.findNewPeers(peerCountLow - peerCount); err != nil {
+	peersNeeded := n.config.PeerCountLow - peerCount
+	if outboundPeersNeeded := n.diversity.outboundPeersNeeded(); outboundPeersNeeded > peersNeeded {
+		peersNeeded = outboundPeersNeeded
+	}
+	if peersNeeded > 0 {
+		if err := n.findNewPeers(peersNeeded); err != nil {
 			return err
 		}
 	}
@@ -454,7 +877,13 @@ func (n *Node) findNewPeers(max int) error {
 	connectCtx, cancel := context.WithTimeout(n.ctx, defaultNetworkTimeout)
 	defer cancel()
 	for peer := range peerChan {
-		if peer.ID == n.host.ID() || len(peer.Addrs) == 0 {
+		if peer.ID == n.host.ID() || len(peer.Addrs) == 0 || !n.allowlist.isAllowed(peer.ID) {
+			continue
+		}
+		if !n.diversity.canDial(peer.Addrs) {
+			log.WithFields(map[string]interface{}{
+				"peerInfo": peer,
+			}).Trace("not dialing peer in a subnet with too many peers")
 			continue
 		}
 		log.WithFields(map[string]interface{}{
@@ -472,7 +901,7 @@ func (n *Node) findNewPeers(max int) error {
 // failedPeerConnectionCache keeps track of peer IDs for which we have already
 // logged a connection error. lru.New only returns an error if size is <= 0, so
 // we can safely ignore it.
-var failedPeerConnectionCache, _ = lru.New(peerCountHigh * 2)
+var failedPeerConnectionCache, _ = lru.New(defaultPeerCountHigh * 2)
 
 func logPeerConnectionError(peerInfo peer.AddrInfo, connectionErr error) {
 	// If we fail to connect to a single peer we should still keep trying the
@@ -546,24 +975,85 @@ func (n *Node) shareBatch() error {
 	return nil
 }
 
-// Send sends a message continaing the given data to all connected peers.
+// Send sends a message continaing the given data to all connected peers. If
+// EncodeLegacyMessages is set, the data is also sent in the legacy format on
+// each of the LegacyTopics.
 func (n *Node) Send(data []byte) error {
-	return n.pubsub.Publish(n.config.Topic, data)
+	if err := n.pubsub.Publish(n.config.Topic, data); err != nil {
+		return err
+	}
+	return n.sendLegacy(data)
+}
+
+// sendLegacy converts the given data to the legacy format and sends it on each
+// of the LegacyTopics. It does nothing if EncodeLegacyMessages is not set.
+func (n *Node) sendLegacy(data []byte) error {
+	if n.config.EncodeLegacyMessages == nil || len(n.config.LegacyTopics) == 0 {
+		return nil
+	}
+	legacyMessages, err := n.config.EncodeLegacyMessages(data)
+	if err != nil {
+		return err
+	}
+	for _, topic := range n.config.LegacyTopics {
+		for _, legacyData := range legacyMessages {
+			if err := n.pubsub.Publish(topic, legacyData); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // receive returns the next pending message. It blocks if no messages are
 // available. If the given context is canceled, it returns nil, ctx.Err().
 func (n *Node) receive(ctx context.Context) (*Message, error) {
-	if n.sub == nil {
-		var err error
-		n.sub, err = n.pubsub.Subscribe(n.config.Topic)
+	n.subscribeOnce.Do(func() {
+		n.subscribeErr = n.subscribe()
+	})
+	if n.subscribeErr != nil {
+		return nil, n.subscribeErr
+	}
+	select {
+	case msg := <-n.incoming:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// subscribe subscribes to Topic and any LegacyTopics. Messages received on any
+// of the topics are sent through n.incoming.
+func (n *Node) subscribe() error {
+	for _, topic := range append([]string{n.config.Topic}, n.config.LegacyTopics...) {
+		sub, err := n.pubsub.Subscribe(topic)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		go n.forwardMessages(sub)
 	}
-	msg, err := n.sub.Next(ctx)
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+// forwardMessages sends all messages received via sub through n.incoming
+// until the Node's context is canceled.
+func (n *Node) forwardMessages(sub *pubsub.Subscription) {
+	defer sub.Cancel()
+	for {
+		msg, err := sub.Next(n.ctx)
+		if err != nil {
+			if err != context.Canceled {
+				log.WithFields(map[string]interface{}{
+					"error": err,
+					"topic": sub.Topic(),
+				}).Error("could not receive message from subscription")
+			}
+			return
+		}
+		select {
+		case n.incoming <- &Message{From: msg.GetFrom(), Data: msg.Data}:
+		case <-n.ctx.Done():
+			return
+		}
 	}
-	return &Message{From: msg.GetFrom(), Data: msg.Data}, nil
 }
@@ -364,6 +364,138 @@ func TestMessagesAreShared(t *testing.T) {
 	assert.Equal(t, expectedAllMessages, node1.messageHandler.(*inMemoryMessageHandler).messages, "node1 should be storing all messages")
 }
 
+func TestLegacyTopics(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// upgradedNode uses a new topic but still receives messages on the topic
+	// used by legacyNode.
+	upgradedNode := newTestNodeWithConfig(t, ctx, nil, Config{
+		Topic:            testTopic + "-upgraded",
+		LegacyTopics:     []string{testTopic},
+		MessageHandler:   &dummyMessageHandler{},
+		RendezvousString: testRendezvousString,
+		UseBootstrapList: false,
+		DataDir:          "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+	})
+	legacyNode := newTestNode(t, ctx, nil)
+	connectTestNodes(t, upgradedNode, legacyNode)
+
+	allMessageHandler := func(msg *Message) (bool, error) {
+		return true, nil
+	}
+	upgradedMessageHandler := newInMemoryMessageHandler(allMessageHandler)
+	upgradedMessageHandler.messages = []*Message{
+		{
+			From: upgradedNode.host.ID(),
+			Data: []byte{1, 2, 3, 4},
+		},
+	}
+	upgradedNode.messageHandler = upgradedMessageHandler
+	legacyMessageHandler := newInMemoryMessageHandler(allMessageHandler)
+	legacyMessageHandler.messages = []*Message{
+		{
+			From: legacyNode.host.ID(),
+			Data: []byte{5, 6, 7, 8},
+		},
+	}
+	legacyNode.messageHandler = legacyMessageHandler
+
+	// Call runOnce to cause each node to share and receive messages.
+	require.NoError(t, upgradedNode.runOnce())
+	require.NoError(t, legacyNode.runOnce())
+	require.NoError(t, upgradedNode.runOnce())
+	require.NoError(t, legacyNode.runOnce())
+
+	// upgradedNode should have received the message from legacyNode, but
+	// legacyNode should not have received the message from upgradedNode because
+	// upgradedNode only sends messages on its new topic.
+	expectedUpgradedMessages := []*Message{
+		{
+			From: upgradedNode.host.ID(),
+			Data: []byte{1, 2, 3, 4},
+		},
+		{
+			From: legacyNode.host.ID(),
+			Data: []byte{5, 6, 7, 8},
+		},
+	}
+	assert.Equal(t, expectedUpgradedMessages, upgradedMessageHandler.messages, "upgradedNode should be storing all messages")
+	expectedLegacyMessages := []*Message{
+		{
+			From: legacyNode.host.ID(),
+			Data: []byte{5, 6, 7, 8},
+		},
+	}
+	assert.Equal(t, expectedLegacyMessages, legacyMessageHandler.messages, "legacyNode should only be storing its own message")
+}
+
+func TestLegacyTopicsDualPublish(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// upgradedNode uses a new topic and also sends each message in the legacy
+	// format on the topic used by legacyNode. To make it easy to tell the two
+	// formats apart, the legacy format appends a 9 to the data.
+	upgradedNode := newTestNodeWithConfig(t, ctx, nil, Config{
+		Topic:        testTopic + "-upgraded",
+		LegacyTopics: []string{testTopic},
+		EncodeLegacyMessages: func(data []byte) ([][]byte, error) {
+			return [][]byte{append(append([]byte{}, data...), 9)}, nil
+		},
+		MessageHandler:   &dummyMessageHandler{},
+		RendezvousString: testRendezvousString,
+		UseBootstrapList: false,
+		DataDir:          "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+	})
+	legacyNode := newTestNode(t, ctx, nil)
+	connectTestNodes(t, upgradedNode, legacyNode)
+
+	allMessageHandler := func(msg *Message) (bool, error) {
+		return true, nil
+	}
+	upgradedMessageHandler := newInMemoryMessageHandler(allMessageHandler)
+	upgradedMessageHandler.messages = []*Message{
+		{
+			From: upgradedNode.host.ID(),
+			Data: []byte{1, 2, 3, 4},
+		},
+	}
+	upgradedNode.messageHandler = upgradedMessageHandler
+	legacyMessageHandler := newInMemoryMessageHandler(allMessageHandler)
+	legacyMessageHandler.messages = []*Message{
+		{
+			From: legacyNode.host.ID(),
+			Data: []byte{5, 6, 7, 8},
+		},
+	}
+	legacyNode.messageHandler = legacyMessageHandler
+
+	// Call runOnce to cause each node to share and receive messages.
+	require.NoError(t, upgradedNode.runOnce())
+	require.NoError(t, legacyNode.runOnce())
+	require.NoError(t, upgradedNode.runOnce())
+	require.NoError(t, legacyNode.runOnce())
+
+	// legacyNode only subscribes to the legacy topic, so it should have
+	// received the message from upgradedNode in the legacy format only.
+	assert.Contains(t, legacyMessageHandler.messages, &Message{
+		From: upgradedNode.host.ID(),
+		Data: []byte{1, 2, 3, 4, 9},
+	}, "legacyNode should receive the legacy message from upgradedNode")
+	assert.NotContains(t, legacyMessageHandler.messages, &Message{
+		From: upgradedNode.host.ID(),
+		Data: []byte{1, 2, 3, 4},
+	}, "legacyNode should not receive messages on the new topic")
+	// upgradedNode still receives messages from legacyNode.
+	assert.Contains(t, upgradedMessageHandler.messages, &Message{
+		From: legacyNode.host.ID(),
+		Data: []byte{5, 6, 7, 8},
+	}, "upgradedNode should receive the message from legacyNode")
+}
+
 func TestPeerDiscovery(t *testing.T) {
 	t.Parallel()
 	// Create a test notifee which will be used to detect new connections.
@@ -698,3 +830,80 @@ loop:
 		}
 	}
 }
+
+func TestPeers(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node0 := newTestNode(t, ctx, nil)
+	node1 := newTestNode(t, ctx, nil)
+	connectTestNodes(t, node0, node1)
+	node0.SetPeerScore(node1.ID(), "test", 5)
+
+	peers := node0.Peers()
+	require.Len(t, peers, 1)
+	assert.Equal(t, node1.ID(), peers[0].ID)
+	assert.Equal(t, p2pnet.DirOutbound, peers[0].Direction)
+	assert.NotEmpty(t, peers[0].Addrs)
+	assert.Equal(t, 5, peers[0].Scores["test"])
+
+	peers = node1.Peers()
+	require.Len(t, peers, 1)
+	assert.Equal(t, node0.ID(), peers[0].ID)
+	assert.Equal(t, p2pnet.DirInbound, peers[0].Direction)
+}
+
+func TestDisconnectPeer(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	disconnected := make(chan peer.ID, 1)
+	node0 := newTestNode(t, ctx, &p2pnet.NotifyBundle{
+		DisconnectedF: func(_ p2pnet.Network, conn p2pnet.Conn) {
+			select {
+			case disconnected <- conn.RemotePeer():
+			default:
+			}
+		},
+	})
+	node1 := newTestNode(t, ctx, nil)
+	connectTestNodes(t, node0, node1)
+
+	require.NoError(t, node0.DisconnectPeer(node1.ID()))
+	select {
+	case id := <-disconnected:
+		assert.Equal(t, node1.ID(), id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for node0 to disconnect from node1")
+	}
+	assert.False(t, node0.IsPeerBanned(node1.ID()))
+
+	// Unlike banned peers, disconnected peers can connect again.
+	connectTestNodes(t, node1, node0)
+}
+
+func TestProtectPeer(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node0 := newTestNode(t, ctx, nil)
+	node1 := newTestNode(t, ctx, nil)
+	connectTestNodes(t, node0, node1)
+	conns := node0.host.Network().ConnsToPeer(node1.ID())
+	require.NotEmpty(t, conns)
+
+	require.NoError(t, node0.ProtectPeer(node1.ID()))
+
+	// The IP addresses of node1 can no longer be banned.
+	for _, conn := range conns {
+		assert.Equal(t, banner.ErrProtectedIP, node0.BanIP(conn.RemoteMultiaddr()))
+	}
+
+	// node1 itself can no longer be banned either.
+	assert.Equal(t, ErrProtectedPeer, node0.BanPeer(node1.ID()))
+	assert.False(t, node0.IsPeerBanned(node1.ID()))
+	for _, conn := range conns {
+		assert.False(t, node0.banner.IsAddrBanned(conn.RemoteMultiaddr()))
+	}
+	assert.NotEmpty(t, node0.host.Network().ConnsToPeer(node1.ID()), "protected peer should not be disconnected")
+}
@@ -22,6 +22,9 @@ const (
 type notifee struct {
 	ctx         context.Context
 	connManager *connmgr.BasicConnMgr
+	reputation  *reputation
+	allowlist   peerAllowlist
+	diversity   *peerDiversity
 }
 
 var _ p2pnet.Notifiee = &notifee{}
@@ -38,6 +41,47 @@ func (n *notifee) Connected(network p2pnet.Network, conn p2pnet.Conn) {
 		"remotePeerID":       conn.RemotePeer(),
 		"remoteMultiaddress": conn.RemoteMultiaddr(),
 	}).Trace("connected to peer")
+
+	remotePeerID := conn.RemotePeer()
+	if !n.allowlist.isAllowed(remotePeerID) {
+		log.WithFields(map[string]interface{}{
+			"remotePeerID":       remotePeerID,
+			"remoteMultiaddress": conn.RemoteMultiaddr(),
+		}).Debug("closing connection to peer which is not in the allowlist")
+		go func() {
+			_ = conn.Close()
+		}()
+		return
+	}
+	if n.reputation.isBanned(remotePeerID) {
+		log.WithFields(map[string]interface{}{
+			"remotePeerID":       remotePeerID,
+			"remoteMultiaddress": conn.RemoteMultiaddr(),
+		}).Debug("closing connection to banned peer")
+		// Closing the connection from within the notifee can deadlock, so we do
+		// it in a separate goroutine.
+		go func() {
+			_ = network.ClosePeer(remotePeerID)
+		}()
+		return
+	}
+
+	if !n.diversity.addPeer(remotePeerID, conn.RemoteMultiaddr(), conn.Stat().Direction) {
+		log.WithFields(map[string]interface{}{
+			"remotePeerID":       remotePeerID,
+			"remoteMultiaddress": conn.RemoteMultiaddr(),
+		}).Debug("closing connection to peer in a subnet with too many peers")
+		go func() {
+			_ = conn.Close()
+		}()
+		return
+	}
+
+	// The Connection Manager forgets the scores for a peer when it disconnects.
+	// Re-apply any scores we remember from previous connections.
+	for tag, val := range n.reputation.getScores(remotePeerID) {
+		n.connManager.TagPeer(remotePeerID, tag, val)
+	}
 }
 
 // Disconnected is called when a connection closed
@@ -46,6 +90,11 @@ func (n *notifee) Disconnected(network p2pnet.Network, conn p2pnet.Conn) {
 		"remotePeerID":       conn.RemotePeer(),
 		"remoteMultiaddress": conn.RemoteMultiaddr(),
 	}).Trace("disconnected from peer")
+
+	// We might still have other connections to the same peer.
+	if network.Connectedness(conn.RemotePeer()) != p2pnet.Connected {
+		n.diversity.removePeer(conn.RemotePeer())
+	}
 }
 
 // OpenedStream is called when a stream opened
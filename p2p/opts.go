@@ -23,15 +23,18 @@ const (
 	maxReceiveBatch = 500
 	// maxShareBatch is the maximum number of messages to share at once.
 	maxShareBatch = 100
-	// peerCountLow is the target number of peers to connect to at any given time.
-	peerCountLow = 100
-	// peerCountHigh is the maximum number of peers to be connected to. If the
-	// number of connections exceeds this number, we will prune connections until
-	// we reach peerCountLow.
-	peerCountHigh = 110
+	// defaultPeerCountLow is the default value for PeerCountLow.
+	defaultPeerCountLow = 100
+	// defaultPeerCountHigh is the default value for PeerCountHigh.
+	defaultPeerCountHigh = 110
 )
 
 func getHostOptions(ctx context.Context, config Config) ([]libp2p.Option, error) {
+	privateNetworkOpts, err := getPrivateNetworkOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
 	// Note: 0.0.0.0 will use all available addresses.
 	tcpBindAddr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", config.TCPPort))
 	if err != nil {
@@ -65,15 +68,16 @@ func getHostOptions(ctx context.Context, config Config) ([]libp2p.Option, error)
 	if err != nil {
 		return nil, err
 	}
+	closeOnDone(ctx, store)
 	pstore, err := pstoreds.NewPeerstore(ctx, store, pstoreds.DefaultOpts())
 	if err != nil {
 		return nil, err
 	}
-	return []libp2p.Option{
+	return append([]libp2p.Option{
 		libp2p.ListenAddrs(tcpBindAddr, wsBindAddr),
 		libp2p.AddrsFactory(newAddrsFactory(advertiseAddrs)),
 		libp2p.Peerstore(pstore),
-	}, nil
+	}, privateNetworkOpts...), nil
 }
 
 func getPubSubOptions() []pubsub.Option {
@@ -113,5 +117,26 @@ func NewDHT(ctx context.Context, storageDir string, host host.Host) (*dht.IpfsDH
 		return nil, err
 	}
 
-	return dht.New(ctx, host, dhtopts.Datastore(store), dhtopts.Protocols(DHTProtocolID))
+	kadDHT, err := dht.New(ctx, host, dhtopts.Datastore(store), dhtopts.Protocols(DHTProtocolID))
+	if err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	// The DHT doesn't close its datastore. It also flushes it when shutting
+	// down, so we can only close the datastore once the DHT has shut down.
+	go func() {
+		<-kadDHT.Process().Closed()
+		_ = store.Close()
+	}()
+	return kadDHT, nil
+}
+
+// closeOnDone closes the given LevelDB datastore when ctx is canceled. The host
+// doesn't close the datastore used by the peerstore, and LevelDB starts several
+// goroutines which would otherwise run forever.
+func closeOnDone(ctx context.Context, store *leveldbStore.Datastore) {
+	go func() {
+		<-ctx.Done()
+		_ = store.Close()
+	}()
 }
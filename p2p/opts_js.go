@@ -18,21 +18,23 @@ const (
 	maxReceiveBatch = 100
 	// maxShareBatch is the maximum number of messages to share at once.
 	maxShareBatch = 50
-	// peerCountLow is the target number of peers to connect to at any given time.
-	peerCountLow = 10
-	// peerCountHigh is the maximum number of peers to be connected to. If the
-	// number of connections exceeds this number, we will prune connections until
-	// we reach peerCountLow.
-	peerCountHigh = 12
+	// defaultPeerCountLow is the default value for PeerCountLow.
+	defaultPeerCountLow = 10
+	// defaultPeerCountHigh is the default value for PeerCountHigh.
+	defaultPeerCountHigh = 12
 )
 
 func getHostOptions(ctx context.Context, config Config) ([]libp2p.Option, error) {
-	return []libp2p.Option{
+	privateNetworkOpts, err := getPrivateNetworkOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	return append([]libp2p.Option{
 		libp2p.Transport(ws.New),
 		// Don't listen on any addresses by default. We can't accept incoming
 		// connections in the browser.
 		libp2p.ListenAddrs(),
-	}, nil
+	}, privateNetworkOpts...), nil
 }
 
 func getPubSubOptions() []pubsub.Option {
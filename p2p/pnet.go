@@ -0,0 +1,82 @@
+package p2p
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	libp2p "github.com/libp2p/go-libp2p"
+	ipnet "github.com/libp2p/go-libp2p-core/pnet"
+	pnet "github.com/libp2p/go-libp2p-pnet"
+)
+
+// privateNetworkKeySize is the size of a private network pre-shared key in
+// bytes.
+const privateNetworkKeySize = 32
+
+// ParsePrivateNetworkKey parses a hex-encoded private network pre-shared key.
+// A new key can be generated with `openssl rand -hex 32`.
+func ParsePrivateNetworkKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimPrefix(strings.TrimSpace(encoded), "0x")
+	key, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private network key: %s", err.Error())
+	}
+	if len(key) != privateNetworkKeySize {
+		return nil, fmt.Errorf("invalid private network key: expected %d bytes but got %d", privateNetworkKeySize, len(key))
+	}
+	return key, nil
+}
+
+// getPrivateNetworkOptions returns the host options which are required to
+// only connect to peers with the same config.PrivateNetworkKey. It returns
+// nil if no key is configured.
+func getPrivateNetworkOptions(config Config) ([]libp2p.Option, error) {
+	if len(config.PrivateNetworkKey) == 0 {
+		return nil, nil
+	}
+	if len(config.PrivateNetworkKey) != privateNetworkKeySize {
+		return nil, fmt.Errorf("private network key must be %d bytes but got %d", privateNetworkKeySize, len(config.PrivateNetworkKey))
+	}
+	psk := &[privateNetworkKeySize]byte{}
+	copy(psk[:], config.PrivateNetworkKey)
+	// The protector encrypts all traffic with the pre-shared key as described
+	// in the libp2p private network specification. Peers which don't know the
+	// key can't complete the multistream handshake so connections to them fail
+	// immediately.
+	protector, err := pnet.NewV1ProtectorFromBytes(psk)
+	if err != nil {
+		return nil, err
+	}
+	return []libp2p.Option{libp2p.PrivateNetwork(&serialWriteProtector{Protector: protector})}, nil
+}
+
+// serialWriteProtector wraps a protector so that writes to the connections it
+// protects are serialized. The multistream handshake may write to a connection
+// from more than one goroutine at a time, and the protected connection shares a
+// single cipher stream between all writes.
+type serialWriteProtector struct {
+	ipnet.Protector
+}
+
+func (p *serialWriteProtector) Protect(conn net.Conn) (net.Conn, error) {
+	protected, err := p.Protector.Protect(conn)
+	if err != nil {
+		return nil, err
+	}
+	return &serialWriteConn{Conn: protected}, nil
+}
+
+// serialWriteConn is a net.Conn that only allows one Write at a time.
+type serialWriteConn struct {
+	net.Conn
+	writeMu sync.Mutex
+}
+
+func (c *serialWriteConn) Write(b []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,100 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	p2pnet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPrivateNetworkKey(t *testing.T) []byte {
+	key := make([]byte, privateNetworkKeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return key
+}
+
+func newTestPrivateNode(t *testing.T, ctx context.Context, notifee p2pnet.Notifiee, key []byte, allowlist []peer.ID) *Node {
+	return newTestNodeWithConfig(t, ctx, notifee, Config{
+		Topic:             testTopic,
+		MessageHandler:    &dummyMessageHandler{},
+		RendezvousString:  testRendezvousString,
+		UseBootstrapList:  false,
+		DataDir:           newTestDataDir(),
+		PrivateNetworkKey: key,
+		PeerAllowlist:     allowlist,
+	})
+}
+
+func TestParsePrivateNetworkKey(t *testing.T) {
+	t.Parallel()
+	encoded := "0x000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	key, err := ParsePrivateNetworkKey(encoded)
+	require.NoError(t, err)
+	require.Len(t, key, privateNetworkKeySize)
+	assert.Equal(t, byte(0x1f), key[31])
+
+	_, err = ParsePrivateNetworkKey(encoded[2:])
+	assert.NoError(t, err, "0x prefix should be optional")
+	_, err = ParsePrivateNetworkKey("0x0001")
+	assert.Error(t, err, "key is too short")
+	_, err = ParsePrivateNetworkKey("not hex")
+	assert.Error(t, err, "key is not hex encoded")
+}
+
+func TestGetPrivateNetworkOptions(t *testing.T) {
+	t.Parallel()
+	opts, err := getPrivateNetworkOptions(Config{})
+	require.NoError(t, err)
+	assert.Empty(t, opts, "no options are needed without a private network key")
+
+	opts, err = getPrivateNetworkOptions(Config{PrivateNetworkKey: newTestPrivateNetworkKey(t)})
+	require.NoError(t, err)
+	assert.Len(t, opts, 1)
+
+	_, err = getPrivateNetworkOptions(Config{PrivateNetworkKey: []byte{1, 2, 3}})
+	assert.Error(t, err, "key is too short")
+}
+
+func TestPrivateNetwork(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	key := newTestPrivateNetworkKey(t)
+	node0 := newTestPrivateNode(t, ctx, nil, key, nil)
+	node1 := newTestPrivateNode(t, ctx, nil, key, nil)
+	otherNetworkNode := newTestPrivateNode(t, ctx, nil, newTestPrivateNetworkKey(t), nil)
+	publicNode := newTestNode(t, ctx, nil)
+
+	// Nodes with the same key can connect.
+	connectTestNodes(t, node0, node1)
+
+	// Nodes with a different key or without any key can't connect in either
+	// direction.
+	for _, other := range []*Node{otherNetworkNode, publicNode} {
+		assert.Error(t, node0.Connect(peer.AddrInfo{ID: other.ID(), Addrs: other.Multiaddrs()}, testConnectionTimeout))
+	}
+	assert.Error(t, otherNetworkNode.Connect(peer.AddrInfo{ID: node0.ID(), Addrs: node0.Multiaddrs()}, testConnectionTimeout))
+	// The public node's connections aren't protected, and a failed multistream
+	// handshake writes to the connection from two goroutines at once. That
+	// isn't safe for WebSocket connections, so only dial over TCP here.
+	assert.Error(t, publicNode.Connect(peer.AddrInfo{ID: node0.ID(), Addrs: tcpOnlyAddrs(node0.Multiaddrs())}, testConnectionTimeout))
+}
+
+// tcpOnlyAddrs returns the addresses in addrs which don't use WebSockets.
+func tcpOnlyAddrs(addrs []ma.Multiaddr) []ma.Multiaddr {
+	tcpAddrs := []ma.Multiaddr{}
+	for _, addr := range addrs {
+		if _, err := addr.ValueForProtocol(ma.P_WS); err != nil {
+			tcpAddrs = append(tcpAddrs, addr)
+		}
+	}
+	return tcpAddrs
+}
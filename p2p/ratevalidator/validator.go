@@ -3,9 +3,11 @@ package ratevalidator
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
-	"github.com/karlseguin/ccache"
+	"github.com/0xProject/0x-mesh/p2p/ttlcache"
+	"github.com/benbjohnson/clock"
 	peer "github.com/libp2p/go-libp2p-peer"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	log "github.com/sirupsen/logrus"
@@ -19,10 +21,15 @@ const (
 	peerLimiterCacheSize = 500
 	// peerLimiterCacheTTL is the TTL for rate limiters for each peer. If a peer
 	// does not send any messages for this duration, they will be removed from the
-	// cache and their rate limiter will be reset.
+	// cache and their rate limiter and stats will be reset.
 	peerLimiterCacheTTL = 5 * time.Minute
 	// logStatsInterval is how often to log stats about rate limiting.
 	logStatsInterval = 1 * time.Hour
+	// defaultHighScoreBurstMultiplier is the default value for
+	// HighScoreBurstMultiplier.
+	defaultHighScoreBurstMultiplier = 4
+	// defaultLowScoreLimitDivisor is the default value for LowScoreLimitDivisor.
+	defaultLowScoreLimitDivisor = 4
 )
 
 // Dummy declaration to ensure that Validate can be used as a pubsub.Validator
@@ -33,8 +40,9 @@ var _ pubsub.Validator = (&Validator{}).Validate
 type Validator struct {
 	ctx           context.Context
 	config        Config
+	clock         clock.Clock
 	globalLimiter *trackingRateLimiter
-	peerLimiters  *ccache.Cache
+	peerLimiters  *ttlcache.Cache
 }
 
 // Config is a set of configuration options for the validator.
@@ -56,35 +64,98 @@ type Config struct {
 	// MaxMessageSize is the maximum size (in bytes) for a message. Any messages
 	// that exceed this size will be considered invalid.
 	MaxMessageSize int
+	// AdaptiveLimits determines whether the per-peer limits depend on the score
+	// of each peer. If true, peers with a score of at least HighScoreThreshold
+	// can send bigger bursts of messages and peers with a score below
+	// LowScoreThreshold are limited to a lower rate.
+	AdaptiveLimits bool
+	// PeerScore returns the current score for the given peer. It is required if
+	// AdaptiveLimits is true.
+	PeerScore func(peer.ID) int
+	// HighScoreThreshold is the minimum score for a peer to be considered a
+	// high score peer.
+	HighScoreThreshold int
+	// HighScoreBurstMultiplier is the factor by which PerPeerBurst is multiplied
+	// for high score peers. Defaults to 4.
+	HighScoreBurstMultiplier int
+	// LowScoreThreshold is the score below which a peer is considered a low
+	// score peer.
+	LowScoreThreshold int
+	// LowScoreLimitDivisor is the factor by which PerPeerLimit and PerPeerBurst
+	// are divided for low score peers. Defaults to 4.
+	LowScoreLimitDivisor int
+	// Clock is used to determine the current time. Defaults to the system
+	// clock. It can be overridden in tests.
+	Clock clock.Clock
 }
 
-// New creates and returns a new rate limiting validator.
-// BUG(albrow): New currently leaks goroutines due to a limitation of the
-// caching library used under the hood.
+// PeerStats contains the number of messages received from a peer, grouped by
+// what happened to them.
+type PeerStats struct {
+	// Accepted is the number of messages which were not rate limited.
+	Accepted uint64
+	// RateLimited is the number of messages which were dropped because either
+	// the per-peer or the global rate limit was exceeded.
+	RateLimited uint64
+	// Oversize is the number of messages which were dropped because they
+	// exceeded MaxMessageSize.
+	Oversize uint64
+	// Invalid is the number of accepted messages which were later found to be
+	// invalid (see RecordInvalidMessage).
+	Invalid uint64
+}
+
+// scoreTier is the group a peer belongs to based on its score. Peers in
+// different tiers have different per-peer limits.
+type scoreTier uint8
+
+const (
+	normalScoreTier scoreTier = iota
+	highScoreTier
+	lowScoreTier
+)
+
+// peerState holds the rate limiter and the stats for a single peer.
+type peerState struct {
+	mu      sync.Mutex
+	tier    scoreTier
+	limiter *rate.Limiter
+	stats   PeerStats
+}
+
+// New creates and returns a new rate limiting validator. Any goroutines it
+// starts exit when ctx is canceled.
 func New(ctx context.Context, config Config) (*Validator, error) {
 	if config.MyPeerID.String() == "" {
 		return nil, errors.New("config.MyPeerID is required")
 	} else if config.MaxMessageSize == 0 {
 		return nil, errors.New("config.MaxMessageSize is required")
+	} else if config.AdaptiveLimits && config.PeerScore == nil {
+		return nil, errors.New("config.PeerScore is required if config.AdaptiveLimits is true")
+	}
+	if config.HighScoreBurstMultiplier == 0 {
+		config.HighScoreBurstMultiplier = defaultHighScoreBurstMultiplier
+	}
+	if config.LowScoreLimitDivisor == 0 {
+		config.LowScoreLimitDivisor = defaultLowScoreLimitDivisor
+	}
+	if config.Clock == nil {
+		config.Clock = clock.New()
+	}
+	peerLimiters, err := ttlcache.New(ctx, ttlcache.Config{
+		MaxSize: peerLimiterCacheSize,
+		Clock:   config.Clock,
+	})
+	if err != nil {
+		return nil, err
 	}
 	validator := &Validator{
 		ctx:           ctx,
 		config:        config,
+		clock:         config.Clock,
 		globalLimiter: newTrackingRateLimiter(config.GlobalLimit, config.GlobalBurst),
-		peerLimiters:  ccache.New(ccache.Configure().MaxSize(peerLimiterCacheSize)),
-	}
-	// TODO(albrow): We should be calling Stop to cleanup any goroutines
-	// started by ccache, but doing so now results in a race condition. Figure
-	// out a workaround or use a different library, possibly one we write
-	// ourselves.
-	// go func() {
-	// 	// Stop and clear the cache when the context is canceled.
-	// 	select {
-	// 	case <-ctx.Done():
-	// 		// validator.peerLimiters.Clear()
-	// 		// validator.peerLimiters.Stop()
-	// 	}
-	// }()
+		peerLimiters:  peerLimiters,
+	}
 	go validator.periodicallyLogStats(ctx)
 	return validator, nil
 }
@@ -102,33 +173,115 @@ func (v *Validator) Validate(ctx context.Context, peerID peer.ID, msg *pubsub.Me
 		return true
 	}
 
+	state := v.getOrCreateStateForPeer(peerID)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
 	if msg.Size() > v.config.MaxMessageSize {
+		state.stats.Oversize++
 		return false
 	}
 
 	// Note: We check the per-peer rate limiter first so that peers who are
 	// exceeding the limit do not contribute toward the global rate limit.
-	peerLimiter, err := v.getOrCreateLimiterForPeer(peerID)
-	if err != nil {
-		log.WithError(err).Error("unexpected error in getOrCreateLimiterForPeer")
+	now := v.clock.Now()
+	v.updateTier(now, peerID, state)
+	if !state.limiter.AllowN(now, 1) || !v.globalLimiter.allow(now) {
+		state.stats.RateLimited++
 		return false
 	}
-	if !peerLimiter.Allow() {
-		return false
+	state.stats.Accepted++
+	return true
+}
+
+// RecordInvalidMessage records that a message from the given peer which was
+// accepted by Validate turned out to be invalid.
+func (v *Validator) RecordInvalidMessage(peerID peer.ID) {
+	if peerID == v.config.MyPeerID {
+		return
 	}
+	state := v.getOrCreateStateForPeer(peerID)
+	state.mu.Lock()
+	state.stats.Invalid++
+	state.mu.Unlock()
+}
 
-	return v.globalLimiter.allow()
+// PeerStats returns the stats for the given peer. Stats are reset if the peer
+// does not send any messages for 5 minutes.
+func (v *Validator) PeerStats(peerID peer.ID) PeerStats {
+	value, found := v.peerLimiters.Get(string(peerID))
+	if !found {
+		return PeerStats{}
+	}
+	state := value.(*peerState)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.stats
 }
 
-func (v *Validator) getOrCreateLimiterForPeer(peerID peer.ID) (*rate.Limiter, error) {
-	item, err := v.peerLimiters.Fetch(peerID.String(), peerLimiterCacheTTL, func() (interface{}, error) {
-		limiter := rate.NewLimiter(v.config.PerPeerLimit, v.config.PerPeerBurst)
-		return limiter, nil
+// getOrCreateStateForPeer returns the state for the given peer. Fetching the
+// state resets its TTL, so it is kept around for as long as the peer keeps
+// sending messages.
+func (v *Validator) getOrCreateStateForPeer(peerID peer.ID) *peerState {
+	value := v.peerLimiters.Fetch(string(peerID), peerLimiterCacheTTL, func() interface{} {
+		tier := v.getTier(peerID)
+		return &peerState{
+			tier:    tier,
+			limiter: rate.NewLimiter(v.limitsForTier(tier)),
+		}
 	})
-	if err != nil {
-		return nil, err
+	return value.(*peerState)
+}
+
+// updateTier updates the limit and burst of the rate limiter for the given
+// peer if its score tier changed. state.mu must be held when calling it. The
+// existing rate limiter is kept so that a peer doesn't get a full burst of
+// tokens each time its tier changes.
+func (v *Validator) updateTier(now time.Time, peerID peer.ID, state *peerState) {
+	if !v.config.AdaptiveLimits {
+		return
+	}
+	tier := v.getTier(peerID)
+	if tier == state.tier {
+		return
+	}
+	state.tier = tier
+	limit, burst := v.limitsForTier(tier)
+	state.limiter.SetLimitAt(now, limit)
+	state.limiter.SetBurstAt(now, burst)
+}
+
+func (v *Validator) getTier(peerID peer.ID) scoreTier {
+	if !v.config.AdaptiveLimits {
+		return normalScoreTier
+	}
+	score := v.config.PeerScore(peerID)
+	switch {
+	case score >= v.config.HighScoreThreshold:
+		return highScoreTier
+	case score < v.config.LowScoreThreshold:
+		return lowScoreTier
+	default:
+		return normalScoreTier
+	}
+}
+
+// limitsForTier returns the per-peer limit and burst for peers in the given
+// tier.
+func (v *Validator) limitsForTier(tier scoreTier) (rate.Limit, int) {
+	switch tier {
+	case highScoreTier:
+		return v.config.PerPeerLimit, v.config.PerPeerBurst * v.config.HighScoreBurstMultiplier
+	case lowScoreTier:
+		limit := v.config.PerPeerLimit / rate.Limit(v.config.LowScoreLimitDivisor)
+		burst := v.config.PerPeerBurst / v.config.LowScoreLimitDivisor
+		if burst < 1 {
+			burst = 1
+		}
+		return limit, burst
+	default:
+		return v.config.PerPeerLimit, v.config.PerPeerBurst
 	}
-	return item.Value().(*rate.Limiter), nil
 }
 
 // isClosed returns true if the context is done and false otherwise.
@@ -143,6 +296,7 @@ func (v *Validator) isClosed() bool {
 
 func (v *Validator) periodicallyLogStats(ctx context.Context) {
 	ticker := time.NewTicker(logStatsInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
@@ -2,9 +2,12 @@ package ratevalidator
 
 import (
 	"context"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/libp2p/go-libp2p-core/peer"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	pb "github.com/libp2p/go-libp2p-pubsub/pb"
@@ -115,3 +118,189 @@ func TestValidatorMaxMessageSize(t *testing.T) {
 	})
 	assert.False(t, valid, "message should be valid")
 }
+
+func TestValidatorWithMockClock(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	mockClock := clock.NewMock()
+	validator, err := New(ctx, Config{
+		MyPeerID:       peerIDs[0],
+		GlobalLimit:    rate.Inf,
+		PerPeerLimit:   1,
+		PerPeerBurst:   5,
+		MaxMessageSize: 1024,
+		Clock:          mockClock,
+	})
+	require.NoError(t, err)
+
+	peerID := peerIDs[1]
+	for i := 0; i < validator.config.PerPeerBurst; i++ {
+		assert.True(t, validator.Validate(ctx, peerID, &pubsub.Message{}), "message should be valid")
+	}
+	assert.False(t, validator.Validate(ctx, peerID, &pubsub.Message{}), "message should be invalid")
+
+	// No new messages should be allowed until the clock moves forward.
+	assert.False(t, validator.Validate(ctx, peerID, &pubsub.Message{}), "message should be invalid")
+	mockClock.Add(1 * time.Second)
+	assert.True(t, validator.Validate(ctx, peerID, &pubsub.Message{}), "message should be valid")
+	assert.False(t, validator.Validate(ctx, peerID, &pubsub.Message{}), "message should be invalid")
+
+	assert.Equal(t, PeerStats{Accepted: 6, RateLimited: 3}, validator.PeerStats(peerID))
+}
+
+func TestValidatorPeerStats(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	validator, err := New(ctx, Config{
+		MyPeerID:       peerIDs[0],
+		GlobalLimit:    rate.Inf,
+		PerPeerLimit:   rate.Inf,
+		MaxMessageSize: 48,
+		Clock:          clock.NewMock(),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, PeerStats{}, validator.PeerStats(peerIDs[1]), "unknown peers should have empty stats")
+
+	assert.True(t, validator.Validate(ctx, peerIDs[1], &pubsub.Message{}))
+	assert.True(t, validator.Validate(ctx, peerIDs[1], &pubsub.Message{}))
+	oversizeMessage := &pubsub.Message{
+		Message: &pb.Message{
+			Data: make([]byte, validator.config.MaxMessageSize+1),
+		},
+	}
+	assert.False(t, validator.Validate(ctx, peerIDs[1], oversizeMessage))
+	validator.RecordInvalidMessage(peerIDs[1])
+	assert.Equal(t, PeerStats{Accepted: 2, Oversize: 1, Invalid: 1}, validator.PeerStats(peerIDs[1]))
+
+	// Stats for other peers are tracked separately.
+	assert.True(t, validator.Validate(ctx, peerIDs[2], &pubsub.Message{}))
+	assert.Equal(t, PeerStats{Accepted: 1}, validator.PeerStats(peerIDs[2]))
+
+	// Our own messages are not counted.
+	assert.True(t, validator.Validate(ctx, peerIDs[0], &pubsub.Message{}))
+	validator.RecordInvalidMessage(peerIDs[0])
+	assert.Equal(t, PeerStats{}, validator.PeerStats(peerIDs[0]))
+}
+
+func TestValidatorAdaptiveLimits(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	highScorePeer := peerIDs[0]
+	lowScorePeer := peerIDs[1]
+	normalScorePeer := peerIDs[2]
+	var scoresMu sync.Mutex
+	scores := map[peer.ID]int{
+		highScorePeer:   10,
+		lowScorePeer:    -5,
+		normalScorePeer: 0,
+	}
+	mockClock := clock.NewMock()
+	validator, err := New(ctx, Config{
+		MyPeerID:       "myPeerID",
+		GlobalLimit:    rate.Inf,
+		PerPeerLimit:   1,
+		PerPeerBurst:   8,
+		MaxMessageSize: 1024,
+		AdaptiveLimits: true,
+		PeerScore: func(peerID peer.ID) int {
+			scoresMu.Lock()
+			defer scoresMu.Unlock()
+			return scores[peerID]
+		},
+		HighScoreThreshold:       10,
+		HighScoreBurstMultiplier: 2,
+		LowScoreThreshold:        0,
+		LowScoreLimitDivisor:     4,
+		Clock:                    mockClock,
+	})
+	require.NoError(t, err)
+
+	// countAllowed sends messages from the given peer until one is rate limited
+	// and returns the number of messages that were allowed.
+	countAllowed := func(peerID peer.ID) int {
+		allowed := 0
+		for validator.Validate(ctx, peerID, &pubsub.Message{}) {
+			allowed++
+			require.True(t, allowed <= 100, "too many messages were allowed")
+		}
+		return allowed
+	}
+
+	assert.Equal(t, 16, countAllowed(highScorePeer), "high score peers should get a bigger burst")
+	assert.Equal(t, 8, countAllowed(normalScorePeer))
+	assert.Equal(t, 2, countAllowed(lowScorePeer), "low score peers should get a smaller burst")
+
+	// Low score peers should also get a lower rate.
+	mockClock.Add(1 * time.Second)
+	assert.Equal(t, 1, countAllowed(highScorePeer))
+	assert.Equal(t, 1, countAllowed(normalScorePeer))
+	assert.Equal(t, 0, countAllowed(lowScorePeer))
+	mockClock.Add(3 * time.Second)
+	assert.Equal(t, 1, countAllowed(lowScorePeer))
+
+	// Limits should change as soon as the score of a peer changes, but the peer
+	// should not get a new burst of tokens when it does.
+	assert.Equal(t, 3, countAllowed(normalScorePeer))
+	scoresMu.Lock()
+	scores[normalScorePeer] = 10
+	scoresMu.Unlock()
+	assert.Equal(t, 0, countAllowed(normalScorePeer), "changing tiers should not refill tokens")
+	mockClock.Add(20 * time.Second)
+	assert.Equal(t, 16, countAllowed(normalScorePeer))
+
+	// A peer which drops to a lower tier should not keep its bigger burst.
+	mockClock.Add(20 * time.Second)
+	scoresMu.Lock()
+	scores[highScorePeer] = -5
+	scoresMu.Unlock()
+	assert.Equal(t, 2, countAllowed(highScorePeer))
+}
+
+func TestValidatorAdaptiveLimitsRequiresPeerScore(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := New(ctx, Config{
+		MyPeerID:       peerIDs[0],
+		MaxMessageSize: 1024,
+		AdaptiveLimits: true,
+	})
+	assert.Error(t, err)
+}
+
+func TestValidatorDoesNotLeakGoroutines(t *testing.T) {
+	// This test is intentionally not run in parallel since it counts the
+	// number of goroutines.
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		validator, err := New(ctx, Config{
+			MyPeerID:       peerIDs[0],
+			GlobalLimit:    rate.Inf,
+			PerPeerLimit:   rate.Inf,
+			MaxMessageSize: 1024,
+		})
+		require.NoError(t, err)
+		assert.True(t, validator.Validate(ctx, peerIDs[1], &pubsub.Message{Message: &pb.Message{}}))
+		cancel()
+	}
+
+	// Wait for all goroutines started by the validators to exit.
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, runtime.NumGoroutine() <= before, "expected at most %d goroutines but got %d", before, runtime.NumGoroutine())
+}
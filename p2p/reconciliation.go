@@ -0,0 +1,348 @@
+package p2p
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/p2p/bloom"
+	"github.com/libp2p/go-libp2p-core/helpers"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// setReconciliationProtocolID is the protocol ID for set reconciliation.
+	setReconciliationProtocolID = protocol.ID("/0x-mesh/set-reconciliation/1.0.0")
+	// setReconciliationInterval is how often we reconcile with some of our
+	// peers.
+	setReconciliationInterval = 1 * time.Minute
+	// setReconciliationPeersPerRound is the number of randomly chosen peers that
+	// we reconcile with every setReconciliationInterval.
+	setReconciliationPeersPerRound = 3
+	// setReconciliationFalsePositiveRate is the target false positive rate for
+	// the Bloom filters sent to peers. A false positive means that a peer will
+	// not send us a message we are missing. Since a new seed is used every time,
+	// the message will most likely be sent the next time we reconcile.
+	setReconciliationFalsePositiveRate = 0.001
+	// maxSetReconciliationFilterSize is the maximum size of a Bloom filter sent
+	// by a peer. It is large enough for a few million messages.
+	maxSetReconciliationFilterSize = 8 * 1024 * 1024
+	// maxSetReconciliationMessages is the maximum number of messages that will be
+	// sent in response to a single reconciliation request. Any remaining missing
+	// messages are sent the next time the peer reconciles with us.
+	maxSetReconciliationMessages = 500
+	// setReconciliationTimeout is the maximum amount of time that a single
+	// reconciliation is allowed to take.
+	setReconciliationTimeout = 30 * time.Second
+	// setReconciliationPerPeerLimit is the maximum number of reconciliation
+	// requests per second that each peer is allowed to send.
+	setReconciliationPerPeerLimit = rate.Limit(1.0 / 10.0)
+	// setReconciliationPerPeerBurst is the maximum number of reconciliation
+	// requests that each peer is allowed to send at once.
+	setReconciliationPerPeerBurst = 3
+	// invalidReconciliationScoreTag is the peer score tag used for peers which
+	// violate the set reconciliation protocol.
+	invalidReconciliationScoreTag  = "invalid-set-reconciliation"
+	invalidReconciliationScoreDiff = -5
+)
+
+var errSetReconciliationRateLimited = errors.New("set reconciliation request was rate limited")
+
+// SetReconciliationHandler is an interface responsible for providing the set of
+// messages which are used for set reconciliation. During set reconciliation, a
+// node sends a compact summary of the IDs of all its messages to a peer, which
+// then responds with only the messages the node is missing. Messages received
+// this way are passed to MessageHandler.HandleMessages, just like messages
+// received via GossipSub.
+type SetReconciliationHandler interface {
+	// MessageIDs returns the unique IDs (e.g. hashes) of all messages which can
+	// be shared with peers.
+	MessageIDs() ([][]byte, error)
+	// MessagesByID returns the messages with the given IDs. IDs for messages
+	// which no longer exist should be skipped.
+	MessagesByID(ids [][]byte) ([][]byte, error)
+}
+
+// SetReconciliationStats contains stats about a single reconciliation with a
+// peer.
+type SetReconciliationStats struct {
+	// BytesSent is the number of bytes we sent to the peer.
+	BytesSent int
+	// BytesReceived is the number of bytes we received from the peer.
+	BytesReceived int
+	// MessagesReceived is the number of messages we received from the peer.
+	MessagesReceived int
+}
+
+// ReconcileWithPeer sends a Bloom filter of the IDs of all our messages to the
+// given peer, which responds with (up to maxSetReconciliationMessages of) the
+// messages we are missing. The messages we receive are passed to
+// MessageHandler.HandleMessages. It returns an error if set reconciliation is
+// not enabled.
+func (n *Node) ReconcileWithPeer(ctx context.Context, id peer.ID) (*SetReconciliationStats, error) {
+	if n.config.SetReconciliationHandler == nil {
+		return nil, errors.New("set reconciliation is not enabled")
+	}
+	ids, err := n.config.SetReconciliationHandler.MessageIDs()
+	if err != nil {
+		return nil, err
+	}
+	var seedBytes [8]byte
+	if _, err := rand.Read(seedBytes[:]); err != nil {
+		return nil, err
+	}
+	filter := bloom.New(len(ids), setReconciliationFalsePositiveRate, binary.BigEndian.Uint64(seedBytes[:]))
+	for _, id := range ids {
+		filter.Add(id)
+	}
+	filterData, err := filter.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, setReconciliationTimeout)
+	defer cancel()
+	stream, err := n.NewStream(ctx, id, setReconciliationProtocolID)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+	stats := &SetReconciliationStats{}
+	writer := &countingWriter{writer: stream}
+	if err := writeLengthPrefixed(writer, filterData); err != nil {
+		_ = stream.Reset()
+		return nil, err
+	}
+	stats.BytesSent = writer.count
+
+	reader := &countingReader{reader: stream}
+	bufReader := bufio.NewReader(reader)
+	messages := []*Message{}
+	for {
+		data, err := readLengthPrefixed(bufReader, constants.MaxOrderSizeInBytes)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			_ = stream.Reset()
+			n.AddPeerScore(id, invalidReconciliationScoreTag, invalidReconciliationScoreDiff)
+			return nil, err
+		}
+		if len(data) == 0 {
+			// An empty message means that the peer refused our request because we
+			// exceeded the rate limit.
+			_ = stream.Reset()
+			return nil, errSetReconciliationRateLimited
+		}
+		if len(messages) >= maxSetReconciliationMessages {
+			_ = stream.Reset()
+			n.AddPeerScore(id, invalidReconciliationScoreTag, invalidReconciliationScoreDiff)
+			return nil, fmt.Errorf("peer sent more than %d messages during set reconciliation", maxSetReconciliationMessages)
+		}
+		messages = append(messages, &Message{From: id, Data: data})
+	}
+	_ = helpers.FullClose(stream)
+	stats.BytesReceived = reader.count
+	stats.MessagesReceived = len(messages)
+
+	if len(messages) > 0 {
+		if err := n.messageHandler.HandleMessages(messages); err != nil {
+			return nil, fmt.Errorf("could not validate or store messages: %s", err.Error())
+		}
+	}
+	return stats, nil
+}
+
+// handleSetReconciliationStream responds to a set reconciliation request from
+// a peer with the messages the peer is missing.
+func (n *Node) handleSetReconciliationStream(stream network.Stream) {
+	requester := stream.Conn().RemotePeer()
+	_ = stream.SetDeadline(time.Now().Add(setReconciliationTimeout))
+	logger := log.WithField("requester", requester.String())
+
+	// Check the rate limit before reading the request so that peers which
+	// exceed it can't make us read and decode large filters.
+	if !n.getSetReconciliationLimiter(requester).Allow() {
+		logger.Debug("set reconciliation request rate limit exceeded")
+		// Send an empty message to indicate that the request was refused. The
+		// request itself is never read. The requester resets the stream once it
+		// reads the refusal, but if it doesn't we reset it after the timeout.
+		_ = writeLengthPrefixed(stream, nil)
+		_ = stream.Close()
+		time.AfterFunc(setReconciliationTimeout, func() {
+			_ = stream.Reset()
+		})
+		return
+	}
+
+	filterData, err := readLengthPrefixed(bufio.NewReader(stream), maxSetReconciliationFilterSize)
+	if err != nil {
+		logger.WithError(err).Trace("could not read set reconciliation request")
+		n.AddPeerScore(requester, invalidReconciliationScoreTag, invalidReconciliationScoreDiff)
+		_ = stream.Reset()
+		return
+	}
+	var filter bloom.Filter
+	if err := filter.UnmarshalBinary(filterData); err != nil {
+		logger.WithError(err).Trace("received invalid set reconciliation filter")
+		n.AddPeerScore(requester, invalidReconciliationScoreTag, invalidReconciliationScoreDiff)
+		_ = stream.Reset()
+		return
+	}
+
+	ids, err := n.config.SetReconciliationHandler.MessageIDs()
+	if err != nil {
+		logger.WithError(err).Error("could not get message IDs for set reconciliation")
+		_ = stream.Reset()
+		return
+	}
+	missingIDs := [][]byte{}
+	for _, id := range ids {
+		if len(missingIDs) >= maxSetReconciliationMessages {
+			break
+		}
+		if !filter.Contains(id) {
+			missingIDs = append(missingIDs, id)
+		}
+	}
+	messages, err := n.config.SetReconciliationHandler.MessagesByID(missingIDs)
+	if err != nil {
+		logger.WithError(err).Error("could not get messages for set reconciliation")
+		_ = stream.Reset()
+		return
+	}
+	writer := bufio.NewWriter(stream)
+	for _, data := range messages {
+		if len(data) == 0 || len(data) > constants.MaxOrderSizeInBytes {
+			continue
+		}
+		if err := writeLengthPrefixed(writer, data); err != nil {
+			logger.WithError(err).Trace("could not send set reconciliation response")
+			_ = stream.Reset()
+			return
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		logger.WithError(err).Trace("could not send set reconciliation response")
+		_ = stream.Reset()
+		return
+	}
+	_ = helpers.FullClose(stream)
+	logger.WithField("numMessages", len(messages)).Trace("sent missing messages to peer via set reconciliation")
+}
+
+// periodicallyReconcile reconciles with a few randomly chosen peers every
+// setReconciliationInterval until the Node's context is canceled.
+func (n *Node) periodicallyReconcile() {
+	ticker := time.NewTicker(setReconciliationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		peers := n.ConnectedPeers()
+		mathrand.Shuffle(len(peers), func(i, j int) {
+			peers[i], peers[j] = peers[j], peers[i]
+		})
+		if len(peers) > setReconciliationPeersPerRound {
+			peers = peers[:setReconciliationPeersPerRound]
+		}
+		for _, id := range peers {
+			stats, err := n.ReconcileWithPeer(n.ctx, id)
+			if err != nil {
+				// Peers running older versions may not support set reconciliation.
+				log.WithFields(map[string]interface{}{
+					"error":  err.Error(),
+					"peerID": id.String(),
+				}).Trace("could not reconcile with peer")
+				continue
+			}
+			log.WithFields(map[string]interface{}{
+				"peerID":           id.String(),
+				"bytesSent":        stats.BytesSent,
+				"bytesReceived":    stats.BytesReceived,
+				"messagesReceived": stats.MessagesReceived,
+			}).Debug("reconciled with peer")
+		}
+	}
+}
+
+// getSetReconciliationLimiter returns the rate limiter for set reconciliation
+// requests from the given peer, creating one if needed.
+func (n *Node) getSetReconciliationLimiter(id peer.ID) *rate.Limiter {
+	n.setReconciliationLimitersMu.Lock()
+	defer n.setReconciliationLimitersMu.Unlock()
+	if limiter, found := n.setReconciliationLimiters.Get(id); found {
+		return limiter.(*rate.Limiter)
+	}
+	limiter := rate.NewLimiter(setReconciliationPerPeerLimit, setReconciliationPerPeerBurst)
+	n.setReconciliationLimiters.Add(id, limiter)
+	return limiter
+}
+
+// writeLengthPrefixed writes data prefixed by its length encoded as a uvarint.
+func writeLengthPrefixed(writer io.Writer, data []byte) error {
+	var lengthBytes [binary.MaxVarintLen64]byte
+	lengthSize := binary.PutUvarint(lengthBytes[:], uint64(len(data)))
+	if _, err := writer.Write(lengthBytes[:lengthSize]); err != nil {
+		return err
+	}
+	_, err := writer.Write(data)
+	return err
+}
+
+// readLengthPrefixed reads data written by writeLengthPrefixed. It returns
+// io.EOF if there is no more data and an error if the length exceeds maxSize.
+func readLengthPrefixed(reader *bufio.Reader, maxSize int) ([]byte, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(maxSize) {
+		return nil, fmt.Errorf("length %d exceeds maximum of %d", length, maxSize)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+type countingWriter struct {
+	writer io.Writer
+	count  int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.count += n
+	return n, err
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += n
+	return n, err
+}
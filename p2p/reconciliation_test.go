@@ -0,0 +1,231 @@
+// +build !js
+
+package p2p
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setReconciliationMessageHandler stores all messages it receives in memory
+// and implements SetReconciliationHandler. Message IDs are sha256 hashes of the
+// message data.
+type setReconciliationMessageHandler struct {
+	mu         sync.Mutex
+	idToData   map[string][]byte
+	numHandled int
+}
+
+func newSetReconciliationMessageHandler(messages [][]byte) *setReconciliationMessageHandler {
+	handler := &setReconciliationMessageHandler{
+		idToData: map[string][]byte{},
+	}
+	for _, data := range messages {
+		handler.idToData[messageID(data)] = data
+	}
+	return handler
+}
+
+func (h *setReconciliationMessageHandler) HandleMessages(messages []*Message) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, msg := range messages {
+		h.numHandled++
+		h.idToData[messageID(msg.Data)] = msg.Data
+	}
+	return nil
+}
+
+func (*setReconciliationMessageHandler) GetMessagesToShare(max int) ([][]byte, error) {
+	return nil, nil
+}
+
+func (h *setReconciliationMessageHandler) MessageIDs() ([][]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([][]byte, 0, len(h.idToData))
+	for id := range h.idToData {
+		ids = append(ids, []byte(id))
+	}
+	return ids, nil
+}
+
+func (h *setReconciliationMessageHandler) MessagesByID(ids [][]byte) ([][]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	messages := [][]byte{}
+	for _, id := range ids {
+		if data, found := h.idToData[string(id)]; found {
+			messages = append(messages, data)
+		}
+	}
+	return messages, nil
+}
+
+func (h *setReconciliationMessageHandler) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.idToData)
+}
+
+func messageID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return string(sum[:])
+}
+
+func randomMessages(t *testing.T, count int) [][]byte {
+	messages := make([][]byte, count)
+	for i := range messages {
+		// 1 KiB is roughly the size of an encoded order.
+		messages[i] = make([]byte, 1024)
+		_, err := rand.Read(messages[i])
+		require.NoError(t, err)
+	}
+	return messages
+}
+
+func newSetReconciliationTestNode(t *testing.T, ctx context.Context, handler *setReconciliationMessageHandler) *Node {
+	return newTestNodeWithConfig(t, ctx, nil, Config{
+		Topic:                    testTopic,
+		MessageHandler:           handler,
+		SetReconciliationHandler: handler,
+		RendezvousString:         testRendezvousString,
+		UseBootstrapList:         false,
+		DataDir:                  "/tmp/0x-mesh/p2p-testing/" + uuid.New().String(),
+	})
+}
+
+// TestSetReconciliationBandwidthSavings creates several nodes which share most
+// of their messages and checks that set reconciliation delivers the missing
+// messages using much less bandwidth than sending every message.
+func TestSetReconciliationBandwidthSavings(t *testing.T) {
+	const (
+		numNodes          = 4
+		numSharedMessages = 2000
+		numUniqueMessages = 20
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sharedMessages := randomMessages(t, numSharedMessages)
+	nodes := make([]*Node, numNodes)
+	handlers := make([]*setReconciliationMessageHandler, numNodes)
+	for i := range nodes {
+		uniqueMessages := randomMessages(t, numUniqueMessages)
+		handlers[i] = newSetReconciliationMessageHandler(append(uniqueMessages, sharedMessages...))
+		nodes[i] = newSetReconciliationTestNode(t, ctx, handlers[i])
+	}
+
+	// The nodes are connected in a star topology with nodes[0] at the center.
+	hub := nodes[0]
+	spokes := nodes[1:]
+	for _, spoke := range spokes {
+		connectTestNodes(t, hub, spoke)
+	}
+
+	// First the hub collects the messages it is missing from every spoke, then
+	// every spoke collects the messages it is missing from the hub.
+	reconciliationBytes := 0
+	for _, spoke := range spokes {
+		stats, err := hub.ReconcileWithPeer(ctx, spoke.ID())
+		require.NoError(t, err)
+		reconciliationBytes += stats.BytesSent + stats.BytesReceived
+	}
+	for _, spoke := range spokes {
+		stats, err := spoke.ReconcileWithPeer(ctx, hub.ID())
+		require.NoError(t, err)
+		reconciliationBytes += stats.BytesSent + stats.BytesReceived
+	}
+
+	expectedCount := numSharedMessages + numNodes*numUniqueMessages
+	for i, handler := range handlers {
+		// Because of Bloom filter false positives, a node may occasionally miss a
+		// message until the next time it reconciles. With the false positive rate
+		// we use, missing more than a few is extremely unlikely.
+		assert.InDelta(t, expectedCount, handler.count(), 3, "node %d is missing messages", i)
+	}
+
+	// Without set reconciliation, both nodes on each connection would need to
+	// send all of their messages to each other for all nodes to be guaranteed
+	// to have every message.
+	messageSize := len(sharedMessages[0])
+	naiveBytes := len(spokes) * 2 * (numSharedMessages + numUniqueMessages) * messageSize
+	t.Logf("set reconciliation used %d bytes; sending every message would use %d bytes", reconciliationBytes, naiveBytes)
+	assert.True(t, reconciliationBytes*10 < naiveBytes, "set reconciliation used %d bytes but sending every message would use %d bytes", reconciliationBytes, naiveBytes)
+}
+
+func TestSetReconciliationRateLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler0 := newSetReconciliationMessageHandler(nil)
+	node0 := newSetReconciliationTestNode(t, ctx, handler0)
+	handler1 := newSetReconciliationMessageHandler(randomMessages(t, 5))
+	node1 := newSetReconciliationTestNode(t, ctx, handler1)
+	connectTestNodes(t, node0, node1)
+
+	for i := 0; i < setReconciliationPerPeerBurst; i++ {
+		_, err := node0.ReconcileWithPeer(ctx, node1.ID())
+		require.NoError(t, err)
+	}
+	_, err := node0.ReconcileWithPeer(ctx, node1.ID())
+	assert.Equal(t, errSetReconciliationRateLimited, err)
+
+	// The first reconciliation should have sent all messages and later ones
+	// should not have sent any more.
+	assert.Equal(t, 5, handler0.count())
+	assert.Equal(t, 5, handler0.numHandled)
+}
+
+func TestSetReconciliationRateLimitCheckedBeforeReading(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node0 := newSetReconciliationTestNode(t, ctx, newSetReconciliationMessageHandler(nil))
+	node1 := newSetReconciliationTestNode(t, ctx, newSetReconciliationMessageHandler(randomMessages(t, 5)))
+	connectTestNodes(t, node0, node1)
+
+	// Use up all of node0's requests.
+	limiter := node1.getSetReconciliationLimiter(node0.ID())
+	for limiter.Allow() {
+	}
+
+	// Send a request with an invalid filter. node1 should refuse it without
+	// reading the filter, so node0 should not be penalized for it.
+	stream, err := node0.NewStream(ctx, node1.ID(), setReconciliationProtocolID)
+	require.NoError(t, err)
+	defer func() {
+		_ = stream.Reset()
+	}()
+	require.NoError(t, writeLengthPrefixed(stream, []byte("not a valid filter")))
+	data, err := readLengthPrefixed(bufio.NewReader(stream), maxSetReconciliationFilterSize)
+	require.NoError(t, err)
+	assert.Empty(t, data, "expected an empty message indicating that the request was refused")
+	if tagInfo := node1.connManager.GetTagInfo(node0.ID()); tagInfo != nil {
+		assert.Equal(t, 0, tagInfo.Tags[invalidReconciliationScoreTag])
+	}
+}
+
+func TestSetReconciliationDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node0 := newTestNode(t, ctx, nil)
+	handler1 := newSetReconciliationMessageHandler(randomMessages(t, 5))
+	node1 := newSetReconciliationTestNode(t, ctx, handler1)
+	connectTestNodes(t, node0, node1)
+
+	_, err := node0.ReconcileWithPeer(ctx, node1.ID())
+	assert.Error(t, err)
+	// Peers without set reconciliation enabled do not support the protocol.
+	_, err = node1.ReconcileWithPeer(ctx, node0.ID())
+	assert.Error(t, err)
+}
@@ -0,0 +1,340 @@
+package p2p
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0xProject/0x-mesh/p2p/banner"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// reputationFilename is the name of the file (relative to DataDir) in which
+	// peer scores and bans are persisted.
+	reputationFilename = "reputation.json"
+	// reputationSaveInterval is how often peer scores and bans are saved to
+	// disk.
+	reputationSaveInterval = 1 * time.Minute
+	// defaultPeerScoreTTL is the default value for PeerScoreTTL.
+	defaultPeerScoreTTL = 24 * time.Hour
+	// defaultBanDuration is the default value for BanDuration.
+	defaultBanDuration = 24 * time.Hour
+)
+
+func getReputationPath(datadir string) string {
+	return filepath.Join(datadir, reputationFilename)
+}
+
+// reputation keeps track of the scores set via the Node's PeerScore methods
+// and of banned peers so that they can be persisted across restarts. Unlike the
+// connection manager, it remembers the scores for peers we are not currently
+// connected to.
+type reputation struct {
+	mu       sync.Mutex
+	path     string
+	scoreTTL time.Duration
+	// now returns the current time. It can be overridden in tests.
+	now         func() time.Time
+	peers       map[peer.ID]*peerReputation
+	bannedPeers map[peer.ID]*peerBan
+}
+
+type peerReputation struct {
+	scores      map[string]int
+	lastUpdated time.Time
+}
+
+type peerBan struct {
+	// expiration is the time at which the ban expires. A zero value means the
+	// ban never expires.
+	expiration time.Time
+	// addrs are the addresses whose IPs were banned along with the peer.
+	addrs []ma.Multiaddr
+}
+
+// reputationSnapshot is the JSON representation of the reputation file.
+type reputationSnapshot struct {
+	Peers       []peerReputationSnapshot `json:"peers"`
+	BannedPeers []bannedPeerSnapshot     `json:"bannedPeers"`
+	BannedIPs   []banner.Ban             `json:"bannedIPs"`
+}
+
+type peerReputationSnapshot struct {
+	ID          string         `json:"id"`
+	Scores      map[string]int `json:"scores"`
+	LastUpdated time.Time      `json:"lastUpdated"`
+}
+
+type bannedPeerSnapshot struct {
+	ID string `json:"id"`
+	// Expiration is the time at which the ban expires. A zero value means the
+	// ban never expires.
+	Expiration time.Time `json:"expiration"`
+	Addrs      []string  `json:"addrs,omitempty"`
+}
+
+func newReputation(path string, scoreTTL time.Duration) *reputation {
+	return &reputation{
+		path:        path,
+		scoreTTL:    scoreTTL,
+		now:         time.Now,
+		peers:       map[peer.ID]*peerReputation{},
+		bannedPeers: map[peer.ID]*peerBan{},
+	}
+}
+
+// load reads the reputation file and returns the IP bans it contains. Scores
+// which have not been updated within the score TTL and expired bans are
+// ignored. It is not an error if the file does not exist.
+func (r *reputation) load() ([]banner.Ban, error) {
+	data, err := readReputationFile(r.path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var snapshot reputationSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	for _, peerSnapshot := range snapshot.Peers {
+		id, err := peer.IDB58Decode(peerSnapshot.ID)
+		if err != nil {
+			log.WithFields(map[string]interface{}{
+				"error":  err.Error(),
+				"peerID": peerSnapshot.ID,
+			}).Warn("ignoring invalid peer ID in reputation file")
+			continue
+		}
+		if r.isScoreExpired(peerSnapshot.LastUpdated, now) || len(peerSnapshot.Scores) == 0 {
+			continue
+		}
+		r.peers[id] = &peerReputation{
+			scores:      peerSnapshot.Scores,
+			lastUpdated: peerSnapshot.LastUpdated,
+		}
+	}
+	for _, banSnapshot := range snapshot.BannedPeers {
+		id, err := peer.IDB58Decode(banSnapshot.ID)
+		if err != nil {
+			log.WithFields(map[string]interface{}{
+				"error":  err.Error(),
+				"peerID": banSnapshot.ID,
+			}).Warn("ignoring invalid peer ID in reputation file")
+			continue
+		}
+		if isBanExpired(banSnapshot.Expiration, now) {
+			continue
+		}
+		ban := &peerBan{expiration: banSnapshot.Expiration}
+		for _, addr := range banSnapshot.Addrs {
+			maddr, err := ma.NewMultiaddr(addr)
+			if err != nil {
+				log.WithFields(map[string]interface{}{
+					"error": err.Error(),
+					"addr":  addr,
+				}).Warn("ignoring invalid address in reputation file")
+				continue
+			}
+			ban.addrs = append(ban.addrs, maddr)
+		}
+		r.bannedPeers[id] = ban
+	}
+	return snapshot.BannedIPs, nil
+}
+
+// save writes all peer scores and bans, including the given IP bans, to the
+// reputation file.
+func (r *reputation) save(bannedIPs []banner.Ban) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot := reputationSnapshot{
+		Peers:       make([]peerReputationSnapshot, 0, len(r.peers)),
+		BannedPeers: make([]bannedPeerSnapshot, 0, len(r.bannedPeers)),
+		BannedIPs:   bannedIPs,
+	}
+	for id, peerRep := range r.peers {
+		snapshot.Peers = append(snapshot.Peers, peerReputationSnapshot{
+			ID:          id.Pretty(),
+			Scores:      peerRep.scores,
+			LastUpdated: peerRep.lastUpdated,
+		})
+	}
+	for id, ban := range r.bannedPeers {
+		addrs := make([]string, len(ban.addrs))
+		for i, addr := range ban.addrs {
+			addrs[i] = addr.String()
+		}
+		snapshot.BannedPeers = append(snapshot.BannedPeers, bannedPeerSnapshot{
+			ID:         id.Pretty(),
+			Expiration: ban.expiration,
+			Addrs:      addrs,
+		})
+	}
+	// Sort by peer ID so that the file doesn't change unless the data does.
+	sort.Slice(snapshot.Peers, func(i, j int) bool {
+		return snapshot.Peers[i].ID < snapshot.Peers[j].ID
+	})
+	sort.Slice(snapshot.BannedPeers, func(i, j int) bool {
+		return snapshot.BannedPeers[i].ID < snapshot.BannedPeers[j].ID
+	})
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return writeReputationFile(r.path, data)
+}
+
+// addScore adds diff to the score for the given peer and tag and returns the
+// new score.
+func (r *reputation) addScore(id peer.ID, tag string, diff int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	peerRep := r.getOrCreatePeer(id)
+	peerRep.scores[tag] += diff
+	return peerRep.scores[tag]
+}
+
+// setScore sets the score for the given peer and tag.
+func (r *reputation) setScore(id peer.ID, tag string, val int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.getOrCreatePeer(id).scores[tag] = val
+}
+
+// unsetScore removes the score for the given peer and tag.
+func (r *reputation) unsetScore(id peer.ID, tag string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	peerRep, found := r.peers[id]
+	if !found {
+		return
+	}
+	delete(peerRep.scores, tag)
+	if len(peerRep.scores) == 0 {
+		delete(r.peers, id)
+	}
+}
+
+// getScore returns the score for the given peer and tag. It returns 0 if
+// there is no score for the tag.
+func (r *reputation) getScore(id peer.ID, tag string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	peerRep, found := r.peers[id]
+	if !found {
+		return 0
+	}
+	return peerRep.scores[tag]
+}
+
+// getScores returns a copy of all scores for the given peer.
+func (r *reputation) getScores(id peer.ID) map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	scores := map[string]int{}
+	if peerRep, found := r.peers[id]; found {
+		for tag, val := range peerRep.scores {
+			scores[tag] = val
+		}
+	}
+	return scores
+}
+
+// getTotalScore returns the sum of all scores for the given peer.
+func (r *reputation) getTotalScore(id peer.ID) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := 0
+	if peerRep, found := r.peers[id]; found {
+		for _, val := range peerRep.scores {
+			total += val
+		}
+	}
+	return total
+}
+
+// getOrCreatePeer returns the reputation for the given peer and marks it as
+// updated. r.mu must be held when calling it.
+func (r *reputation) getOrCreatePeer(id peer.ID) *peerReputation {
+	peerRep, found := r.peers[id]
+	if !found {
+		peerRep = &peerReputation{scores: map[string]int{}}
+		r.peers[id] = peerRep
+	}
+	peerRep.lastUpdated = r.now()
+	return peerRep
+}
+
+// ban records that the given peer is banned until expiration. A zero
+// expiration means the ban never expires. addrs are the addresses whose IPs
+// were banned along with the peer.
+func (r *reputation) ban(id peer.ID, expiration time.Time, addrs []ma.Multiaddr) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bannedPeers[id] = &peerBan{
+		expiration: expiration,
+		addrs:      addrs,
+	}
+}
+
+// unban removes the ban for the given peer, if any. It returns the addresses
+// whose IPs were banned along with the peer.
+func (r *reputation) unban(id peer.ID) []ma.Multiaddr {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ban, found := r.bannedPeers[id]
+	if !found {
+		return nil
+	}
+	delete(r.bannedPeers, id)
+	return ban.addrs
+}
+
+// isBanned returns true if the given peer is currently banned.
+func (r *reputation) isBanned(id peer.ID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ban, found := r.bannedPeers[id]
+	return found && !isBanExpired(ban.expiration, r.now())
+}
+
+// removeExpired removes all scores which have not been updated within the
+// score TTL and all expired bans. It returns the scores that were removed so
+// that they can also be removed from the connection manager.
+func (r *reputation) removeExpired() map[peer.ID]map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	removed := map[peer.ID]map[string]int{}
+	for id, peerRep := range r.peers {
+		if r.isScoreExpired(peerRep.lastUpdated, now) {
+			removed[id] = peerRep.scores
+			delete(r.peers, id)
+		}
+	}
+	for id, ban := range r.bannedPeers {
+		if isBanExpired(ban.expiration, now) {
+			delete(r.bannedPeers, id)
+		}
+	}
+	return removed
+}
+
+func (r *reputation) isScoreExpired(lastUpdated time.Time, now time.Time) bool {
+	return lastUpdated.Add(r.scoreTTL).Before(now)
+}
+
+func isBanExpired(expiration time.Time, now time.Time) bool {
+	return !expiration.IsZero() && !expiration.After(now)
+}
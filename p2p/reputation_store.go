@@ -0,0 +1,34 @@
+// +build !js
+
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// readReputationFile returns the contents of the reputation file at path. It
+// returns nil data and no error if the file does not exist.
+func readReputationFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// writeReputationFile atomically replaces the reputation file at path with
+// data.
+func writeReputationFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that the existing file is never left
+	// partially written.
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, os.ModePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,14 @@
+// +build js,wasm
+
+package p2p
+
+// Browser nodes don't persist any other p2p state (e.g. the peerstore or the
+// DHT) so peer scores and bans are only kept in memory.
+
+func readReputationFile(path string) ([]byte, error) {
+	return nil, nil
+}
+
+func writeReputationFile(path string, data []byte) error {
+	return nil
+}
@@ -0,0 +1,183 @@
+// +build !js
+
+package p2p
+
+import (
+	"context"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	p2pcrypto "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDataDir() string {
+	return "/tmp/0x-mesh/p2p-testing/" + uuid.New().String()
+}
+
+func newTestPeerID(t *testing.T) peer.ID {
+	_, pubKey, err := p2pcrypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+	id, err := peer.IDFromPublicKey(pubKey)
+	require.NoError(t, err)
+	return id
+}
+
+func TestReputationPersistence(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node0 := newTestNode(t, ctx, nil)
+	node1 := newTestNode(t, ctx, nil)
+	node2 := newTestNode(t, ctx, nil)
+	connectTestNodes(t, node0, node2)
+
+	node0.AddPeerScore(node1.ID(), "invalid-message", -5)
+	node0.AddPeerScore(node1.ID(), "invalid-message", -5)
+	node0.SetPeerScore(node1.ID(), "order-stored", 10)
+	node2Addr := node0.host.Network().ConnsToPeer(node2.ID())[0].RemoteMultiaddr()
+	require.NoError(t, node0.BanPeer(node2.ID()))
+	require.True(t, node0.banner.IsAddrBanned(node2Addr))
+	node0.saveReputation()
+
+	// Simulate a restart by starting a new node with a copy of the reputation
+	// file.
+	data, err := ioutil.ReadFile(getReputationPath(node0.config.DataDir))
+	require.NoError(t, err)
+	dataDir := newTestDataDir()
+	require.NoError(t, os.MkdirAll(dataDir, os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dataDir, reputationFilename), data, os.ModePerm))
+	restarted := newTestNodeWithConfig(t, ctx, nil, Config{
+		Topic:            testTopic,
+		MessageHandler:   &dummyMessageHandler{},
+		RendezvousString: testRendezvousString,
+		DataDir:          dataDir,
+	})
+
+	assert.Equal(t, -10, restarted.GetPeerScore(node1.ID(), "invalid-message"))
+	assert.Equal(t, 10, restarted.GetPeerScore(node1.ID(), "order-stored"))
+	assert.Equal(t, 0, restarted.GetPeerScore(node2.ID(), "invalid-message"))
+	assert.True(t, restarted.IsPeerBanned(node2.ID()))
+	assert.False(t, restarted.IsPeerBanned(node1.ID()))
+	assert.True(t, restarted.banner.IsAddrBanned(node2Addr))
+	require.NoError(t, restarted.UnbanPeer(node2.ID()))
+	assert.False(t, restarted.banner.IsAddrBanned(node2Addr))
+}
+
+func TestBanPeer(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node0 := newTestNode(t, ctx, nil)
+	node1 := newTestNode(t, ctx, nil)
+	connectTestNodes(t, node0, node1)
+	conns := node0.host.Network().ConnsToPeer(node1.ID())
+	require.NotEmpty(t, conns)
+
+	require.NoError(t, node0.BanPeer(node1.ID()))
+	assert.True(t, node0.IsPeerBanned(node1.ID()))
+	waitForNodesToDisconect(t, node0, node1, 5*time.Second)
+
+	// The IP addresses node1 connected from should be banned as well.
+	for _, conn := range conns {
+		assert.True(t, node0.banner.IsAddrBanned(conn.RemoteMultiaddr()))
+	}
+
+	// Unbanning the peer should unban its IP addresses too.
+	require.NoError(t, node0.UnbanPeer(node1.ID()))
+	assert.False(t, node0.IsPeerBanned(node1.ID()))
+	for _, conn := range conns {
+		assert.False(t, node0.banner.IsAddrBanned(conn.RemoteMultiaddr()))
+	}
+}
+
+func TestBannedPeerIsDisconnected(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node0 := newTestNode(t, ctx, nil)
+	node1 := newTestNode(t, ctx, nil)
+
+	// Ban the peer ID without banning the IP address, which is what happens
+	// when a peer connects from a different IP address after being banned.
+	node0.reputation.ban(node1.ID(), time.Time{}, nil)
+	_ = node1.Connect(peer.AddrInfo{ID: node0.ID(), Addrs: node0.Multiaddrs()}, testConnectionTimeout)
+	waitForNodesToDisconect(t, node0, node1, 5*time.Second)
+}
+
+func TestPeerScoresAreAppliedOnConnect(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node0 := newTestNode(t, ctx, nil)
+	node1 := newTestNode(t, ctx, nil)
+
+	// Simulate a score which was loaded from disk. The Connection Manager
+	// doesn't know about it until node1 connects.
+	node0.reputation.setScore(node1.ID(), "test", 42)
+	assert.Nil(t, node0.connManager.GetTagInfo(node1.ID()))
+
+	connectTestNodes(t, node0, node1)
+	tagInfo := node0.connManager.GetTagInfo(node1.ID())
+	require.NotNil(t, tagInfo)
+	assert.Equal(t, 42, tagInfo.Tags["test"])
+}
+
+func TestReputationExpiration(t *testing.T) {
+	t.Parallel()
+	ttl := time.Hour
+	now := time.Now()
+	rep := newReputation(getReputationPath(newTestDataDir()), ttl)
+	rep.now = func() time.Time { return now }
+
+	expiredID := newTestPeerID(t)
+	otherID := newTestPeerID(t)
+
+	rep.setScore(expiredID, "test", 1)
+	rep.ban(expiredID, now.Add(ttl), nil)
+	rep.ban(otherID, time.Time{}, nil)
+
+	// Move the clock forward and update the score for otherID so that only the
+	// score for expiredID expires.
+	now = now.Add(2 * ttl)
+	rep.setScore(otherID, "test", 2)
+	assert.False(t, rep.isBanned(expiredID))
+	assert.True(t, rep.isBanned(otherID))
+
+	// Expired entries should not be loaded from disk.
+	require.NoError(t, rep.save(nil))
+	loaded := newReputation(rep.path, ttl)
+	loaded.now = rep.now
+	_, err := loaded.load()
+	require.NoError(t, err)
+	assert.Equal(t, 0, loaded.getScore(expiredID, "test"))
+	assert.Equal(t, 2, loaded.getScore(otherID, "test"))
+	assert.False(t, loaded.isBanned(expiredID))
+	assert.True(t, loaded.isBanned(otherID))
+
+	removed := rep.removeExpired()
+	assert.Equal(t, map[string]int{"test": 1}, removed[expiredID])
+	assert.NotContains(t, removed, otherID)
+	assert.Equal(t, 0, rep.getScore(expiredID, "test"))
+	assert.Equal(t, 2, rep.getScore(otherID, "test"))
+}
+
+func TestReputationTotalScore(t *testing.T) {
+	t.Parallel()
+	rep := newReputation(getReputationPath(newTestDataDir()), time.Hour)
+	id := newTestPeerID(t)
+	assert.Equal(t, 0, rep.getTotalScore(id))
+	rep.setScore(id, "order-stored", 10)
+	rep.addScore(id, "invalid-message", -5)
+	rep.addScore(id, "invalid-message", -5)
+	assert.Equal(t, 0, rep.getTotalScore(id))
+	rep.setScore(id, "valid-message", 5)
+	assert.Equal(t, 5, rep.getTotalScore(id))
+}
@@ -0,0 +1,204 @@
+// Package ttlcache implements a fixed-size LRU cache in which each item expires
+// after a TTL. Unlike most caching libraries, it doesn't leak goroutines: the
+// only goroutine it starts exits as soon as the given context is canceled.
+package ttlcache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// defaultCleanupInterval is the default value for CleanupInterval.
+const defaultCleanupInterval = 1 * time.Minute
+
+// Config is a set of configuration options for a Cache.
+type Config struct {
+	// MaxSize is the maximum number of items in the cache. When it is reached,
+	// the least recently used item is removed to make room for new items.
+	MaxSize int
+	// CleanupInterval is how often expired items are removed from the cache.
+	// Expired items are never returned, so it only affects memory usage.
+	// Defaults to 1 minute.
+	CleanupInterval time.Duration
+	// Clock is used to determine the current time. Defaults to the system
+	// clock. It can be overridden in tests.
+	Clock clock.Clock
+}
+
+// Cache is a fixed-size LRU cache in which each item expires after a TTL. It
+// is safe for concurrent use.
+type Cache struct {
+	mu              sync.Mutex
+	maxSize         int
+	cleanupInterval time.Duration
+	clock           clock.Clock
+	items           map[string]*list.Element
+	// recent is a list of *entry, ordered from most to least recently used.
+	recent *list.List
+}
+
+type entry struct {
+	key        string
+	value      interface{}
+	expiration time.Time
+}
+
+// New creates and returns a new Cache. The goroutine which periodically
+// removes expired items exits when ctx is canceled. The Cache can still be
+// used after that.
+func New(ctx context.Context, config Config) (*Cache, error) {
+	if config.MaxSize <= 0 {
+		return nil, errors.New("config.MaxSize must be greater than 0")
+	}
+	if config.CleanupInterval == 0 {
+		config.CleanupInterval = defaultCleanupInterval
+	}
+	if config.Clock == nil {
+		config.Clock = clock.New()
+	}
+	cache := &Cache{
+		maxSize:         config.MaxSize,
+		cleanupInterval: config.CleanupInterval,
+		clock:           config.Clock,
+		items:           map[string]*list.Element{},
+		recent:          list.New(),
+	}
+	// The ticker is created here rather than in the goroutine so that items
+	// are removed at predictable times when using a mock clock.
+	ticker := cache.clock.Ticker(cache.cleanupInterval)
+	go cache.periodicallyRemoveExpired(ctx, ticker)
+	return cache, nil
+}
+
+// Get returns the value for the given key and true if it is in the cache and
+// not expired. Otherwise it returns nil and false.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, found := c.getElement(key)
+	if !found {
+		return nil, false
+	}
+	c.recent.MoveToFront(elem)
+	return elem.Value.(*entry).value, true
+}
+
+// Set adds the given value to the cache, replacing any existing value for
+// the key. It expires after ttl.
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiration := c.clock.Now().Add(ttl)
+	if elem, found := c.items[key]; found {
+		e := elem.Value.(*entry)
+		e.value = value
+		e.expiration = expiration
+		c.recent.MoveToFront(elem)
+		return
+	}
+	c.add(key, value, expiration)
+}
+
+// Fetch returns the value for the given key if it is in the cache and not
+// expired. Otherwise it calls create and adds the value it returns to the
+// cache. In both cases the value expires after ttl, so values which are
+// fetched regularly stay in the cache.
+func (c *Cache) Fetch(key string, ttl time.Duration, create func() interface{}) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiration := c.clock.Now().Add(ttl)
+	if elem, found := c.getElement(key); found {
+		e := elem.Value.(*entry)
+		e.expiration = expiration
+		c.recent.MoveToFront(elem)
+		return e.value
+	}
+	value := create()
+	c.add(key, value, expiration)
+	return value
+}
+
+// Delete removes the value for the given key, if any.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, found := c.items[key]; found {
+		c.remove(elem)
+	}
+}
+
+// Len returns the number of items in the cache, including expired items which
+// have not been removed yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// RemoveExpired removes all expired items from the cache.
+func (c *Cache) RemoveExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	for _, elem := range c.items {
+		if isExpired(elem.Value.(*entry), now) {
+			c.remove(elem)
+		}
+	}
+}
+
+// getElement returns the element for the given key if it is in the cache and
+// not expired. Expired elements are removed. c.mu must be held when calling
+// it.
+func (c *Cache) getElement(key string) (*list.Element, bool) {
+	elem, found := c.items[key]
+	if !found {
+		return nil, false
+	}
+	if isExpired(elem.Value.(*entry), c.clock.Now()) {
+		c.remove(elem)
+		return nil, false
+	}
+	return elem, true
+}
+
+// add adds a new item to the cache, removing the least recently used item if
+// the cache is full. c.mu must be held when calling it.
+func (c *Cache) add(key string, value interface{}, expiration time.Time) {
+	if len(c.items) >= c.maxSize {
+		c.remove(c.recent.Back())
+	}
+	c.items[key] = c.recent.PushFront(&entry{
+		key:        key,
+		value:      value,
+		expiration: expiration,
+	})
+}
+
+// remove removes the given element from the cache. c.mu must be held when
+// calling it.
+func (c *Cache) remove(elem *list.Element) {
+	c.recent.Remove(elem)
+	delete(c.items, elem.Value.(*entry).key)
+}
+
+func (c *Cache) periodicallyRemoveExpired(ctx context.Context, ticker *clock.Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.RemoveExpired()
+		}
+	}
+}
+
+func isExpired(e *entry, now time.Time) bool {
+	return !e.expiration.After(now)
+}
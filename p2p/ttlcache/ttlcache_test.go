@@ -0,0 +1,158 @@
+package ttlcache
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(t *testing.T, ctx context.Context, maxSize int) (*Cache, *clock.Mock) {
+	mockClock := clock.NewMock()
+	cache, err := New(ctx, Config{
+		MaxSize:         maxSize,
+		CleanupInterval: time.Minute,
+		Clock:           mockClock,
+	})
+	require.NoError(t, err)
+	return cache, mockClock
+}
+
+func TestCacheGetAndSet(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, _ := newTestCache(t, ctx, 10)
+
+	_, found := cache.Get("a")
+	assert.False(t, found)
+	cache.Set("a", 1, time.Hour)
+	value, found := cache.Get("a")
+	require.True(t, found)
+	assert.Equal(t, 1, value)
+
+	cache.Set("a", 2, time.Hour)
+	value, found = cache.Get("a")
+	require.True(t, found)
+	assert.Equal(t, 2, value)
+	assert.Equal(t, 1, cache.Len())
+
+	cache.Delete("a")
+	_, found = cache.Get("a")
+	assert.False(t, found)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestCacheExpiration(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, mockClock := newTestCache(t, ctx, 10)
+
+	cache.Set("a", 1, time.Second)
+	cache.Set("b", 2, time.Hour)
+	mockClock.Add(time.Second)
+	_, found := cache.Get("a")
+	assert.False(t, found, "item should be expired")
+	value, found := cache.Get("b")
+	require.True(t, found)
+	assert.Equal(t, 2, value)
+}
+
+func TestCacheFetch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, mockClock := newTestCache(t, ctx, 10)
+
+	created := 0
+	create := func() interface{} {
+		created++
+		return created
+	}
+	assert.Equal(t, 1, cache.Fetch("a", time.Minute, create))
+	assert.Equal(t, 1, cache.Fetch("a", time.Minute, create), "existing value should be returned")
+
+	// Each Fetch resets the TTL, so the value should not expire as long as it
+	// is fetched regularly.
+	for i := 0; i < 5; i++ {
+		mockClock.Add(30 * time.Second)
+		assert.Equal(t, 1, cache.Fetch("a", time.Minute, create))
+	}
+
+	mockClock.Add(time.Minute)
+	assert.Equal(t, 2, cache.Fetch("a", time.Minute, create), "a new value should be created after the TTL")
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, _ := newTestCache(t, ctx, 3)
+
+	cache.Set("a", 1, time.Hour)
+	cache.Set("b", 2, time.Hour)
+	cache.Set("c", 3, time.Hour)
+	// Use "a" so that "b" becomes the least recently used item.
+	_, found := cache.Get("a")
+	require.True(t, found)
+	cache.Set("d", 4, time.Hour)
+
+	assert.Equal(t, 3, cache.Len())
+	_, found = cache.Get("b")
+	assert.False(t, found, "least recently used item should have been removed")
+	for _, key := range []string{"a", "c", "d"} {
+		_, found := cache.Get(key)
+		assert.True(t, found, "item %q should still be in the cache", key)
+	}
+}
+
+func TestCacheRemovesExpiredItemsPeriodically(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, mockClock := newTestCache(t, ctx, 100)
+
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprint(i), i, time.Second)
+	}
+	cache.Set("long", 0, time.Hour)
+	assert.Equal(t, 11, cache.Len())
+
+	// Move past the cleanup interval so that the cleanup goroutine runs.
+	mockClock.Add(time.Minute)
+	deadline := time.Now().Add(5 * time.Second)
+	for cache.Len() != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestCacheDoesNotLeakGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cache, err := New(ctx, Config{MaxSize: 10})
+		require.NoError(t, err)
+		cache.Set("a", 1, time.Hour)
+		cancel()
+	}
+	assertGoroutinesExit(t, before, 5*time.Second)
+}
+
+func TestNewRequiresMaxSize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := New(ctx, Config{})
+	assert.Error(t, err)
+}
+
+// assertGoroutinesExit waits until the number of goroutines is at most
+// expected and fails the test if that doesn't happen before timeout.
+func assertGoroutinesExit(t *testing.T, expected int, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for runtime.NumGoroutine() > expected && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, runtime.NumGoroutine() <= expected, "expected at most %d goroutines but got %d", expected, runtime.NumGoroutine())
+}
@@ -54,6 +54,49 @@ func (c *Client) AddOrders(orders []*zeroex.SignedOrder, opts ...AddOrdersOpts)
 	return &validationResults, nil
 }
 
+// ValidateOrdersOpts is a set of options for the ValidateOrders RPC method.
+type ValidateOrdersOpts struct {
+	// BlockNumber is the number of the block at which the orders are validated.
+	// Defaults to the latest block.
+	BlockNumber *int `json:"blockNumber,omitempty"`
+}
+
+// ValidateOrders validates orders the same way AddOrders does and returns the
+// validation results, without adding the orders to the 0x Mesh node or
+// broadcasting them.
+func (c *Client) ValidateOrders(orders []*zeroex.SignedOrder, opts ...ValidateOrdersOpts) (*ordervalidator.ValidationResults, error) {
+	var validationResults ordervalidator.ValidationResults
+	if len(opts) > 0 {
+		if err := c.rpcClient.Call(&validationResults, "mesh_validateOrders", orders, opts[0]); err != nil {
+			return nil, err
+		}
+		return &validationResults, nil
+	}
+	if err := c.rpcClient.Call(&validationResults, "mesh_validateOrders", orders); err != nil {
+		return nil, err
+	}
+	return &validationResults, nil
+}
+
+// GetOrderStateAtBlock validates the orders with the given hashes and the given
+// signed orders at the given block and returns their state. Orders given by
+// hash must be stored by the Mesh node. Validating orders at older blocks
+// requires the Mesh node to use an archive node as its Ethereum RPC endpoint.
+func (c *Client) GetOrderStateAtBlock(orderHashes []common.Hash, signedOrders []*zeroex.SignedOrder, blockNumber int) (*ordervalidator.ValidationResults, error) {
+	ordersOrHashes := make([]interface{}, 0, len(orderHashes)+len(signedOrders))
+	for _, orderHash := range orderHashes {
+		ordersOrHashes = append(ordersOrHashes, orderHash)
+	}
+	for _, signedOrder := range signedOrders {
+		ordersOrHashes = append(ordersOrHashes, signedOrder)
+	}
+	var validationResults ordervalidator.ValidationResults
+	if err := c.rpcClient.Call(&validationResults, "mesh_getOrderStateAtBlock", ordersOrHashes, blockNumber); err != nil {
+		return nil, err
+	}
+	return &validationResults, nil
+}
+
 // GetOrdersResponse is the response returned for an RPC request to mesh_getOrders
 type GetOrdersResponse struct {
 	SnapshotID  string       `json:"snapshotID"`
@@ -116,6 +159,116 @@ func (c *Client) GetStats() (*GetStatsResponse, error) {
 	return getStatsResponse, nil
 }
 
+// BackupDatabase makes the Mesh node write a consistent copy of its database to
+// the given path. The path is on the machine where the Mesh node is running and
+// must not already exist.
+func (c *Client) BackupDatabase(path string) error {
+	if err := c.rpcClient.Call(nil, "mesh_backupDatabase", path); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CompactDatabase makes the Mesh node compact its database, reclaiming space
+// used by removed orders.
+func (c *Client) CompactDatabase() error {
+	if err := c.rpcClient.Call(nil, "mesh_compactDatabase"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PeerInfo contains information about a peer that the Mesh node is connected
+// to.
+type PeerInfo struct {
+	ID string `json:"id"`
+	// Multiaddrs are the remote addresses of all open connections to the peer.
+	Multiaddrs []string `json:"multiaddrs"`
+	// Direction is the direction of the oldest open connection to the peer.
+	// It is either "inbound", "outbound", or "unknown".
+	Direction string `json:"direction"`
+	// Latency is the observed latency to the peer in nanoseconds.
+	Latency time.Duration `json:"latency"`
+	// Scores are the scores for the peer indexed by tag. Peers with a low total
+	// score are more likely to be disconnected.
+	Scores map[string]int `json:"scores"`
+	// BytesIn and BytesOut are the total number of bytes received from and
+	// sent to the peer.
+	BytesIn  int64 `json:"bytesIn"`
+	BytesOut int64 `json:"bytesOut"`
+	// RateIn and RateOut are the current rates in bytes per second at which
+	// data is received from and sent to the peer.
+	RateIn  float64 `json:"rateIn"`
+	RateOut float64 `json:"rateOut"`
+	// Messages contains the number of GossipSub messages received from the
+	// peer in the last few minutes.
+	Messages MessageStats `json:"messages"`
+}
+
+// MessageStats contains the number of GossipSub messages received from a peer,
+// grouped by what happened to them.
+type MessageStats struct {
+	// Accepted is the number of messages which were not rate limited.
+	Accepted uint64 `json:"accepted"`
+	// RateLimited is the number of messages which were dropped because the peer
+	// or the network as a whole exceeded the rate limit.
+	RateLimited uint64 `json:"rateLimited"`
+	// Oversize is the number of messages which were dropped because they were
+	// too big.
+	Oversize uint64 `json:"oversize"`
+	// Invalid is the number of accepted messages which turned out to be
+	// invalid.
+	Invalid uint64 `json:"invalid"`
+}
+
+// GetPeers returns information about every peer the Mesh node is connected to.
+func (c *Client) GetPeers() ([]*PeerInfo, error) {
+	var peers []*PeerInfo
+	if err := c.rpcClient.Call(&peers, "mesh_getPeers"); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// BanPeer bans a peer. peerIDOrIP can either be a peer ID, in which case the
+// peer and the IP addresses it is connected from are banned and the peer is
+// disconnected, or an IP address (e.g. "1.2.3.4" or "/ip4/1.2.3.4"). Bans
+// expire after 24 hours.
+func (c *Client) BanPeer(peerIDOrIP string) error {
+	if err := c.rpcClient.Call(nil, "mesh_banPeer", peerIDOrIP); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnbanPeer removes a ban that was added by BanPeer. peerIDOrIP can either be
+// a peer ID or an IP address.
+func (c *Client) UnbanPeer(peerIDOrIP string) error {
+	if err := c.rpcClient.Call(nil, "mesh_unbanPeer", peerIDOrIP); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ProtectPeer protects a peer from being banned. peerIDOrIP can either be a
+// peer ID, in which case the peer is also protected from being disconnected,
+// or an IP address. Any existing ban is removed.
+func (c *Client) ProtectPeer(peerIDOrIP string) error {
+	if err := c.rpcClient.Call(nil, "mesh_protectPeer", peerIDOrIP); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DisconnectPeer closes all connections to the given peer. Unlike BanPeer, it
+// does not prevent the peer from connecting again.
+func (c *Client) DisconnectPeer(peerID peer.ID) error {
+	if err := c.rpcClient.Call(nil, "mesh_disconnectPeer", peer.IDB58Encode(peerID)); err != nil {
+		return err
+	}
+	return nil
+}
+
 // SubscribeToOrders subscribes a stream of order events
 // Note copied from `go-ethereum` codebase: Slow subscribers will be dropped eventually. Client
 // buffers up to 8000 notifications before considering the subscriber dead. The subscription Err
@@ -27,11 +27,23 @@ import (
 // dummyRPCHandler is used for testing purposes. It allows declaring handlers
 // for some requests or all of them, depending on testing needs.
 type dummyRPCHandler struct {
-	addOrdersHandler         func(signedOrdersRaw []*json.RawMessage, opts AddOrdersOpts) (*ordervalidator.ValidationResults, error)
-	getOrdersHandler         func(page, perPage int, snapshotID string) (*GetOrdersResponse, error)
-	addPeerHandler           func(peerInfo peerstore.PeerInfo) error
-	getStatsHandler          func() (*GetStatsResponse, error)
-	subscribeToOrdersHandler func(ctx context.Context) (*rpc.Subscription, error)
+	addOrdersHandler            func(signedOrdersRaw []*json.RawMessage, opts AddOrdersOpts) (*ordervalidator.ValidationResults, error)
+	validateOrdersHandler       func(signedOrdersRaw []*json.RawMessage, opts ValidateOrdersOpts) (*ordervalidator.ValidationResults, error)
+	getOrderStateAtBlockHandler func(orderHashes []common.Hash, signedOrdersRaw []*json.RawMessage, blockNumber int) (*ordervalidator.ValidationResults, error)
+	getOrdersHandler            func(page, perPage int, snapshotID string) (*GetOrdersResponse, error)
+	addPeerHandler              func(peerInfo peerstore.PeerInfo) error
+	getStatsHandler             func() (*GetStatsResponse, error)
+	subscribeToOrdersHandler    func(ctx context.Context) (*rpc.Subscription, error)
+	backupDatabaseHandler       func(path string) error
+	compactDatabaseHandler      func() error
+	getPeersHandler             func() ([]*PeerInfo, error)
+	banPeerHandler              func(peerID peer.ID) error
+	banIPHandler                func(maddr ma.Multiaddr) error
+	unbanPeerHandler            func(peerID peer.ID) error
+	unbanIPHandler              func(maddr ma.Multiaddr) error
+	protectPeerHandler          func(peerID peer.ID) error
+	protectIPHandler            func(maddr ma.Multiaddr) error
+	disconnectPeerHandler       func(peerID peer.ID) error
 }
 
 func (d *dummyRPCHandler) AddOrders(signedOrdersRaw []*json.RawMessage, opts AddOrdersOpts) (*ordervalidator.ValidationResults, error) {
@@ -41,6 +53,20 @@ func (d *dummyRPCHandler) AddOrders(signedOrdersRaw []*json.RawMessage, opts Add
 	return d.addOrdersHandler(signedOrdersRaw, opts)
 }
 
+func (d *dummyRPCHandler) ValidateOrders(signedOrdersRaw []*json.RawMessage, opts ValidateOrdersOpts) (*ordervalidator.ValidationResults, error) {
+	if d.validateOrdersHandler == nil {
+		return nil, errors.New("dummyRPCHandler: no handler set for ValidateOrders")
+	}
+	return d.validateOrdersHandler(signedOrdersRaw, opts)
+}
+
+func (d *dummyRPCHandler) GetOrderStateAtBlock(orderHashes []common.Hash, signedOrdersRaw []*json.RawMessage, blockNumber int) (*ordervalidator.ValidationResults, error) {
+	if d.getOrderStateAtBlockHandler == nil {
+		return nil, errors.New("dummyRPCHandler: no handler set for GetOrderStateAtBlock")
+	}
+	return d.getOrderStateAtBlockHandler(orderHashes, signedOrdersRaw, blockNumber)
+}
+
 func (d *dummyRPCHandler) GetOrders(page, perPage int, snapshotID string) (*GetOrdersResponse, error) {
 	if d.getOrdersHandler == nil {
 		return nil, errors.New("dummyRPCHandler: no handler set for GetOrders")
@@ -69,6 +95,76 @@ func (d *dummyRPCHandler) SubscribeToOrders(ctx context.Context) (*rpc.Subscript
 	return d.subscribeToOrdersHandler(ctx)
 }
 
+func (d *dummyRPCHandler) BackupDatabase(path string) error {
+	if d.backupDatabaseHandler == nil {
+		return errors.New("dummyRPCHandler: no handler set for BackupDatabase")
+	}
+	return d.backupDatabaseHandler(path)
+}
+
+func (d *dummyRPCHandler) CompactDatabase() error {
+	if d.compactDatabaseHandler == nil {
+		return errors.New("dummyRPCHandler: no handler set for CompactDatabase")
+	}
+	return d.compactDatabaseHandler()
+}
+
+func (d *dummyRPCHandler) GetPeers() ([]*PeerInfo, error) {
+	if d.getPeersHandler == nil {
+		return nil, errors.New("dummyRPCHandler: no handler set for GetPeers")
+	}
+	return d.getPeersHandler()
+}
+
+func (d *dummyRPCHandler) BanPeer(peerID peer.ID) error {
+	if d.banPeerHandler == nil {
+		return errors.New("dummyRPCHandler: no handler set for BanPeer")
+	}
+	return d.banPeerHandler(peerID)
+}
+
+func (d *dummyRPCHandler) BanIP(maddr ma.Multiaddr) error {
+	if d.banIPHandler == nil {
+		return errors.New("dummyRPCHandler: no handler set for BanIP")
+	}
+	return d.banIPHandler(maddr)
+}
+
+func (d *dummyRPCHandler) UnbanPeer(peerID peer.ID) error {
+	if d.unbanPeerHandler == nil {
+		return errors.New("dummyRPCHandler: no handler set for UnbanPeer")
+	}
+	return d.unbanPeerHandler(peerID)
+}
+
+func (d *dummyRPCHandler) UnbanIP(maddr ma.Multiaddr) error {
+	if d.unbanIPHandler == nil {
+		return errors.New("dummyRPCHandler: no handler set for UnbanIP")
+	}
+	return d.unbanIPHandler(maddr)
+}
+
+func (d *dummyRPCHandler) ProtectPeer(peerID peer.ID) error {
+	if d.protectPeerHandler == nil {
+		return errors.New("dummyRPCHandler: no handler set for ProtectPeer")
+	}
+	return d.protectPeerHandler(peerID)
+}
+
+func (d *dummyRPCHandler) ProtectIP(maddr ma.Multiaddr) error {
+	if d.protectIPHandler == nil {
+		return errors.New("dummyRPCHandler: no handler set for ProtectIP")
+	}
+	return d.protectIPHandler(maddr)
+}
+
+func (d *dummyRPCHandler) DisconnectPeer(peerID peer.ID) error {
+	if d.disconnectPeerHandler == nil {
+		return errors.New("dummyRPCHandler: no handler set for DisconnectPeer")
+	}
+	return d.disconnectPeerHandler(peerID)
+}
+
 // newTestServerAndClient returns a server and client which have been connected
 // to one another on the local network. The server will use the given
 // orderHandler to handle incoming requests. Useful for testing purposes. Will
@@ -171,6 +267,170 @@ func TestAddOrdersSuccess(t *testing.T) {
 	wg.Wait()
 }
 
+func TestValidateOrders(t *testing.T) {
+	signedTestOrder, err := zeroex.SignTestOrder(testOrder)
+	require.NoError(t, err)
+	expectedOrderHash, err := testOrder.ComputeOrderHash()
+	require.NoError(t, err)
+
+	expectedBlockNumber := 42
+	testCases := []struct {
+		opts                []ValidateOrdersOpts
+		expectedBlockNumber *int
+	}{
+		{
+			opts:                nil,
+			expectedBlockNumber: nil,
+		},
+		{
+			opts:                []ValidateOrdersOpts{{BlockNumber: &expectedBlockNumber}},
+			expectedBlockNumber: &expectedBlockNumber,
+		},
+	}
+	for _, testCase := range testCases {
+		// Set up the dummy handler with a validateOrdersHandler
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		rpcHandler := &dummyRPCHandler{
+			validateOrdersHandler: func(signedOrdersRaw []*json.RawMessage, opts ValidateOrdersOpts) (*ordervalidator.ValidationResults, error) {
+				require.Len(t, signedOrdersRaw, 1)
+				assert.Equal(t, testCase.expectedBlockNumber, opts.BlockNumber, "ValidateOrders was called with an unexpected block number")
+				signedOrder := &zeroex.SignedOrder{}
+				require.NoError(t, signedOrder.UnmarshalJSON([]byte(*signedOrdersRaw[0])))
+				orderHash, err := signedOrder.ComputeOrderHash()
+				require.NoError(t, err)
+				wg.Done()
+				return &ordervalidator.ValidationResults{
+					Accepted: []*ordervalidator.AcceptedOrderInfo{},
+					Rejected: []*ordervalidator.RejectedOrderInfo{
+						{
+							OrderHash:   orderHash,
+							SignedOrder: signedOrder,
+							Kind:        ordervalidator.ZeroExValidation,
+							Status:      ordervalidator.ROExpired,
+						},
+					},
+				}, nil
+			},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		_, client := newTestServerAndClient(t, rpcHandler, ctx)
+
+		validationResponse, err := client.ValidateOrders([]*zeroex.SignedOrder{signedTestOrder}, testCase.opts...)
+		require.NoError(t, err)
+		assert.Len(t, validationResponse.Accepted, 0)
+		require.Len(t, validationResponse.Rejected, 1)
+		assert.Equal(t, expectedOrderHash, validationResponse.Rejected[0].OrderHash, "orderHashes did not match")
+		assert.Equal(t, ordervalidator.ROExpired, validationResponse.Rejected[0].Status, "status did not match")
+
+		// The WaitGroup signals that ValidateOrders was called on the server-side.
+		wg.Wait()
+		cancel()
+	}
+}
+
+func TestValidateOrdersNegativeBlockNumber(t *testing.T) {
+	rpcHandler := &dummyRPCHandler{
+		validateOrdersHandler: func(signedOrdersRaw []*json.RawMessage, opts ValidateOrdersOpts) (*ordervalidator.ValidationResults, error) {
+			t.Error("ValidateOrders should not be called with a negative block number")
+			return nil, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, client := newTestServerAndClient(t, rpcHandler, ctx)
+
+	signedTestOrder, err := zeroex.SignTestOrder(testOrder)
+	require.NoError(t, err)
+	blockNumber := -1
+	_, err = client.ValidateOrders([]*zeroex.SignedOrder{signedTestOrder}, ValidateOrdersOpts{BlockNumber: &blockNumber})
+	require.Error(t, err)
+}
+
+func TestGetOrderStateAtBlock(t *testing.T) {
+	signedTestOrder, err := zeroex.SignTestOrder(testOrder)
+	require.NoError(t, err)
+	expectedOrderHash, err := testOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	storedOrderHash := common.HexToHash("0xa0fcb54919f0b3823aa14b3f511146f6ac087ab333a70f9b24bbb1ba657a4250")
+	expectedBlockNumber := 42
+
+	// Set up the dummy handler with a getOrderStateAtBlockHandler
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	rpcHandler := &dummyRPCHandler{
+		getOrderStateAtBlockHandler: func(orderHashes []common.Hash, signedOrdersRaw []*json.RawMessage, blockNumber int) (*ordervalidator.ValidationResults, error) {
+			assert.Equal(t, []common.Hash{storedOrderHash}, orderHashes, "GetOrderStateAtBlock was called with unexpected order hashes")
+			assert.Equal(t, expectedBlockNumber, blockNumber, "GetOrderStateAtBlock was called with an unexpected block number")
+			require.Len(t, signedOrdersRaw, 1)
+			signedOrder := &zeroex.SignedOrder{}
+			require.NoError(t, signedOrder.UnmarshalJSON([]byte(*signedOrdersRaw[0])))
+			orderHash, err := signedOrder.ComputeOrderHash()
+			require.NoError(t, err)
+			wg.Done()
+			return &ordervalidator.ValidationResults{
+				Accepted: []*ordervalidator.AcceptedOrderInfo{
+					{
+						OrderHash:                orderHash,
+						SignedOrder:              signedOrder,
+						FillableTakerAssetAmount: signedOrder.TakerAssetAmount,
+					},
+				},
+				Rejected: []*ordervalidator.RejectedOrderInfo{
+					{
+						OrderHash: storedOrderHash,
+						Kind:      ordervalidator.ZeroExValidation,
+						Status:    ordervalidator.ROFullyFilled,
+					},
+				},
+			}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, client := newTestServerAndClient(t, rpcHandler, ctx)
+
+	results, err := client.GetOrderStateAtBlock([]common.Hash{storedOrderHash}, []*zeroex.SignedOrder{signedTestOrder}, expectedBlockNumber)
+	require.NoError(t, err)
+	require.Len(t, results.Accepted, 1)
+	assert.Equal(t, expectedOrderHash, results.Accepted[0].OrderHash, "orderHashes did not match")
+	assert.Equal(t, signedTestOrder.TakerAssetAmount, results.Accepted[0].FillableTakerAssetAmount, "fillableTakerAssetAmount did not match")
+	require.Len(t, results.Rejected, 1)
+	assert.Equal(t, storedOrderHash, results.Rejected[0].OrderHash, "orderHashes did not match")
+	assert.Equal(t, ordervalidator.ROFullyFilled, results.Rejected[0].Status, "status did not match")
+
+	// The WaitGroup signals that GetOrderStateAtBlock was called on the server-side.
+	wg.Wait()
+}
+
+func TestGetOrderStateAtBlockInvalidParams(t *testing.T) {
+	rpcHandler := &dummyRPCHandler{
+		getOrderStateAtBlockHandler: func(orderHashes []common.Hash, signedOrdersRaw []*json.RawMessage, blockNumber int) (*ordervalidator.ValidationResults, error) {
+			t.Error("GetOrderStateAtBlock should not be called with invalid params")
+			return nil, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, client := newTestServerAndClient(t, rpcHandler, ctx)
+
+	_, err := client.GetOrderStateAtBlock([]common.Hash{common.HexToHash("0x1")}, nil, -1)
+	assert.Error(t, err, "expected an error for a negative block number")
+
+	// The typed client can't send invalid order hashes, so we use the underlying
+	// client directly.
+	rawClient, err := rpc.Dial("ws://" + server.Addr().String())
+	require.NoError(t, err)
+	defer rawClient.Close()
+	var results ordervalidator.ValidationResults
+	err = rawClient.Call(&results, "mesh_getOrderStateAtBlock", []string{"0x1234"}, 42)
+	assert.Error(t, err, "expected an error for an invalid order hash")
+}
+
 func TestGetOrdersSuccess(t *testing.T) {
 	signedTestOrder, err := zeroex.SignTestOrder(testOrder)
 	require.NoError(t, err)
@@ -303,6 +563,196 @@ func TestGetStats(t *testing.T) {
 	wg.Wait()
 }
 
+func TestBackupDatabase(t *testing.T) {
+	expectedPath := "/tmp/mesh_backup"
+
+	// Set up the dummy handler with a backupDatabaseHandler
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	rpcHandler := &dummyRPCHandler{
+		backupDatabaseHandler: func(path string) error {
+			assert.Equal(t, expectedPath, path, "BackupDatabase was called with an unexpected path argument")
+			wg.Done()
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, client := newTestServerAndClient(t, rpcHandler, ctx)
+
+	require.NoError(t, client.BackupDatabase(expectedPath))
+
+	// The WaitGroup signals that BackupDatabase was called on the server-side.
+	wg.Wait()
+}
+
+func TestCompactDatabase(t *testing.T) {
+	// Set up the dummy handler with a compactDatabaseHandler
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	rpcHandler := &dummyRPCHandler{
+		compactDatabaseHandler: func() error {
+			wg.Done()
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, client := newTestServerAndClient(t, rpcHandler, ctx)
+
+	require.NoError(t, client.CompactDatabase())
+
+	// The WaitGroup signals that CompactDatabase was called on the server-side.
+	wg.Wait()
+}
+
+func TestGetPeers(t *testing.T) {
+	expectedPeers := []*PeerInfo{
+		{
+			ID:         "16Uiu2HAmJ827EAibLvJxGMj6BvT1tr2e2ssW4cMtpP15qoQqZGSA",
+			Multiaddrs: []string{"/ip4/127.0.0.1/tcp/60558"},
+			Direction:  "inbound",
+			Latency:    25 * time.Millisecond,
+			Scores:     map[string]int{"order-stored": 10},
+			BytesIn:    1024,
+			BytesOut:   2048,
+			RateIn:     12.5,
+			RateOut:    25,
+			Messages: MessageStats{
+				Accepted:    100,
+				RateLimited: 3,
+				Oversize:    1,
+				Invalid:     2,
+			},
+		},
+	}
+
+	rpcHandler := &dummyRPCHandler{
+		getPeersHandler: func() ([]*PeerInfo, error) {
+			return expectedPeers, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, client := newTestServerAndClient(t, rpcHandler, ctx)
+
+	actualPeers, err := client.GetPeers()
+	require.NoError(t, err)
+	assert.Equal(t, expectedPeers, actualPeers)
+}
+
+// peerManagementCall records which RPCHandler method was called by one of
+// the peer management RPC methods and with which argument.
+type peerManagementCall struct {
+	method string
+	peerID peer.ID
+	maddr  string
+}
+
+func newPeerManagementTestHandler(calls chan<- peerManagementCall) *dummyRPCHandler {
+	peerHandler := func(method string) func(peer.ID) error {
+		return func(peerID peer.ID) error {
+			calls <- peerManagementCall{method: method, peerID: peerID}
+			return nil
+		}
+	}
+	ipHandler := func(method string) func(ma.Multiaddr) error {
+		return func(maddr ma.Multiaddr) error {
+			calls <- peerManagementCall{method: method, maddr: maddr.String()}
+			return nil
+		}
+	}
+	return &dummyRPCHandler{
+		banPeerHandler:        peerHandler("BanPeer"),
+		banIPHandler:          ipHandler("BanIP"),
+		unbanPeerHandler:      peerHandler("UnbanPeer"),
+		unbanIPHandler:        ipHandler("UnbanIP"),
+		protectPeerHandler:    peerHandler("ProtectPeer"),
+		protectIPHandler:      ipHandler("ProtectIP"),
+		disconnectPeerHandler: peerHandler("DisconnectPeer"),
+	}
+}
+
+func TestPeerManagement(t *testing.T) {
+	peerIDString := "QmagLpXZHNrTraqWpY49xtFmZMTLBWctx2PF96s4aFrj9f"
+	peerID, err := peer.IDB58Decode(peerIDString)
+	require.NoError(t, err)
+
+	calls := make(chan peerManagementCall, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, client := newTestServerAndClient(t, newPeerManagementTestHandler(calls), ctx)
+
+	testCases := []struct {
+		name         string
+		call         func() error
+		expectedCall peerManagementCall
+	}{
+		{
+			name:         "ban peer ID",
+			call:         func() error { return client.BanPeer(peerIDString) },
+			expectedCall: peerManagementCall{method: "BanPeer", peerID: peerID},
+		},
+		{
+			name:         "ban IPv4 address",
+			call:         func() error { return client.BanPeer("159.65.4.82") },
+			expectedCall: peerManagementCall{method: "BanIP", maddr: "/ip4/159.65.4.82"},
+		},
+		{
+			name:         "ban IPv6 address",
+			call:         func() error { return client.BanPeer("fe80::1") },
+			expectedCall: peerManagementCall{method: "BanIP", maddr: "/ip6/fe80::1"},
+		},
+		{
+			name:         "ban multiaddress",
+			call:         func() error { return client.BanPeer("/ip4/159.65.4.82/tcp/60558") },
+			expectedCall: peerManagementCall{method: "BanIP", maddr: "/ip4/159.65.4.82/tcp/60558"},
+		},
+		{
+			name:         "unban peer ID",
+			call:         func() error { return client.UnbanPeer(peerIDString) },
+			expectedCall: peerManagementCall{method: "UnbanPeer", peerID: peerID},
+		},
+		{
+			name:         "unban IP address",
+			call:         func() error { return client.UnbanPeer("159.65.4.82") },
+			expectedCall: peerManagementCall{method: "UnbanIP", maddr: "/ip4/159.65.4.82"},
+		},
+		{
+			name:         "protect peer ID",
+			call:         func() error { return client.ProtectPeer(peerIDString) },
+			expectedCall: peerManagementCall{method: "ProtectPeer", peerID: peerID},
+		},
+		{
+			name:         "protect IP address",
+			call:         func() error { return client.ProtectPeer("159.65.4.82") },
+			expectedCall: peerManagementCall{method: "ProtectIP", maddr: "/ip4/159.65.4.82"},
+		},
+		{
+			name:         "disconnect peer",
+			call:         func() error { return client.DisconnectPeer(peerID) },
+			expectedCall: peerManagementCall{method: "DisconnectPeer", peerID: peerID},
+		},
+	}
+	for _, testCase := range testCases {
+		require.NoError(t, testCase.call(), testCase.name)
+		select {
+		case actualCall := <-calls:
+			assert.Equal(t, testCase.expectedCall, actualCall, testCase.name)
+		default:
+			t.Errorf("RPCHandler was not called (%s)", testCase.name)
+		}
+	}
+
+	// Invalid arguments should return an error without calling the RPCHandler.
+	assert.Error(t, client.BanPeer("not a peer ID or IP address"))
+	assert.Error(t, client.UnbanPeer(""))
+	assert.Empty(t, calls)
+}
+
 func TestOrdersSubscription(t *testing.T) {
 	ctx := context.Background()
 
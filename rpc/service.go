@@ -1,14 +1,18 @@
 package rpc
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"strings"
 	"time"
 
 	"github.com/0xProject/0x-mesh/constants"
 	"github.com/0xProject/0x-mesh/zeroex/ordervalidator"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/rpc"
 	ethrpc "github.com/ethereum/go-ethereum/rpc"
 	peer "github.com/libp2p/go-libp2p-peer"
@@ -29,6 +33,11 @@ type rpcService struct {
 type RPCHandler interface {
 	// AddOrders is called when the client sends an AddOrders request.
 	AddOrders(signedOrdersRaw []*json.RawMessage, opts AddOrdersOpts) (*ordervalidator.ValidationResults, error)
+	// ValidateOrders is called when the client sends a ValidateOrders request.
+	ValidateOrders(signedOrdersRaw []*json.RawMessage, opts ValidateOrdersOpts) (*ordervalidator.ValidationResults, error)
+	// GetOrderStateAtBlock is called when the client sends a
+	// GetOrderStateAtBlock request.
+	GetOrderStateAtBlock(orderHashes []common.Hash, signedOrdersRaw []*json.RawMessage, blockNumber int) (*ordervalidator.ValidationResults, error)
 	// GetOrders is called when the clients sends a GetOrders request
 	GetOrders(page, perPage int, snapshotID string) (*GetOrdersResponse, error)
 	// AddPeer is called when the client sends an AddPeer request.
@@ -37,6 +46,31 @@ type RPCHandler interface {
 	GetStats() (*GetStatsResponse, error)
 	// SubscribeToOrders is called when a client sends a Subscribe to `orders` request
 	SubscribeToOrders(ctx context.Context) (*rpc.Subscription, error)
+	// BackupDatabase is called when the client sends a BackupDatabase request.
+	BackupDatabase(path string) error
+	// CompactDatabase is called when the client sends a CompactDatabase request.
+	CompactDatabase() error
+	// GetPeers is called when the client sends a GetPeers request.
+	GetPeers() ([]*PeerInfo, error)
+	// BanPeer is called when the client sends a BanPeer request with a peer ID.
+	BanPeer(peerID peer.ID) error
+	// BanIP is called when the client sends a BanPeer request with an IP
+	// address.
+	BanIP(maddr ma.Multiaddr) error
+	// UnbanPeer is called when the client sends an UnbanPeer request with a
+	// peer ID.
+	UnbanPeer(peerID peer.ID) error
+	// UnbanIP is called when the client sends an UnbanPeer request with an IP
+	// address.
+	UnbanIP(maddr ma.Multiaddr) error
+	// ProtectPeer is called when the client sends a ProtectPeer request with a
+	// peer ID.
+	ProtectPeer(peerID peer.ID) error
+	// ProtectIP is called when the client sends a ProtectPeer request with an
+	// IP address.
+	ProtectIP(maddr ma.Multiaddr) error
+	// DisconnectPeer is called when the client sends a DisconnectPeer request.
+	DisconnectPeer(peerID peer.ID) error
 }
 
 // Orders calls rpcHandler.SubscribeToOrders and returns the rpc subscription.
@@ -132,6 +166,45 @@ func (s *rpcService) AddOrders(signedOrdersRaw []*json.RawMessage, opts *AddOrde
 	return s.rpcHandler.AddOrders(signedOrdersRaw, *opts)
 }
 
+// ValidateOrders calls rpcHandler.ValidateOrders and returns the validation
+// results. It returns an error if the block number is negative.
+func (s *rpcService) ValidateOrders(signedOrdersRaw []*json.RawMessage, opts *ValidateOrdersOpts) (*ordervalidator.ValidationResults, error) {
+	if opts == nil {
+		opts = &ValidateOrdersOpts{}
+	}
+	if opts.BlockNumber != nil && *opts.BlockNumber < 0 {
+		return nil, fmt.Errorf("invalid block number: %d", *opts.BlockNumber)
+	}
+	return s.rpcHandler.ValidateOrders(signedOrdersRaw, *opts)
+}
+
+// GetOrderStateAtBlock splits ordersOrHashes into order hashes (JSON strings)
+// and signed orders (JSON objects) and calls rpcHandler.GetOrderStateAtBlock. It
+// returns an error if an order hash or the block number is invalid.
+func (s *rpcService) GetOrderStateAtBlock(ordersOrHashes []*json.RawMessage, blockNumber int) (*ordervalidator.ValidationResults, error) {
+	if blockNumber < 0 {
+		return nil, fmt.Errorf("invalid block number: %d", blockNumber)
+	}
+	orderHashes := []common.Hash{}
+	signedOrdersRaw := []*json.RawMessage{}
+	for _, orderOrHash := range ordersOrHashes {
+		if orderOrHash == nil {
+			return nil, errors.New("expected an order hash or a signed order but got null")
+		}
+		trimmed := bytes.TrimSpace(*orderOrHash)
+		if len(trimmed) > 0 && trimmed[0] == '"' {
+			var orderHash common.Hash
+			if err := json.Unmarshal(trimmed, &orderHash); err != nil {
+				return nil, fmt.Errorf("invalid order hash %s: %s", string(trimmed), err.Error())
+			}
+			orderHashes = append(orderHashes, orderHash)
+			continue
+		}
+		signedOrdersRaw = append(signedOrdersRaw, orderOrHash)
+	}
+	return s.rpcHandler.GetOrderStateAtBlock(orderHashes, signedOrdersRaw, blockNumber)
+}
+
 // GetOrders calls rpcHandler.GetOrders and returns the validation results.
 func (s *rpcService) GetOrders(page, perPage int, snapshotID string) (*GetOrdersResponse, error) {
 	return s.rpcHandler.GetOrders(page, perPage, snapshotID)
@@ -167,3 +240,91 @@ func (s *rpcService) AddPeer(peerID string, multiaddrs []string) error {
 func (s *rpcService) GetStats() (*GetStatsResponse, error) {
 	return s.rpcHandler.GetStats()
 }
+
+// BackupDatabase calls rpcHandler.BackupDatabase. If there is an error, it
+// returns it.
+func (s *rpcService) BackupDatabase(path string) error {
+	return s.rpcHandler.BackupDatabase(path)
+}
+
+// CompactDatabase calls rpcHandler.CompactDatabase. If there is an error, it
+// returns it.
+func (s *rpcService) CompactDatabase() error {
+	return s.rpcHandler.CompactDatabase()
+}
+
+// GetPeers calls rpcHandler.GetPeers. If there is an error, it returns it.
+func (s *rpcService) GetPeers() ([]*PeerInfo, error) {
+	return s.rpcHandler.GetPeers()
+}
+
+// BanPeer calls rpcHandler.BanPeer if peerIDOrIP is a peer ID and
+// rpcHandler.BanIP otherwise. If there is an error, it returns it.
+func (s *rpcService) BanPeer(peerIDOrIP string) error {
+	peerID, maddr, err := parsePeerIDOrIP(peerIDOrIP)
+	if err != nil {
+		return err
+	}
+	if maddr != nil {
+		return s.rpcHandler.BanIP(maddr)
+	}
+	return s.rpcHandler.BanPeer(peerID)
+}
+
+// UnbanPeer calls rpcHandler.UnbanPeer if peerIDOrIP is a peer ID and
+// rpcHandler.UnbanIP otherwise. If there is an error, it returns it.
+func (s *rpcService) UnbanPeer(peerIDOrIP string) error {
+	peerID, maddr, err := parsePeerIDOrIP(peerIDOrIP)
+	if err != nil {
+		return err
+	}
+	if maddr != nil {
+		return s.rpcHandler.UnbanIP(maddr)
+	}
+	return s.rpcHandler.UnbanPeer(peerID)
+}
+
+// ProtectPeer calls rpcHandler.ProtectPeer if peerIDOrIP is a peer ID and
+// rpcHandler.ProtectIP otherwise. If there is an error, it returns it.
+func (s *rpcService) ProtectPeer(peerIDOrIP string) error {
+	peerID, maddr, err := parsePeerIDOrIP(peerIDOrIP)
+	if err != nil {
+		return err
+	}
+	if maddr != nil {
+		return s.rpcHandler.ProtectIP(maddr)
+	}
+	return s.rpcHandler.ProtectPeer(peerID)
+}
+
+// DisconnectPeer parses the given peer ID and calls
+// rpcHandler.DisconnectPeer. If there is an error, it returns it.
+func (s *rpcService) DisconnectPeer(peerID string) error {
+	parsedPeerID, err := peer.IDB58Decode(peerID)
+	if err != nil {
+		return err
+	}
+	return s.rpcHandler.DisconnectPeer(parsedPeerID)
+}
+
+// parsePeerIDOrIP parses s as either a peer ID, an IP address (e.g. "1.2.3.4")
+// or a multiaddress which contains an IP address (e.g. "/ip4/1.2.3.4"). If s
+// is an IP address or multiaddress, the returned Multiaddr is non-nil.
+func parsePeerIDOrIP(s string) (peer.ID, ma.Multiaddr, error) {
+	if peerID, err := peer.IDB58Decode(s); err == nil {
+		return peerID, nil, nil
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			maddr, err := ma.NewMultiaddr("/ip4/" + ip4.String())
+			return "", maddr, err
+		}
+		maddr, err := ma.NewMultiaddr("/ip6/" + ip.String())
+		return "", maddr, err
+	}
+	maddr, err := ma.NewMultiaddr(s)
+	if err != nil {
+		return "", nil, fmt.Errorf("%q is not a valid peer ID, IP address, or multiaddress", s)
+	}
+	return "", maddr, nil
+}
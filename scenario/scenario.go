@@ -2,7 +2,6 @@ package scenario
 
 import (
 	"context"
-	"fmt"
 	"math/big"
 	"testing"
 	"time"
@@ -216,10 +215,11 @@ func CreateNFTForZRXSignedTestOrder(t *testing.T, ethClient *ethclient.Client, m
 	require.NoError(t, err)
 	waitTxnSuccessfullyMined(t, ethClient, txn)
 
-	makerAssetDataHex := fmt.Sprintf("%s000000000000000000000000%s000000000000000000000000000000000000000000000000000000000000000%s", zeroex.ERC721AssetDataID, constants.GanacheDummyERC721TokenAddress.Hex()[2:], tokenID)
-	makerAssetData := common.Hex2Bytes(
-		makerAssetDataHex,
-	)
+	makerAssetData, err := zeroex.NewAssetDataEncoder().EncodeERC721AssetData(zeroex.ERC721AssetData{
+		Address: constants.GanacheDummyERC721TokenAddress,
+		TokenId: tokenID,
+	})
+	require.NoError(t, err)
 
 	// Create order
 	testOrder := &zeroex.Order{
@@ -306,19 +306,12 @@ func CreateERC1155ForZRXSignedTestOrder(t *testing.T, ethClient *ethclient.Clien
 
 	ganacheAddresses := ethereum.ChainIDToContractAddresses[constants.TestChainID]
 
-	devUtils, err := wrappers.NewDevUtils(ganacheAddresses.DevUtils, ethClient)
-	require.NoError(t, err)
-
-	callOpts := &bind.CallOpts{
-		From: makerAddress,
-	}
-	erc1155AssetData, err := devUtils.EncodeERC1155AssetData(
-		callOpts,
-		constants.GanacheDummyERC1155MintableAddress,
-		[]*big.Int{tokenID},
-		[]*big.Int{erc1155FungibleAmount},
-		[]byte{},
-	)
+	erc1155AssetData, err := zeroex.NewAssetDataEncoder().EncodeERC1155AssetData(zeroex.ERC1155AssetData{
+		Address:      constants.GanacheDummyERC1155MintableAddress,
+		Ids:          []*big.Int{tokenID},
+		Values:       []*big.Int{erc1155FungibleAmount},
+		CallbackData: []byte{},
+	})
 	require.NoError(t, err)
 
 	// Create order
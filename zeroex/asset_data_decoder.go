@@ -23,10 +23,18 @@ const ERC1155AssetDataID = "a7cb5fb7"
 // MultiAssetDataID is the assetDataId for multiAsset tokens
 const MultiAssetDataID = "94cfcdd7"
 
+// StaticCallAssetDataID is the assetDataId for staticCalls
+const StaticCallAssetDataID = "c339d10a"
+
+// ERC20BridgeAssetDataID is the assetDataId for ERC20Bridge tokens
+const ERC20BridgeAssetDataID = "dc1600f3"
+
 const erc20AssetDataAbi = "[{\"inputs\":[{\"name\":\"address\",\"type\":\"address\"}],\"name\":\"ERC20Token\",\"type\":\"function\"}]"
 const erc721AssetDataAbi = "[{\"inputs\":[{\"name\":\"address\",\"type\":\"address\"},{\"name\":\"tokenId\",\"type\":\"uint256\"}],\"name\":\"ERC721Token\",\"type\":\"function\"}]"
 const erc1155AssetDataAbi = "[{\"constant\":false,\"inputs\":[{\"name\":\"address\",\"type\":\"address\"},{\"name\":\"ids\",\"type\":\"uint256[]\"},{\"name\":\"values\",\"type\":\"uint256[]\"},{\"name\":\"callbackData\",\"type\":\"bytes\"}],\"name\":\"ERC1155Assets\",\"outputs\":[],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]"
 const multiAssetDataAbi = "[{\"inputs\":[{\"name\":\"amounts\",\"type\":\"uint256[]\"},{\"name\":\"nestedAssetData\",\"type\":\"bytes[]\"}],\"name\":\"MultiAsset\",\"type\":\"function\"}]"
+const staticCallAssetDataAbi = "[{\"inputs\":[{\"name\":\"staticCallTargetAddress\",\"type\":\"address\"},{\"name\":\"staticCallData\",\"type\":\"bytes\"},{\"name\":\"expectedReturnDataHash\",\"type\":\"bytes32\"}],\"name\":\"StaticCall\",\"type\":\"function\"}]"
+const erc20BridgeAssetDataAbi = "[{\"inputs\":[{\"name\":\"tokenAddress\",\"type\":\"address\"},{\"name\":\"bridgeAddress\",\"type\":\"address\"},{\"name\":\"bridgeData\",\"type\":\"bytes\"}],\"name\":\"ERC20Bridge\",\"type\":\"function\"}]"
 
 // ERC20AssetData represents an ERC20 assetData
 type ERC20AssetData struct {
@@ -53,6 +61,23 @@ type MultiAssetData struct {
 	NestedAssetData [][]byte
 }
 
+// StaticCallAssetData represents a StaticCall assetData. Orders with StaticCall
+// asset data can only be filled if calling the target contract with the given
+// call data returns data with the expected hash.
+type StaticCallAssetData struct {
+	StaticCallTargetAddress common.Address
+	StaticCallData          []byte
+	ExpectedReturnDataHash  [32]byte
+}
+
+// ERC20BridgeAssetData represents an ERC20Bridge assetData. The tokens are
+// transferred by the bridge contract instead of directly from the maker.
+type ERC20BridgeAssetData struct {
+	TokenAddress  common.Address
+	BridgeAddress common.Address
+	BridgeData    []byte
+}
+
 type assetDataInfo struct {
 	name string
 	abi  abi.ABI
@@ -65,6 +90,15 @@ type AssetDataDecoder struct {
 
 // NewAssetDataDecoder instantiates a new asset data decoder
 func NewAssetDataDecoder() *AssetDataDecoder {
+	decoder := &AssetDataDecoder{
+		idToAssetDataInfo: newIDToAssetDataInfo(),
+	}
+	return decoder
+}
+
+// newIDToAssetDataInfo returns a mapping of assetDataId to the name and ABI of
+// the corresponding asset data type
+func newIDToAssetDataInfo() map[string]assetDataInfo {
 	erc20AssetDataABI, err := abi.JSON(strings.NewReader(erc20AssetDataAbi))
 	if err != nil {
 		log.WithField("erc20AssetDataAbi", erc20AssetDataAbi).Panic("erc20AssetDataAbi should be ABI parsable")
@@ -81,7 +115,15 @@ func NewAssetDataDecoder() *AssetDataDecoder {
 	if err != nil {
 		log.WithField("erc20AssetDataAbi", erc20AssetDataAbi).Panic("erc20AssetDataAbi should be ABI parsable")
 	}
-	idToAssetDataInfo := map[string]assetDataInfo{
+	staticCallAssetDataABI, err := abi.JSON(strings.NewReader(staticCallAssetDataAbi))
+	if err != nil {
+		log.WithField("staticCallAssetDataAbi", staticCallAssetDataAbi).Panic("staticCallAssetDataAbi should be ABI parsable")
+	}
+	erc20BridgeAssetDataABI, err := abi.JSON(strings.NewReader(erc20BridgeAssetDataAbi))
+	if err != nil {
+		log.WithField("erc20BridgeAssetDataAbi", erc20BridgeAssetDataAbi).Panic("erc20BridgeAssetDataAbi should be ABI parsable")
+	}
+	return map[string]assetDataInfo{
 		ERC20AssetDataID: assetDataInfo{
 			name: "ERC20Token",
 			abi:  erc20AssetDataABI,
@@ -98,11 +140,15 @@ func NewAssetDataDecoder() *AssetDataDecoder {
 			name: "MultiAsset",
 			abi:  multiAssetDataABI,
 		},
+		StaticCallAssetDataID: assetDataInfo{
+			name: "StaticCall",
+			abi:  staticCallAssetDataABI,
+		},
+		ERC20BridgeAssetDataID: assetDataInfo{
+			name: "ERC20Bridge",
+			abi:  erc20BridgeAssetDataABI,
+		},
 	}
-	decoder := &AssetDataDecoder{
-		idToAssetDataInfo: idToAssetDataInfo,
-	}
-	return decoder
 }
 
 // GetName returns the name of the assetData type
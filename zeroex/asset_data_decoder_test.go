@@ -77,3 +77,45 @@ func TestDecodeERC1155AssetData(t *testing.T) {
 	}
 	assert.Equal(t, expectedDecodedAssetData, actualDecodedAssetData, "ERC1155 Asset Data properly decoded")
 }
+
+func TestDecodeStaticCallAssetData(t *testing.T) {
+	assetData := common.Hex2Bytes("c339d10a0000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000006001010101010101010101010101010101010101010101010101010101010101010000000000000000000000000000000000000000000000000000000000000004deadbeef00000000000000000000000000000000000000000000000000000000")
+
+	d := NewAssetDataDecoder()
+
+	name, err := d.GetName(assetData)
+	require.NoError(t, err)
+	assert.Equal(t, "StaticCall", name)
+
+	var actualDecodedAssetData StaticCallAssetData
+	err = d.Decode(assetData, &actualDecodedAssetData)
+	require.NoError(t, err)
+
+	expectedDecodedAssetData := StaticCallAssetData{
+		StaticCallTargetAddress: common.HexToAddress("0x1dc4c1cefef38a777b15aa20260a54e584b16c48"),
+		StaticCallData:          common.Hex2Bytes("deadbeef"),
+		ExpectedReturnDataHash:  common.HexToHash("0x0101010101010101010101010101010101010101010101010101010101010101"),
+	}
+	assert.Equal(t, expectedDecodedAssetData, actualDecodedAssetData, "StaticCall Asset Data properly decoded")
+}
+
+func TestDecodeERC20BridgeAssetData(t *testing.T) {
+	assetData := common.Hex2Bytes("dc1600f30000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c4800000000000000000000000038ae374ecf4db50b0ff37125b591a04997106a3200000000000000000000000000000000000000000000000000000000000000600000000000000000000000000000000000000000000000000000000000000004deadbeef00000000000000000000000000000000000000000000000000000000")
+
+	d := NewAssetDataDecoder()
+
+	name, err := d.GetName(assetData)
+	require.NoError(t, err)
+	assert.Equal(t, "ERC20Bridge", name)
+
+	var actualDecodedAssetData ERC20BridgeAssetData
+	err = d.Decode(assetData, &actualDecodedAssetData)
+	require.NoError(t, err)
+
+	expectedDecodedAssetData := ERC20BridgeAssetData{
+		TokenAddress:  common.HexToAddress("0x1dc4c1cefef38a777b15aa20260a54e584b16c48"),
+		BridgeAddress: common.HexToAddress("0x38ae374ecf4db50b0ff37125b591a04997106a32"),
+		BridgeData:    common.Hex2Bytes("deadbeef"),
+	}
+	assert.Equal(t, expectedDecodedAssetData, actualDecodedAssetData, "ERC20Bridge Asset Data properly decoded")
+}
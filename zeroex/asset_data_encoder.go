@@ -0,0 +1,139 @@
+package zeroex
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// AssetDataEncoder encodes 0x order asset data
+type AssetDataEncoder struct {
+	idToAssetDataInfo map[string]assetDataInfo
+}
+
+// NewAssetDataEncoder instantiates a new asset data encoder
+func NewAssetDataEncoder() *AssetDataEncoder {
+	encoder := &AssetDataEncoder{
+		idToAssetDataInfo: newIDToAssetDataInfo(),
+	}
+	return encoder
+}
+
+// Encode encodes the given asset data sub-components into asset data. It
+// accepts ERC20AssetData, ERC721AssetData, ERC1155AssetData, MultiAssetData,
+// StaticCallAssetData and ERC20BridgeAssetData (or pointers to them), i.e. the
+// same types AssetDataDecoder decodes into.
+func (a *AssetDataEncoder) Encode(decodedAssetData interface{}) ([]byte, error) {
+	switch decoded := decodedAssetData.(type) {
+	case ERC20AssetData:
+		return a.EncodeERC20AssetData(decoded)
+	case *ERC20AssetData:
+		return a.EncodeERC20AssetData(*decoded)
+	case ERC721AssetData:
+		return a.EncodeERC721AssetData(decoded)
+	case *ERC721AssetData:
+		return a.EncodeERC721AssetData(*decoded)
+	case ERC1155AssetData:
+		return a.EncodeERC1155AssetData(decoded)
+	case *ERC1155AssetData:
+		return a.EncodeERC1155AssetData(*decoded)
+	case MultiAssetData:
+		return a.EncodeMultiAssetData(decoded)
+	case *MultiAssetData:
+		return a.EncodeMultiAssetData(*decoded)
+	case StaticCallAssetData:
+		return a.EncodeStaticCallAssetData(decoded)
+	case *StaticCallAssetData:
+		return a.EncodeStaticCallAssetData(*decoded)
+	case ERC20BridgeAssetData:
+		return a.EncodeERC20BridgeAssetData(decoded)
+	case *ERC20BridgeAssetData:
+		return a.EncodeERC20BridgeAssetData(*decoded)
+	default:
+		return nil, fmt.Errorf("cannot encode asset data of type %T", decodedAssetData)
+	}
+}
+
+// EncodeERC20AssetData encodes ERC20 asset data
+func (a *AssetDataEncoder) EncodeERC20AssetData(assetData ERC20AssetData) ([]byte, error) {
+	return a.pack(ERC20AssetDataID, assetData.Address)
+}
+
+// EncodeERC721AssetData encodes ERC721 asset data
+func (a *AssetDataEncoder) EncodeERC721AssetData(assetData ERC721AssetData) ([]byte, error) {
+	if err := checkUint256s(assetData.TokenId); err != nil {
+		return nil, err
+	}
+	return a.pack(ERC721AssetDataID, assetData.Address, assetData.TokenId)
+}
+
+// EncodeERC1155AssetData encodes ERC1155 asset data
+func (a *AssetDataEncoder) EncodeERC1155AssetData(assetData ERC1155AssetData) ([]byte, error) {
+	if len(assetData.Ids) != len(assetData.Values) {
+		return nil, fmt.Errorf("ERC1155 asset data must have the same number of ids and values (got %d ids and %d values)", len(assetData.Ids), len(assetData.Values))
+	}
+	if err := checkUint256s(assetData.Ids...); err != nil {
+		return nil, err
+	}
+	if err := checkUint256s(assetData.Values...); err != nil {
+		return nil, err
+	}
+	callbackData := assetData.CallbackData
+	if callbackData == nil {
+		callbackData = []byte{}
+	}
+	return a.pack(ERC1155AssetDataID, assetData.Address, assetData.Ids, assetData.Values, callbackData)
+}
+
+// EncodeMultiAssetData encodes MultiAsset asset data
+func (a *AssetDataEncoder) EncodeMultiAssetData(assetData MultiAssetData) ([]byte, error) {
+	if len(assetData.Amounts) != len(assetData.NestedAssetData) {
+		return nil, fmt.Errorf("MultiAsset asset data must have the same number of amounts and nested asset data (got %d amounts and %d nested asset data)", len(assetData.Amounts), len(assetData.NestedAssetData))
+	}
+	if err := checkUint256s(assetData.Amounts...); err != nil {
+		return nil, err
+	}
+	return a.pack(MultiAssetDataID, assetData.Amounts, assetData.NestedAssetData)
+}
+
+// EncodeStaticCallAssetData encodes StaticCall asset data
+func (a *AssetDataEncoder) EncodeStaticCallAssetData(assetData StaticCallAssetData) ([]byte, error) {
+	staticCallData := assetData.StaticCallData
+	if staticCallData == nil {
+		staticCallData = []byte{}
+	}
+	return a.pack(StaticCallAssetDataID, assetData.StaticCallTargetAddress, staticCallData, assetData.ExpectedReturnDataHash)
+}
+
+// EncodeERC20BridgeAssetData encodes ERC20Bridge asset data
+func (a *AssetDataEncoder) EncodeERC20BridgeAssetData(assetData ERC20BridgeAssetData) ([]byte, error) {
+	bridgeData := assetData.BridgeData
+	if bridgeData == nil {
+		bridgeData = []byte{}
+	}
+	return a.pack(ERC20BridgeAssetDataID, assetData.TokenAddress, assetData.BridgeAddress, bridgeData)
+}
+
+// pack ABI encodes the arguments for the asset data type with the given
+// assetDataId and prefixes them with the assetDataId
+func (a *AssetDataEncoder) pack(id string, args ...interface{}) ([]byte, error) {
+	info, ok := a.idToAssetDataInfo[id]
+	if !ok {
+		return nil, fmt.Errorf("Unrecognized assetData with prefix: %s", id)
+	}
+	return info.abi.Pack(info.name, args...)
+}
+
+// checkUint256s returns an error if any of the values can't be encoded as a
+// uint256. The ABI encoder would silently wrap them around otherwise.
+func checkUint256s(values ...*big.Int) error {
+	for _, value := range values {
+		if value == nil {
+			return errors.New("asset data must not contain nil amounts or ids")
+		}
+		if value.Sign() < 0 || value.BitLen() > 256 {
+			return fmt.Errorf("asset data amount or id is not a uint256: %s", value)
+		}
+	}
+	return nil
+}
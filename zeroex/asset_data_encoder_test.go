@@ -0,0 +1,262 @@
+package zeroex
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"testing/quick"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeERC20AssetData(t *testing.T) {
+	expectedAssetData := common.Hex2Bytes("f47261b000000000000000000000000038ae374ecf4db50b0ff37125b591a04997106a32")
+
+	e := NewAssetDataEncoder()
+
+	actualAssetData, err := e.Encode(ERC20AssetData{
+		Address: common.HexToAddress("0x38ae374ecf4db50b0ff37125b591a04997106a32"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expectedAssetData, actualAssetData, "ERC20 Asset Data properly encoded")
+}
+
+func TestEncodeERC721AssetData(t *testing.T) {
+	expectedAssetData := common.Hex2Bytes("025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001")
+
+	e := NewAssetDataEncoder()
+
+	actualAssetData, err := e.Encode(&ERC721AssetData{
+		Address: common.HexToAddress("0x1dC4c1cEFEF38a777b15aA20260a54E584b16C48"),
+		TokenId: big.NewInt(1),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expectedAssetData, actualAssetData, "ERC721 Asset Data properly encoded")
+}
+
+func TestEncodeERC1155AssetData(t *testing.T) {
+	expectedAssetData := common.Hex2Bytes("a7cb5fb70000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000080000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000001800000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000000000000000000000000000000006400000000000000000000000000000000000000000000000000000000000003e90000000000000000000000000000000000000000000000000000000000002711000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000000000000000000000000000000000000c800000000000000000000000000000000000000000000000000000000000007d10000000000000000000000000000000000000000000000000000000000004e210000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000")
+
+	e := NewAssetDataEncoder()
+
+	actualAssetData, err := e.Encode(ERC1155AssetData{
+		Address:      common.HexToAddress("0x1dc4c1cefef38a777b15aa20260a54e584b16c48"),
+		Ids:          []*big.Int{big.NewInt(100), big.NewInt(1001), big.NewInt(10001)},
+		Values:       []*big.Int{big.NewInt(200), big.NewInt(2001), big.NewInt(20001)},
+		CallbackData: common.Hex2Bytes("025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expectedAssetData, actualAssetData, "ERC1155 Asset Data properly encoded")
+}
+
+func TestEncodeMultiAssetData(t *testing.T) {
+	// Build the same MultiAsset asset data as in TestDecodeMultiAssetData from
+	// its nested asset data.
+	e := NewAssetDataEncoder()
+	tokenAddress := common.HexToAddress("0x1dc4c1cefef38a777b15aa20260a54e584b16c48")
+	erc20AssetData, err := e.Encode(ERC20AssetData{Address: tokenAddress})
+	require.NoError(t, err)
+	erc721AssetData, err := e.Encode(ERC721AssetData{Address: tokenAddress, TokenId: big.NewInt(1)})
+	require.NoError(t, err)
+	erc1155AssetData, err := e.Encode(ERC1155AssetData{
+		Address:      tokenAddress,
+		Ids:          []*big.Int{big.NewInt(100), big.NewInt(1001), big.NewInt(10001)},
+		Values:       []*big.Int{big.NewInt(200), big.NewInt(2001), big.NewInt(20001)},
+		CallbackData: erc721AssetData,
+	})
+	require.NoError(t, err)
+
+	actualAssetData, err := e.Encode(MultiAssetData{
+		Amounts:         []*big.Int{big.NewInt(70), big.NewInt(1), big.NewInt(18)},
+		NestedAssetData: [][]byte{erc20AssetData, erc721AssetData, erc1155AssetData},
+	})
+	require.NoError(t, err)
+
+	expectedAssetData := common.Hex2Bytes("94cfcdd7000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000c000000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000000000000000000000000000000000046000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000120000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000000000000000000000000000000006000000000000000000000000000000000000000000000000000000000000000c000000000000000000000000000000000000000000000000000000000000001400000000000000000000000000000000000000000000000000000000000000024f47261b00000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000204a7cb5fb70000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000080000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000001800000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000000000000000000000000000000006400000000000000000000000000000000000000000000000000000000000003e90000000000000000000000000000000000000000000000000000000000002711000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000000000000000000000000000000000000c800000000000000000000000000000000000000000000000000000000000007d10000000000000000000000000000000000000000000000000000000000004e210000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c4800000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000")
+	assert.Equal(t, expectedAssetData, actualAssetData, "Multi Asset Data properly encoded")
+}
+
+func TestEncodeStaticCallAssetData(t *testing.T) {
+	expectedAssetData := common.Hex2Bytes("c339d10a0000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000006001010101010101010101010101010101010101010101010101010101010101010000000000000000000000000000000000000000000000000000000000000004deadbeef00000000000000000000000000000000000000000000000000000000")
+
+	e := NewAssetDataEncoder()
+
+	actualAssetData, err := e.Encode(StaticCallAssetData{
+		StaticCallTargetAddress: common.HexToAddress("0x1dc4c1cefef38a777b15aa20260a54e584b16c48"),
+		StaticCallData:          common.Hex2Bytes("deadbeef"),
+		ExpectedReturnDataHash:  common.HexToHash("0x0101010101010101010101010101010101010101010101010101010101010101"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expectedAssetData, actualAssetData, "StaticCall Asset Data properly encoded")
+}
+
+func TestEncodeERC20BridgeAssetData(t *testing.T) {
+	expectedAssetData := common.Hex2Bytes("dc1600f30000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c4800000000000000000000000038ae374ecf4db50b0ff37125b591a04997106a3200000000000000000000000000000000000000000000000000000000000000600000000000000000000000000000000000000000000000000000000000000004deadbeef00000000000000000000000000000000000000000000000000000000")
+
+	e := NewAssetDataEncoder()
+
+	actualAssetData, err := e.Encode(&ERC20BridgeAssetData{
+		TokenAddress:  common.HexToAddress("0x1dc4c1cefef38a777b15aa20260a54e584b16c48"),
+		BridgeAddress: common.HexToAddress("0x38ae374ecf4db50b0ff37125b591a04997106a32"),
+		BridgeData:    common.Hex2Bytes("deadbeef"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expectedAssetData, actualAssetData, "ERC20Bridge Asset Data properly encoded")
+}
+
+func TestEncodeInvalidAssetData(t *testing.T) {
+	e := NewAssetDataEncoder()
+	tokenAddress := common.HexToAddress("0x1dc4c1cefef38a777b15aa20260a54e584b16c48")
+	testCases := []struct {
+		name      string
+		assetData interface{}
+	}{
+		{"unsupported type", "foo"},
+		{"ERC721 without token ID", ERC721AssetData{Address: tokenAddress}},
+		{"ERC721 with negative token ID", ERC721AssetData{Address: tokenAddress, TokenId: big.NewInt(-1)}},
+		{"ERC721 with token ID larger than uint256", ERC721AssetData{Address: tokenAddress, TokenId: new(big.Int).Lsh(big.NewInt(1), 256)}},
+		{"ERC1155 with more ids than values", ERC1155AssetData{Address: tokenAddress, Ids: []*big.Int{big.NewInt(1)}}},
+		{"ERC1155 with nil value", ERC1155AssetData{Address: tokenAddress, Ids: []*big.Int{big.NewInt(1)}, Values: []*big.Int{nil}}},
+		{"MultiAsset with more amounts than nested asset data", MultiAssetData{Amounts: []*big.Int{big.NewInt(1)}}},
+	}
+	for _, testCase := range testCases {
+		_, err := e.Encode(testCase.assetData)
+		assert.Error(t, err, testCase.name)
+	}
+}
+
+// uint256FromBytes converts a random 32 byte array into a *big.Int which is a
+// valid uint256.
+func uint256FromBytes(b [32]byte) *big.Int {
+	return new(big.Int).SetBytes(b[:])
+}
+
+// assertBigIntsEqual compares big.Ints by value, since values with the same
+// value can have different internal representations.
+func assertBigIntsEqual(t *testing.T, expected []*big.Int, actual []*big.Int) bool {
+	if !assert.Equal(t, len(expected), len(actual)) {
+		return false
+	}
+	for i := range expected {
+		if !assert.Equal(t, expected[i].String(), actual[i].String()) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestERC20AssetDataRoundTrip(t *testing.T) {
+	e := NewAssetDataEncoder()
+	d := NewAssetDataDecoder()
+	roundTrip := func(address common.Address) bool {
+		assetData, err := e.Encode(ERC20AssetData{Address: address})
+		if !assert.NoError(t, err) {
+			return false
+		}
+		var decoded ERC20AssetData
+		if !assert.NoError(t, d.Decode(assetData, &decoded)) {
+			return false
+		}
+		return assert.Equal(t, address, decoded.Address)
+	}
+	require.NoError(t, quick.Check(roundTrip, nil))
+}
+
+func TestERC721AssetDataRoundTrip(t *testing.T) {
+	e := NewAssetDataEncoder()
+	d := NewAssetDataDecoder()
+	roundTrip := func(address common.Address, tokenID [32]byte) bool {
+		expected := ERC721AssetData{Address: address, TokenId: uint256FromBytes(tokenID)}
+		assetData, err := e.Encode(expected)
+		if !assert.NoError(t, err) {
+			return false
+		}
+		var decoded ERC721AssetData
+		if !assert.NoError(t, d.Decode(assetData, &decoded)) {
+			return false
+		}
+		return assert.Equal(t, expected.Address, decoded.Address) &&
+			assertBigIntsEqual(t, []*big.Int{expected.TokenId}, []*big.Int{decoded.TokenId})
+	}
+	require.NoError(t, quick.Check(roundTrip, nil))
+}
+
+type testERC1155Asset struct {
+	ID    [32]byte
+	Value [32]byte
+}
+
+func TestERC1155AssetDataRoundTrip(t *testing.T) {
+	e := NewAssetDataEncoder()
+	d := NewAssetDataDecoder()
+	roundTrip := func(address common.Address, assets []testERC1155Asset, callbackData []byte) bool {
+		expected := ERC1155AssetData{
+			Address:      address,
+			Ids:          []*big.Int{},
+			Values:       []*big.Int{},
+			CallbackData: callbackData,
+		}
+		for _, asset := range assets {
+			expected.Ids = append(expected.Ids, uint256FromBytes(asset.ID))
+			expected.Values = append(expected.Values, uint256FromBytes(asset.Value))
+		}
+		assetData, err := e.Encode(expected)
+		if !assert.NoError(t, err) {
+			return false
+		}
+		var decoded ERC1155AssetData
+		if !assert.NoError(t, d.Decode(assetData, &decoded)) {
+			return false
+		}
+		return assert.Equal(t, expected.Address, decoded.Address) &&
+			assertBigIntsEqual(t, expected.Ids, decoded.Ids) &&
+			assertBigIntsEqual(t, expected.Values, decoded.Values) &&
+			assert.True(t, bytes.Equal(expected.CallbackData, decoded.CallbackData), "callback data should be equal")
+	}
+	require.NoError(t, quick.Check(roundTrip, nil))
+}
+
+type testNestedAsset struct {
+	Amount  [32]byte
+	Address common.Address
+	TokenID [32]byte
+	IsERC20 bool
+}
+
+func TestMultiAssetDataRoundTrip(t *testing.T) {
+	e := NewAssetDataEncoder()
+	d := NewAssetDataDecoder()
+	roundTrip := func(nestedAssets []testNestedAsset) bool {
+		expected := MultiAssetData{
+			Amounts:         []*big.Int{},
+			NestedAssetData: [][]byte{},
+		}
+		for _, nestedAsset := range nestedAssets {
+			var nestedAssetData []byte
+			var err error
+			if nestedAsset.IsERC20 {
+				nestedAssetData, err = e.Encode(ERC20AssetData{Address: nestedAsset.Address})
+			} else {
+				nestedAssetData, err = e.Encode(ERC721AssetData{Address: nestedAsset.Address, TokenId: uint256FromBytes(nestedAsset.TokenID)})
+			}
+			if !assert.NoError(t, err) {
+				return false
+			}
+			expected.Amounts = append(expected.Amounts, uint256FromBytes(nestedAsset.Amount))
+			expected.NestedAssetData = append(expected.NestedAssetData, nestedAssetData)
+		}
+		assetData, err := e.Encode(expected)
+		if !assert.NoError(t, err) {
+			return false
+		}
+		var decoded MultiAssetData
+		if !assert.NoError(t, d.Decode(assetData, &decoded)) {
+			return false
+		}
+		return assertBigIntsEqual(t, expected.Amounts, decoded.Amounts) &&
+			assert.Equal(t, expected.NestedAssetData, decoded.NestedAssetData)
+	}
+	require.NoError(t, quick.Check(roundTrip, nil))
+}
@@ -449,18 +449,49 @@ func SignOrder(signer signer.Signer, order *Order) (*SignedOrder, error) {
 	}
 
 	// Generate 0x EthSign Signature (append the signature type byte)
-	signature := make([]byte, 66)
-	signature[0] = ecSignature.V
-	copy(signature[1:33], ecSignature.R[:])
-	copy(signature[33:65], ecSignature.S[:])
-	signature[65] = byte(EthSignSignature)
 	signedOrder := &SignedOrder{
 		Order:     *order,
-		Signature: signature,
+		Signature: encodeSignature(ecSignature, EthSignSignature),
+	}
+	return signedOrder, nil
+}
+
+// SignOrderEIP712 signs the 0x order with the supplied EIP712Signer. Unlike
+// SignOrder, the order hash is signed directly (without the `eth_sign` message
+// prefix) and the resulting signature has the EIP712Signature type.
+func SignOrderEIP712(signer signer.EIP712Signer, order *Order) (*SignedOrder, error) {
+	if order == nil {
+		return nil, errors.New("cannot sign nil order")
+	}
+	orderHash, err := order.ComputeOrderHash()
+	if err != nil {
+		return nil, err
+	}
+
+	ecSignature, err := signer.EIP712Sign(orderHash.Bytes(), order.MakerAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate 0x EIP712 Signature (append the signature type byte)
+	signedOrder := &SignedOrder{
+		Order:     *order,
+		Signature: encodeSignature(ecSignature, EIP712Signature),
 	}
 	return signedOrder, nil
 }
 
+// encodeSignature encodes the elliptic curve signature in the format expected by
+// the 0x smart contracts, i.e. [V || R || S || SignatureType].
+func encodeSignature(ecSignature *signer.ECSignature, signatureType SignatureType) []byte {
+	signature := make([]byte, 66)
+	signature[0] = ecSignature.V
+	copy(signature[1:33], ecSignature.R[:])
+	copy(signature[33:65], ecSignature.S[:])
+	signature[65] = byte(signatureType)
+	return signature
+}
+
 // SignTestOrder signs the 0x order with the local test signer
 func SignTestOrder(order *Order) (*SignedOrder, error) {
 	testSigner := signer.NewTestSigner()
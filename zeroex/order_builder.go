@@ -0,0 +1,222 @@
+package zeroex
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultOrderExpiration is how long orders built with an OrderBuilder are valid
+// for if no expiration time is set.
+const DefaultOrderExpiration = 24 * time.Hour
+
+// maxSalt is the exclusive upper bound of randomly generated salts (2^256).
+var maxSalt = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// OrderBuilder builds 0x orders which are ready to be signed with SignOrder. By
+// default, orders have no taker, sender or fee recipient, no fees, a random salt,
+// expire after DefaultOrderExpiration and use the Exchange contract address for
+// the chain. Setters can be chained and errors (e.g. from encoding asset data)
+// are returned by Build.
+type OrderBuilder struct {
+	chainID        int
+	order          Order
+	expirationTime time.Time
+	encoder        *AssetDataEncoder
+	err            error
+}
+
+// NewOrderBuilder instantiates a new OrderBuilder for orders on the given chain.
+// It returns an error if there are no contract addresses for the chain.
+func NewOrderBuilder(chainID int) (*OrderBuilder, error) {
+	contractAddresses, err := ethereum.GetContractAddressesForChainID(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderBuilder{
+		chainID: chainID,
+		order: Order{
+			TakerAddress:        constants.NullAddress,
+			SenderAddress:       constants.NullAddress,
+			FeeRecipientAddress: constants.NullAddress,
+			ExchangeAddress:     contractAddresses.Exchange,
+			MakerFee:            big.NewInt(0),
+			TakerFee:            big.NewInt(0),
+		},
+		encoder: NewAssetDataEncoder(),
+	}, nil
+}
+
+// ChainID returns the chain ID the OrderBuilder builds orders for
+func (b *OrderBuilder) ChainID() int {
+	return b.chainID
+}
+
+// MakerAddress sets the maker address
+func (b *OrderBuilder) MakerAddress(makerAddress common.Address) *OrderBuilder {
+	b.order.MakerAddress = makerAddress
+	return b
+}
+
+// TakerAddress sets the taker address
+func (b *OrderBuilder) TakerAddress(takerAddress common.Address) *OrderBuilder {
+	b.order.TakerAddress = takerAddress
+	return b
+}
+
+// SenderAddress sets the sender address
+func (b *OrderBuilder) SenderAddress(senderAddress common.Address) *OrderBuilder {
+	b.order.SenderAddress = senderAddress
+	return b
+}
+
+// FeeRecipientAddress sets the fee recipient address
+func (b *OrderBuilder) FeeRecipientAddress(feeRecipientAddress common.Address) *OrderBuilder {
+	b.order.FeeRecipientAddress = feeRecipientAddress
+	return b
+}
+
+// ExchangeAddress overrides the Exchange contract address for the chain
+func (b *OrderBuilder) ExchangeAddress(exchangeAddress common.Address) *OrderBuilder {
+	b.order.ExchangeAddress = exchangeAddress
+	return b
+}
+
+// MakerAssetData sets the encoded maker asset data
+func (b *OrderBuilder) MakerAssetData(assetData []byte) *OrderBuilder {
+	b.order.MakerAssetData = assetData
+	return b
+}
+
+// TakerAssetData sets the encoded taker asset data
+func (b *OrderBuilder) TakerAssetData(assetData []byte) *OrderBuilder {
+	b.order.TakerAssetData = assetData
+	return b
+}
+
+// MakerAsset encodes the given asset data sub-components (see
+// AssetDataEncoder.Encode) and sets them as the maker asset data
+func (b *OrderBuilder) MakerAsset(decodedAssetData interface{}) *OrderBuilder {
+	assetData, err := b.encoder.Encode(decodedAssetData)
+	if err != nil {
+		b.setErr(err)
+		return b
+	}
+	return b.MakerAssetData(assetData)
+}
+
+// TakerAsset encodes the given asset data sub-components (see
+// AssetDataEncoder.Encode) and sets them as the taker asset data
+func (b *OrderBuilder) TakerAsset(decodedAssetData interface{}) *OrderBuilder {
+	assetData, err := b.encoder.Encode(decodedAssetData)
+	if err != nil {
+		b.setErr(err)
+		return b
+	}
+	return b.TakerAssetData(assetData)
+}
+
+// MakerAssetAmount sets the maker asset amount
+func (b *OrderBuilder) MakerAssetAmount(amount *big.Int) *OrderBuilder {
+	b.order.MakerAssetAmount = amount
+	return b
+}
+
+// TakerAssetAmount sets the taker asset amount
+func (b *OrderBuilder) TakerAssetAmount(amount *big.Int) *OrderBuilder {
+	b.order.TakerAssetAmount = amount
+	return b
+}
+
+// MakerFee sets the maker fee
+func (b *OrderBuilder) MakerFee(fee *big.Int) *OrderBuilder {
+	b.order.MakerFee = fee
+	return b
+}
+
+// TakerFee sets the taker fee
+func (b *OrderBuilder) TakerFee(fee *big.Int) *OrderBuilder {
+	b.order.TakerFee = fee
+	return b
+}
+
+// Salt sets the salt. If it is not set, a random salt is generated for each
+// order.
+func (b *OrderBuilder) Salt(salt *big.Int) *OrderBuilder {
+	b.order.Salt = salt
+	return b
+}
+
+// ExpirationTime sets the time at which the order expires. If it is not set,
+// orders expire DefaultOrderExpiration after they are built.
+func (b *OrderBuilder) ExpirationTime(expirationTime time.Time) *OrderBuilder {
+	b.expirationTime = expirationTime
+	return b
+}
+
+// Build returns a new order with the values set on the OrderBuilder. It returns
+// an error if any of the setters failed or if a required value (maker address,
+// asset data and asset amounts) is missing.
+func (b *OrderBuilder) Build() (*Order, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.order.MakerAddress == constants.NullAddress {
+		return nil, errors.New("maker address is required")
+	}
+	if len(b.order.MakerAssetData) == 0 {
+		return nil, errors.New("maker asset data is required")
+	}
+	if len(b.order.TakerAssetData) == 0 {
+		return nil, errors.New("taker asset data is required")
+	}
+	if b.order.MakerAssetAmount == nil || b.order.MakerAssetAmount.Sign() <= 0 {
+		return nil, errors.New("maker asset amount must be greater than 0")
+	}
+	if b.order.TakerAssetAmount == nil || b.order.TakerAssetAmount.Sign() <= 0 {
+		return nil, errors.New("taker asset amount must be greater than 0")
+	}
+	if b.order.MakerFee == nil || b.order.MakerFee.Sign() < 0 {
+		return nil, errors.New("maker fee must not be negative")
+	}
+	if b.order.TakerFee == nil || b.order.TakerFee.Sign() < 0 {
+		return nil, errors.New("taker fee must not be negative")
+	}
+
+	order := b.order
+	salt := b.order.Salt
+	if salt == nil {
+		var err error
+		salt, err = rand.Int(rand.Reader, maxSalt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	expirationTime := b.expirationTime
+	if expirationTime.IsZero() {
+		expirationTime = time.Now().Add(DefaultOrderExpiration)
+	}
+	// Copy all big.Ints and byte slices so that orders built with the same
+	// OrderBuilder never share memory.
+	order.MakerAssetData = common.CopyBytes(b.order.MakerAssetData)
+	order.TakerAssetData = common.CopyBytes(b.order.TakerAssetData)
+	order.MakerAssetAmount = new(big.Int).Set(b.order.MakerAssetAmount)
+	order.TakerAssetAmount = new(big.Int).Set(b.order.TakerAssetAmount)
+	order.MakerFee = new(big.Int).Set(b.order.MakerFee)
+	order.TakerFee = new(big.Int).Set(b.order.TakerFee)
+	order.Salt = new(big.Int).Set(salt)
+	order.ExpirationTimeSeconds = big.NewInt(expirationTime.Unix())
+	return &order, nil
+}
+
+// setErr records the first error which occurred while building an order
+func (b *OrderBuilder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
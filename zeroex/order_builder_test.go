@@ -0,0 +1,139 @@
+package zeroex
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderBuilderDefaults(t *testing.T) {
+	contractAddresses, err := ethereum.GetContractAddressesForChainID(constants.TestChainID)
+	require.NoError(t, err)
+	builder, err := NewOrderBuilder(constants.TestChainID)
+	require.NoError(t, err)
+	assert.Equal(t, constants.TestChainID, builder.ChainID())
+
+	before := time.Now()
+	order, err := builder.
+		MakerAddress(constants.GanacheAccount0).
+		MakerAsset(ERC20AssetData{Address: contractAddresses.ZRXToken}).
+		TakerAsset(ERC20AssetData{Address: contractAddresses.WETH9}).
+		MakerAssetAmount(big.NewInt(1000)).
+		TakerAssetAmount(big.NewInt(2000)).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, constants.GanacheAccount0, order.MakerAddress)
+	assert.Equal(t, constants.NullAddress, order.TakerAddress)
+	assert.Equal(t, constants.NullAddress, order.SenderAddress)
+	assert.Equal(t, constants.NullAddress, order.FeeRecipientAddress)
+	assert.Equal(t, contractAddresses.Exchange, order.ExchangeAddress)
+	assert.Equal(t, "0", order.MakerFee.String())
+	assert.Equal(t, "0", order.TakerFee.String())
+	assert.Equal(t, "1000", order.MakerAssetAmount.String())
+	assert.Equal(t, "2000", order.TakerAssetAmount.String())
+	require.NotNil(t, order.Salt)
+	expectedExpiration := before.Add(DefaultOrderExpiration).Unix()
+	assert.True(t, order.ExpirationTimeSeconds.Int64() >= expectedExpiration && order.ExpirationTimeSeconds.Int64() <= expectedExpiration+1)
+
+	var makerAssetData ERC20AssetData
+	require.NoError(t, NewAssetDataDecoder().Decode(order.MakerAssetData, &makerAssetData))
+	assert.Equal(t, contractAddresses.ZRXToken, makerAssetData.Address)
+
+	// Each order should get a new random salt.
+	otherOrder, err := builder.Build()
+	require.NoError(t, err)
+	assert.NotEqual(t, order.Salt.String(), otherOrder.Salt.String())
+
+	// The order should be ready to be signed.
+	signedOrder, err := SignTestOrder(order)
+	require.NoError(t, err)
+	assert.Len(t, signedOrder.Signature, 66)
+}
+
+func TestOrderBuilderOverrides(t *testing.T) {
+	builder, err := NewOrderBuilder(constants.TestChainID)
+	require.NoError(t, err)
+	expirationTime := time.Unix(1600000000, 0)
+	exchangeAddress := common.HexToAddress("0x1dc4c1cefef38a777b15aa20260a54e584b16c48")
+	makerAssetData := common.Hex2Bytes("f47261b000000000000000000000000038ae374ecf4db50b0ff37125b591a04997106a32")
+	order, err := builder.
+		MakerAddress(constants.GanacheAccount0).
+		TakerAddress(constants.GanacheAccount1).
+		SenderAddress(constants.GanacheAccount2).
+		FeeRecipientAddress(constants.GanacheAccount3).
+		ExchangeAddress(exchangeAddress).
+		MakerAssetData(makerAssetData).
+		TakerAsset(ERC721AssetData{Address: exchangeAddress, TokenId: big.NewInt(1)}).
+		MakerAssetAmount(big.NewInt(1)).
+		TakerAssetAmount(big.NewInt(1)).
+		MakerFee(big.NewInt(10)).
+		TakerFee(big.NewInt(20)).
+		Salt(big.NewInt(42)).
+		ExpirationTime(expirationTime).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, constants.GanacheAccount1, order.TakerAddress)
+	assert.Equal(t, constants.GanacheAccount2, order.SenderAddress)
+	assert.Equal(t, constants.GanacheAccount3, order.FeeRecipientAddress)
+	assert.Equal(t, exchangeAddress, order.ExchangeAddress)
+	assert.Equal(t, makerAssetData, order.MakerAssetData)
+	assert.Equal(t, "10", order.MakerFee.String())
+	assert.Equal(t, "20", order.TakerFee.String())
+	assert.Equal(t, "42", order.Salt.String())
+	assert.Equal(t, expirationTime.Unix(), order.ExpirationTimeSeconds.Int64())
+
+	// Changing the order should not affect the builder.
+	order.MakerFee.SetInt64(100)
+	order.MakerAssetData[0] = 0
+	otherOrder, err := builder.Build()
+	require.NoError(t, err)
+	assert.Equal(t, "10", otherOrder.MakerFee.String())
+	assert.Equal(t, makerAssetData, otherOrder.MakerAssetData)
+}
+
+func TestOrderBuilderErrors(t *testing.T) {
+	_, err := NewOrderBuilder(-1)
+	assert.Error(t, err, "unknown chain ID")
+
+	newValidBuilder := func() *OrderBuilder {
+		builder, err := NewOrderBuilder(constants.TestChainID)
+		require.NoError(t, err)
+		return builder.
+			MakerAddress(constants.GanacheAccount0).
+			MakerAsset(ERC20AssetData{Address: constants.GanacheAccount1}).
+			TakerAsset(ERC20AssetData{Address: constants.GanacheAccount2}).
+			MakerAssetAmount(big.NewInt(1)).
+			TakerAssetAmount(big.NewInt(1))
+	}
+	_, err = newValidBuilder().Build()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		modify func(*OrderBuilder)
+	}{
+		{"no maker address", func(b *OrderBuilder) { b.MakerAddress(constants.NullAddress) }},
+		{"no maker asset data", func(b *OrderBuilder) { b.MakerAssetData(nil) }},
+		{"no taker asset data", func(b *OrderBuilder) { b.TakerAssetData(nil) }},
+		{"invalid maker asset", func(b *OrderBuilder) { b.MakerAsset(ERC721AssetData{}) }},
+		{"invalid taker asset", func(b *OrderBuilder) { b.TakerAsset("foo") }},
+		{"zero maker asset amount", func(b *OrderBuilder) { b.MakerAssetAmount(big.NewInt(0)) }},
+		{"no taker asset amount", func(b *OrderBuilder) { b.TakerAssetAmount(nil) }},
+		{"negative maker fee", func(b *OrderBuilder) { b.MakerFee(big.NewInt(-1)) }},
+		{"no taker fee", func(b *OrderBuilder) { b.TakerFee(nil) }},
+	}
+	for _, testCase := range testCases {
+		builder := newValidBuilder()
+		testCase.modify(builder)
+		_, err := builder.Build()
+		assert.Error(t, err, testCase.name)
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"testing"
 
 	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/ethereum/signer"
 	"github.com/0xProject/0x-mesh/zeroex/orderwatch/decoder"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -49,6 +51,23 @@ func TestSignOrder(t *testing.T) {
 	assert.Equal(t, expectedSignature, actualSignature)
 }
 
+func TestSignOrderEIP712(t *testing.T) {
+	testSigner := signer.NewTestSigner().(*signer.TestSigner)
+	signedOrder, err := SignOrderEIP712(testSigner, testOrder)
+	require.NoError(t, err)
+	require.Len(t, signedOrder.Signature, 66)
+	assert.Equal(t, byte(EIP712Signature), signedOrder.Signature[65])
+
+	// EIP712 signatures are signatures of the order hash itself, so the maker
+	// address can be recovered from it directly.
+	orderHash, err := testOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	rsv := append(append([]byte{}, signedOrder.Signature[1:65]...), signedOrder.Signature[0]-27)
+	publicKey, err := crypto.SigToPub(orderHash.Bytes(), rsv)
+	require.NoError(t, err)
+	assert.Equal(t, testOrder.MakerAddress, crypto.PubkeyToAddress(*publicKey))
+}
+
 func TestMarshalUnmarshalOrderEvent(t *testing.T) {
 	signedOrder, err := SignTestOrder(testOrder)
 	require.NoError(t, err)
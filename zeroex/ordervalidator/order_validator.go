@@ -175,8 +175,67 @@ var (
 		Code:    "DatabaseFullOfOrders",
 		Message: "database is full of pinned orders and no orders can be deleted to make space (consider increasing MAX_ORDERS_IN_STORAGE)",
 	}
+	ROMakerQuotaExceeded = RejectedOrderStatus{
+		Code:    "MakerQuotaExceeded",
+		Message: "maker already has the maximum number of orders allowed in storage (consider increasing MAX_ORDERS_PER_MAKER)",
+	}
+	ROFeeRecipientQuotaExceeded = RejectedOrderStatus{
+		Code:    "FeeRecipientQuotaExceeded",
+		Message: "fee recipient already has the maximum number of orders allowed in storage (consider increasing MAX_ORDERS_PER_FEE_RECIPIENT)",
+	}
+	ROBlockStateUnavailable = RejectedOrderStatus{
+		Code:    "BlockStateUnavailable",
+		Message: "Ethereum RPC endpoint does not have the state for the requested block (validating orders at older blocks requires an archive node)",
+	}
+	ROOrderNotFound = RejectedOrderStatus{
+		Code:    "OrderNotFound",
+		Message: "no order with the given hash is stored by this Mesh node",
+	}
 )
 
+// JSON-RPC error codes defined in EIP-1474 which Ethereum nodes return when
+// they don't have the requested data.
+const (
+	rpcResourceNotFoundErrorCode    = -32001
+	rpcResourceUnavailableErrorCode = -32002
+)
+
+// stateUnavailableErrorSubstrings are substrings of the errors returned by
+// Ethereum nodes (geth, Parity and hosted node providers respectively) when they
+// don't have the state for the block an eth_call is made at. This typically
+// means the node is not an archive node and has pruned the state. Most nodes
+// use the generic -32000 error code for these errors, so the message is the
+// only way to tell them apart from other errors.
+var stateUnavailableErrorSubstrings = []string{
+	"missing trie node",
+	"header not found",
+	"pruning=archive",
+	"archive state",
+}
+
+// IsStateUnavailableError returns true if err was returned by an Ethereum node
+// because it doesn't have the state for the block an eth_call was made at. The
+// JSON-RPC error code is checked first and the error message is only used as a
+// fallback.
+func IsStateUnavailableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if rpcErr, ok := err.(rpc.Error); ok {
+		switch rpcErr.ErrorCode() {
+		case rpcResourceNotFoundErrorCode, rpcResourceUnavailableErrorCode:
+			return true
+		}
+	}
+	errMessage := strings.ToLower(err.Error())
+	for _, substring := range stateUnavailableErrorSubstrings {
+		if strings.Contains(errMessage, substring) {
+			return true
+		}
+	}
+	return false
+}
+
 // ROInvalidSchemaCode is the RejectedOrderStatus emitted if an order doesn't conform to the order schema
 const ROInvalidSchemaCode = "InvalidSchema"
 
@@ -334,6 +393,33 @@ func (o *OrderValidator) BatchValidate(ctx context.Context, rawSignedOrders []*z
 				}
 
 				results, err := o.devUtils.GetOrderRelevantStates(opts, orders, signatures)
+				isHistoricalBlock := blockNumber != rpc.LatestBlockNumber && blockNumber != rpc.PendingBlockNumber
+				if err != nil && isHistoricalBlock && IsStateUnavailableError(err) {
+					// Retrying won't help if the Ethereum RPC endpoint has pruned
+					// the state for the block. At the latest block, these errors
+					// are usually transient (e.g. a load-balanced provider whose
+					// backends are out of sync) so they are retried below.
+					<-semaphoreChan
+					log.WithFields(log.Fields{
+						"error":       err.Error(),
+						"blockNumber": blockNumber,
+						"numOrders":   len(orders),
+					}).Warning("Ethereum RPC endpoint does not have the state for GetOrderRelevantStates request")
+					for _, signedOrder := range signedOrders {
+						orderHash, err := signedOrder.ComputeOrderHash()
+						if err != nil {
+							log.WithField("error", err).Error("Unexpectedly failed to generate orderHash")
+							continue
+						}
+						validationResults.Rejected = append(validationResults.Rejected, &RejectedOrderInfo{
+							OrderHash:   orderHash,
+							SignedOrder: signedOrder,
+							Kind:        MeshError,
+							Status:      ROBlockStateUnavailable,
+						})
+					}
+					return
+				}
 				if err != nil {
 					log.WithFields(log.Fields{
 						"error":     err.Error(),
@@ -717,6 +803,18 @@ func (o *OrderValidator) isSupportedAssetData(assetData []byte) bool {
 		if err != nil {
 			return false
 		}
+	case "StaticCall":
+		var decodedAssetData zeroex.StaticCallAssetData
+		err := o.assetDataDecoder.Decode(assetData, &decodedAssetData)
+		if err != nil {
+			return false
+		}
+	case "ERC20Bridge":
+		var decodedAssetData zeroex.ERC20BridgeAssetData
+		err := o.assetDataDecoder.Decode(assetData, &decodedAssetData)
+		if err != nil {
+			return false
+		}
 	default:
 		return false
 	}
@@ -6,6 +6,7 @@ package ordervalidator
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"math/big"
@@ -22,6 +23,7 @@ import (
 	"github.com/0xProject/0x-mesh/ethereum/wrappers"
 	"github.com/0xProject/0x-mesh/scenario"
 	"github.com/0xProject/0x-mesh/zeroex"
+	goethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -51,6 +53,8 @@ var (
 	unsupportedAssetData = common.Hex2Bytes("a2cb61b000000000000000000000000034d402f14d58e001d8efbe6585051bf9706aa064")
 	malformedAssetData   = []byte("9HJhsAAAAAAAAAAAAAAAAInSSmtMyxtvqiYl")
 	malformedSignature   = []byte("9HJhsAAAAAAAAAAAAAAAAInSSmtMyxtvqiYl")
+	staticCallAssetData  = common.Hex2Bytes("c339d10a0000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000006001010101010101010101010101010101010101010101010101010101010101010000000000000000000000000000000000000000000000000000000000000004deadbeef00000000000000000000000000000000000000000000000000000000")
+	erc20BridgeAssetData = common.Hex2Bytes("dc1600f30000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c4800000000000000000000000038ae374ecf4db50b0ff37125b591a04997106a3200000000000000000000000000000000000000000000000000000000000000600000000000000000000000000000000000000000000000000000000000000004deadbeef00000000000000000000000000000000000000000000000000000000")
 	multiAssetAssetData  = common.Hex2Bytes("94cfcdd7000000000000000000000000000000000000000000000000000000000000004000000000000000000000000000000000000000000000000000000000000000c000000000000000000000000000000000000000000000000000000000000000030000000000000000000000000000000000000000000000000000000000000046000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000120000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000000000000000000000000000000006000000000000000000000000000000000000000000000000000000000000000c000000000000000000000000000000000000000000000000000000000000001400000000000000000000000000000000000000000000000000000000000000024f47261b00000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c48000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000204a7cb5fb70000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c480000000000000000000000000000000000000000000000000000000000000080000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000001800000000000000000000000000000000000000000000000000000000000000003000000000000000000000000000000000000000000000000000000000000006400000000000000000000000000000000000000000000000000000000000003e90000000000000000000000000000000000000000000000000000000000002711000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000000000000000000000000000000000000c800000000000000000000000000000000000000000000000000000000000007d10000000000000000000000000000000000000000000000000000000000004e210000000000000000000000000000000000000000000000000000000000000044025717920000000000000000000000001dc4c1cefef38a777b15aa20260a54e584b16c4800000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000")
 )
 
@@ -118,6 +122,18 @@ func TestBatchValidateOffChainCases(t *testing.T) {
 			SignedOrder: signedOrderWithCustomMakerAssetData(t, testSignedOrder, multiAssetAssetData),
 			IsValid:     true,
 		},
+		testCase{
+			SignedOrder: signedOrderWithCustomMakerAssetData(t, testSignedOrder, staticCallAssetData),
+			IsValid:     true,
+		},
+		testCase{
+			SignedOrder: signedOrderWithCustomTakerAssetData(t, testSignedOrder, staticCallAssetData),
+			IsValid:     true,
+		},
+		testCase{
+			SignedOrder: signedOrderWithCustomMakerAssetData(t, testSignedOrder, erc20BridgeAssetData),
+			IsValid:     true,
+		},
 		testCase{
 			SignedOrder:                 signedOrderWithCustomMakerAssetData(t, testSignedOrder, malformedAssetData),
 			IsValid:                     false,
@@ -317,6 +333,122 @@ func TestBatchValidateCoordinatorSoftCancels(t *testing.T) {
 
 const singleOrderPayloadSize = 1980
 
+// testRPCError is an rpc.Error with the given JSON-RPC error code.
+type testRPCError struct {
+	code    int
+	message string
+}
+
+func (e testRPCError) Error() string {
+	return e.message
+}
+
+func (e testRPCError) ErrorCode() int {
+	return e.code
+}
+
+func TestIsStateUnavailableError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: false,
+		},
+		{
+			name:     "resource not found error code",
+			err:      testRPCError{code: -32001, message: "resource not found"},
+			expected: true,
+		},
+		{
+			name:     "resource unavailable error code",
+			err:      testRPCError{code: -32002, message: "resource unavailable"},
+			expected: true,
+		},
+		{
+			name:     "geth missing trie node",
+			err:      testRPCError{code: -32000, message: "missing trie node 7b3dc0d8a0f9d0f3a1c4a2c1f5e0e3b4b0d3a6f7d9b1c2e3f4a5b6c7d8e9f0a1 (path )"},
+			expected: true,
+		},
+		{
+			name:     "geth header not found",
+			err:      testRPCError{code: -32000, message: "header not found"},
+			expected: true,
+		},
+		{
+			name:     "Parity pruned state",
+			err:      testRPCError{code: -32000, message: "This request is not supported because your node is running with state pruning. Run with --pruning=archive."},
+			expected: true,
+		},
+		{
+			name:     "hosted node without archive access",
+			err:      testRPCError{code: -32000, message: "project ID does not have access to archive state"},
+			expected: true,
+		},
+		{
+			name:     "message without error code",
+			err:      errors.New("header not found"),
+			expected: true,
+		},
+		{
+			name:     "execution reverted",
+			err:      testRPCError{code: -32000, message: "execution reverted"},
+			expected: false,
+		},
+		{
+			name:     "context deadline exceeded",
+			err:      context.DeadlineExceeded,
+			expected: false,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, IsStateUnavailableError(testCase.err), "unexpected result for error: %v", testCase.err)
+		})
+	}
+}
+
+// stateUnavailableContractCaller is a bind.ContractCaller which fails every
+// call with the error geth returns for blocks it doesn't have.
+type stateUnavailableContractCaller struct{}
+
+func (stateUnavailableContractCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, errors.New("header not found")
+}
+
+func (stateUnavailableContractCaller) CallContract(ctx context.Context, call goethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, errors.New("header not found")
+}
+
+func TestBatchValidateStateUnavailable(t *testing.T) {
+	// Other tests modify testSignedOrder, so make sure the order doesn't require
+	// a coordinator.
+	order := testSignedOrder.Order
+	order.SenderAddress = constants.NullAddress
+	signedOrder, err := zeroex.SignTestOrder(&order)
+	require.NoError(t, err)
+	orderValidator, err := New(stateUnavailableContractCaller{}, constants.TestChainID, constants.TestMaxContentLength)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// At a historical block, the request is not retried since it can't succeed.
+	validationResults := orderValidator.BatchValidate(ctx, []*zeroex.SignedOrder{signedOrder}, areNewOrders, rpc.BlockNumber(1))
+	assert.Len(t, validationResults.Accepted, 0)
+	require.Len(t, validationResults.Rejected, 1)
+	assert.Equal(t, ROBlockStateUnavailable, validationResults.Rejected[0].Status)
+
+	// At the latest block, the error is probably transient, so the request is
+	// retried and reported as a failed request.
+	validationResults = orderValidator.BatchValidate(ctx, []*zeroex.SignedOrder{signedOrder}, areNewOrders, rpc.LatestBlockNumber)
+	assert.Len(t, validationResults.Accepted, 0)
+	require.Len(t, validationResults.Rejected, 1)
+	assert.Equal(t, ROEthRPCRequestFailed, validationResults.Rejected[0].Status)
+}
+
 func TestComputeOptimalChunkSizesMaxContentLengthTooLow(t *testing.T) {
 	signedOrder, err := zeroex.SignTestOrder(&testSignedOrder.Order)
 	require.NoError(t, err)
@@ -0,0 +1,74 @@
+package orderwatch
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/0xProject/0x-mesh/constants"
+	"github.com/0xProject/0x-mesh/meshdb"
+)
+
+// EvictionPolicy determines which orders are removed from storage when the
+// number of stored orders reaches the configured maximum. Pinned orders must
+// never be evicted.
+type EvictionPolicy interface {
+	// Evict removes orders from the database until at most targetMaxOrders
+	// orders remain and returns the orders that were removed. It also returns a
+	// new max expiration time. Incoming orders that expire after the new max
+	// expiration time will not be stored. Policies which do not evict orders
+	// based on expiration time should return constants.UnlimitedExpirationTime.
+	// If the database is full of pinned orders, Evict should return
+	// meshdb.ErrDBFilledWithPinnedOrders.
+	Evict(meshDB *meshdb.MeshDB, targetMaxOrders int) (newMaxExpirationTime *big.Int, removedOrders []*meshdb.Order, err error)
+}
+
+// Names for the built-in eviction policies.
+const (
+	// ExpirationTimeEvictionPolicyName is the name of ExpirationTimeEvictionPolicy.
+	ExpirationTimeEvictionPolicyName = "expirationTime"
+	// FairShareByMakerEvictionPolicyName is the name of
+	// FairShareByMakerEvictionPolicy.
+	FairShareByMakerEvictionPolicyName = "fairShareByMaker"
+)
+
+// EvictionPolicyFromName returns the built-in eviction policy with the given
+// name. An empty name corresponds to the default policy,
+// ExpirationTimeEvictionPolicy.
+func EvictionPolicyFromName(name string) (EvictionPolicy, error) {
+	switch name {
+	case "", ExpirationTimeEvictionPolicyName:
+		return ExpirationTimeEvictionPolicy{}, nil
+	case FairShareByMakerEvictionPolicyName:
+		return FairShareByMakerEvictionPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown eviction policy: %q", name)
+	}
+}
+
+// ExpirationTimeEvictionPolicy evicts the orders with the highest expiration
+// time first. It is the default eviction policy.
+type ExpirationTimeEvictionPolicy struct{}
+
+var _ EvictionPolicy = ExpirationTimeEvictionPolicy{}
+
+// Evict implements EvictionPolicy.
+func (ExpirationTimeEvictionPolicy) Evict(meshDB *meshdb.MeshDB, targetMaxOrders int) (*big.Int, []*meshdb.Order, error) {
+	return meshDB.TrimOrdersByExpirationTime(targetMaxOrders)
+}
+
+// FairShareByMakerEvictionPolicy evicts orders from the makers with the most
+// orders first, so that a single maker cannot push every other maker's orders
+// out of storage. For each maker, the orders with the highest expiration time
+// are evicted first.
+type FairShareByMakerEvictionPolicy struct{}
+
+var _ EvictionPolicy = FairShareByMakerEvictionPolicy{}
+
+// Evict implements EvictionPolicy.
+func (FairShareByMakerEvictionPolicy) Evict(meshDB *meshdb.MeshDB, targetMaxOrders int) (*big.Int, []*meshdb.Order, error) {
+	removedOrders, err := meshDB.TrimOrdersByMaker(targetMaxOrders)
+	if err != nil {
+		return nil, nil, err
+	}
+	return constants.UnlimitedExpirationTime, removedOrders, nil
+}
@@ -0,0 +1,29 @@
+// +build !js
+
+package orderwatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvictionPolicyFromName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected EvictionPolicy
+	}{
+		{"", ExpirationTimeEvictionPolicy{}},
+		{ExpirationTimeEvictionPolicyName, ExpirationTimeEvictionPolicy{}},
+		{FairShareByMakerEvictionPolicyName, FairShareByMakerEvictionPolicy{}},
+	}
+	for _, testCase := range testCases {
+		actual, err := EvictionPolicyFromName(testCase.name)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, actual, "wrong policy for name %q", testCase.name)
+	}
+
+	_, err := EvictionPolicyFromName("notARealPolicy")
+	assert.Error(t, err)
+}
@@ -56,6 +56,12 @@ const (
 	// defaultMaxOrders is the default max number of orders in storage.
 	defaultMaxOrders = 100000
 
+	// staticCallRevalidationInterval is how often orders with StaticCall asset
+	// data are re-validated. The result of a static call can change without any
+	// contract events being emitted, so these orders can't be watched like other
+	// orders.
+	staticCallRevalidationInterval = 1 * time.Minute
+
 	// maxExpirationTimeCheckInterval is how often to check whether we can
 	// increase the max expiration time.
 	maxExpirationTimeCheckInterval = 30 * time.Second
@@ -70,12 +76,21 @@ const (
 	slowCounterInterval = 5 * time.Minute
 )
 
+var (
+	// ErrMakerQuotaExceeded is returned by Add when the maker of a non-pinned
+	// order already has the maximum number of orders allowed in storage.
+	ErrMakerQuotaExceeded = errors.New("maker has reached the maximum number of orders allowed in storage")
+	// ErrFeeRecipientQuotaExceeded is returned by Add when the fee recipient of a
+	// non-pinned order already has the maximum number of orders allowed in
+	// storage.
+	ErrFeeRecipientQuotaExceeded = errors.New("fee recipient has reached the maximum number of orders allowed in storage")
+)
+
 // Watcher watches all order-relevant state and handles the state transitions
 type Watcher struct {
 	meshDB                     *meshdb.MeshDB
 	blockWatcher               *blockwatch.Watcher
 	eventDecoder               *decoder.Decoder
-	assetDataDecoder           *zeroex.AssetDataDecoder
 	blockSubscription          event.Subscription
 	contractAddresses          ethereum.ContractAddresses
 	expirationWatcher          *expirationwatch.Watcher
@@ -88,7 +103,14 @@ type Watcher struct {
 	maxExpirationTime          *big.Int
 	maxExpirationCounter       *slowcounter.SlowCounter
 	maxOrders                  int
+	maxOrdersPerMaker          int
+	maxOrdersPerFeeRecipient   int
+	makerToOrderCount          map[common.Address]int
+	feeRecipientToOrderCount   map[common.Address]int
+	evictionPolicy             EvictionPolicy
 	latestBlockTimestamp       time.Time
+	staticCallOrdersMu         sync.Mutex
+	staticCallOrderHashes      map[common.Hash]struct{}
 }
 
 type Config struct {
@@ -98,6 +120,20 @@ type Config struct {
 	ChainID           int
 	MaxOrders         int
 	MaxExpirationTime *big.Int
+	// MaxOrdersPerMaker is the maximum number of orders from a single maker
+	// that will be kept in storage. Pinned orders are counted towards the
+	// quota but are never rejected because of it. A value of 0 means there is
+	// no quota.
+	MaxOrdersPerMaker int
+	// MaxOrdersPerFeeRecipient is the maximum number of orders with a single
+	// fee recipient that will be kept in storage. Pinned orders are counted
+	// towards the quota but are never rejected because of it. A value of 0
+	// means there is no quota.
+	MaxOrdersPerFeeRecipient int
+	// EvictionPolicy determines which orders are removed when the number of
+	// orders in storage reaches MaxOrders. Defaults to
+	// ExpirationTimeEvictionPolicy.
+	EvictionPolicy EvictionPolicy
 }
 
 // New instantiates a new order watcher
@@ -106,7 +142,6 @@ func New(config Config) (*Watcher, error) {
 	if err != nil {
 		return nil, err
 	}
-	assetDataDecoder := zeroex.NewAssetDataDecoder()
 	contractAddresses, err := ethereum.GetContractAddressesForChainID(config.ChainID)
 	if err != nil {
 		return nil, err
@@ -122,6 +157,15 @@ func New(config Config) (*Watcher, error) {
 		// MaxExpirationTime should never be in the past.
 		config.MaxExpirationTime = big.NewInt(time.Now().Unix())
 	}
+	if config.MaxOrdersPerMaker < 0 {
+		return nil, errors.New("config.MaxOrdersPerMaker cannot be negative")
+	}
+	if config.MaxOrdersPerFeeRecipient < 0 {
+		return nil, errors.New("config.MaxOrdersPerFeeRecipient cannot be negative")
+	}
+	if config.EvictionPolicy == nil {
+		config.EvictionPolicy = ExpirationTimeEvictionPolicy{}
+	}
 
 	// Configure a SlowCounter to be used for increasing max expiration time.
 	slowCounterConfig := slowcounter.Config{
@@ -142,11 +186,16 @@ func New(config Config) (*Watcher, error) {
 		contractAddressToSeenCount: map[common.Address]uint{},
 		orderValidator:             config.OrderValidator,
 		eventDecoder:               decoder,
-		assetDataDecoder:           assetDataDecoder,
 		contractAddresses:          contractAddresses,
 		maxExpirationTime:          big.NewInt(0).Set(config.MaxExpirationTime),
 		maxExpirationCounter:       maxExpirationCounter,
 		maxOrders:                  config.MaxOrders,
+		maxOrdersPerMaker:          config.MaxOrdersPerMaker,
+		maxOrdersPerFeeRecipient:   config.MaxOrdersPerFeeRecipient,
+		makerToOrderCount:          map[common.Address]int{},
+		feeRecipientToOrderCount:   map[common.Address]int{},
+		evictionPolicy:             config.EvictionPolicy,
+		staticCallOrderHashes:      map[common.Hash]struct{}{},
 	}
 
 	// Check if any orders need to be removed right away due to high expiration
@@ -162,7 +211,7 @@ func New(config Config) (*Watcher, error) {
 		return nil, err
 	}
 	for _, order := range orders {
-		err := w.setupInMemoryOrderState(order.SignedOrder)
+		err := w.setupInMemoryOrderState(order)
 		if err != nil {
 			return nil, err
 		}
@@ -191,8 +240,9 @@ func (w *Watcher) Watch(ctx context.Context) error {
 	// A waitgroup lets us wait for all goroutines to exit.
 	wg := &sync.WaitGroup{}
 
-	// Start four independent goroutines. The main loop, cleanup loop, removed orders
-	// checker and max expirationTime checker. Use four separate channels to communicate errors.
+	// Start five independent goroutines. The main loop, cleanup loop, removed orders
+	// checker, max expirationTime checker and StaticCall order re-validator. Use five
+	// separate channels to communicate errors.
 	mainLoopErrChan := make(chan error, 1)
 	wg.Add(1)
 	go func() {
@@ -217,6 +267,12 @@ func (w *Watcher) Watch(ctx context.Context) error {
 		defer wg.Done()
 		removedCheckerLoopErrChan <- w.removedCheckerLoop(innerCtx)
 	}()
+	staticCallRevalidationLoopErrChan := make(chan error, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		staticCallRevalidationLoopErrChan <- w.staticCallRevalidationLoop(innerCtx)
+	}()
 
 	// If any error channel returns a non-nil error, we cancel the inner context
 	// and return the error. Note that this means we only return the first error
@@ -242,6 +298,11 @@ func (w *Watcher) Watch(ctx context.Context) error {
 			cancel()
 			return err
 		}
+	case err := <-staticCallRevalidationLoopErrChan:
+		if err != nil {
+			cancel()
+			return err
+		}
 	}
 
 	// Wait for all goroutines to exit. If we reached here it means we are done
@@ -327,6 +388,21 @@ func (w *Watcher) removedCheckerLoop(ctx context.Context) error {
 	}
 }
 
+func (w *Watcher) staticCallRevalidationLoop(ctx context.Context) error {
+	ticker := time.NewTicker(staticCallRevalidationInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			ticker.Stop()
+			return nil
+		case <-ticker.C:
+			if err := w.revalidateStaticCallOrders(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 func (w *Watcher) generateExpirationOrderEventsIfAny(ordersColTxn *db.Transaction, latestBlockTimestamp time.Time) ([]*zeroex.OrderEvent, error) {
 	orderEvents := []*zeroex.OrderEvent{}
 
@@ -766,6 +842,48 @@ func (w *Watcher) Cleanup(ctx context.Context, lastUpdatedBuffer time.Duration)
 	return nil
 }
 
+// revalidateStaticCallOrders re-validates all orders with StaticCall asset data.
+// Since there are no contract events which signal that the result of a static
+// call has changed, this is the only way to keep these orders up-to-date.
+func (w *Watcher) revalidateStaticCallOrders(ctx context.Context) error {
+	ordersColTxn := w.meshDB.Orders.OpenTransaction()
+	defer func() {
+		_ = ordersColTxn.Discard()
+	}()
+	orderHashToDBOrder := map[common.Hash]*meshdb.Order{}
+	orderHashToEvents := map[common.Hash][]*zeroex.ContractEvent{} // No events when re-validating StaticCall orders
+	for _, orderHash := range w.staticCallOrderHashesSnapshot() {
+		order := w.findOrder(orderHash)
+		if order == nil {
+			continue
+		}
+		orderHashToDBOrder[order.Hash] = order
+		orderHashToEvents[order.Hash] = []*zeroex.ContractEvent{}
+	}
+	if len(orderHashToDBOrder) == 0 {
+		return nil
+	}
+	// This timeout of 1min is for limiting how long this call should block at the ETH RPC rate limiter
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+	orderEvents, err := w.generateOrderEventsIfChanged(ctx, ordersColTxn, orderHashToDBOrder, orderHashToEvents, rpc.LatestBlockNumber)
+	if err != nil {
+		return err
+	}
+
+	if err := ordersColTxn.Commit(); err != nil {
+		logger.WithFields(logger.Fields{
+			"error": err.Error(),
+		}).Error("Failed to commit orders collection transaction")
+	}
+
+	if len(orderEvents) > 0 {
+		w.orderFeed.Send(orderEvents)
+	}
+
+	return nil
+}
+
 func (w *Watcher) permanentlyDeleteStaleRemovedOrders(ctx context.Context) error {
 	removedOrders, err := w.meshDB.FindRemovedOrders()
 	if err != nil {
@@ -835,6 +953,19 @@ func (w *Watcher) Add(orderInfo *ordervalidator.AcceptedOrderInfo, pinned bool)
 		return nil
 	}
 
+	// Pinned orders are not affected by quotas.
+	if !pinned {
+		if err := w.checkQuotas(orderInfo.SignedOrder); err != nil {
+			return err
+		}
+	}
+
+	// Parse the maker asset data once so that it doesn't need to be parsed again
+	// whenever it is needed later on.
+	parsedMakerAssetData, err := meshdb.ParseAssetData(orderInfo.SignedOrder.MakerAssetData)
+	if err != nil {
+		return err
+	}
 	order := &meshdb.Order{
 		Hash:                     orderInfo.OrderHash,
 		SignedOrder:              orderInfo.SignedOrder,
@@ -842,8 +973,9 @@ func (w *Watcher) Add(orderInfo *ordervalidator.AcceptedOrderInfo, pinned bool)
 		FillableTakerAssetAmount: orderInfo.FillableTakerAssetAmount,
 		IsRemoved:                false,
 		IsPinned:                 pinned,
+		ParsedMakerAssetData:     parsedMakerAssetData,
 	}
-	err := txn.Insert(order)
+	err = txn.Insert(order)
 	if err != nil {
 		if _, ok := err.(db.AlreadyExistsError); ok {
 			// If we're already watching the order, that's fine in this case. Don't
@@ -856,7 +988,7 @@ func (w *Watcher) Add(orderInfo *ordervalidator.AcceptedOrderInfo, pinned bool)
 		return err
 	}
 
-	err = w.setupInMemoryOrderState(orderInfo.SignedOrder)
+	err = w.setupInMemoryOrderState(order)
 	if err != nil {
 		return err
 	}
@@ -872,9 +1004,21 @@ func (w *Watcher) Add(orderInfo *ordervalidator.AcceptedOrderInfo, pinned bool)
 	return nil
 }
 
+// checkQuotas returns ErrMakerQuotaExceeded or ErrFeeRecipientQuotaExceeded
+// if storing the given order would exceed the maker or fee recipient quota.
+func (w *Watcher) checkQuotas(signedOrder *zeroex.SignedOrder) error {
+	if w.maxOrdersPerMaker > 0 && w.makerToOrderCount[signedOrder.MakerAddress] >= w.maxOrdersPerMaker {
+		return ErrMakerQuotaExceeded
+	}
+	if w.maxOrdersPerFeeRecipient > 0 && w.feeRecipientToOrderCount[signedOrder.FeeRecipientAddress] >= w.maxOrdersPerFeeRecipient {
+		return ErrFeeRecipientQuotaExceeded
+	}
+	return nil
+}
+
 func (w *Watcher) trimOrdersAndFireEvents() error {
 	targetMaxOrders := int(maxOrdersTrimRatio * float64(w.maxOrders))
-	newMaxExpirationTime, removedOrders, err := w.meshDB.TrimOrdersByExpirationTime(targetMaxOrders)
+	newMaxExpirationTime, removedOrders, err := w.evictionPolicy.Evict(w.meshDB, targetMaxOrders)
 	if err != nil {
 		return err
 	}
@@ -897,7 +1041,7 @@ func (w *Watcher) trimOrdersAndFireEvents() error {
 		// Remove in-memory state
 		expirationTimestamp := time.Unix(removedOrder.SignedOrder.ExpirationTimeSeconds.Int64(), 0)
 		w.expirationWatcher.Remove(expirationTimestamp, removedOrder.Hash.Hex())
-		err = w.removeAssetDataAddressFromEventDecoder(removedOrder.SignedOrder.MakerAssetData)
+		parsedMakerAssetData, err := removedOrder.GetParsedMakerAssetData()
 		if err != nil {
 			// This should never happen since the same error would have happened when adding
 			// the assetData to the EventDecoder.
@@ -907,6 +1051,13 @@ func (w *Watcher) trimOrdersAndFireEvents() error {
 			}).Error("Unexpected error when trying to remove an assetData from decoder")
 			return err
 		}
+		w.removeAssetDataAddressFromEventDecoder(parsedMakerAssetData)
+		if !removedOrder.IsRemoved {
+			// Removed orders were already subtracted from the counts when they
+			// were unwatched.
+			w.decrementOrderCounts(removedOrder.SignedOrder)
+		}
+		w.removeStaticCallOrder(removedOrder.Hash)
 	}
 	if newMaxExpirationTime.Cmp(w.maxExpirationTime) == -1 {
 		// Decrease the max expiration time to account for the fact that orders were
@@ -929,21 +1080,23 @@ func (w *Watcher) MaxExpirationTime() *big.Int {
 	return w.maxExpirationTime
 }
 
-func (w *Watcher) setupInMemoryOrderState(signedOrder *zeroex.SignedOrder) error {
-	orderHash, err := signedOrder.ComputeOrderHash()
+func (w *Watcher) setupInMemoryOrderState(order *meshdb.Order) error {
+	w.eventDecoder.AddKnownExchange(order.SignedOrder.ExchangeAddress)
+
+	parsedMakerAssetData, err := order.GetParsedMakerAssetData()
 	if err != nil {
 		return err
 	}
-
-	w.eventDecoder.AddKnownExchange(signedOrder.ExchangeAddress)
-
-	err = w.addAssetDataAddressToEventDecoder(signedOrder.MakerAssetData)
-	if err != nil {
+	w.addAssetDataAddressToEventDecoder(parsedMakerAssetData)
+	if !order.IsRemoved {
+		w.incrementOrderCounts(order.SignedOrder)
+	}
+	if err := w.addStaticCallOrderIfNeeded(order, parsedMakerAssetData); err != nil {
 		return err
 	}
 
-	expirationTimestamp := time.Unix(signedOrder.ExpirationTimeSeconds.Int64(), 0)
-	w.expirationWatcher.Add(expirationTimestamp, orderHash.Hex())
+	expirationTimestamp := time.Unix(order.SignedOrder.ExpirationTimeSeconds.Int64(), 0)
+	w.expirationWatcher.Add(expirationTimestamp, order.Hash.Hex())
 
 	return nil
 }
@@ -1105,6 +1258,9 @@ func (w *Watcher) updateOrderDBEntry(u orderUpdater, order *meshdb.Order) {
 }
 
 func (w *Watcher) rewatchOrder(u orderUpdater, order *meshdb.Order, fillableTakerAssetAmount *big.Int) {
+	if order.IsRemoved {
+		w.incrementOrderCounts(order.SignedOrder)
+	}
 	order.IsRemoved = false
 	order.LastUpdated = time.Now().UTC()
 	order.FillableTakerAssetAmount = fillableTakerAssetAmount
@@ -1122,6 +1278,9 @@ func (w *Watcher) rewatchOrder(u orderUpdater, order *meshdb.Order, fillableTake
 }
 
 func (w *Watcher) unwatchOrder(u orderUpdater, order *meshdb.Order, newFillableAmount *big.Int) {
+	if !order.IsRemoved {
+		w.decrementOrderCounts(order.SignedOrder)
+	}
 	order.IsRemoved = true
 	order.LastUpdated = time.Now().UTC()
 	order.FillableTakerAssetAmount = newFillableAmount
@@ -1157,7 +1316,7 @@ func (w *Watcher) permanentlyDeleteOrder(deleter orderDeleter, order *meshdb.Ord
 	}
 
 	// After permanently deleting an order, we also remove it's assetData from the Decoder
-	err = w.removeAssetDataAddressFromEventDecoder(order.SignedOrder.MakerAssetData)
+	parsedMakerAssetData, err := order.GetParsedMakerAssetData()
 	if err != nil {
 		// This should never happen since the same error would have happened when adding
 		// the assetData to the EventDecoder.
@@ -1167,6 +1326,11 @@ func (w *Watcher) permanentlyDeleteOrder(deleter orderDeleter, order *meshdb.Ord
 		}).Error("Unexpected error when trying to remove an assetData from decoder")
 		return err
 	}
+	w.removeAssetDataAddressFromEventDecoder(parsedMakerAssetData)
+	if !order.IsRemoved {
+		w.decrementOrderCounts(order.SignedOrder)
+	}
+	w.removeStaticCallOrder(order.Hash)
 
 	return nil
 }
@@ -1187,117 +1351,124 @@ func (w *Watcher) checkDecodeErr(err error, eventType string) bool {
 	return false
 }
 
-// addAssetDataAddressToEventDecoder decodes the supplied AssetData and figures out which
-// tokens (address & token standard) it contains. It then registers these token addresses
-// with the contract events decoder so that knows how to properly decode events from that
-// contract address. This is necessary because different token standards share identical
-// event signatures but use different parameter names (see: decoder.go for more context).
-// In order to unregister token addresses when the last order involving it is deleted, we
-// also keep track of the number of tokens seen referencing a particular token address.
-func (w *Watcher) addAssetDataAddressToEventDecoder(assetData []byte) error {
-	assetDataName, err := w.assetDataDecoder.GetName(assetData)
-	if err != nil {
-		return err
-	}
-	switch assetDataName {
-	case "ERC20Token":
-		var decodedAssetData zeroex.ERC20AssetData
-		err := w.assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return err
-		}
-		w.eventDecoder.AddKnownERC20(decodedAssetData.Address)
-		w.contractAddressToSeenCount[decodedAssetData.Address] = w.contractAddressToSeenCount[decodedAssetData.Address] + 1
+// addAssetDataAddressToEventDecoder figures out which tokens (address & token
+// standard) the supplied parsed AssetData contains. It then registers these
+// token addresses with the contract events decoder so that knows how to
+// properly decode events from that contract address. This is necessary because
+// different token standards share identical event signatures but use different
+// parameter names (see: decoder.go for more context). In order to unregister
+// token addresses when the last order involving it is deleted, we also keep
+// track of the number of tokens seen referencing a particular token address.
+func (w *Watcher) addAssetDataAddressToEventDecoder(parsedAssetData *meshdb.ParsedAssetData) {
+	switch parsedAssetData.Name {
+	case "ERC20Token", "ERC20Bridge":
+		// For ERC20Bridge, the bridge transfers the token at Address. Tracking
+		// its events lets us notice when the maker's (usually the bridge's)
+		// balance changes.
+		w.eventDecoder.AddKnownERC20(parsedAssetData.Address)
+		w.contractAddressToSeenCount[parsedAssetData.Address] = w.contractAddressToSeenCount[parsedAssetData.Address] + 1
 	case "ERC721Token":
-		var decodedAssetData zeroex.ERC721AssetData
-		err := w.assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return err
-		}
-		w.eventDecoder.AddKnownERC721(decodedAssetData.Address)
-		w.contractAddressToSeenCount[decodedAssetData.Address] = w.contractAddressToSeenCount[decodedAssetData.Address] + 1
+		w.eventDecoder.AddKnownERC721(parsedAssetData.Address)
+		w.contractAddressToSeenCount[parsedAssetData.Address] = w.contractAddressToSeenCount[parsedAssetData.Address] + 1
 	case "ERC1155Assets":
-		var decodedAssetData zeroex.ERC1155AssetData
-		err := w.assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return err
-		}
-		w.eventDecoder.AddKnownERC1155(decodedAssetData.Address)
-		w.contractAddressToSeenCount[decodedAssetData.Address] = w.contractAddressToSeenCount[decodedAssetData.Address] + 1
+		w.eventDecoder.AddKnownERC1155(parsedAssetData.Address)
+		w.contractAddressToSeenCount[parsedAssetData.Address] = w.contractAddressToSeenCount[parsedAssetData.Address] + 1
 	case "MultiAsset":
-		var decodedAssetData zeroex.MultiAssetData
-		err := w.assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return err
-		}
-		for _, assetData := range decodedAssetData.NestedAssetData {
-			if err := w.addAssetDataAddressToEventDecoder(assetData); err != nil {
-				return err
-			}
+		for _, nestedAssetData := range parsedAssetData.NestedAssetData {
+			w.addAssetDataAddressToEventDecoder(nestedAssetData)
 		}
-	default:
-		return fmt.Errorf("unrecognized assetData type name found: %s", assetDataName)
 	}
-	return nil
 }
 
 // Whenever we delete an order from the DB, we must also decrement the count of orders
 // involving a specific token address. We therefore call this method which decrements the
 // count, and if it reaches 0 for a given token, it removes the token address from the
 // contract event decoder.
-func (w *Watcher) removeAssetDataAddressFromEventDecoder(assetData []byte) error {
-	assetDataName, err := w.assetDataDecoder.GetName(assetData)
-	if err != nil {
-		return err
-	}
-	switch assetDataName {
-	case "ERC20Token":
-		var decodedAssetData zeroex.ERC20AssetData
-		err := w.assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return err
-		}
-		w.contractAddressToSeenCount[decodedAssetData.Address] = w.contractAddressToSeenCount[decodedAssetData.Address] - 1
-		if w.contractAddressToSeenCount[decodedAssetData.Address] == 0 {
-			w.eventDecoder.RemoveKnownERC20(decodedAssetData.Address)
+func (w *Watcher) removeAssetDataAddressFromEventDecoder(parsedAssetData *meshdb.ParsedAssetData) {
+	switch parsedAssetData.Name {
+	case "ERC20Token", "ERC20Bridge":
+		w.contractAddressToSeenCount[parsedAssetData.Address] = w.contractAddressToSeenCount[parsedAssetData.Address] - 1
+		if w.contractAddressToSeenCount[parsedAssetData.Address] == 0 {
+			w.eventDecoder.RemoveKnownERC20(parsedAssetData.Address)
 		}
 	case "ERC721Token":
-		var decodedAssetData zeroex.ERC721AssetData
-		err := w.assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return err
-		}
-		w.contractAddressToSeenCount[decodedAssetData.Address] = w.contractAddressToSeenCount[decodedAssetData.Address] - 1
-		if w.contractAddressToSeenCount[decodedAssetData.Address] == 0 {
-			w.eventDecoder.RemoveKnownERC721(decodedAssetData.Address)
+		w.contractAddressToSeenCount[parsedAssetData.Address] = w.contractAddressToSeenCount[parsedAssetData.Address] - 1
+		if w.contractAddressToSeenCount[parsedAssetData.Address] == 0 {
+			w.eventDecoder.RemoveKnownERC721(parsedAssetData.Address)
 		}
 	case "ERC1155Assets":
-		var decodedAssetData zeroex.ERC1155AssetData
-		err := w.assetDataDecoder.Decode(assetData, &decodedAssetData)
-		if err != nil {
-			return err
-		}
-		w.contractAddressToSeenCount[decodedAssetData.Address] = w.contractAddressToSeenCount[decodedAssetData.Address] - 1
-		if w.contractAddressToSeenCount[decodedAssetData.Address] == 0 {
-			w.eventDecoder.RemoveKnownERC1155(decodedAssetData.Address)
+		w.contractAddressToSeenCount[parsedAssetData.Address] = w.contractAddressToSeenCount[parsedAssetData.Address] - 1
+		if w.contractAddressToSeenCount[parsedAssetData.Address] == 0 {
+			w.eventDecoder.RemoveKnownERC1155(parsedAssetData.Address)
 		}
 	case "MultiAsset":
-		var decodedAssetData zeroex.MultiAssetData
-		err := w.assetDataDecoder.Decode(assetData, &decodedAssetData)
+		for _, nestedAssetData := range parsedAssetData.NestedAssetData {
+			w.removeAssetDataAddressFromEventDecoder(nestedAssetData)
+		}
+	}
+}
+
+// addStaticCallOrderIfNeeded starts periodically re-validating the given order
+// if its maker or taker asset data contains StaticCall asset data.
+func (w *Watcher) addStaticCallOrderIfNeeded(order *meshdb.Order, parsedMakerAssetData *meshdb.ParsedAssetData) error {
+	containsStaticCall := parsedMakerAssetData.ContainsStaticCall()
+	if !containsStaticCall {
+		parsedTakerAssetData, err := meshdb.ParseAssetData(order.SignedOrder.TakerAssetData)
 		if err != nil {
 			return err
 		}
-		for _, assetData := range decodedAssetData.NestedAssetData {
-			if err := w.removeAssetDataAddressFromEventDecoder(assetData); err != nil {
-				return err
-			}
-		}
-	default:
-		return fmt.Errorf("unrecognized assetData type name found: %s", assetDataName)
+		containsStaticCall = parsedTakerAssetData.ContainsStaticCall()
+	}
+	if containsStaticCall {
+		w.staticCallOrdersMu.Lock()
+		defer w.staticCallOrdersMu.Unlock()
+		w.staticCallOrderHashes[order.Hash] = struct{}{}
 	}
 	return nil
 }
 
+// removeStaticCallOrder stops periodically re-validating the given order. It
+// must be called whenever an order is permanently deleted from the database.
+func (w *Watcher) removeStaticCallOrder(orderHash common.Hash) {
+	w.staticCallOrdersMu.Lock()
+	defer w.staticCallOrdersMu.Unlock()
+	delete(w.staticCallOrderHashes, orderHash)
+}
+
+// staticCallOrderHashesSnapshot returns the hashes of all orders which are
+// periodically re-validated because they contain StaticCall asset data.
+func (w *Watcher) staticCallOrderHashesSnapshot() []common.Hash {
+	w.staticCallOrdersMu.Lock()
+	defer w.staticCallOrdersMu.Unlock()
+	orderHashes := make([]common.Hash, 0, len(w.staticCallOrderHashes))
+	for orderHash := range w.staticCallOrderHashes {
+		orderHashes = append(orderHashes, orderHash)
+	}
+	return orderHashes
+}
+
+// incrementOrderCounts increments the number of watched orders for the maker and
+// fee recipient of the given order. These counts are used to enforce the maker
+// and fee recipient quotas. Orders that have been marked as removed are not
+// counted.
+func (w *Watcher) incrementOrderCounts(signedOrder *zeroex.SignedOrder) {
+	w.makerToOrderCount[signedOrder.MakerAddress]++
+	w.feeRecipientToOrderCount[signedOrder.FeeRecipientAddress]++
+}
+
+// decrementOrderCounts is the inverse of incrementOrderCounts. It must be called
+// whenever a watched order is marked as removed or deleted from the database.
+func (w *Watcher) decrementOrderCounts(signedOrder *zeroex.SignedOrder) {
+	w.makerToOrderCount[signedOrder.MakerAddress]--
+	if w.makerToOrderCount[signedOrder.MakerAddress] <= 0 {
+		delete(w.makerToOrderCount, signedOrder.MakerAddress)
+	}
+	w.feeRecipientToOrderCount[signedOrder.FeeRecipientAddress]--
+	if w.feeRecipientToOrderCount[signedOrder.FeeRecipientAddress] <= 0 {
+		delete(w.feeRecipientToOrderCount, signedOrder.FeeRecipientAddress)
+	}
+}
+
 func (w *Watcher) decreaseMaxExpirationTimeIfNeeded() error {
 	if orderCount, err := w.meshDB.Orders.Count(); err != nil {
 		return err
@@ -783,6 +783,127 @@ func TestOrderWatcherDecreaseExpirationTime(t *testing.T) {
 	}
 }
 
+func TestOrderWatcherMakerAndFeeRecipientQuotas(t *testing.T) {
+	if !serialTestsEnabled {
+		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
+	}
+
+	teardownSubTest := setupSubTest(t)
+	defer teardownSubTest(t)
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/" + uuid.New().String())
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	orderWatcher := setupOrderWatcher(ctx, t, ethClient, meshDB)
+	orderWatcher.maxOrdersPerMaker = 2
+
+	// Fill up the quota for makerAddress.
+	for i := 0; i < orderWatcher.maxOrdersPerMaker; i++ {
+		signedOrder := scenario.CreateSignedTestOrderWithExpirationTime(t, ethClient, makerAddress, takerAddress, time.Now().Add(10*time.Minute+time.Duration(i)*time.Minute))
+		watchOrder(t, orderWatcher, signedOrder)
+	}
+
+	// The next order from makerAddress should be rejected unless it is pinned.
+	signedOrder := scenario.CreateSignedTestOrderWithExpirationTime(t, ethClient, makerAddress, takerAddress, time.Now().Add(20*time.Minute))
+	assert.Equal(t, ErrMakerQuotaExceeded, addOrder(t, orderWatcher, signedOrder, false))
+	require.NoError(t, addOrder(t, orderWatcher, signedOrder, true))
+
+	// Orders from other makers should not be affected.
+	otherMakerOrder := scenario.CreateSignedTestOrderWithExpirationTime(t, ethClient, takerAddress, makerAddress, time.Now().Add(10*time.Minute))
+	watchOrder(t, orderWatcher, otherMakerOrder)
+
+	// All orders so far have the same fee recipient. Pinned orders count towards
+	// the quota.
+	orderWatcher.maxOrdersPerMaker = 0
+	orderWatcher.maxOrdersPerFeeRecipient = 4
+	signedOrder = scenario.CreateSignedTestOrderWithExpirationTime(t, ethClient, takerAddress, makerAddress, time.Now().Add(11*time.Minute))
+	assert.Equal(t, ErrFeeRecipientQuotaExceeded, addOrder(t, orderWatcher, signedOrder, false))
+
+	var storedOrders []*meshdb.Order
+	require.NoError(t, meshDB.Orders.FindAll(&storedOrders))
+	assert.Len(t, storedOrders, 4)
+}
+
+func TestOrderWatcherMakerQuotaExcludesRemovedOrders(t *testing.T) {
+	if !serialTestsEnabled {
+		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
+	}
+
+	teardownSubTest := setupSubTest(t)
+	defer teardownSubTest(t)
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/" + uuid.New().String())
+	require.NoError(t, err)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	signedOrder := scenario.CreateZRXForWETHSignedTestOrder(t, ethClient, makerAddress, takerAddress, wethAmount, zrxAmount)
+	orderWatcher := setupOrderWatcher(ctx, t, ethClient, meshDB)
+	orderWatcher.maxOrdersPerMaker = 1
+	watchOrder(t, orderWatcher, signedOrder)
+	orderEventsChan := make(chan []*zeroex.OrderEvent, 10)
+	orderWatcher.Subscribe(orderEventsChan)
+
+	// Cancel the only order from makerAddress.
+	opts := &bind.TransactOpts{
+		From:   makerAddress,
+		Signer: scenario.GetTestSignerFn(makerAddress),
+	}
+	txn, err := exchange.CancelOrder(opts, signedOrder.ConvertToOrderWithoutExchangeAddress())
+	require.NoError(t, err)
+	waitTxnSuccessfullyMined(t, ethClient, txn)
+	orderEvents := waitForOrderEvents(t, orderEventsChan, 1, 4*time.Second)
+	require.Len(t, orderEvents, 1)
+	assert.Equal(t, zeroex.ESOrderCancelled, orderEvents[0].EndState)
+
+	// The cancelled order is still stored but should no longer count towards
+	// the quota.
+	newOrder := scenario.CreateSignedTestOrderWithExpirationTime(t, ethClient, makerAddress, takerAddress, time.Now().Add(10*time.Minute))
+	require.NoError(t, addOrder(t, orderWatcher, newOrder, false))
+}
+
+func TestOrderWatcherFairShareByMakerEviction(t *testing.T) {
+	if !serialTestsEnabled {
+		t.Skip("Serial tests (tests which cannot run in parallel) are disabled. You can enable them with the --serial flag")
+	}
+
+	teardownSubTest := setupSubTest(t)
+	defer teardownSubTest(t)
+	meshDB, err := meshdb.New("/tmp/leveldb_testing/" + uuid.New().String())
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	orderWatcher := setupOrderWatcher(ctx, t, ethClient, meshDB)
+	orderWatcher.maxOrders = 10
+	orderWatcher.evictionPolicy = FairShareByMakerEvictionPolicy{}
+
+	// The other maker's order has the highest expiration time, which means it
+	// would be the first order removed by ExpirationTimeEvictionPolicy.
+	otherMakerOrder := scenario.CreateSignedTestOrderWithExpirationTime(t, ethClient, takerAddress, makerAddress, time.Now().Add(time.Hour))
+	watchOrder(t, orderWatcher, otherMakerOrder)
+	for i := 0; i < orderWatcher.maxOrders-1; i++ {
+		signedOrder := scenario.CreateSignedTestOrderWithExpirationTime(t, ethClient, makerAddress, takerAddress, time.Now().Add(10*time.Minute+time.Duration(i)*time.Minute))
+		watchOrder(t, orderWatcher, signedOrder)
+	}
+
+	orderEventsChan := make(chan []*zeroex.OrderEvent, 2*orderWatcher.maxOrders)
+	orderWatcher.Subscribe(orderEventsChan)
+
+	// The next order should cause an order from makerAddress to be removed.
+	signedOrder := scenario.CreateSignedTestOrderWithExpirationTime(t, ethClient, makerAddress, takerAddress, time.Now().Add(10*time.Minute+1*time.Second))
+	watchOrder(t, orderWatcher, signedOrder)
+	orderEvents := waitForOrderEvents(t, orderEventsChan, 2, 4*time.Second)
+	require.Len(t, orderEvents, 2, "wrong number of order events were fired")
+	assert.Equal(t, zeroex.ESStoppedWatching, orderEvents[0].EndState)
+	assert.Equal(t, makerAddress, orderEvents[0].SignedOrder.MakerAddress)
+	assert.Equal(t, zeroex.ESOrderAdded, orderEvents[1].EndState)
+
+	// The other maker's order should still be stored and the max expiration
+	// time should not have changed.
+	otherMakerOrderHash, err := otherMakerOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	require.NoError(t, meshDB.Orders.FindByID(otherMakerOrderHash.Bytes(), &meshdb.Order{}))
+	assert.Equal(t, constants.UnlimitedExpirationTime, orderWatcher.MaxExpirationTime())
+}
+
 func setupOrderWatcherScenario(ctx context.Context, t *testing.T, ethClient *ethclient.Client, meshDB *meshdb.MeshDB, signedOrder *zeroex.SignedOrder) chan []*zeroex.OrderEvent {
 	orderWatcher := setupOrderWatcher(ctx, t, ethClient, meshDB)
 
@@ -809,6 +930,18 @@ func watchOrder(t *testing.T, orderWatcher *Watcher, signedOrder *zeroex.SignedO
 	require.NoError(t, err)
 }
 
+func addOrder(t *testing.T, orderWatcher *Watcher, signedOrder *zeroex.SignedOrder, pinned bool) error {
+	orderHash, err := signedOrder.ComputeOrderHash()
+	require.NoError(t, err)
+	orderInfo := &ordervalidator.AcceptedOrderInfo{
+		SignedOrder:              signedOrder,
+		OrderHash:                orderHash,
+		FillableTakerAssetAmount: signedOrder.TakerAssetAmount,
+		IsNew:                    true,
+	}
+	return orderWatcher.Add(orderInfo, pinned)
+}
+
 func setupOrderWatcher(ctx context.Context, t *testing.T, ethClient *ethclient.Client, meshDB *meshdb.MeshDB) *Watcher {
 	rateLimiter := ratelimit.NewFakeLimiter()
 	ethRPCClient, err := ethrpcclient.New(constants.GanacheEndpoint, ethereumRPCRequestTimeout, rateLimiter)